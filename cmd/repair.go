@@ -0,0 +1,106 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/hellofresh/klepto/pkg/dumper"
+	"github.com/hellofresh/klepto/pkg/journal"
+)
+
+// RepairOptions represents the command options
+type RepairOptions struct {
+	to          string
+	journalPath string
+}
+
+// NewRepairCmd creates a new repair command
+func NewRepairCmd() *cobra.Command {
+	opts := new(RepairOptions)
+	cmd := &cobra.Command{
+		Use:   "repair",
+		Short: "Restores the target database changes left by an interrupted steal",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return RunRepair(cmd.Context(), opts)
+		},
+	}
+
+	persistentFlags := cmd.PersistentFlags()
+	persistentFlags.StringVarP(&opts.to, "to", "t", "", "Database dsn the interrupted steal was writing to")
+	persistentFlags.StringVar(&opts.journalPath, "journal", "", "Path to the journal left by the interrupted steal (default the journal of the target database in $XDG_STATE_HOME/klepto or ~/.local/state/klepto)")
+
+	return cmd
+}
+
+// RunRepair runs the repair command
+func RunRepair(ctx context.Context, opts *RepairOptions) error {
+	if opts.to == "" {
+		return errors.New("the target database dsn is required, set it with --to")
+	}
+
+	journalPath, err := journalPath(opts.journalPath, opts.to)
+	if err != nil {
+		return err
+	}
+	opts.journalPath = journalPath
+
+	exists, err := journal.Exists(opts.journalPath)
+	if err != nil {
+		return fmt.Errorf("could not check journal: %w", err)
+	}
+	if !exists {
+		log.WithField("journal", opts.journalPath).Info("No journal found, nothing to repair")
+		return nil
+	}
+
+	entries, err := journal.Read(opts.journalPath)
+	if err != nil {
+		return fmt.Errorf("could not read journal: %w", err)
+	}
+
+	target, err := dumper.NewDumper(dumper.ConnOpts{DSN: opts.to, MaxConns: 1}, nil)
+	if err != nil {
+		return fmt.Errorf("error creating dumper: %w", err)
+	}
+	defer func() {
+		if err := target.Close(); err != nil {
+			log.WithError(err).Error("Something is not ok with closing target connection")
+		}
+	}()
+
+	repairer, ok := target.(dumper.Repairer)
+	if !ok {
+		return errors.New("the target database does not support repair")
+	}
+
+	log.WithField("changes", len(entries)).Info("Repairing...")
+	if err := repairer.Repair(ctx, entries); err != nil {
+		return fmt.Errorf("could not repair target database: %w", err)
+	}
+
+	if err := journal.New(opts.journalPath).Clear(); err != nil {
+		return fmt.Errorf("could not clear journal: %w", err)
+	}
+
+	log.Info("Done!")
+
+	return nil
+}
+
+// journalPath returns the journal path set by the user, the default path of the target database otherwise.
+func journalPath(path, dsn string) (string, error) {
+	if path != "" {
+		return path, nil
+	}
+
+	path, err := journal.DefaultPath(dsn)
+	if err != nil {
+		return "", fmt.Errorf("could not find journal: %w", err)
+	}
+
+	return path, nil
+}
@@ -39,6 +39,8 @@ func init() {
 	RootCmd.AddCommand(NewUpdateCmd())
 	RootCmd.AddCommand(NewInitCmd())
 	RootCmd.AddCommand(NewStealCmd())
+	RootCmd.AddCommand(NewValidateCmd())
+	RootCmd.AddCommand(NewRepairCmd())
 
 	log.SetOutput(os.Stderr)
 	log.SetFormatter(&formatter.CliFormatter{})
@@ -1,8 +1,14 @@
 package cmd
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"os"
+	"os/signal"
 	"runtime"
+	"strings"
+	"syscall"
 	"time"
 
 	log "github.com/sirupsen/logrus"
@@ -11,14 +17,23 @@ import (
 	"github.com/hellofresh/klepto/pkg/anonymiser"
 	"github.com/hellofresh/klepto/pkg/config"
 	"github.com/hellofresh/klepto/pkg/dumper"
+	"github.com/hellofresh/klepto/pkg/journal"
+	"github.com/hellofresh/klepto/pkg/metrics"
+	"github.com/hellofresh/klepto/pkg/progress"
 	"github.com/hellofresh/klepto/pkg/reader"
+	"github.com/hellofresh/klepto/pkg/report"
+	"github.com/hellofresh/klepto/pkg/subset"
 
 	// imports dumpers and readers
+	_ "github.com/hellofresh/klepto/pkg/dumper/export"
 	_ "github.com/hellofresh/klepto/pkg/dumper/mysql"
 	_ "github.com/hellofresh/klepto/pkg/dumper/postgres"
 	_ "github.com/hellofresh/klepto/pkg/dumper/query"
+	_ "github.com/hellofresh/klepto/pkg/dumper/sqlite"
+	_ "github.com/hellofresh/klepto/pkg/reader/file"
 	_ "github.com/hellofresh/klepto/pkg/reader/mysql"
 	_ "github.com/hellofresh/klepto/pkg/reader/postgres"
+	_ "github.com/hellofresh/klepto/pkg/reader/sqlite"
 )
 
 type (
@@ -27,13 +42,29 @@ type (
 		configPath string
 		cfgTables  config.Tables
 
-		from        string
-		to          string
-		toRDS       bool
-		concurrency int
-		readOpts    connOpts
-		writeOpts   connOpts
-		dataOnly    bool
+		from         string
+		to           string
+		toRDS        bool
+		concurrency  int
+		readOpts     connOpts
+		writeOpts    connOpts
+		dataOnly     bool
+		failFast     bool
+		fkOrder      bool
+		journalPath  string
+		chunkSize    uint64
+		chunkWorkers int
+		snapshot     bool
+		native       bool
+		schemas      []string
+		excludes     []string
+		schemaMap    map[string]string
+		progress     time.Duration
+		reportPath   string
+		reportFormat string
+		metricsAddr  string
+		salt         string
+		subsetOpts   subset.Opts
 	}
 	connOpts struct {
 		timeout         time.Duration
@@ -56,10 +87,22 @@ func NewStealCmd() *cobra.Command {
 				return err
 			}
 
+			if err := anonymiser.Validate(opts.cfgTables); err != nil {
+				return fmt.Errorf("invalid anonymiser config: %w", err)
+			}
+
+			if opts.reportFormat != report.FormatJSON && opts.reportFormat != report.FormatText {
+				return fmt.Errorf("unsupported report format %q, expected %s or %s", opts.reportFormat, report.FormatJSON, report.FormatText)
+			}
+
+			if opts.snapshot && opts.readOpts.maxConns == 1 {
+				return errors.New("--read-snapshot requires --read-max-conns of at least 2")
+			}
+
 			return nil
 		},
 		RunE: func(cmd *cobra.Command, args []string) error {
-			return RunSteal(opts)
+			return RunSteal(cmd.Context(), opts)
 		},
 	}
 
@@ -73,23 +116,96 @@ func NewStealCmd() *cobra.Command {
 	persistentFlags.DurationVar(&opts.readOpts.maxConnLifetime, "read-conn-lifetime", 0, "Sets the maximum amount of time a connection may be reused on the read database")
 	persistentFlags.IntVar(&opts.readOpts.maxConns, "read-max-conns", 5, "Sets the maximum number of open connections to the read database")
 	persistentFlags.IntVar(&opts.readOpts.maxIdleConns, "read-max-idle-conns", 0, "Sets the maximum number of connections in the idle connection pool for the read database")
+	persistentFlags.Uint64Var(&opts.chunkSize, "read-chunk-size", 0, "Reads the tables with a primary key in chunks of this number of rows, the read timeout applies to each chunk (0 reads each table with a single query)")
+	persistentFlags.BoolVar(&opts.snapshot, "read-snapshot", false, "Reads all the tables from the same consistent snapshot of the source database, requires --read-max-conns of at least 2")
+	persistentFlags.BoolVar(&opts.native, "read-native-structure", false, "Generates the PostgreSQL structure from the catalog instead of running pg_dump, the catalog is also used when pg_dump is not installed")
+	persistentFlags.StringSliceVar(&opts.schemas, "read-schemas", nil, "Reads only the tables of these PostgreSQL schemas, e.g. public,billing (default all the schemas)")
+	persistentFlags.StringSliceVar(&opts.excludes, "read-exclude-schemas", nil, "Does not read the tables of these PostgreSQL schemas")
+	persistentFlags.IntVar(&opts.chunkWorkers, "read-chunk-workers", 1, "Sets the number of chunks of a table read in parallel, only tables with an integer primary key are read in parallel")
 	persistentFlags.DurationVar(&opts.writeOpts.timeout, "write-timeout", 30*time.Second, "Sets the timeout for write operations")
 	persistentFlags.DurationVar(&opts.writeOpts.maxConnLifetime, "write-conn-lifetime", 0, "Sets the maximum amount of time a connection may be reused on the write database")
 	persistentFlags.IntVar(&opts.writeOpts.maxConns, "write-max-conns", 5, "Sets the maximum number of open connections to the write database")
 	persistentFlags.IntVar(&opts.writeOpts.maxIdleConns, "write-max-idle-conns", 0, "Sets the maximum number of connections in the idle connection pool for the write database")
+	persistentFlags.StringToStringVar(&opts.schemaMap, "write-schema-map", nil, "Renames the PostgreSQL schemas on the target database, e.g. billing=archive")
 	persistentFlags.BoolVar(&opts.dataOnly, "data-only", false, "Only steal data; requires that the target database structure already exists")
+	persistentFlags.BoolVar(&opts.failFast, "fail-fast", false, "Stops the steal at the first table failing to be read or dumped, by default the remaining tables are stolen and all the failures reported")
+	persistentFlags.BoolVar(&opts.fkOrder, "foreign-key-order", false, "Loads the tables after the tables they reference so the foreign key constraints stay enabled on the target database, they are only disabled for the tables referencing each other in a cycle")
+	persistentFlags.BoolVar(&opts.subsetOpts.Children, "subset-children", false, "When seed tables are configured, also pull the rows referencing the seed rows")
+	persistentFlags.IntVar(&opts.subsetOpts.MaxRows, "subset-max-rows", 0, "Fails the steal when the subset, held in memory, has more rows than this (0 does not limit it)")
+	persistentFlags.DurationVar(&opts.progress, "progress-interval", 10*time.Second, "Sets the interval the progress of the tables is logged at, on terminals the progress is displayed live instead (0 disables progress reporting)")
+	persistentFlags.StringVar(&opts.reportPath, "report", "", "Path to write the steal report to: the rows stolen from every table, the filters applied, the anonymised columns and the failures")
+	persistentFlags.StringVar(&opts.reportFormat, "report-format", report.FormatJSON, "Sets the report format, json or text")
+	persistentFlags.StringVar(&opts.metricsAddr, "metrics-addr", "", "Address to serve the Prometheus metrics on /metrics and the steal status on /status, e.g. :9090 (disabled by default)")
+	persistentFlags.StringVar(&opts.journalPath, "journal", "", "Path to the journal recording the changes made on the MySQL and PostgreSQL target databases (triggers, foreign keys, settings) until they are reverted, replayed by the repair command after an interrupted steal (default the journal of the target database in $XDG_STATE_HOME/klepto or ~/.local/state/klepto)")
+	persistentFlags.StringVar(&opts.salt, "anonymise-salt", "", "Secret used to derive anonymised values from the original ones, so the same input always gets the same fake value. If not set KLEPTO_ANONYMISE_SALT environment variable value is used.")
 
 	return cmd
 }
 
 // RunSteal is the handler for the rootCmd.
-func RunSteal(opts *StealOptions) (err error) {
+func RunSteal(ctx context.Context, opts *StealOptions) (err error) {
+	if opts.salt == "" {
+		opts.salt = os.Getenv("KLEPTO_ANONYMISE_SALT")
+	}
+
+	startedAt := time.Now()
+
+	// only the dumpers changing the target database record a journal, the other targets have nothing to repair
+	var targetJournal *journal.Journal
+	if dumper.Journaled(opts.to) {
+		opts.journalPath, err = journalPath(opts.journalPath, opts.to)
+		if err != nil {
+			return err
+		}
+
+		exists, err := journal.Exists(opts.journalPath)
+		if err != nil {
+			return fmt.Errorf("could not check journal: %w", err)
+		}
+		if exists {
+			return fmt.Errorf("journal %s was left by an interrupted steal, run `klepto repair` to restore the target database first", opts.journalPath)
+		}
+		targetJournal = journal.New(opts.journalPath)
+	}
+
+	// An interruption cancels the steal, the changes made on the target are still reverted before exiting.
+	// A second interruption exits immediately.
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go func() {
+		<-ctx.Done()
+		stop()
+	}()
+
+	// the tracker is shared by the progress reporter, the report and the metrics server, each one may need it alone
+	var tracker *progress.Tracker
+	if opts.progress > 0 || opts.reportPath != "" || opts.metricsAddr != "" {
+		tracker = progress.NewTracker()
+	}
+
+	if opts.metricsAddr != "" {
+		// the metrics are served until the steal returns, the target is still restored after an interruption
+		metricsCtx, stopMetrics := context.WithCancel(context.WithoutCancel(ctx))
+		defer stopMetrics()
+
+		if err := metrics.Serve(metricsCtx, opts.metricsAddr, tracker); err != nil {
+			return fmt.Errorf("could not serve metrics: %w", err)
+		}
+	}
+
 	source, err := reader.Connect(reader.ConnOpts{
 		DSN:             opts.from,
 		Timeout:         opts.readOpts.timeout,
+		ChunkSize:       opts.chunkSize,
+		ChunkWorkers:    opts.chunkWorkers,
+		Snapshot:        opts.snapshot,
+		NativeStructure: opts.native,
+		Schemas:         opts.schemas,
+		ExcludeSchemas:  opts.excludes,
 		MaxConnLifetime: opts.readOpts.maxConnLifetime,
 		MaxConns:        opts.readOpts.maxConns,
 		MaxIdleConns:    opts.readOpts.maxIdleConns,
+		Progress:        tracker,
 	})
 	if err != nil {
 		return fmt.Errorf("could not connecting to reader: %w", err)
@@ -100,14 +216,27 @@ func RunSteal(opts *StealOptions) (err error) {
 		}
 	}()
 
-	source = anonymiser.NewAnonymiser(source, opts.cfgTables)
+	if len(opts.cfgTables.Seeds()) > 0 {
+		log.Info("Computing subset...")
+		tracker.SetPhase(progress.PhaseSubset)
+		source, err = subset.New(ctx, source, opts.cfgTables, opts.subsetOpts)
+		if err != nil {
+			return fmt.Errorf("could not compute subset: %w", err)
+		}
+	}
+
+	source = anonymiser.NewAnonymiser(source, opts.cfgTables, opts.salt)
 	target, err := dumper.NewDumper(dumper.ConnOpts{
 		DSN:             opts.to,
+		Source:          opts.from,
 		IsRDS:           opts.toRDS,
 		Timeout:         opts.writeOpts.timeout,
 		MaxConnLifetime: opts.writeOpts.maxConnLifetime,
 		MaxConns:        opts.writeOpts.maxConns,
 		MaxIdleConns:    opts.writeOpts.maxIdleConns,
+		Journal:         targetJournal,
+		Progress:        tracker,
+		Schemas:         opts.schemaMap,
 	}, source)
 	if err != nil {
 		return fmt.Errorf("error creating dumper: %w", err)
@@ -116,20 +245,95 @@ func RunSteal(opts *StealOptions) (err error) {
 		if err := target.Close(); err != nil {
 			log.WithError(err).Error("Something is not ok with closing target connection")
 		}
+
+		// the journal is cleared once all the changes made on the target are reverted
+		if targetJournal == nil {
+			return
+		}
+		if exists, _ := journal.Exists(targetJournal.Path()); exists {
+			log.WithField("journal", targetJournal.Path()).Error("The target database was not fully restored, run `klepto repair` to restore it")
+		}
 	}()
 
 	log.Info("Stealing...")
 
-	done := make(chan struct{})
-	defer close(done)
-
 	start := time.Now()
-	if err := target.Dump(done, opts.cfgTables, opts.concurrency, opts.dataOnly); err != nil {
-		return fmt.Errorf("error while dumping: %w", err)
+	stopProgress := reportProgress(ctx, tracker, opts.progress)
+	err = target.Dump(ctx, opts.cfgTables, dumper.DumpOpts{
+		Concurrency:     opts.concurrency,
+		DataOnly:        opts.dataOnly,
+		FailFast:        opts.failFast,
+		ForeignKeyOrder: opts.fkOrder,
+	})
+	stopProgress()
+	err = stealError(ctx, err)
+
+	if opts.reportPath != "" {
+		tables, tablesErr := source.GetTables()
+		if tablesErr != nil {
+			log.WithError(tablesErr).Error("Could not list the tables of the report")
+		}
+
+		r := report.New(startedAt, tables, opts.cfgTables, opts.subsetOpts, tracker, err)
+		if reportErr := r.WriteFile(opts.reportPath, opts.reportFormat); reportErr != nil {
+			if err == nil {
+				return fmt.Errorf("could not write report: %w", reportErr)
+			}
+			log.WithError(reportErr).Error("Could not write report")
+		}
+	}
+
+	if err != nil {
+		return err
 	}
 
-	<-done
 	log.WithField("total_time", time.Since(start)).Info("Done!")
 
 	return nil
 }
+
+// stealError describes the error the dump failed with.
+func stealError(ctx context.Context, err error) error {
+	if ctx.Err() != nil {
+		log.Warn("Steal was interrupted")
+		return fmt.Errorf("steal interrupted: %w", ctx.Err())
+	}
+	if failed := dumper.FailedTables(err); len(failed) > 0 {
+		return fmt.Errorf("failed to steal %d table(s) %s: %w", len(failed), strings.Join(failed, ", "), err)
+	}
+	if err != nil {
+		return fmt.Errorf("error while dumping: %w", err)
+	}
+
+	return nil
+}
+
+// reportProgress reports the progress of the tables until the returned function is called.
+// On terminals the logs are written above the live progress.
+func reportProgress(ctx context.Context, tracker *progress.Tracker, interval time.Duration) func() {
+	// the tracker may only be kept for the report or the metrics, a zero interval disables the progress reporting
+	if tracker == nil || interval <= 0 {
+		return func() {}
+	}
+
+	terminal := progress.IsTerminal(os.Stderr)
+	reporter := progress.NewReporter(tracker, os.Stderr, interval, terminal)
+	if terminal {
+		log.SetOutput(reporter)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		if err := reporter.Run(ctx); err != nil {
+			log.WithError(err).Error("could not report progress")
+		}
+	}()
+
+	return func() {
+		cancel()
+		<-done
+		log.SetOutput(os.Stderr)
+	}
+}
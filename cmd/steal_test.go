@@ -0,0 +1,65 @@
+package cmd
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunStealWithoutProgress(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		scenario    string
+		metricsAddr string
+	}{
+		{scenario: "when only the report needs the progress tracker"},
+		{scenario: "when the metrics need the progress tracker", metricsAddr: "127.0.0.1:0"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.scenario, func(t *testing.T) {
+			t.Parallel()
+
+			dir := t.TempDir()
+			opts := &StealOptions{
+				from:         "file://../fixtures/sqlite_simple.sql?dialect=sqlite",
+				to:           "sqlite://" + filepath.Join(dir, "target.db"),
+				concurrency:  2,
+				readOpts:     connOpts{timeout: 5 * time.Second},
+				writeOpts:    connOpts{timeout: 5 * time.Second},
+				journalPath:  filepath.Join(dir, "journal.jsonl"),
+				reportPath:   filepath.Join(dir, "report.json"),
+				reportFormat: "json",
+				metricsAddr:  test.metricsAddr,
+			}
+
+			require.NoError(t, RunSteal(context.Background(), opts))
+			assert.FileExists(t, opts.reportPath)
+		})
+	}
+}
+
+func TestRunStealWithoutJournal(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	opts := &StealOptions{
+		from:        "file://../fixtures/sqlite_simple.sql?dialect=sqlite",
+		to:          "sqlite://" + filepath.Join(dir, "target.db"),
+		concurrency: 2,
+		readOpts:    connOpts{timeout: 5 * time.Second},
+		writeOpts:   connOpts{timeout: 5 * time.Second},
+		journalPath: filepath.Join(dir, "journal.jsonl"),
+	}
+	require.NoError(t, os.WriteFile(opts.journalPath, []byte(`{"action":"disable_triggers","table":"users"}`+"\n"), 0600))
+
+	// the sqlite dumper does not change the target database, a journal left by another target does not stop it
+	require.NoError(t, RunSteal(context.Background(), opts))
+	assert.FileExists(t, opts.journalPath)
+}
@@ -1,6 +1,7 @@
 package features
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	"os"
@@ -51,11 +52,7 @@ func (s *MysqlTestSuite) TestExample() {
 		s.Assert().NoError(err)
 	}()
 
-	done := make(chan struct{})
-	defer close(done)
-	s.Require().NoError(dmp.Dump(done, config.Tables{}, 4, false), "Failed to dump")
-
-	<-done
+	s.Require().NoError(dmp.Dump(context.Background(), config.Tables{}, dumper.DumpOpts{Concurrency: 4}), "Failed to dump")
 
 	s.assertDatabaseAreTheSame(readDSN, dumpDSN)
 }
@@ -1,6 +1,7 @@
 package features
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	"net/url"
@@ -52,15 +53,75 @@ func (s *PostgresTestSuite) TestExample() {
 	s.Require().NoError(err, "Unable to create dumper")
 	defer dmp.Close()
 
-	done := make(chan struct{})
-	defer close(done)
-	s.Require().NoError(dmp.Dump(done, config.Tables{}, 4, false), "Failed to dump")
+	s.Require().NoError(dmp.Dump(context.Background(), config.Tables{}, dumper.DumpOpts{Concurrency: 4}), "Failed to dump")
 
-	<-done
+	s.assertDatabaseAreTheSame(readDSN, dumpDSN)
+}
+
+func (s *PostgresTestSuite) TestNativeStructure() {
+	readDSN := s.createDatabase("pg_native")
+	dumpDSN := s.createDatabase("pg_native_dump")
+
+	s.loadFixture(readDSN, "pg_simple.sql")
+
+	rdr, err := reader.Connect(reader.ConnOpts{DSN: readDSN, Timeout: s.timeout, NativeStructure: true})
+	s.Require().NoError(err, "Unable to create reader")
+	defer rdr.Close()
+
+	dmp, err := dumper.NewDumper(dumper.ConnOpts{DSN: dumpDSN}, rdr)
+	s.Require().NoError(err, "Unable to create dumper")
+	defer dmp.Close()
+
+	s.Require().NoError(dmp.Dump(context.Background(), config.Tables{}, dumper.DumpOpts{Concurrency: 4}), "Failed to dump")
 
 	s.assertDatabaseAreTheSame(readDSN, dumpDSN)
 }
 
+func (s *PostgresTestSuite) TestSchemas() {
+	readDSN := s.createDatabase("pg_schemas")
+	dumpDSN := s.createDatabase("pg_schemas_dump")
+
+	s.loadFixture(readDSN, "pg_simple.sql")
+
+	source, err := sql.Open("postgres", readDSN)
+	s.Require().NoError(err, "Unable to connect to source db")
+	defer source.Close()
+
+	_, err = source.Exec(`CREATE SCHEMA billing;
+		CREATE TABLE billing.users (id integer PRIMARY KEY, plan text NOT NULL);
+		INSERT INTO billing.users VALUES (1, 'yearly');
+		CREATE SCHEMA audit;
+		CREATE TABLE audit.events (id integer PRIMARY KEY);`)
+	s.Require().NoError(err, "Unable to create schemas")
+
+	rdr, err := reader.Connect(reader.ConnOpts{DSN: readDSN, Timeout: s.timeout, ExcludeSchemas: []string{"audit"}})
+	s.Require().NoError(err, "Unable to create reader")
+	defer rdr.Close()
+
+	tables, err := rdr.GetTables()
+	s.Require().NoError(err, "Unable to get tables")
+	s.Contains(tables, "billing.users")
+	s.NotContains(tables, "audit.events")
+
+	dmp, err := dumper.NewDumper(dumper.ConnOpts{DSN: dumpDSN, Schemas: map[string]string{"billing": "archive"}}, rdr)
+	s.Require().NoError(err, "Unable to create dumper")
+	defer dmp.Close()
+
+	s.Require().NoError(dmp.Dump(context.Background(), config.Tables{}, dumper.DumpOpts{Concurrency: 4}), "Failed to dump")
+
+	target, err := sql.Open("postgres", dumpDSN)
+	s.Require().NoError(err, "Unable to connect to target db")
+	defer target.Close()
+
+	var plan string
+	s.Require().NoError(target.QueryRow("SELECT plan FROM archive.users WHERE id = 1").Scan(&plan))
+	s.Equal("yearly", plan)
+
+	var users int
+	s.Require().NoError(target.QueryRow("SELECT COUNT(*) FROM public.users").Scan(&users))
+	s.Equal(4, users)
+}
+
 func (s *PostgresTestSuite) SetupSuite() {
 	rootDSN, ok := os.LookupEnv("TEST_POSTGRES")
 	if !ok {
@@ -0,0 +1,213 @@
+package features
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+
+	"github.com/hellofresh/klepto/pkg/config"
+	"github.com/hellofresh/klepto/pkg/dumper"
+	_ "github.com/hellofresh/klepto/pkg/dumper/sqlite"
+	"github.com/hellofresh/klepto/pkg/reader"
+	_ "github.com/hellofresh/klepto/pkg/reader/file"
+	_ "github.com/hellofresh/klepto/pkg/reader/sqlite"
+)
+
+type SqliteTestSuite struct {
+	suite.Suite
+	dir     string
+	timeout time.Duration
+}
+
+func TestSqliteTestSuite(t *testing.T) {
+	s := &SqliteTestSuite{timeout: time.Second * 3}
+	suite.Run(t, s)
+}
+
+func (s *SqliteTestSuite) TestExample() {
+	s.steal("simple", true)
+}
+
+func (s *SqliteTestSuite) TestTranslatedStructure() {
+	// without source dsn the structure is translated from the reader columns and keys
+	s.steal("translated", false)
+}
+
+func (s *SqliteTestSuite) TestDumpFile() {
+	expectedPath := path.Join(s.dir, "dump_file.db")
+	dumpPath := path.Join(s.dir, "dump_file_dump.db")
+
+	s.loadFixture(expectedPath, "sqlite_simple.sql")
+
+	source := "file://../fixtures/sqlite_simple.sql?dialect=sqlite"
+	rdr, err := reader.Connect(reader.ConnOpts{DSN: source, Timeout: s.timeout})
+	s.Require().NoError(err, "Unable to create reader")
+	defer func() {
+		err := rdr.Close()
+		s.Assert().NoError(err)
+	}()
+
+	dmp, err := dumper.NewDumper(dumper.ConnOpts{DSN: "sqlite://" + dumpPath, Source: source}, rdr)
+	s.Require().NoError(err, "Unable to create dumper")
+
+	s.Require().NoError(dmp.Dump(context.Background(), config.Tables{}, dumper.DumpOpts{Concurrency: 4}), "Failed to dump")
+	s.Require().NoError(dmp.Close())
+
+	s.assertDatabaseAreTheSame(expectedPath, dumpPath)
+}
+
+func (s *SqliteTestSuite) steal(name string, withSource bool) {
+	readPath := path.Join(s.dir, name+".db")
+	dumpPath := path.Join(s.dir, name+"_dump.db")
+
+	s.loadFixture(readPath, "sqlite_simple.sql")
+
+	rdr, err := reader.Connect(reader.ConnOpts{DSN: "sqlite://" + readPath, Timeout: s.timeout, MaxConns: 2})
+	s.Require().NoError(err, "Unable to create reader")
+	defer func() {
+		err := rdr.Close()
+		s.Assert().NoError(err)
+	}()
+
+	opts := dumper.ConnOpts{DSN: "sqlite://" + dumpPath}
+	if withSource {
+		opts.Source = "sqlite://" + readPath
+	}
+	dmp, err := dumper.NewDumper(opts, rdr)
+	s.Require().NoError(err, "Unable to create dumper")
+
+	s.Require().NoError(dmp.Dump(context.Background(), config.Tables{}, dumper.DumpOpts{Concurrency: 4}), "Failed to dump")
+	s.Require().NoError(dmp.Close())
+
+	s.assertDatabaseAreTheSame(readPath, dumpPath)
+}
+
+func (s *SqliteTestSuite) SetupSuite() {
+	s.dir = s.T().TempDir()
+}
+
+func (s *SqliteTestSuite) loadFixture(file string, fixture string) {
+	data, err := os.ReadFile(path.Join("../fixtures/", fixture))
+	s.Require().NoError(err, "Unable to load fixture file")
+
+	conn, err := sql.Open("sqlite", file)
+	s.Require().NoError(err, "Unable to open db connection to load fixture")
+	defer func() {
+		err := conn.Close()
+		s.Assert().NoError(err)
+	}()
+
+	_, err = conn.Exec(string(data))
+	s.Require().NoError(err, "Unable to execute fixture")
+}
+
+func (s *SqliteTestSuite) assertDatabaseAreTheSame(expectedFile string, dumpFile string) {
+	sourceConn, err := sql.Open("sqlite", expectedFile)
+	s.Require().NoError(err, "Unable to connect to source db")
+	defer func() {
+		err := sourceConn.Close()
+		s.Assert().NoError(err)
+	}()
+
+	targetConn, err := sql.Open("sqlite", dumpFile)
+	s.Require().NoError(err, "Unable to connect to target db")
+	defer func() {
+		err := targetConn.Close()
+		s.Assert().NoError(err)
+	}()
+
+	tables := s.fetchTables(sourceConn)
+	s.Require().Equal(tables, s.fetchTables(targetConn))
+
+	for _, table := range tables {
+		s.Require().Equal(s.fetchForeignKeys(sourceConn, table), s.fetchForeignKeys(targetConn, table))
+		s.compareTable(sourceConn, targetConn, table)
+	}
+}
+
+func (s *SqliteTestSuite) fetchTables(db *sql.DB) []string {
+	rows, err := db.Query("SELECT name FROM sqlite_master WHERE type = 'table' ORDER BY name")
+	s.Require().NoError(err, "Unable to fetch tables")
+	defer rows.Close()
+
+	var tables []string
+	for rows.Next() {
+		var table string
+		s.Require().NoError(rows.Scan(&table), "Unable to fetch table row")
+		tables = append(tables, table)
+	}
+
+	return tables
+}
+
+func (s *SqliteTestSuite) fetchForeignKeys(db *sql.DB, table string) []string {
+	rows, err := db.Query(`SELECT "table", "from", "to" FROM pragma_foreign_key_list(?) ORDER BY id, seq`, table)
+	s.Require().NoError(err, "Unable to fetch foreign keys")
+	defer rows.Close()
+
+	var foreignKeys []string
+	for rows.Next() {
+		var referencedTable, column, referencedColumn string
+		s.Require().NoError(rows.Scan(&referencedTable, &column, &referencedColumn), "Unable to fetch foreign key row")
+		foreignKeys = append(foreignKeys, fmt.Sprintf("%s -> %s.%s", column, referencedTable, referencedColumn))
+	}
+
+	return foreignKeys
+}
+
+func (s *SqliteTestSuite) compareTable(source *sql.DB, target *sql.DB, table string) {
+	assert := s.Require()
+	query := fmt.Sprintf("SELECT * FROM %s ORDER BY 1", table)
+
+	expectedRows, err := source.Query(query)
+	assert.NoError(err, "Unable to query source table")
+	defer func() {
+		err := expectedRows.Close()
+		s.Assert().NoError(err)
+	}()
+
+	rows, err := target.Query(query)
+	assert.NoError(err, "Unable to query target table")
+	defer func() {
+		err := rows.Close()
+		s.Assert().NoError(err)
+	}()
+
+	columns, err := expectedRows.Columns()
+	assert.NoError(err, "Unable to fetch columns")
+
+	for expectedRows.Next() {
+		assert.True(rows.Next(), "target row mismatch")
+
+		expectedFields := make([]interface{}, len(columns))
+		targetFields := make([]interface{}, len(columns))
+		for i := range columns {
+			var sourceValue interface{}
+			expectedFields[i] = &sourceValue
+
+			var targetValue interface{}
+			targetFields[i] = &targetValue
+		}
+
+		assert.NoError(expectedRows.Scan(expectedFields...), "failed to fetch expected rows")
+		assert.NoError(rows.Scan(targetFields...), "failed to fetch target rows")
+
+		// the times are written with their offset, they are compared in UTC
+		for _, fields := range [][]interface{}{expectedFields, targetFields} {
+			for _, field := range fields {
+				if t, ok := (*field.(*interface{})).(time.Time); ok {
+					*field.(*interface{}) = t.UTC()
+				}
+			}
+		}
+
+		assert.Equal(expectedFields, targetFields)
+	}
+	assert.False(rows.Next(), "target has more rows")
+}
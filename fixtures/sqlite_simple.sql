@@ -0,0 +1,41 @@
+CREATE TABLE users
+(
+  id varchar(36) PRIMARY KEY NOT NULL,
+  username varchar(50) NOT NULL,
+  email varchar(255) NOT NULL UNIQUE,
+  active boolean NOT NULL,
+  gender char(1),
+  avatar blob,
+  created_at timestamp
+);
+
+CREATE TABLE orders
+(
+  id varchar(36) PRIMARY KEY NOT NULL,
+  user_id varchar(36) NOT NULL,
+  total decimal(10, 2),
+  created_at timestamp,
+  CONSTRAINT fk_user_id FOREIGN KEY (user_id) REFERENCES users (id)
+);
+
+CREATE TABLE order_items (
+  id integer PRIMARY KEY,
+  order_id varchar(36) NOT NULL,
+  quantity integer NOT NULL,
+  created_at timestamp,
+  CONSTRAINT fk_order_id FOREIGN KEY (order_id) REFERENCES orders (id)
+);
+
+INSERT INTO users VALUES ('0d60a85e-0b90-4482-a14c-108aea2557aa', 'wbo', 'wbo@hellofresh.com', true, 'm', X'89504e47', '2017-01-01 00:00:00');
+INSERT INTO users VALUES ('39240e9f-ae09-4e95-9fd0-a712035c8ad7', 'kp', 'kp@hellofresh.com', true, NULL, NULL, '2017-01-01 00:00:00');
+INSERT INTO users VALUES ('9e4de779-d6a0-44bc-a531-20cdb97178d2', 'lp', 'lp@hellofresh.com', false, 'f', NULL, '2017-01-01 00:00:00');
+INSERT INTO users VALUES ('66a45c1b-19af-4ab5-8747-1b0e2d79339d', 'il', 'il@hellofresh.com', true, 'm', NULL, '2017-01-01 00:00:00');
+
+INSERT INTO orders VALUES ('b9bcd5e1-75e6-412d-be87-278003519717', '66a45c1b-19af-4ab5-8747-1b0e2d79339d', 10.5, '2018-01-01 00:00:00');
+INSERT INTO orders VALUES ('7ee31a7f-5140-483b-8ba1-fa8f116219c0', '66a45c1b-19af-4ab5-8747-1b0e2d79339d', 20, '2018-01-01 00:00:00');
+INSERT INTO orders VALUES ('453f4498-b4e0-485f-94fa-72f233bb7958', '9e4de779-d6a0-44bc-a531-20cdb97178d2', NULL, '2018-01-01 00:00:00');
+INSERT INTO orders VALUES ('f1f7c9c7-bdb7-4626-a5c9-44d8942e52dd', '39240e9f-ae09-4e95-9fd0-a712035c8ad7', 7.25, '2018-01-01 00:00:00');
+
+INSERT INTO order_items VALUES (1, 'b9bcd5e1-75e6-412d-be87-278003519717', 2, '2018-01-01 00:00:00');
+INSERT INTO order_items VALUES (2, '7ee31a7f-5140-483b-8ba1-fa8f116219c0', 1, '2018-01-01 00:00:00');
+INSERT INTO order_items VALUES (3, '453f4498-b4e0-485f-94fa-72f233bb7958', 5, '2018-01-01 00:00:00');
@@ -1,13 +1,25 @@
 package anonymiser
 
 import (
+	"context"
+	"crypto/hmac"
 	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
 	"encoding/hex"
+	"encoding/json"
+	"errors"
 	"fmt"
+	mrand "math/rand"
 	"reflect"
+	"sort"
 	"strconv"
 	"strings"
+	"sync"
+	"time"
 
+	"github.com/corpix/uarand"
+	"github.com/icrowley/fake"
 	log "github.com/sirupsen/logrus"
 
 	"github.com/hellofresh/klepto/pkg/config"
@@ -20,8 +32,6 @@ const (
 	literalPrefix = "literal:"
 	email         = "EmailAddress"
 	username      = "UserName"
-	latitude      = "Latitude"
-	longitude     = "Longitude"
 )
 
 var requireArgs = map[string]bool{
@@ -35,37 +45,85 @@ var requireArgs = map[string]bool{
 	"Year":          true,
 }
 
+// seededFunctions replace the fake functions which output does not only depend on the fake PRNG
+// (e.g. they iterate over a map), so they can be used in keyed mode.
+var seededFunctions = map[string]func(*mrand.Rand) string{
+	"CreditCardType": func(r *mrand.Rand) string {
+		vendors := []string{"American Express", "Discover", "MasterCard", "VISA"}
+		return vendors[r.Intn(len(vendors))]
+	},
+}
+
+// fakeMu guards the global fake PRNGs while they are seeded for keyed anonymisation.
+var fakeMu sync.Mutex
+
 type (
+	// faker is a parsed anonymiser definition of a column.
+	faker struct {
+		fakerType string
+		function  reflect.Value
+		args      []reflect.Value
+		isLiteral bool
+		literal   string
+	}
+
 	anonymiser struct {
 		reader.Reader
 		tables config.Tables
+		// salt is the secret key used to derive values from the original ones,
+		// when empty values are generated randomly.
+		salt []byte
 	}
 )
 
 // NewAnonymiser returns a new anonymiser reader.
-func NewAnonymiser(source reader.Reader, tables config.Tables) reader.Reader {
-	return &anonymiser{source, tables}
+// When salt is not empty the anonymiser runs in keyed mode: every fake value is derived
+// from a keyed hash of the original value, so the same input always maps to the same output.
+func NewAnonymiser(source reader.Reader, tables config.Tables, salt string) reader.Reader {
+	a := &anonymiser{Reader: source, tables: tables}
+	if salt != "" {
+		a.salt = []byte(salt)
+	}
+
+	return a
 }
 
 // ReadTable decorates reader.ReadTable method for anonymising rows published from the reader.Reader
-func (a *anonymiser) ReadTable(tableName string, rowChan chan<- database.Row, opts reader.ReadTableOpt) error {
+func (a *anonymiser) ReadTable(ctx context.Context, tableName string, rowChan chan<- database.Row, opts reader.ReadTableOpt) error {
 	logger := log.WithField("table", tableName)
 	logger.Debug("Loading anonymiser config")
 	table := a.tables.FindByName(tableName)
 	if table == nil {
 		logger.Debug("the table is not configured to be anonymised")
-		return a.Reader.ReadTable(tableName, rowChan, opts)
+		return a.Reader.ReadTable(ctx, tableName, rowChan, opts)
 	}
 
 	if len(table.Anonymise) == 0 {
 		logger.Debug("Skipping anonymiser")
-		return a.Reader.ReadTable(tableName, rowChan, opts)
+		return a.Reader.ReadTable(ctx, tableName, rowChan, opts)
+	}
+
+	fakers, err := parseFakers(table)
+	if err != nil {
+		close(rowChan)
+		return fmt.Errorf("anonymiser: %w", err)
+	}
+
+	columns, err := a.Reader.GetColumns(tableName)
+	if err != nil {
+		close(rowChan)
+		return fmt.Errorf("anonymiser: failed to get columns: %w", err)
+	}
+
+	kinds := make(map[string]reader.ColumnKind, len(columns))
+	for _, c := range columns {
+		kinds[c.Name] = c.Kind()
 	}
 
 	// Create read/write chanel
 	rawChan := make(chan database.Row)
 
-	go func(rowChan chan<- database.Row, rawChan chan database.Row, table *config.Table) {
+	go func(rowChan chan<- database.Row, rawChan chan database.Row) {
 		for {
 			row, more := <-rawChan
 			if !more {
@@ -73,92 +131,254 @@ func (a *anonymiser) ReadTable(tableName string, rowChan chan<- database.Row, op
 				return
 			}
 
-			for column, fakerType := range table.Anonymise {
-				if strings.HasPrefix(fakerType, literalPrefix) {
-					row[column] = strings.TrimPrefix(fakerType, literalPrefix)
-					continue
-				}
-
-				fakerType, args := getTypeArgs(fakerType)
-				faker, found := Functions[fakerType]
-				if !found {
-					logger.WithField("anonymiser", fakerType).Error("Anonymiser is not found")
-					// TODO: actually we should stop the whole process here,
-					// but currently there is no simple way of doing this, so as a workaround
-					// we'll just break dump in case log error will be missed by the user
-					row[column] = fmt.Sprintf("Invalid anonymiser: %s", fakerType)
+			for column, f := range fakers {
+				if f.isLiteral {
+					row[column] = f.literal
 					continue
 				}
 
-				var value string
-				switch fakerType {
-				case email, username:
-					b := make([]byte, 2)
-					rand.Read(b)
-					value = fmt.Sprintf(
-						"%s.%s",
-						faker.Call([]reflect.Value{})[0].String(),
-						hex.EncodeToString(b),
-					)
-				case latitude, longitude:
-					value = fmt.Sprintf("%f", faker.Call(args)[0].Float())
-				default:
-					value = faker.Call(args)[0].String()
-				}
-				row[column] = value
+				row[column] = typedValue(kinds[column], a.fake(f, row[column]))
 			}
 
-			rowChan <- row
+			// the rows are drained until the reader stops, so it is never blocked
+			select {
+			case rowChan <- row:
+			case <-ctx.Done():
+			}
 		}
-	}(rowChan, rawChan, table)
+	}(rowChan, rawChan)
 
-	if err := a.Reader.ReadTable(tableName, rawChan, opts); err != nil {
+	if err := a.Reader.ReadTable(ctx, tableName, rawChan, opts); err != nil {
 		return fmt.Errorf("anonymiser: error while reading table: %w", err)
 	}
 
 	return nil
 }
 
-func getTypeArgs(fakerType string) (string, []reflect.Value) {
-	parts := strings.Split(fakerType, ":")
-	fType := parts[0]
-	if !requireArgs[fType] {
-		return fType, nil
+// fake generates the anonymised value for the given original value.
+func (a *anonymiser) fake(f *faker, original interface{}) string {
+	suffix := make([]byte, 2)
+	if a.salt != nil {
+		sum := a.hash(f.fakerType, f.args, original)
+		copy(suffix, sum[8:])
+
+		seed := int64(binary.BigEndian.Uint64(sum[:8]))
+		if seeded, ok := seededFunctions[f.fakerType]; ok {
+			return seeded(mrand.New(mrand.NewSource(seed)))
+		}
+
+		fakeMu.Lock()
+		defer fakeMu.Unlock()
+		fake.Seed(seed)
+		// UserAgent is picked by uarand which keeps its own PRNG
+		uarand.Default.Seed(seed)
+	} else if f.fakerType == email || f.fakerType == username {
+		rand.Read(suffix)
 	}
 
-	return fType, parseArgs(Functions[fType], parts[1:])
+	switch f.fakerType {
+	case email, username:
+		return fmt.Sprintf("%s.%s", f.function.Call([]reflect.Value{})[0].String(), hex.EncodeToString(suffix))
+	default:
+		return formatValue(f.function.Call(f.args)[0])
+	}
+}
+
+// hash returns the keyed hash of the anonymiser definition and the original value.
+func (a *anonymiser) hash(fakerType string, args []reflect.Value, original interface{}) []byte {
+	mac := hmac.New(sha256.New, a.salt)
+	mac.Write([]byte(fakerType))
+	for _, arg := range args {
+		fmt.Fprintf(mac, ":%v", arg.Interface())
+	}
+	mac.Write([]byte{0})
+
+	switch v := original.(type) {
+	case nil:
+	case []byte:
+		mac.Write(v)
+	case string:
+		mac.Write([]byte(v))
+	case time.Time:
+		mac.Write([]byte(v.UTC().Format(time.RFC3339Nano)))
+	default:
+		fmt.Fprintf(mac, "%v", v)
+	}
+
+	return mac.Sum(nil)
+}
+
+// formatValue converts the value returned by a fake function to its string representation.
+func formatValue(v reflect.Value) string {
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(v.Int(), 10)
+	case reflect.Float32, reflect.Float64:
+		return fmt.Sprintf("%f", v.Float())
+	case reflect.Slice:
+		values := make([]string, v.Len())
+		for i := range values {
+			values[i] = formatValue(v.Index(i))
+		}
+		// slices may be built from map iterations, sort them to keep the output stable
+		sort.Strings(values)
+		return strings.Join(values, ",")
+	default:
+		return v.String()
+	}
+}
+
+// typedValue converts the faked value to the type expected by the column kind,
+// the value is kept as a string when it can not be converted.
+func typedValue(kind reader.ColumnKind, value string) interface{} {
+	switch kind {
+	case reader.KindInteger:
+		if i, err := strconv.ParseInt(value, 10, 64); err == nil {
+			return i
+		}
+	case reader.KindFloat, reader.KindDecimal:
+		if f, err := strconv.ParseFloat(value, 64); err == nil {
+			return f
+		}
+	case reader.KindBool:
+		if b, err := strconv.ParseBool(value); err == nil {
+			return b
+		}
+	case reader.KindJSON:
+		// faked values are not JSON documents, they are stored as JSON strings
+		if b, err := json.Marshal(value); err == nil {
+			return string(b)
+		}
+	}
+
+	return value
 }
 
-func parseArgs(function reflect.Value, values []string) []reflect.Value {
+// Validate checks that every anonymiser of the given tables exists and receives valid arguments.
+func Validate(tables config.Tables) error {
+	var errs []error
+	for _, table := range tables {
+		if _, err := parseFakers(table); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// ValidateSpec checks that the anonymiser of a column exists and receives valid arguments, e.g. `DigitsN:5`.
+func ValidateSpec(spec string) error {
+	_, err := parseFaker(spec)
+	return err
+}
+
+// parseFakers parses the anonymisers of all the table columns.
+func parseFakers(table *config.Table) (map[string]*faker, error) {
+	var errs []error
+	fakers := make(map[string]*faker, len(table.Anonymise))
+	for column, spec := range table.Anonymise {
+		f, err := parseFaker(spec)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("table %q column %q: %w", table.Name, column, err))
+			continue
+		}
+
+		fakers[column] = f
+	}
+
+	return fakers, errors.Join(errs...)
+}
+
+// parseFaker parses an anonymiser definition, e.g. `FirstName`, `DigitsN:5` or `literal:foo`.
+func parseFaker(spec string) (*faker, error) {
+	if strings.HasPrefix(spec, literalPrefix) {
+		return &faker{isLiteral: true, literal: strings.TrimPrefix(spec, literalPrefix)}, nil
+	}
+
+	parts := strings.Split(spec, ":")
+	if strings.EqualFold(parts[0], strings.TrimSuffix(literalPrefix, ":")) {
+		return nil, fmt.Errorf("malformed literal %q, expected %s<value>", spec, literalPrefix)
+	}
+
+	f := &faker{fakerType: parts[0]}
+	function, found := Functions[f.fakerType]
+	if !found {
+		return nil, fmt.Errorf("unknown anonymiser %q", f.fakerType)
+	}
+	f.function = function
+
+	if !requireArgs[f.fakerType] {
+		if len(parts) > 1 {
+			return nil, fmt.Errorf("anonymiser %q does not accept arguments", f.fakerType)
+		}
+		return f, nil
+	}
+
+	args, err := parseArgs(function, parts[1:])
+	if err != nil {
+		return nil, fmt.Errorf("anonymiser %q: %w", f.fakerType, err)
+	}
+	f.args = args
+
+	// some fake functions panic with invalid arguments, e.g. Year when from >= to
+	if err := dryRun(f); err != nil {
+		return nil, fmt.Errorf("anonymiser %q: %w", spec, err)
+	}
+
+	return f, nil
+}
+
+func dryRun(f *faker) (err error) {
+	// the fake PRNG must not be used while a keyed value is being generated
+	fakeMu.Lock()
+	defer fakeMu.Unlock()
+
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("invalid arguments: %v", r)
+		}
+	}()
+	f.function.Call(f.args)
+
+	return nil
+}
+
+func parseArgs(function reflect.Value, values []string) ([]reflect.Value, error) {
 	t := function.Type()
 	argsN := t.NumIn()
+	if len(values) > argsN {
+		return nil, fmt.Errorf("expected at most %d arguments, received %d", argsN, len(values))
+	}
 	if argsN > len(values) {
 		log.WithFields(log.Fields{"expected": argsN, "received": len(values)}).Warn("Not enough arguments passed. Falling back to defaults")
-		values = append(values, make([]string, argsN-len(values))...)
 	}
 
 	argsV := make([]reflect.Value, argsN)
 	for i := 0; i < argsN; i++ {
 		argT := t.In(i)
 		v := reflect.New(argT).Elem()
+		argsV[i] = v
+		if i >= len(values) {
+			continue
+		}
+
 		switch argT.Kind() {
 		case reflect.String:
 			v.SetString(values[i])
 		case reflect.Int:
 			n, err := strconv.ParseInt(values[i], 10, 0)
 			if err != nil {
-				log.WithField("argument", values[i]).Warn("Failed to parse argument as string. Falling back to default")
+				return nil, fmt.Errorf("argument %d %q is not an integer", i+1, values[i])
 			}
 			v.SetInt(n)
 		case reflect.Bool:
 			b, err := strconv.ParseBool(values[i])
 			if err != nil {
-				log.WithField("argument", values[i]).Warn("Failed to parse argument as boolean. Falling back to default")
+				return nil, fmt.Errorf("argument %d %q is not a boolean", i+1, values[i])
 			}
 			v.SetBool(b)
 		}
-
-		argsV[i] = v
 	}
-	return argsV
+
+	return argsV, nil
 }
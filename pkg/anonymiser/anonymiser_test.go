@@ -1,6 +1,7 @@
 package anonymiser
 
 import (
+	"context"
 	"fmt"
 	"strconv"
 	"testing"
@@ -94,33 +95,140 @@ func TestReadTable(t *testing.T) {
 	}
 }
 
+func TestReadTableWithSalt(t *testing.T) {
+	t.Parallel()
+
+	tables := config.Tables{
+		{Name: "users", Anonymise: map[string]string{"column_test": "EmailAddress"}},
+		{Name: "subscribers", Anonymise: map[string]string{"column_test": "EmailAddress"}},
+	}
+
+	readValue := func(anonymiser reader.Reader, tableName string) interface{} {
+		rowChan := make(chan database.Row)
+		defer close(rowChan)
+
+		err := anonymiser.ReadTable(context.Background(), tableName, rowChan, reader.ReadTableOpt{})
+		require.NoError(t, err)
+
+		select {
+		case row := <-rowChan:
+			return row["column_test"]
+		case <-time.After(waitTimeout):
+			require.FailNow(t, "Failing due to timeout")
+		}
+		return nil
+	}
+
+	anonymiser := NewAnonymiser(&mockReader{}, tables, "secret")
+	users := readValue(anonymiser, "users")
+	assert.NotEqual(t, "to_be_anonimised", users)
+	assert.Equal(t, users, readValue(anonymiser, "subscribers"))
+	assert.Equal(t, users, readValue(NewAnonymiser(&mockReader{}, tables, "secret"), "users"))
+	assert.NotEqual(t, users, readValue(NewAnonymiser(&mockReader{}, tables, "other secret"), "users"))
+}
+
+func TestFakeWithSalt(t *testing.T) {
+	t.Parallel()
+
+	a := &anonymiser{salt: []byte("secret")}
+	for fakerType := range Functions {
+		t.Run(fakerType, func(t *testing.T) {
+			spec := fakerType
+			if fakerType == "Year" {
+				spec = "Year:1990:2020"
+			}
+			f, err := parseFaker(spec)
+			require.NoError(t, err)
+
+			value := a.fake(f, "original")
+			assert.Equal(t, value, a.fake(f, "original"))
+			assert.NotContains(t, value, "Value>")
+		})
+	}
+}
+
+func TestValidate(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		scenario  string
+		anonymise map[string]string
+		err       string
+	}{
+		{scenario: "when anonymisers are valid", anonymise: map[string]string{"a": "FirstName", "b": "DigitsN:5", "c": "Password:3:5:true", "d": "literal:foo", "e": "CreditCardNum"}},
+		{scenario: "when anonymiser is unknown", anonymise: map[string]string{"a": "Hello"}, err: `table "test" column "a": unknown anonymiser "Hello"`},
+		{scenario: "when argument is invalid", anonymise: map[string]string{"a": "DigitsN:abc"}, err: `table "test" column "a": anonymiser "DigitsN": argument 1 "abc" is not an integer`},
+		{scenario: "when there are too many arguments", anonymise: map[string]string{"a": "DigitsN:5:6"}, err: `table "test" column "a": anonymiser "DigitsN": expected at most 1 arguments, received 2`},
+		{scenario: "when arguments are not accepted", anonymise: map[string]string{"a": "FirstName:5"}, err: `table "test" column "a": anonymiser "FirstName" does not accept arguments`},
+		{scenario: "when arguments make the anonymiser fail", anonymise: map[string]string{"a": "Year:2020:2010"}, err: `table "test" column "a": anonymiser "Year:2020:2010": invalid arguments`},
+		{scenario: "when literal is malformed", anonymise: map[string]string{"a": "Literal:foo"}, err: `table "test" column "a": malformed literal "Literal:foo", expected literal:<value>`},
+	}
+
+	for _, test := range tests {
+		t.Run(test.scenario, func(t *testing.T) {
+			err := Validate(config.Tables{{Name: "test", Anonymise: test.anonymise}})
+			if test.err == "" {
+				assert.NoError(t, err)
+				return
+			}
+
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), test.err)
+		})
+	}
+}
+
+func TestTypedValue(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		scenario string
+		kind     reader.ColumnKind
+		value    string
+		expected interface{}
+	}{
+		{scenario: "when column is a string", kind: reader.KindString, value: "42", expected: "42"},
+		{scenario: "when column is an integer", kind: reader.KindInteger, value: "42", expected: int64(42)},
+		{scenario: "when column is a decimal", kind: reader.KindDecimal, value: "4.200000", expected: 4.2},
+		{scenario: "when column is a boolean", kind: reader.KindBool, value: "true", expected: true},
+		{scenario: "when column is a JSON document", kind: reader.KindJSON, value: `John "Doe"`, expected: `"John \"Doe\""`},
+		{scenario: "when value can not be converted", kind: reader.KindInteger, value: "John", expected: "John"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.scenario, func(t *testing.T) {
+			assert.Equal(t, test.expected, typedValue(test.kind, test.value))
+		})
+	}
+}
+
 func testWhenAnonymiserIsNotInitialized(t *testing.T, opts reader.ReadTableOpt, tables config.Tables) {
-	anonymiser := NewAnonymiser(&mockReader{}, tables)
+	anonymiser := NewAnonymiser(&mockReader{}, tables, "")
 
 	rowChan := make(chan database.Row, 1)
 	defer close(rowChan)
 
-	err := anonymiser.ReadTable("test", rowChan, opts)
+	err := anonymiser.ReadTable(context.Background(), "test", rowChan, opts)
 	require.NoError(t, err)
 }
 
 func testWhenTableIsNotSetInConfig(t *testing.T, opts reader.ReadTableOpt, tables config.Tables) {
-	anonymiser := NewAnonymiser(&mockReader{}, tables)
+	anonymiser := NewAnonymiser(&mockReader{}, tables, "")
 
 	rowChan := make(chan database.Row, 1)
 	defer close(rowChan)
 
-	err := anonymiser.ReadTable("other_table", rowChan, opts)
+	err := anonymiser.ReadTable(context.Background(), "other_table", rowChan, opts)
 	require.NoError(t, err)
 }
 
 func testWhenColumnIsAnonymised(t *testing.T, opts reader.ReadTableOpt, tables config.Tables) {
-	anonymiser := NewAnonymiser(&mockReader{}, tables)
+	anonymiser := NewAnonymiser(&mockReader{}, tables, "")
 
 	rowChan := make(chan database.Row)
 	defer close(rowChan)
 
-	err := anonymiser.ReadTable("test", rowChan, opts)
+	err := anonymiser.ReadTable(context.Background(), "test", rowChan, opts)
 	require.NoError(t, err)
 
 	timeoutChan := time.After(waitTimeout)
@@ -133,12 +241,12 @@ func testWhenColumnIsAnonymised(t *testing.T, opts reader.ReadTableOpt, tables c
 }
 
 func testWhenColumnIsAnonymisedWithLiteral(t *testing.T, opts reader.ReadTableOpt, tables config.Tables) {
-	anonymiser := NewAnonymiser(&mockReader{}, tables)
+	anonymiser := NewAnonymiser(&mockReader{}, tables, "")
 
 	rowChan := make(chan database.Row)
 	defer close(rowChan)
 
-	err := anonymiser.ReadTable("test", rowChan, opts)
+	err := anonymiser.ReadTable(context.Background(), "test", rowChan, opts)
 	require.NoError(t, err)
 
 	timeoutChan := time.After(waitTimeout)
@@ -151,12 +259,12 @@ func testWhenColumnIsAnonymisedWithLiteral(t *testing.T, opts reader.ReadTableOp
 }
 
 func testWhenColumnIsAnonymisedWithFloatValue(t *testing.T, opts reader.ReadTableOpt, tables config.Tables) {
-	anonymiser := NewAnonymiser(&mockReader{}, tables)
+	anonymiser := NewAnonymiser(&mockReader{}, tables, "")
 
 	rowChan := make(chan database.Row)
 	defer close(rowChan)
 
-	err := anonymiser.ReadTable("test", rowChan, opts)
+	err := anonymiser.ReadTable(context.Background(), "test", rowChan, opts)
 	require.NoError(t, err)
 
 	timeoutChan := time.After(waitTimeout)
@@ -169,30 +277,25 @@ func testWhenColumnIsAnonymisedWithFloatValue(t *testing.T, opts reader.ReadTabl
 }
 
 func testWhenColumnAnonymiserIsInvalid(t *testing.T, opts reader.ReadTableOpt, tables config.Tables) {
-	anonymiser := NewAnonymiser(&mockReader{}, tables)
+	anonymiser := NewAnonymiser(&mockReader{}, tables, "")
 
 	rowChan := make(chan database.Row)
-	defer close(rowChan)
 
-	err := anonymiser.ReadTable("test", rowChan, opts)
-	require.NoError(t, err)
+	err := anonymiser.ReadTable(context.Background(), "test", rowChan, opts)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `unknown anonymiser "Hello"`)
 
-	timeoutChan := time.After(waitTimeout)
-	select {
-	case row := <-rowChan:
-		assert.Equal(t, "Invalid anonymiser: Hello", row["column_test"])
-	case <-timeoutChan:
-		assert.FailNow(t, "Failing due to timeout")
-	}
+	_, more := <-rowChan
+	assert.False(t, more)
 }
 
 func testWhenColumnAnonymiserRequireArgs(t *testing.T, opts reader.ReadTableOpt, tables config.Tables) {
-	anonymiser := NewAnonymiser(&mockReader{}, tables)
+	anonymiser := NewAnonymiser(&mockReader{}, tables, "")
 
 	rowChan := make(chan database.Row)
 	defer close(rowChan)
 
-	err := anonymiser.ReadTable("test", rowChan, opts)
+	err := anonymiser.ReadTable(context.Background(), "test", rowChan, opts)
 	require.NoError(t, err)
 
 	timeoutChan := time.After(waitTimeout)
@@ -206,12 +309,12 @@ func testWhenColumnAnonymiserRequireArgs(t *testing.T, opts reader.ReadTableOpt,
 }
 
 func testWhenColumnAnonymiserRequireMultipleArgs(t *testing.T, opts reader.ReadTableOpt, tables config.Tables) {
-	anonymiser := NewAnonymiser(&mockReader{}, tables)
+	anonymiser := NewAnonymiser(&mockReader{}, tables, "")
 
 	rowChan := make(chan database.Row)
 	defer close(rowChan)
 
-	err := anonymiser.ReadTable("test", rowChan, opts)
+	err := anonymiser.ReadTable(context.Background(), "test", rowChan, opts)
 	require.NoError(t, err)
 
 	timeoutChan := time.After(waitTimeout)
@@ -224,12 +327,12 @@ func testWhenColumnAnonymiserRequireMultipleArgs(t *testing.T, opts reader.ReadT
 }
 
 func testWhenColumnAnonymiserRequireArgsNoValues(t *testing.T, opts reader.ReadTableOpt, tables config.Tables) {
-	anonymiser := NewAnonymiser(&mockReader{}, tables)
+	anonymiser := NewAnonymiser(&mockReader{}, tables, "")
 
 	rowChan := make(chan database.Row)
 	defer close(rowChan)
 
-	err := anonymiser.ReadTable("test", rowChan, opts)
+	err := anonymiser.ReadTable(context.Background(), "test", rowChan, opts)
 	require.NoError(t, err)
 
 	timeoutChan := time.After(waitTimeout)
@@ -242,35 +345,35 @@ func testWhenColumnAnonymiserRequireArgsNoValues(t *testing.T, opts reader.ReadT
 }
 
 func testWhenColumnAnonymiserRequireArgsInvalidValues(t *testing.T, opts reader.ReadTableOpt, tables config.Tables) {
-	anonymiser := NewAnonymiser(&mockReader{}, tables)
+	anonymiser := NewAnonymiser(&mockReader{}, tables, "")
 
 	rowChan := make(chan database.Row)
-	defer close(rowChan)
 
-	err := anonymiser.ReadTable("test", rowChan, opts)
-	require.NoError(t, err)
+	err := anonymiser.ReadTable(context.Background(), "test", rowChan, opts)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `column "column_test1"`)
+	assert.Contains(t, err.Error(), `column "column_test2"`)
 
-	timeoutChan := time.After(waitTimeout)
-	select {
-	case row := <-rowChan:
-		assert.NotEqual(t, "to_be_anonimised", row["column_test1"])
-		assert.NotEqual(t, "to_be_anonimised", row["column_test2"])
-	case <-timeoutChan:
-		assert.FailNow(t, "Failing due to timeout")
-	}
+	_, more := <-rowChan
+	assert.False(t, more)
 }
 
 type mockReader struct{}
 
-func (m *mockReader) GetTables() ([]string, error)        { return []string{"table_test"}, nil }
-func (m *mockReader) GetStructure() (string, error)       { return "", nil }
-func (m *mockReader) GetColumns(string) ([]string, error) { return []string{"column_test"}, nil }
-func (m *mockReader) GetPreamble() (string, error)        { return "", nil }
-func (m *mockReader) Close() error                        { return nil }
+func (m *mockReader) GetTables() ([]string, error)  { return []string{"table_test"}, nil }
+func (m *mockReader) GetStructure() (string, error) { return "", nil }
+func (m *mockReader) GetColumns(string) ([]*reader.Column, error) {
+	return []*reader.Column{{Name: "column_test", Type: "varchar"}}, nil
+}
+func (m *mockReader) GetPrimaryKey(string) (*reader.Key, error)           { return nil, nil }
+func (m *mockReader) GetUniqueKeys(string) ([]*reader.Key, error)         { return nil, nil }
+func (m *mockReader) GetForeignKeys(string) ([]*reader.ForeignKey, error) { return nil, nil }
+func (m *mockReader) GetPreamble() (string, error)                        { return "", nil }
+func (m *mockReader) Close() error                                        { return nil }
 func (m *mockReader) FormatColumn(tbl string, col string) string {
 	return fmt.Sprintf("%s.%s", strconv.Quote(tbl), strconv.Quote(col))
 }
-func (m *mockReader) ReadTable(tableName string, rowChan chan<- database.Row, opts reader.ReadTableOpt) error {
+func (m *mockReader) ReadTable(_ context.Context, tableName string, rowChan chan<- database.Row, opts reader.ReadTableOpt) error {
 	row := make(database.Row)
 	row["column_test"] = "to_be_anonimised"
 	rowChan <- row
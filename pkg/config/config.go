@@ -36,6 +36,8 @@ type (
 		Name string
 		// IgnoreData if set to true, it will dump the table structure without importing data.
 		IgnoreData bool
+		// Seed if set to true, the table rows matching the filter are the starting point of a subset.
+		Seed bool
 		// Filter represents the way you want to filter the results.
 		Filter Filter
 		// Anonymise anonymises columns.
@@ -78,6 +80,18 @@ func (t Tables) FindByName(name string) *Table {
 	return nil
 }
 
+// Seeds returns the tables which are the starting point of a subset.
+func (t Tables) Seeds() Tables {
+	var seeds Tables
+	for _, table := range t {
+		if table.Seed {
+			seeds = append(seeds, table)
+		}
+	}
+
+	return seeds
+}
+
 // LoadFromFile loads klepto tables config from file
 func LoadFromFile(configPath string) (Tables, error) {
 	if configPath == "" {
@@ -49,6 +49,7 @@ const (
 [[Tables]]
   Name = "users"
   IgnoreData = false
+  Seed = false
   [Tables.Filter]
     Match = "users.active = TRUE"
     Limit = 100
@@ -61,6 +62,7 @@ const (
 [[Tables]]
   Name = "orders"
   IgnoreData = false
+  Seed = false
   [Tables.Filter]
     Match = "ActiveUsers"
     Limit = 10
@@ -74,6 +76,7 @@ const (
 [[Tables]]
   Name = "logs"
   IgnoreData = true
+  Seed = false
   [Tables.Filter]
     Match = ""
     Limit = 0
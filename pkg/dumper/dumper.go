@@ -1,12 +1,15 @@
 package dumper
 
 import (
+	"context"
 	"fmt"
 	"time"
 
 	log "github.com/sirupsen/logrus"
 
 	"github.com/hellofresh/klepto/pkg/config"
+	"github.com/hellofresh/klepto/pkg/journal"
+	"github.com/hellofresh/klepto/pkg/progress"
 	"github.com/hellofresh/klepto/pkg/reader"
 )
 
@@ -21,16 +24,55 @@ type (
 
 	// A Dumper writes a database's structure to the provided stream.
 	Dumper interface {
-		// Dump executes the dump process.
-		Dump(chan<- struct{}, config.Tables, int, bool) error
+		// Dump executes the dump process, it returns once all the tables are dumped or the context is done.
+		// The tables failing to be read or dumped are reported as TableError joined in the returned error.
+		Dump(context.Context, config.Tables, DumpOpts) error
 		// Close closes the dumper resources and releases them.
 		Close() error
 	}
 
+	// Journaler is implemented by the drivers which dumpers record the changes made on the target database
+	// in the journal, the other dumpers leave no change to repair.
+	Journaler interface {
+		// Journaled returns true when the driver dumpers record their changes in the journal.
+		Journaled() bool
+	}
+
+	// Repairer is implemented by the dumpers able to revert the changes left on the target database
+	// by an interrupted dump.
+	Repairer interface {
+		// Repair reverts the changes recorded in the journal entries.
+		Repair(context.Context, []journal.Entry) error
+	}
+
+	// DumpOpts are the dump process options.
+	DumpOpts struct {
+		// Concurrency is the number of tables dumped concurrently.
+		Concurrency int
+		// DataOnly if set to true, the database structure is not dumped.
+		DataOnly bool
+		// FailFast if set to true, the dump stops at the first table failing,
+		// otherwise the remaining tables are dumped and all the failures are reported.
+		FailFast bool
+		// ForeignKeyOrder if set to true, the tables are loaded after the tables they reference so the
+		// constraints can stay enabled, they are only disabled for the tables referencing each other in a cycle.
+		ForeignKeyOrder bool
+	}
+
+	// TableError is the error of a table which failed to be read or dumped.
+	TableError struct {
+		// Table is the table name.
+		Table string
+		// Err is the failure cause.
+		Err error
+	}
+
 	// ConnOpts are the options to create a connection
 	ConnOpts struct {
 		// DSN is the connection address.
 		DSN string
+		// Source is the dsn of the database the rows are read from, the dumpers writing SQL infer its dialect from it.
+		Source string
 		// IsRDS identifies if the server is an AWS RDS server
 		IsRDS bool
 		// Timeout is the timeout for dump operations.
@@ -41,21 +83,68 @@ type (
 		MaxConns int
 		// MaxIdleConns is the maximum number of connections in the idle connection pool for the write database.
 		MaxIdleConns int
+		// Journal records the changes made on the target database until they are reverted, nil records nothing.
+		Journal *journal.Journal
+		// Progress counts the rows written per table, nil counts nothing.
+		Progress *progress.Tracker
+		// Schemas renames the postgres schemas on the target database, e.g. billing to archive.
+		Schemas map[string]string
 	}
 )
 
-// NewDumper is a factory method that will create a dumper based on the provided DSN
-func NewDumper(opts ConnOpts, rdr reader.Reader) (dumper Dumper, err error) {
-	drivers.Range(func(key, value interface{}) bool {
-		driver, ok := value.(Driver)
-		if !ok || !driver.IsSupported(opts.DSN) {
-			return true
+// Error returns the table error message.
+func (e *TableError) Error() string {
+	return fmt.Sprintf("table %s: %s", e.Table, e.Err)
+}
+
+// Unwrap returns the failure cause.
+func (e *TableError) Unwrap() error {
+	return e.Err
+}
+
+// FailedTables returns the names of the tables reported as failed in the error.
+func FailedTables(err error) []string {
+	var tables []string
+	for _, e := range TableErrors(err) {
+		tables = append(tables, e.Table)
+	}
+
+	return tables
+}
+
+// TableErrors returns the table errors joined or wrapped in the error.
+func TableErrors(err error) []*TableError {
+	switch e := err.(type) {
+	case *TableError:
+		return []*TableError{e}
+	case interface{ Unwrap() []error }:
+		var errs []*TableError
+		for _, err := range e.Unwrap() {
+			errs = append(errs, TableErrors(err)...)
 		}
-		log.WithField("driver", key).Debug("found driver")
+		return errs
+	case interface{ Unwrap() error }:
+		return TableErrors(e.Unwrap())
+	default:
+		return nil
+	}
+}
+
+// TargetTable returns the name of the table on the target database, its postgres schema renamed by the schemas map.
+func TargetTable(schemas map[string]string, tableName string) string {
+	schema, name := reader.SplitTableName(tableName)
+	if target, ok := schemas[schema]; ok {
+		schema = target
+	}
 
+	return reader.TableName(schema, name)
+}
+
+// NewDumper is a factory method that will create a dumper based on the provided DSN
+func NewDumper(opts ConnOpts, rdr reader.Reader) (dumper Dumper, err error) {
+	if driver := findDriver(opts.DSN); driver != nil {
 		dumper, err = driver.NewConnection(opts, rdr)
-		return false
-	})
+	}
 
 	if err != nil {
 		return nil, fmt.Errorf("could not create dumper for DSN %q : %w", opts.DSN, err)
@@ -67,3 +156,25 @@ func NewDumper(opts ConnOpts, rdr reader.Reader) (dumper Dumper, err error) {
 
 	return
 }
+
+// Journaled checks if the dumper of the DSN records the changes made on the target database in the journal.
+func Journaled(dsn string) bool {
+	journaler, ok := findDriver(dsn).(Journaler)
+	return ok && journaler.Journaled()
+}
+
+// findDriver returns the driver supporting the DSN, nil when none does.
+func findDriver(dsn string) (driver Driver) {
+	drivers.Range(func(key, value interface{}) bool {
+		d, ok := value.(Driver)
+		if !ok || !d.IsSupported(dsn) {
+			return true
+		}
+		log.WithField("driver", key).Debug("found driver")
+
+		driver = d
+		return false
+	})
+
+	return driver
+}
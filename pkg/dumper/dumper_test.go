@@ -0,0 +1,61 @@
+package dumper
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFailedTables(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		scenario string
+		err      error
+		tables   []string
+	}{
+		{scenario: "when there is no error"},
+		{scenario: "when error is not a table error", err: errors.New("failed")},
+		{scenario: "when error is a table error", err: &TableError{Table: "users", Err: errors.New("failed")}, tables: []string{"users"}},
+		{
+			scenario: "when table errors are joined and wrapped",
+			err: fmt.Errorf("dump: %w", errors.Join(
+				&TableError{Table: "users", Err: errors.New("failed")},
+				errors.New("post dump tables failed"),
+				&TableError{Table: "orders", Err: errors.New("failed")},
+			)),
+			tables: []string{"users", "orders"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.scenario, func(t *testing.T) {
+			assert.Equal(t, test.tables, FailedTables(test.err))
+		})
+	}
+}
+
+func TestTargetTable(t *testing.T) {
+	t.Parallel()
+
+	schemas := map[string]string{"billing": "archive", "public": "legacy"}
+
+	tests := []struct {
+		scenario string
+		table    string
+		target   string
+	}{
+		{scenario: "when the table is in a renamed schema", table: "billing.invoices", target: "archive.invoices"},
+		{scenario: "when the table is in the renamed public schema", table: "users", target: "legacy.users"},
+		{scenario: "when the table is in another schema", table: "audit.events", target: "audit.events"},
+		{scenario: "when the public table name holds a dot", table: `"public"."billing.invoices"`, target: `"legacy"."billing.invoices"`},
+	}
+
+	for _, test := range tests {
+		t.Run(test.scenario, func(t *testing.T) {
+			assert.Equal(t, test.target, TargetTable(schemas, test.table))
+		})
+	}
+}
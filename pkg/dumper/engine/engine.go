@@ -1,70 +1,107 @@
 package engine
 
 import (
+	"context"
+	"errors"
 	"fmt"
-	"sync"
+	"slices"
 
 	log "github.com/sirupsen/logrus"
 
 	"github.com/hellofresh/klepto/pkg/config"
 	"github.com/hellofresh/klepto/pkg/database"
 	"github.com/hellofresh/klepto/pkg/dumper"
+	"github.com/hellofresh/klepto/pkg/journal"
+	"github.com/hellofresh/klepto/pkg/progress"
 	"github.com/hellofresh/klepto/pkg/reader"
 )
 
+// errFailFast is the cause of the cancellation of the tables still running when a table fails and the dump fails fast.
+var errFailFast = errors.New("dump stopped after a table failed")
+
 type (
 	// Engine is the engine which dispatches and orchestrates a dump.
 	Engine struct {
 		Dumper
-		reader reader.Reader
+		reader   reader.Reader
+		progress *progress.Tracker
 	}
 
 	// Dumper is the dump engine.
 	Dumper interface {
 		// DumpStructure dumps database structure given a sql.
-		DumpStructure(sql string) error
-		// DumpTable dumps a table by name.
-		DumpTable(tableName string, rowChan <-chan database.Row) error
+		DumpStructure(ctx context.Context, sql string) error
+		// DumpTable dumps a table by name, the rows written are discarded when the context is done.
+		DumpTable(ctx context.Context, tableName string, rowChan <-chan database.Row) error
 		// Close closes the dumper resources and releases them.
 		Close() error
 	}
 
 	// Hooker are the actions you perform before or after a specified database operation.
+	// The hooks receive the tables loaded in any order, which constraints must be disabled while dumping.
 	Hooker interface {
 		// PreDumpTables performs a action before dumping tables before dumping tables.
-		PreDumpTables([]string) error
+		PreDumpTables(context.Context, []string) error
 		// PostDumpTables performs a action after dumping tables before dumping tables.
-		PostDumpTables([]string) error
+		PostDumpTables(context.Context, []string) error
+	}
+
+	// Sequencer is implemented by the dumpers writing the tables to a stream in a stable order, sorted by name or in
+	// foreign key order. The tables are still dumped concurrently, a dumped table is written once the tables before
+	// it are written, failed or skipped.
+	Sequencer interface {
+		// WriteTable writes the dumped table to the stream.
+		WriteTable(ctx context.Context, tableName string) error
+	}
+
+	// tableResult is the outcome of a table dump.
+	tableResult struct {
+		table string
+		err   error
 	}
 )
 
-// New creates a new engine given the reader and dumper.
-func New(rdr reader.Reader, dumper Dumper) dumper.Dumper {
+// New creates a new engine given the reader and dumper, the progress of the tables is tracked when the tracker is not nil.
+func New(rdr reader.Reader, dumper Dumper, tracker *progress.Tracker) dumper.Dumper {
 	return &Engine{
-		Dumper: dumper,
-		reader: rdr,
+		Dumper:   dumper,
+		reader:   rdr,
+		progress: tracker,
 	}
 }
 
 // Dump executes the dump process.
-func (e *Engine) Dump(done chan<- struct{}, cfgTables config.Tables, concurrency int, dataOnly bool) error {
-	if !dataOnly {
-		if err := e.readAndDumpStructure(); err != nil {
+func (e *Engine) Dump(ctx context.Context, cfgTables config.Tables, opts dumper.DumpOpts) error {
+	if !opts.DataOnly {
+		e.progress.SetPhase(progress.PhaseStructure)
+		if err := e.readAndDumpStructure(ctx); err != nil {
 			return err
 		}
 	}
 
-	return e.readAndDumpTables(done, cfgTables, concurrency)
+	defer e.progress.SetPhase(progress.PhaseDone)
+
+	return e.readAndDumpTables(ctx, cfgTables, opts)
+}
+
+// Repair reverts the changes recorded in the journal entries when the dumper supports it.
+func (e *Engine) Repair(ctx context.Context, entries []journal.Entry) error {
+	repairer, ok := e.Dumper.(dumper.Repairer)
+	if !ok {
+		return errors.New("the dumper does not support repair")
+	}
+
+	return repairer.Repair(ctx, entries)
 }
 
-func (e *Engine) readAndDumpStructure() error {
+func (e *Engine) readAndDumpStructure(ctx context.Context) error {
 	log.Debug("dumping structure...")
 	sql, err := e.reader.GetStructure()
 	if err != nil {
 		return fmt.Errorf("failed to get structure: %w", err)
 	}
 
-	if err := e.DumpStructure(sql); err != nil {
+	if err := e.DumpStructure(ctx, sql); err != nil {
 		return fmt.Errorf("failed to dump structure: %w", err)
 	}
 
@@ -72,73 +109,262 @@ func (e *Engine) readAndDumpStructure() error {
 	return nil
 }
 
-func (e *Engine) readAndDumpTables(done chan<- struct{}, cfgTables config.Tables, concurrency int) error {
+func (e *Engine) readAndDumpTables(ctx context.Context, cfgTables config.Tables, dumpOpts dumper.DumpOpts) error {
 	tables, err := e.reader.GetTables()
 	if err != nil {
 		return fmt.Errorf("failed to read and dump tables: %w", err)
 	}
 
+	// Without a load order the tables are loaded in any order, so the constraints of all the tables are disabled
+	order := new(loadOrder)
+	unchecked := tables
+	if dumpOpts.ForeignKeyOrder {
+		order, err = newLoadOrder(e.reader, tables)
+		if err != nil {
+			return fmt.Errorf("failed to compute tables load order: %w", err)
+		}
+
+		unchecked = order.cyclic
+		if len(unchecked) > 0 {
+			log.WithField("tables", unchecked).Warn("Foreign keys form a cycle, the constraints of these tables are disabled")
+		}
+
+		partialChildren := order.partialChildren(tables, func(tableName string) bool {
+			return partial(cfgTables.FindByName(tableName))
+		})
+		if len(partialChildren) > 0 {
+			log.WithField("tables", partialChildren).Warn("Tables reference ignored or filtered tables, the constraints of these tables are disabled")
+			unchecked = append(slices.Clip(unchecked), partialChildren...)
+		}
+	}
+
 	// Trigger pre dump tables
 	if adv, ok := e.Dumper.(Hooker); ok {
-		if err := adv.PreDumpTables(tables); err != nil {
+		e.progress.SetPhase(progress.PhasePreDumpHooks)
+		if err := adv.PreDumpTables(ctx, unchecked); err != nil {
 			return fmt.Errorf("failed to execute pre dump tables: %w", err)
 		}
 	}
 
-	semChan := make(chan struct{}, concurrency)
-	var wg sync.WaitGroup
+	// dumpCtx is cancelled by the first table failing when failing fast
+	dumpCtx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+
+	// the tables written in sequence are started in the same order, so they wait less to be written
+	sequencer, sequenced := e.Dumper.(Sequencer)
+	var sequence []string
+	if sequenced {
+		sequence = order.sorted(tables)
+		tables = sequence
+	}
+
+	var (
+		errs    []error
+		ready   []string
+		pending = make(map[string]int, len(tables))
+		skipped = make(map[string]bool)
+		results = make(chan tableResult)
+		running int
+		// settled are the tables which will not run anymore, dumped the ones which succeeded
+		settled = make(map[string]bool, len(tables))
+		dumped  = make(map[string]bool, len(tables))
+		written int
+	)
+	total := 0
 	for _, tbl := range tables {
-		logger := log.WithField("table", tbl)
-		tableConfig := cfgTables.FindByName(tbl)
-		if tableConfig == nil {
-			logger.Debug("no configuration found for table")
+		if tableConfig := cfgTables.FindByName(tbl); tableConfig == nil || !tableConfig.IgnoreData {
+			total++
 		}
 
-		var opts reader.ReadTableOpt
-		if tableConfig != nil {
-			if tableConfig.IgnoreData {
-				logger.Debug("ignoring data to dump")
+		pending[tbl] = len(order.parents[tbl])
+		if pending[tbl] == 0 {
+			ready = append(ready, tbl)
+		}
+	}
+
+	e.progress.SetTotal(total)
+	e.progress.SetPhase(progress.PhaseTables)
+
+	// finish releases the tables waiting for the given table, they are skipped when it was not loaded
+	var finish func(tableName string, loaded bool)
+	finish = func(tableName string, loaded bool) {
+		for _, child := range order.children[tableName] {
+			if skipped[child] {
+				continue
+			}
+
+			if !loaded {
+				skipped[child] = true
+				settled[child] = true
+				if dumpCtx.Err() == nil {
+					log.WithField("table", child).Errorf("Skipping table, referenced table %s was not dumped", tableName)
+					errs = append(errs, &dumper.TableError{
+						Table: child,
+						Err:   fmt.Errorf("referenced table %s was not dumped", tableName),
+					})
+				}
+				finish(child, false)
 				continue
 			}
 
-			opts = reader.NewReadTableOpt(tableConfig)
+			pending[child]--
+			if pending[child] == 0 {
+				ready = append(ready, child)
+			}
 		}
+	}
 
-		// Create read/write chanel
-		rowChan := make(chan database.Row)
-		semChan <- struct{}{}
-		wg.Add(1)
+	// fail reports the failure of a table, the tables stopped because the dump was cancelled are not failures on their own
+	fail := func(tableName string, err error) {
+		if ctx.Err() != nil || errors.Is(err, errFailFast) {
+			return
+		}
 
-		go func(tableName string, rowChan <-chan database.Row, logger *log.Entry) {
-			defer wg.Done()
-			defer func(semChan <-chan struct{}) { <-semChan }(semChan)
+		log.WithField("table", tableName).WithError(err).Error("Failed to dump table")
+		errs = append(errs, &dumper.TableError{Table: tableName, Err: err})
 
-			if err := e.DumpTable(tableName, rowChan); err != nil {
-				logger.WithError(err).Error("Failed to dump table")
+		if dumpOpts.FailFast {
+			cancel(errFailFast)
+		}
+	}
+
+	// write writes the dumped tables of the sequence, up to the first table which is not settled
+	write := func() {
+		for ; sequenced && written < len(sequence) && settled[sequence[written]]; written++ {
+			tbl := sequence[written]
+			if !dumped[tbl] || ctx.Err() != nil {
+				continue
 			}
-		}(tbl, rowChan, logger)
 
-		go func(tableName string, opts reader.ReadTableOpt, rowChan chan<- database.Row, logger *log.Entry) {
-			if err := e.reader.ReadTable(tableName, rowChan, opts); err != nil {
-				logger.WithError(err).Error("Failed to read table")
+			if err := sequencer.WriteTable(ctx, tbl); err != nil {
+				fail(tbl, fmt.Errorf("failed to write table: %w", err))
 			}
-		}(tbl, opts, rowChan, logger)
+		}
 	}
 
+	concurrency := max(dumpOpts.Concurrency, 1)
+	for {
+		for len(ready) > 0 && running < concurrency && dumpCtx.Err() == nil {
+			tbl := ready[0]
+			ready = ready[1:]
+
+			logger := log.WithField("table", tbl)
+			tableConfig := cfgTables.FindByName(tbl)
+			if tableConfig == nil {
+				logger.Debug("no configuration found for table")
+			}
+
+			var opts reader.ReadTableOpt
+			if tableConfig != nil {
+				if tableConfig.IgnoreData {
+					logger.Debug("ignoring data to dump")
+					settled[tbl] = true
+					finish(tbl, true)
+					continue
+				}
+
+				opts = reader.NewReadTableOpt(tableConfig)
+			}
+
+			running++
+			go func(tableName string, opts reader.ReadTableOpt) {
+				results <- tableResult{table: tableName, err: e.readAndDumpTable(dumpCtx, tableName, opts)}
+			}(tbl, opts)
+		}
+
+		write()
+
+		// Wait for all the running tables to be dumped
+		if running == 0 {
+			break
+		}
+
+		result := <-results
+		running--
+
+		settled[result.table] = true
+		dumped[result.table] = result.err == nil
+		if result.err != nil {
+			fail(result.table, result.err)
+		}
+
+		finish(result.table, result.err == nil)
+	}
+
+	// Trigger post dump tables, the target is restored even when the dump was cancelled
+	if adv, ok := e.Dumper.(Hooker); ok {
+		e.progress.SetPhase(progress.PhasePostDumpHooks)
+		if err := adv.PostDumpTables(context.WithoutCancel(ctx), unchecked); err != nil {
+			log.WithError(err).Error("post dump tables failed")
+			errs = append(errs, fmt.Errorf("failed to execute post dump tables: %w", err))
+		}
+	}
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	return errors.Join(errs...)
+}
+
+// partial returns whether only a part of the table rows is dumped, the rows referencing the missing rows would
+// fail their foreign key checks.
+func partial(cfg *config.Table) bool {
+	return cfg != nil && (cfg.IgnoreData || cfg.Filter.Match != "" || cfg.Filter.Limit > 0 || len(cfg.Relationships) > 0)
+}
+
+// readAndDumpTable reads the table and dumps its rows. When the read fails, the table context is cancelled
+// before the dumper sees the end of the rows, so the partially read table is not committed.
+func (e *Engine) readAndDumpTable(ctx context.Context, tableName string, opts reader.ReadTableOpt) (err error) {
+	ctx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+
+	table := e.progress.Start(tableName)
+	defer func() {
+		if err != nil {
+			table.Fail()
+			return
+		}
+		table.Done()
+	}()
+
+	readChan := make(chan database.Row)
+	dumpChan := make(chan database.Row)
+
 	go func() {
-		// Wait for all table to be dumped
-		wg.Wait()
-		close(semChan)
-
-		// Trigger post dump tables
-		if adv, ok := e.Dumper.(Hooker); ok {
-			if err := adv.PostDumpTables(tables); err != nil {
-				log.WithError(err).Error("post dump tables failed")
+		defer close(dumpChan)
+
+		readErr := make(chan error, 1)
+		go func() {
+			readErr <- e.reader.ReadTable(ctx, tableName, readChan, opts)
+		}()
+
+		// the rows are counted here as every reader, the subset and the anonymiser included, publishes through this loop
+		for row := range readChan {
+			table.AddRead(1)
+			select {
+			case dumpChan <- row:
+			case <-ctx.Done():
 			}
 		}
 
-		done <- struct{}{}
+		if err := <-readErr; err != nil {
+			cancel(fmt.Errorf("failed to read table: %w", err))
+		}
 	}()
 
-	return nil
+	err = e.DumpTable(ctx, tableName, dumpChan)
+	if err != nil {
+		cancel(err)
+	}
+
+	// the rows are drained so the reader is not blocked when the dumper stopped early
+	for range dumpChan {
+	}
+
+	if cause := context.Cause(ctx); cause != nil && !errors.Is(cause, context.Canceled) {
+		return cause
+	}
+
+	return err
 }
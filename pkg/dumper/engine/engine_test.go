@@ -0,0 +1,317 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/hellofresh/klepto/pkg/config"
+	"github.com/hellofresh/klepto/pkg/database"
+	"github.com/hellofresh/klepto/pkg/dumper"
+	"github.com/hellofresh/klepto/pkg/progress"
+	"github.com/hellofresh/klepto/pkg/reader"
+)
+
+func TestReadAndDumpTable(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		scenario  string
+		readErr   error
+		dumpErr   error
+		err       string
+		committed bool
+	}{
+		{scenario: "when table is dumped", committed: true},
+		{scenario: "when read fails", readErr: errors.New("read failed"), err: "failed to read table: read failed"},
+		{scenario: "when dump fails", dumpErr: errors.New("dump failed"), err: "dump failed"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.scenario, func(t *testing.T) {
+			rdr := &mockReader{rows: 10, err: test.readErr}
+			dmp := &mockDumper{err: test.dumpErr}
+			tracker := progress.NewTracker()
+			e := New(rdr, dmp, tracker).(*Engine)
+
+			err := e.readAndDumpTable(context.Background(), "users", reader.ReadTableOpt{})
+			if test.err == "" {
+				require.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, test.err)
+			}
+			assert.Equal(t, test.committed, dmp.committed)
+
+			stats := tracker.Stats()
+			require.Len(t, stats, 1)
+			assert.True(t, stats[0].Done)
+			assert.Equal(t, !test.committed, stats[0].Failed)
+			if test.committed {
+				assert.Equal(t, int64(10), stats[0].Read)
+			}
+		})
+	}
+}
+
+func TestDumpCancelled(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	dmp := &mockDumper{}
+	err := New(&mockReader{rows: 10}, dmp, nil).Dump(ctx, config.Tables{}, dumper.DumpOpts{Concurrency: 1, DataOnly: true})
+
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.False(t, dmp.committed)
+	assert.True(t, dmp.postDumped)
+}
+
+func TestDumpFailures(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		scenario string
+		failFast bool
+		failed   []string
+	}{
+		{scenario: "when failures are reported", failed: []string{"orders", "payments"}},
+		{scenario: "when failing fast", failFast: true, failed: []string{"orders"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.scenario, func(t *testing.T) {
+			rdr := &mockReader{
+				tables: []string{"orders", "users", "payments"},
+				rows:   10,
+				failing: map[string]error{
+					"orders":   errors.New("orders failed"),
+					"payments": errors.New("payments failed"),
+				},
+			}
+
+			err := New(rdr, &mockDumper{}, nil).Dump(context.Background(), config.Tables{}, dumper.DumpOpts{
+				Concurrency: 1,
+				DataOnly:    true,
+				FailFast:    test.failFast,
+			})
+
+			require.Error(t, err)
+			assert.Equal(t, test.failed, dumper.FailedTables(err))
+		})
+	}
+}
+
+func TestDumpForeignKeyOrder(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		scenario  string
+		cfgTables config.Tables
+		failing   map[string]error
+		dumped    []string
+		failed    []string
+		unchecked []string
+	}{
+		{
+			scenario:  "when referenced tables are dumped first",
+			dumped:    []string{"users", "teams", "orders", "payments"},
+			unchecked: []string{"users", "teams"},
+		},
+		{
+			scenario:  "when a referenced table fails",
+			failing:   map[string]error{"orders": errors.New("orders failed")},
+			dumped:    []string{"users", "teams"},
+			failed:    []string{"orders", "payments"},
+			unchecked: []string{"users", "teams"},
+		},
+		{
+			scenario:  "when a referenced table is ignored",
+			cfgTables: config.Tables{{Name: "orders", IgnoreData: true}},
+			dumped:    []string{"users", "teams", "payments"},
+			unchecked: []string{"users", "teams", "payments"},
+		},
+		{
+			scenario:  "when a referenced table is filtered",
+			cfgTables: config.Tables{{Name: "orders", Filter: config.Filter{Limit: 10}}},
+			dumped:    []string{"users", "teams", "orders", "payments"},
+			unchecked: []string{"users", "teams", "payments"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.scenario, func(t *testing.T) {
+			rdr := &mockReader{
+				tables: []string{"payments", "orders", "users", "teams"},
+				references: map[string][]string{
+					"payments": {"orders"},
+					"orders":   {"users"},
+					"users":    {"teams"},
+					"teams":    {"users"},
+				},
+				rows:    10,
+				failing: test.failing,
+			}
+			dmp := &mockDumper{}
+
+			err := New(rdr, dmp, nil).Dump(context.Background(), test.cfgTables, dumper.DumpOpts{
+				Concurrency:     4,
+				DataOnly:        true,
+				ForeignKeyOrder: true,
+			})
+
+			assert.Equal(t, test.failed, dumper.FailedTables(err))
+			assert.Equal(t, test.unchecked, dmp.unchecked)
+
+			// the tables of a cycle are dumped concurrently
+			require.Len(t, dmp.dumped, len(test.dumped))
+			assert.ElementsMatch(t, test.dumped[:2], dmp.dumped[:2])
+			assert.Equal(t, test.dumped[2:], dmp.dumped[2:])
+		})
+	}
+}
+
+func TestDumpSequence(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		scenario        string
+		foreignKeyOrder bool
+		failing         map[string]error
+		written         []string
+		failed          []string
+	}{
+		{scenario: "when the tables are written by name", written: []string{"orders", "payments", "users"}},
+		{scenario: "when the tables are written in foreign key order", foreignKeyOrder: true, written: []string{"users", "orders", "payments"}},
+		{
+			scenario: "when a table fails",
+			failing:  map[string]error{"orders": errors.New("orders failed")},
+			written:  []string{"payments", "users"},
+			failed:   []string{"orders"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.scenario, func(t *testing.T) {
+			rdr := &mockReader{
+				tables:     []string{"users", "payments", "orders"},
+				references: map[string][]string{"payments": {"orders"}, "orders": {"users"}},
+				rows:       10,
+				failing:    test.failing,
+			}
+			dmp := &mockSequencer{}
+
+			err := New(rdr, dmp, nil).Dump(context.Background(), config.Tables{}, dumper.DumpOpts{
+				Concurrency:     4,
+				DataOnly:        true,
+				ForeignKeyOrder: test.foreignKeyOrder,
+			})
+
+			assert.Equal(t, test.failed, dumper.FailedTables(err))
+			assert.Equal(t, test.written, dmp.written)
+		})
+	}
+}
+
+type mockReader struct {
+	reader.Reader
+	tables         []string
+	references     map[string][]string
+	foreignKeysErr error
+	rows           int
+	err            error
+	failing        map[string]error
+}
+
+func (m *mockReader) GetTables() ([]string, error) {
+	if m.tables == nil {
+		return []string{"users"}, nil
+	}
+	return m.tables, nil
+}
+
+func (m *mockReader) GetForeignKeys(tableName string) ([]*reader.ForeignKey, error) {
+	if m.foreignKeysErr != nil {
+		return nil, m.foreignKeysErr
+	}
+
+	var foreignKeys []*reader.ForeignKey
+	for _, ref := range m.references[tableName] {
+		foreignKeys = append(foreignKeys, &reader.ForeignKey{Table: tableName, ReferencedTable: ref})
+	}
+	return foreignKeys, nil
+}
+
+func (m *mockReader) ReadTable(ctx context.Context, tableName string, rowChan chan<- database.Row, _ reader.ReadTableOpt) error {
+	defer close(rowChan)
+
+	if err, ok := m.failing[tableName]; ok {
+		return err
+	}
+
+	for i := 0; i < m.rows; i++ {
+		select {
+		case rowChan <- database.Row{"id": i}:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	return m.err
+}
+
+type mockDumper struct {
+	err        error
+	committed  bool
+	postDumped bool
+	unchecked  []string
+	mu         sync.Mutex
+	dumped     []string
+}
+
+func (m *mockDumper) DumpStructure(context.Context, string) error { return nil }
+func (m *mockDumper) Close() error                                { return nil }
+
+func (m *mockDumper) DumpTable(ctx context.Context, tableName string, rowChan <-chan database.Row) error {
+	if m.err != nil {
+		return m.err
+	}
+
+	for range rowChan {
+	}
+
+	// a transaction bound to the context can not be committed once it is done
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	m.mu.Lock()
+	m.committed = true
+	m.dumped = append(m.dumped, tableName)
+	m.mu.Unlock()
+
+	return nil
+}
+
+func (m *mockDumper) PreDumpTables(_ context.Context, tables []string) error {
+	m.unchecked = tables
+	return nil
+}
+
+func (m *mockDumper) PostDumpTables(ctx context.Context, _ []string) error {
+	m.postDumped = ctx.Err() == nil
+	return nil
+}
+
+type mockSequencer struct {
+	mockDumper
+	written []string
+}
+
+func (m *mockSequencer) WriteTable(_ context.Context, tableName string) error {
+	m.written = append(m.written, tableName)
+	return nil
+}
@@ -0,0 +1,175 @@
+package engine
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/hellofresh/klepto/pkg/reader"
+)
+
+// loadOrder is the foreign key dependency graph of the dumped tables.
+// A table is loaded once the tables it references are loaded, so its foreign keys can be checked,
+// the tables referencing each other in a cycle are loaded together with their constraints disabled.
+type loadOrder struct {
+	// parents are the tables to load before a table, the tables of its own cycle excluded
+	parents map[string][]string
+	// children are the tables waiting for a table to be loaded
+	children map[string][]string
+	// cyclic are the tables part of a foreign key cycle, self references included
+	cyclic []string
+}
+
+// newLoadOrder builds the dependency graph of the tables from their foreign keys,
+// the foreign keys referencing tables which are not dumped are ignored.
+func newLoadOrder(rdr reader.Reader, tables []string) (*loadOrder, error) {
+	known := make(map[string]bool, len(tables))
+	for _, tbl := range tables {
+		known[tbl] = true
+	}
+
+	references := make(map[string][]string, len(tables))
+	for _, tbl := range tables {
+		foreignKeys, err := rdr.GetForeignKeys(tbl)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get foreign keys of %s: %w", tbl, err)
+		}
+
+		for _, fk := range foreignKeys {
+			if known[fk.ReferencedTable] {
+				references[tbl] = append(references[tbl], fk.ReferencedTable)
+			}
+		}
+	}
+
+	components := stronglyConnected(tables, references)
+
+	order := &loadOrder{
+		parents:  make(map[string][]string, len(tables)),
+		children: make(map[string][]string, len(tables)),
+	}
+	for _, tbl := range tables {
+		seen := make(map[string]bool)
+		cyclic := false
+		for _, ref := range references[tbl] {
+			if components[ref] == components[tbl] {
+				cyclic = true
+				continue
+			}
+			if seen[ref] {
+				continue
+			}
+			seen[ref] = true
+
+			order.parents[tbl] = append(order.parents[tbl], ref)
+			order.children[ref] = append(order.children[ref], tbl)
+		}
+
+		if cyclic {
+			order.cyclic = append(order.cyclic, tbl)
+		}
+	}
+
+	return order, nil
+}
+
+// partialChildren returns the tables referencing a partial table, which are not cyclic already.
+func (o *loadOrder) partialChildren(tables []string, partial func(string) bool) []string {
+	cyclic := make(map[string]bool, len(o.cyclic))
+	for _, tbl := range o.cyclic {
+		cyclic[tbl] = true
+	}
+
+	var children []string
+	for _, tbl := range tables {
+		if !cyclic[tbl] && slices.ContainsFunc(o.parents[tbl], partial) {
+			children = append(children, tbl)
+		}
+	}
+
+	return children
+}
+
+// sorted returns the tables in a stable load order: a table comes after the tables it references,
+// the tables which do not depend on each other are sorted by name, and so are the tables of a cycle.
+func (o *loadOrder) sorted(tables []string) []string {
+	var ready []string
+	pending := make(map[string]int, len(tables))
+	for _, tbl := range tables {
+		pending[tbl] = len(o.parents[tbl])
+		if pending[tbl] == 0 {
+			ready = append(ready, tbl)
+		}
+	}
+
+	sorted := make([]string, 0, len(tables))
+	for len(ready) > 0 {
+		slices.Sort(ready)
+		tbl := ready[0]
+		ready = ready[1:]
+		sorted = append(sorted, tbl)
+
+		for _, child := range o.children[tbl] {
+			pending[child]--
+			if pending[child] == 0 {
+				ready = append(ready, child)
+			}
+		}
+	}
+
+	return sorted
+}
+
+// stronglyConnected returns the strongly connected component of each table, the tables of a
+// foreign key cycle share the same component (Tarjan's algorithm).
+func stronglyConnected(tables []string, references map[string][]string) map[string]int {
+	var (
+		index      = make(map[string]int, len(tables))
+		lowLink    = make(map[string]int, len(tables))
+		onStack    = make(map[string]bool, len(tables))
+		components = make(map[string]int, len(tables))
+		stack      []string
+		next       int
+		component  int
+	)
+
+	var visit func(tbl string)
+	visit = func(tbl string) {
+		index[tbl] = next
+		lowLink[tbl] = next
+		next++
+		stack = append(stack, tbl)
+		onStack[tbl] = true
+
+		for _, ref := range references[tbl] {
+			if _, visited := index[ref]; !visited {
+				visit(ref)
+				lowLink[tbl] = min(lowLink[tbl], lowLink[ref])
+			} else if onStack[ref] {
+				lowLink[tbl] = min(lowLink[tbl], index[ref])
+			}
+		}
+
+		if lowLink[tbl] != index[tbl] {
+			return
+		}
+
+		for {
+			member := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			onStack[member] = false
+			components[member] = component
+			if member == tbl {
+				break
+			}
+		}
+		component++
+	}
+
+	for _, tbl := range tables {
+		if _, visited := index[tbl]; !visited {
+			visit(tbl)
+		}
+	}
+
+	return components
+}
@@ -0,0 +1,115 @@
+package engine
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadOrder(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		scenario   string
+		tables     []string
+		references map[string][]string
+		parents    map[string][]string
+		cyclic     []string
+	}{
+		{
+			scenario: "when tables have no foreign keys",
+			tables:   []string{"users", "orders"},
+			parents:  map[string][]string{},
+		},
+		{
+			scenario:   "when tables reference each other in a chain",
+			tables:     []string{"payments", "orders", "users"},
+			references: map[string][]string{"payments": {"orders", "users"}, "orders": {"users", "users"}},
+			parents:    map[string][]string{"payments": {"orders", "users"}, "orders": {"users"}},
+		},
+		{
+			scenario:   "when a table references a table which is not dumped",
+			tables:     []string{"orders"},
+			references: map[string][]string{"orders": {"users"}},
+			parents:    map[string][]string{},
+		},
+		{
+			scenario:   "when a table references itself",
+			tables:     []string{"users", "categories"},
+			references: map[string][]string{"categories": {"categories"}, "users": {"categories"}},
+			parents:    map[string][]string{"users": {"categories"}},
+			cyclic:     []string{"categories"},
+		},
+		{
+			scenario: "when tables reference each other in a cycle",
+			tables:   []string{"users", "teams", "orders", "countries"},
+			references: map[string][]string{
+				"users":  {"teams", "countries"},
+				"teams":  {"users"},
+				"orders": {"users"},
+			},
+			parents: map[string][]string{"users": {"countries"}, "orders": {"users"}},
+			cyclic:  []string{"users", "teams"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.scenario, func(t *testing.T) {
+			order, err := newLoadOrder(&mockReader{references: test.references}, test.tables)
+			require.NoError(t, err)
+
+			assert.Equal(t, test.parents, order.parents)
+			assert.Equal(t, test.cyclic, order.cyclic)
+		})
+	}
+}
+
+func TestLoadOrderSorted(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		scenario   string
+		tables     []string
+		references map[string][]string
+		expected   []string
+	}{
+		{
+			scenario: "when tables have no foreign keys",
+			tables:   []string{"users", "orders", "payments"},
+			expected: []string{"orders", "payments", "users"},
+		},
+		{
+			scenario:   "when tables reference each other in a chain",
+			tables:     []string{"payments", "orders", "users", "addresses"},
+			references: map[string][]string{"payments": {"orders", "users"}, "orders": {"users"}},
+			expected:   []string{"addresses", "users", "orders", "payments"},
+		},
+		{
+			scenario: "when tables reference each other in a cycle",
+			tables:   []string{"users", "teams", "orders", "countries"},
+			references: map[string][]string{
+				"users":  {"teams", "countries"},
+				"teams":  {"users"},
+				"orders": {"users"},
+			},
+			expected: []string{"countries", "teams", "users", "orders"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.scenario, func(t *testing.T) {
+			order, err := newLoadOrder(&mockReader{references: test.references}, test.tables)
+			require.NoError(t, err)
+
+			assert.Equal(t, test.expected, order.sorted(test.tables))
+		})
+	}
+}
+
+func TestLoadOrderForeignKeysFailure(t *testing.T) {
+	t.Parallel()
+
+	_, err := newLoadOrder(&mockReader{foreignKeysErr: assert.AnError}, []string{"users"})
+	assert.ErrorIs(t, err, assert.AnError)
+}
@@ -0,0 +1,179 @@
+package export
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/hellofresh/klepto/pkg/database"
+	"github.com/hellofresh/klepto/pkg/dumper"
+	"github.com/hellofresh/klepto/pkg/dumper/engine"
+	"github.com/hellofresh/klepto/pkg/progress"
+	"github.com/hellofresh/klepto/pkg/reader"
+)
+
+// manifestFile is the file listing the exported tables.
+const manifestFile = "manifest.json"
+
+type (
+	exportDumper struct {
+		opts     Options
+		reader   reader.Reader
+		progress *progress.Tracker
+
+		mu     sync.Mutex
+		tables []*ManifestTable
+	}
+
+	// Manifest lists the tables exported to a directory.
+	Manifest struct {
+		// Format is the format of the files, csv, jsonl or parquet.
+		Format string `json:"format"`
+		// Tables are the exported tables, sorted by name.
+		Tables []*ManifestTable `json:"tables"`
+	}
+
+	// ManifestTable describes an exported table.
+	ManifestTable struct {
+		// Name is the table name.
+		Name string `json:"name"`
+		// File is the name of the file the rows are written to, in the export directory.
+		File string `json:"file"`
+		// Columns are the names of the columns, in the order they are written.
+		Columns []string `json:"columns"`
+		// Rows is the number of rows written.
+		Rows int64 `json:"rows"`
+	}
+
+	// countingWriter counts the bytes written to a table file.
+	countingWriter struct {
+		w     io.Writer
+		table *progress.Table
+	}
+)
+
+// NewDumper returns a new dumper writing a csv, jsonl or parquet file per table to the directory,
+// the progress of the tables is tracked when the tracker is not nil.
+func NewDumper(opts Options, rdr reader.Reader, tracker *progress.Tracker) dumper.Dumper {
+	return engine.New(rdr, &exportDumper{
+		opts:     opts,
+		reader:   rdr,
+		progress: tracker,
+	}, tracker)
+}
+
+// DumpStructure does nothing, only the rows are exported.
+func (d *exportDumper) DumpStructure(context.Context, string) error {
+	log.Debug("skipping structure, only the rows are exported")
+	return nil
+}
+
+// DumpTable writes the table rows to its file. The rows are written to a temporary file renamed once
+// all the rows are written, so the file of a table failing to be dumped is not left behind.
+func (d *exportDumper) DumpTable(ctx context.Context, tableName string, rowChan <-chan database.Row) error {
+	columns, err := d.reader.GetColumns(tableName)
+	if err != nil {
+		return fmt.Errorf("failed to get columns: %w", err)
+	}
+
+	name := strings.NewReplacer("/", "_", "\\", "_").Replace(tableName) + "." + d.opts.Format
+	path := filepath.Join(d.opts.Dir, name)
+	f, err := os.CreateTemp(d.opts.Dir, name+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to create table file: %w", err)
+	}
+	defer func() {
+		// the temporary file is gone once renamed
+		f.Close()
+		os.Remove(f.Name())
+	}()
+
+	table := d.progress.Table(tableName)
+	rows, err := d.writeRows(ctx, &countingWriter{w: f, table: table}, columns, rowChan, table)
+	if err != nil {
+		return err
+	}
+
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("failed to close table file: %w", err)
+	}
+	if err := os.Rename(f.Name(), path); err != nil {
+		return fmt.Errorf("failed to rename table file: %w", err)
+	}
+
+	d.mu.Lock()
+	d.tables = append(d.tables, &ManifestTable{Name: tableName, File: name, Columns: reader.ColumnNames(columns), Rows: rows})
+	d.mu.Unlock()
+
+	log.WithFields(log.Fields{"table": tableName, "rows": rows}).Debug("exported rows")
+	return nil
+}
+
+// writeRows encodes the rows to the writer, it stops when the context is done.
+func (d *exportDumper) writeRows(ctx context.Context, w io.Writer, columns []*reader.Column, rowChan <-chan database.Row, table *progress.Table) (int64, error) {
+	enc := newEncoder(d.opts, w, columns)
+	if err := enc.WriteHeader(); err != nil {
+		return 0, fmt.Errorf("failed to write header: %w", err)
+	}
+
+	var rows int64
+	for row := range rowChan {
+		if err := enc.Encode(row); err != nil {
+			return rows, fmt.Errorf("failed to write row: %w", err)
+		}
+
+		rows++
+		table.AddWritten(1)
+	}
+
+	if err := ctx.Err(); err != nil {
+		return rows, err
+	}
+	if err := enc.Flush(); err != nil {
+		return rows, fmt.Errorf("failed to write rows: %w", err)
+	}
+
+	return rows, nil
+}
+
+// Close writes the manifest of the exported tables.
+func (d *exportDumper) Close() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	slices.SortFunc(d.tables, func(a, b *ManifestTable) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+
+	manifest := &Manifest{Format: d.opts.Format, Tables: d.tables}
+	if manifest.Tables == nil {
+		manifest.Tables = []*ManifestTable{}
+	}
+
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode manifest: %w", err)
+	}
+
+	if err := os.WriteFile(filepath.Join(d.opts.Dir, manifestFile), append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to write manifest: %w", err)
+	}
+
+	return nil
+}
+
+// Write writes to the table file and counts the bytes written.
+func (w *countingWriter) Write(p []byte) (int, error) {
+	n, err := w.w.Write(p)
+	w.table.AddBytes(int64(n))
+
+	return n, err
+}
@@ -0,0 +1,144 @@
+package export
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/hellofresh/klepto/pkg/config"
+	"github.com/hellofresh/klepto/pkg/database"
+	"github.com/hellofresh/klepto/pkg/dumper"
+	"github.com/hellofresh/klepto/pkg/reader"
+)
+
+func TestDump(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		scenario string
+		dsn      string
+		files    map[string]string
+	}{
+		{
+			scenario: "when exporting csv",
+			dsn:      "csv://",
+			files: map[string]string{
+				"users.csv":  "id,email\n1,john@example.com\n2,\n",
+				"orders.csv": "id,email\n1,john@example.com\n2,\n",
+			},
+		},
+		{
+			scenario: "when exporting json lines",
+			dsn:      "jsonl://",
+			files: map[string]string{
+				"users.jsonl":  "{\"id\":1,\"email\":\"john@example.com\"}\n{\"id\":2,\"email\":null}\n",
+				"orders.jsonl": "{\"id\":1,\"email\":\"john@example.com\"}\n{\"id\":2,\"email\":null}\n",
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.scenario, func(t *testing.T) {
+			t.Parallel()
+
+			dir := t.TempDir()
+			drv := &driver{}
+			require.True(t, drv.IsSupported(test.dsn+dir))
+
+			rdr := &mockReader{
+				tables:  []string{"users", "payments", "orders"},
+				failing: map[string]error{"payments": errors.New("connection reset")},
+			}
+			dmp, err := drv.NewConnection(dumper.ConnOpts{DSN: test.dsn + dir}, rdr)
+			require.NoError(t, err)
+
+			err = dmp.Dump(context.Background(), config.Tables{}, dumper.DumpOpts{Concurrency: 2})
+			assert.Equal(t, []string{"payments"}, dumper.FailedTables(err))
+			require.NoError(t, dmp.Close())
+
+			entries, err := os.ReadDir(dir)
+			require.NoError(t, err)
+			assert.Len(t, entries, len(test.files)+1, "the failed table is not exported")
+
+			for name, expected := range test.files {
+				content, err := os.ReadFile(filepath.Join(dir, name))
+				require.NoError(t, err)
+				assert.Equal(t, expected, string(content), name)
+			}
+
+			data, err := os.ReadFile(filepath.Join(dir, manifestFile))
+			require.NoError(t, err)
+
+			var manifest Manifest
+			require.NoError(t, json.Unmarshal(data, &manifest))
+			require.Len(t, manifest.Tables, 2)
+			assert.Equal(t, "orders", manifest.Tables[0].Name)
+			assert.Equal(t, "users", manifest.Tables[1].Name)
+			assert.Equal(t, []string{"id", "email"}, manifest.Tables[1].Columns)
+			assert.Equal(t, int64(2), manifest.Tables[1].Rows)
+		})
+	}
+}
+
+func TestIsSupported(t *testing.T) {
+	t.Parallel()
+
+	drv := &driver{}
+	assert.True(t, drv.IsSupported("csv:///tmp/export"))
+	assert.True(t, drv.IsSupported("jsonl://export"))
+	assert.True(t, drv.IsSupported("parquet:///tmp/export?row_group_size=1000"))
+	assert.False(t, drv.IsSupported("os://stdout/"))
+	assert.False(t, drv.IsSupported("postgres://localhost/klepto"))
+
+	_, err := drv.NewConnection(dumper.ConnOpts{DSN: "csv://"}, nil)
+	assert.Error(t, err)
+
+	opts, err := parseDSN("parquet:///tmp/export?row_group_size=1000")
+	require.NoError(t, err)
+	assert.Equal(t, Options{Format: formatParquet, Dir: "/tmp/export", RowGroupSize: 1000}, opts)
+
+	_, err = parseDSN("parquet:///tmp/export?row_group_size=0")
+	assert.Error(t, err)
+}
+
+type mockReader struct {
+	reader.Reader
+	tables  []string
+	failing map[string]error
+}
+
+func (m *mockReader) GetTables() ([]string, error) {
+	return m.tables, nil
+}
+
+func (m *mockReader) GetColumns(string) ([]*reader.Column, error) {
+	return []*reader.Column{{Name: "id", Type: "int"}, {Name: "email", Type: "varchar"}}, nil
+}
+
+func (m *mockReader) GetStructure() (string, error) {
+	return "", nil
+}
+
+func (m *mockReader) ReadTable(ctx context.Context, tableName string, rowChan chan<- database.Row, _ reader.ReadTableOpt) error {
+	defer close(rowChan)
+
+	if err, ok := m.failing[tableName]; ok {
+		return err
+	}
+
+	for _, row := range []database.Row{{"id": int64(1), "email": "john@example.com"}, {"id": int64(2), "email": nil}} {
+		select {
+		case rowChan <- row:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,233 @@
+package export
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hellofresh/klepto/pkg/database"
+	"github.com/hellofresh/klepto/pkg/reader"
+)
+
+type (
+	// encoder writes the rows of a table to a file.
+	encoder interface {
+		// WriteHeader writes the header of the file, if any.
+		WriteHeader() error
+		// Encode writes a row.
+		Encode(row database.Row) error
+		// Flush writes the buffered rows, and the file footer if any.
+		Flush() error
+	}
+
+	// csvEncoder writes the rows as CSV records, the header is the column names and nulls are empty fields.
+	csvEncoder struct {
+		w       *csv.Writer
+		columns []*reader.Column
+		record  []string
+	}
+
+	// jsonlEncoder writes the rows as JSON objects, one per line, with the values typed by their column.
+	jsonlEncoder struct {
+		w       *bufio.Writer
+		columns []*reader.Column
+		keys    [][]byte
+	}
+)
+
+// numberRegexp matches the numbers that can be written as JSON numbers.
+var numberRegexp = regexp.MustCompile(`^-?(0|[1-9]\d*)(\.\d+)?([eE][+-]?\d+)?$`)
+
+// newEncoder returns the encoder of the export format.
+func newEncoder(opts Options, w io.Writer, columns []*reader.Column) encoder {
+	switch opts.Format {
+	case formatParquet:
+		return newParquetEncoder(w, columns, opts.RowGroupSize)
+	case formatJSONL:
+		keys := make([][]byte, len(columns))
+		for i, c := range columns {
+			key, _ := json.Marshal(c.Name)
+			keys[i] = append(key, ':')
+		}
+
+		return &jsonlEncoder{w: bufio.NewWriter(w), columns: columns, keys: keys}
+	default:
+		return &csvEncoder{w: csv.NewWriter(w), columns: columns, record: make([]string, len(columns))}
+	}
+}
+
+// WriteHeader writes the column names.
+func (e *csvEncoder) WriteHeader() error {
+	return e.w.Write(reader.ColumnNames(e.columns))
+}
+
+// Encode writes the row as a record.
+func (e *csvEncoder) Encode(row database.Row) error {
+	for i, c := range e.columns {
+		value, err := textValue(row[c.Name], c)
+		if err != nil {
+			return fmt.Errorf("could not encode column %s: %w", c.Name, err)
+		}
+		e.record[i] = value
+	}
+
+	return e.w.Write(e.record)
+}
+
+// Flush writes the buffered records.
+func (e *csvEncoder) Flush() error {
+	e.w.Flush()
+	return e.w.Error()
+}
+
+// WriteHeader does nothing, every line has the column names.
+func (e *jsonlEncoder) WriteHeader() error {
+	return nil
+}
+
+// Encode writes the row as a JSON object, the keys are in the column order.
+func (e *jsonlEncoder) Encode(row database.Row) error {
+	e.w.WriteByte('{')
+	for i, c := range e.columns {
+		value, err := jsonValue(row[c.Name], c)
+		if err != nil {
+			return fmt.Errorf("could not encode column %s: %w", c.Name, err)
+		}
+		if i > 0 {
+			e.w.WriteByte(',')
+		}
+		e.w.Write(e.keys[i])
+		e.w.Write(value)
+	}
+	_, err := e.w.WriteString("}\n")
+
+	return err
+}
+
+// Flush writes the buffered lines.
+func (e *jsonlEncoder) Flush() error {
+	return e.w.Flush()
+}
+
+// textValue formats the value as text, nulls are empty and binary values are encoded in base64.
+func textValue(value interface{}, column *reader.Column) (string, error) {
+	switch v := deref(value).(type) {
+	case nil:
+		return "", nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	case int64:
+		return strconv.FormatInt(v, 10), nil
+	case int:
+		return strconv.Itoa(v), nil
+	case int32:
+		return strconv.FormatInt(int64(v), 10), nil
+	case uint64:
+		return strconv.FormatUint(v, 10), nil
+	case float64:
+		return strconv.FormatFloat(v, 'g', -1, 64), nil
+	case float32:
+		return strconv.FormatFloat(float64(v), 'g', -1, 32), nil
+	case time.Time:
+		return formatTime(v, column), nil
+	case []byte:
+		if column.Kind() == reader.KindBinary {
+			return base64.StdEncoding.EncodeToString(v), nil
+		}
+		return string(v), nil
+	case string:
+		if column.Kind() == reader.KindBinary {
+			return base64.StdEncoding.EncodeToString([]byte(v)), nil
+		}
+		return v, nil
+	default:
+		return "", fmt.Errorf("could not encode value of type %T", value)
+	}
+}
+
+// jsonValue formats the value as JSON: numeric columns are numbers, JSON columns are kept as documents,
+// binary values are base64 strings and the other values strings.
+func jsonValue(value interface{}, column *reader.Column) ([]byte, error) {
+	value = deref(value)
+	switch v := value.(type) {
+	case nil:
+		return []byte("null"), nil
+	case float64:
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			return json.Marshal(strconv.FormatFloat(v, 'g', -1, 64))
+		}
+	case float32:
+		if math.IsNaN(float64(v)) || math.IsInf(float64(v), 0) {
+			return json.Marshal(strconv.FormatFloat(float64(v), 'g', -1, 32))
+		}
+	}
+
+	text, err := textValue(value, column)
+	if err != nil {
+		return nil, err
+	}
+
+	switch value.(type) {
+	case bool, int64, int, int32, uint64, float64, float32:
+		return []byte(text), nil
+	case time.Time:
+		return json.Marshal(text)
+	}
+
+	switch column.Kind() {
+	case reader.KindInteger, reader.KindFloat, reader.KindDecimal:
+		if numberRegexp.MatchString(text) {
+			return []byte(text), nil
+		}
+	case reader.KindBool:
+		if b, err := strconv.ParseBool(text); err == nil {
+			return []byte(strconv.FormatBool(b)), nil
+		}
+	case reader.KindJSON:
+		if json.Valid([]byte(text)) {
+			return compact(text)
+		}
+	}
+
+	return json.Marshal(text)
+}
+
+// compact removes the insignificant spaces of the JSON document, so it fits on a line.
+func compact(document string) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := json.Compact(&buf, []byte(document)); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// formatTime formats dates without time, and other times in RFC 3339.
+func formatTime(t time.Time, column *reader.Column) string {
+	if strings.ToLower(column.Type) == "date" {
+		return t.Format(time.DateOnly)
+	}
+
+	return t.Format(time.RFC3339Nano)
+}
+
+// deref returns the value the pointers point to.
+func deref(value interface{}) interface{} {
+	if v, ok := value.(*interface{}); ok {
+		if v == nil {
+			return nil
+		}
+		return deref(*v)
+	}
+
+	return value
+}
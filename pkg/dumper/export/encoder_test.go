@@ -0,0 +1,106 @@
+package export
+
+import (
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/hellofresh/klepto/pkg/database"
+	"github.com/hellofresh/klepto/pkg/reader"
+)
+
+func TestEncoder(t *testing.T) {
+	t.Parallel()
+
+	columns := []*reader.Column{
+		{Name: "id", Type: "integer"},
+		{Name: "name", Type: "varchar"},
+		{Name: "price", Type: "numeric"},
+		{Name: "active", Type: "boolean"},
+		{Name: "avatar", Type: "bytea"},
+		{Name: "settings", Type: "jsonb"},
+		{Name: "born_on", Type: "date"},
+		{Name: "created_at", Type: "timestamp with time zone"},
+	}
+	createdAt := time.Date(2021, 1, 2, 3, 4, 5, 0, time.UTC)
+	rows := []database.Row{
+		{
+			"id":         int64(1),
+			"name":       "Doe, \"John\"",
+			"price":      []byte("10.50"),
+			"active":     true,
+			"avatar":     []byte{0xca, 0xfe},
+			"settings":   []byte(`{"theme": "dark"}`),
+			"born_on":    createdAt,
+			"created_at": createdAt,
+		},
+		{"id": int64(2), "name": nil, "price": nil, "active": nil, "avatar": nil, "settings": nil, "born_on": nil, "created_at": nil},
+	}
+
+	tests := []struct {
+		scenario string
+		format   string
+		expected string
+	}{
+		{
+			scenario: "when writing csv",
+			format:   formatCSV,
+			expected: "id,name,price,active,avatar,settings,born_on,created_at\n" +
+				"1,\"Doe, \"\"John\"\"\",10.50,true,yv4=,\"{\"\"theme\"\": \"\"dark\"\"}\",2021-01-02,2021-01-02T03:04:05Z\n" +
+				"2,,,,,,,\n",
+		},
+		{
+			scenario: "when writing json lines",
+			format:   formatJSONL,
+			expected: `{"id":1,"name":"Doe, \"John\"","price":10.50,"active":true,"avatar":"yv4=","settings":{"theme":"dark"},"born_on":"2021-01-02","created_at":"2021-01-02T03:04:05Z"}` + "\n" +
+				`{"id":2,"name":null,"price":null,"active":null,"avatar":null,"settings":null,"born_on":null,"created_at":null}` + "\n",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.scenario, func(t *testing.T) {
+			t.Parallel()
+
+			var buf strings.Builder
+			enc := newEncoder(Options{Format: test.format}, &buf, columns)
+			require.NoError(t, enc.WriteHeader())
+			for _, row := range rows {
+				require.NoError(t, enc.Encode(row))
+			}
+			require.NoError(t, enc.Flush())
+
+			assert.Equal(t, test.expected, buf.String())
+		})
+	}
+}
+
+func TestJSONValue(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		scenario string
+		value    interface{}
+		column   *reader.Column
+		expected string
+	}{
+		{scenario: "when a numeric column is not a number", value: []byte("NaN"), column: &reader.Column{Type: "numeric"}, expected: `"NaN"`},
+		{scenario: "when a number has leading zeros", value: "007", column: &reader.Column{Type: "varchar"}, expected: `"007"`},
+		{scenario: "when a mysql integer is read as bytes", value: []byte("-42"), column: &reader.Column{Type: "bigint"}, expected: `-42`},
+		{scenario: "when a json column is not valid", value: "{", column: &reader.Column{Type: "json"}, expected: `"{"`},
+		{scenario: "when a float is infinite", value: math.Inf(1), column: &reader.Column{Type: "double"}, expected: `"+Inf"`},
+	}
+
+	for _, test := range tests {
+		t.Run(test.scenario, func(t *testing.T) {
+			t.Parallel()
+
+			value, err := jsonValue(test.value, test.column)
+			require.NoError(t, err)
+			assert.Equal(t, test.expected, string(value))
+		})
+	}
+}
@@ -0,0 +1,96 @@
+package export
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/hellofresh/klepto/pkg/dumper"
+	"github.com/hellofresh/klepto/pkg/reader"
+)
+
+// Formats of the exported files, also the schemes of the dsn.
+const (
+	formatCSV     = "csv"
+	formatJSONL   = "jsonl"
+	formatParquet = "parquet"
+)
+
+// defaultRowGroupSize is the number of rows of the parquet row groups when not set.
+const defaultRowGroupSize = 100000
+
+type (
+	driver struct{}
+
+	// Options are the options of an export.
+	Options struct {
+		// Format is the format of the files, csv, jsonl or parquet.
+		Format string
+		// Dir is the directory the files are written to.
+		Dir string
+		// RowGroupSize is the number of rows buffered in memory before being written as a parquet row group.
+		RowGroupSize int64
+	}
+)
+
+// IsSupported checks if the dsn is a `csv://`, `jsonl://` or `parquet://` directory.
+func (m *driver) IsSupported(dsn string) bool {
+	return formatOf(dsn) != ""
+}
+
+// NewConnection creates the output directory and retrieves a new export dumper.
+func (m *driver) NewConnection(opts dumper.ConnOpts, rdr reader.Reader) (dumper.Dumper, error) {
+	exportOpts, err := parseDSN(opts.DSN)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := os.MkdirAll(exportOpts.Dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create export directory: %w", err)
+	}
+
+	return NewDumper(exportOpts, rdr, opts.Progress), nil
+}
+
+// formatOf returns the format of the dsn, empty when it is not an export.
+func formatOf(dsn string) string {
+	for _, f := range []string{formatCSV, formatJSONL, formatParquet} {
+		if strings.HasPrefix(dsn, f+"://") {
+			return f
+		}
+	}
+
+	return ""
+}
+
+// parseDSN returns the export options of the dsn, the parquet row group size is set with the `row_group_size` param.
+func parseDSN(dsn string) (Options, error) {
+	opts := Options{Format: formatOf(dsn), RowGroupSize: defaultRowGroupSize}
+
+	location, rawParams, _ := strings.Cut(strings.TrimPrefix(dsn, opts.Format+"://"), "?")
+	params, err := url.ParseQuery(rawParams)
+	if err != nil {
+		return opts, fmt.Errorf("invalid export params: %w", err)
+	}
+
+	opts.Dir = location
+	if opts.Dir == "" {
+		return opts, errors.New("export directory can not be empty")
+	}
+
+	if size := params.Get("row_group_size"); size != "" {
+		opts.RowGroupSize, err = strconv.ParseInt(size, 10, 64)
+		if err != nil || opts.RowGroupSize <= 0 {
+			return opts, fmt.Errorf("invalid row group size %q, expected a positive number of rows", size)
+		}
+	}
+
+	return opts, nil
+}
+
+func init() {
+	dumper.Register("export", &driver{})
+}
@@ -0,0 +1,271 @@
+package export
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/parquet-go/parquet-go"
+	"github.com/parquet-go/parquet-go/compress/snappy"
+
+	"github.com/hellofresh/klepto/pkg/database"
+	"github.com/hellofresh/klepto/pkg/reader"
+)
+
+// Parquet types of the time columns.
+const (
+	timeDate = iota
+	timeTimestamp
+	timeOfDay
+)
+
+// timeLayouts are the layouts of the times read as text, e.g. by mysql.
+var timeLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02 15:04:05.999999999Z07:00",
+	"2006-01-02 15:04:05.999999999-07",
+	"2006-01-02 15:04:05.999999999",
+	time.DateOnly,
+}
+
+// parquetEncoder writes the rows to a parquet file, the rows are buffered in memory until a row group is complete.
+// Every column is optional and typed from the column kind.
+type parquetEncoder struct {
+	w       *parquet.Writer
+	columns []*reader.Column
+	// leaves are the schema column indexes of the columns, the schema sorts the columns by name
+	leaves []int
+}
+
+func newParquetEncoder(w io.Writer, columns []*reader.Column, rowGroupSize int64) *parquetEncoder {
+	group := make(parquet.Group, len(columns))
+	for _, c := range columns {
+		group[c.Name] = parquet.Optional(parquetNode(c))
+	}
+	schema := parquet.NewSchema("row", group)
+
+	leaves := make([]int, len(columns))
+	for i, c := range columns {
+		leaf, _ := schema.Lookup(c.Name)
+		leaves[i] = leaf.ColumnIndex
+	}
+
+	return &parquetEncoder{
+		w:       parquet.NewWriter(w, schema, parquet.MaxRowsPerRowGroup(rowGroupSize), parquet.Compression(&snappy.Codec{})),
+		columns: columns,
+		leaves:  leaves,
+	}
+}
+
+// WriteHeader does nothing, the schema is written in the footer.
+func (e *parquetEncoder) WriteHeader() error {
+	return nil
+}
+
+// Encode buffers the row, the row group is written once it has the configured number of rows.
+func (e *parquetEncoder) Encode(row database.Row) error {
+	values := make(parquet.Row, len(e.columns))
+	for i, c := range e.columns {
+		value, err := parquetValue(row[c.Name], c)
+		if err != nil {
+			return fmt.Errorf("could not encode column %s: %w", c.Name, err)
+		}
+
+		if value.IsNull() {
+			values[e.leaves[i]] = value.Level(0, 0, e.leaves[i])
+		} else {
+			values[e.leaves[i]] = value.Level(0, 1, e.leaves[i])
+		}
+	}
+
+	_, err := e.w.WriteRows([]parquet.Row{values})
+	return err
+}
+
+// Flush writes the last row group and the file footer.
+func (e *parquetEncoder) Flush() error {
+	return e.w.Close()
+}
+
+// parquetNode returns the parquet type of the column.
+func parquetNode(c *reader.Column) parquet.Node {
+	switch c.Kind() {
+	case reader.KindInteger:
+		if unsignedBigint(c) {
+			return parquet.Uint(64)
+		}
+		return parquet.Int(64)
+	case reader.KindFloat:
+		return parquet.Leaf(parquet.DoubleType)
+	case reader.KindBool:
+		return parquet.Leaf(parquet.BooleanType)
+	case reader.KindBinary:
+		return parquet.Leaf(parquet.ByteArrayType)
+	case reader.KindJSON:
+		return parquet.JSON()
+	case reader.KindTime:
+		switch timeType(c) {
+		case timeDate:
+			return parquet.Date()
+		case timeTimestamp:
+			return parquet.Timestamp(parquet.Microsecond)
+		}
+	}
+
+	// decimals are kept as text, their precision and scale are not known
+	return parquet.String()
+}
+
+// parquetValue converts the value to the parquet type of the column.
+func parquetValue(value interface{}, c *reader.Column) (parquet.Value, error) {
+	value = deref(value)
+	// MySQL zero dates are not valid times, they are read as null by the mysql clients
+	if value == nil || c.IsZeroDate(value) {
+		return parquet.NullValue(), nil
+	}
+
+	switch c.Kind() {
+	case reader.KindInteger:
+		if unsignedBigint(c) {
+			return uint64Value(value, c)
+		}
+
+		switch v := value.(type) {
+		case int64:
+			return parquet.Int64Value(v), nil
+		case bool:
+			if v {
+				return parquet.Int64Value(1), nil
+			}
+			return parquet.Int64Value(0), nil
+		}
+
+		text, err := textValue(value, c)
+		if err != nil {
+			return parquet.Value{}, err
+		}
+		v, err := strconv.ParseInt(text, 10, 64)
+		if err != nil {
+			return parquet.Value{}, fmt.Errorf("invalid integer %q", text)
+		}
+		return parquet.Int64Value(v), nil
+	case reader.KindFloat:
+		if v, ok := value.(float64); ok {
+			return parquet.DoubleValue(v), nil
+		}
+
+		text, err := textValue(value, c)
+		if err != nil {
+			return parquet.Value{}, err
+		}
+		v, err := strconv.ParseFloat(text, 64)
+		if err != nil {
+			return parquet.Value{}, fmt.Errorf("invalid float %q", text)
+		}
+		return parquet.DoubleValue(v), nil
+	case reader.KindBool:
+		if v, ok := value.(bool); ok {
+			return parquet.BooleanValue(v), nil
+		}
+
+		text, err := textValue(value, c)
+		if err != nil {
+			return parquet.Value{}, err
+		}
+		v, err := strconv.ParseBool(text)
+		if err != nil {
+			return parquet.Value{}, fmt.Errorf("invalid boolean %q", text)
+		}
+		return parquet.BooleanValue(v), nil
+	case reader.KindBinary:
+		switch v := value.(type) {
+		case []byte:
+			return parquet.ByteArrayValue(v), nil
+		case string:
+			return parquet.ByteArrayValue([]byte(v)), nil
+		}
+	case reader.KindTime:
+		if timeType(c) != timeOfDay {
+			return timeValue(value, c)
+		}
+	}
+
+	text, err := textValue(value, c)
+	if err != nil {
+		return parquet.Value{}, err
+	}
+	return parquet.ByteArrayValue([]byte(text)), nil
+}
+
+// uint64Value converts the value to an unsigned integer, stored with the bits of an int64.
+func uint64Value(value interface{}, c *reader.Column) (parquet.Value, error) {
+	switch v := value.(type) {
+	case uint64:
+		return parquet.Int64Value(int64(v)), nil
+	case int64:
+		if v >= 0 {
+			return parquet.Int64Value(v), nil
+		}
+	}
+
+	text, err := textValue(value, c)
+	if err != nil {
+		return parquet.Value{}, err
+	}
+	v, err := strconv.ParseUint(text, 10, 64)
+	if err != nil {
+		return parquet.Value{}, fmt.Errorf("invalid unsigned integer %q", text)
+	}
+	return parquet.Int64Value(int64(v)), nil
+}
+
+// unsignedBigint returns whether the column is an unsigned bigint, which values do not all fit in an int64.
+func unsignedBigint(c *reader.Column) bool {
+	return c.Unsigned && strings.EqualFold(c.Type, "bigint")
+}
+
+// timeValue converts the value to a date, the days since epoch, or to a timestamp, the microseconds since epoch.
+func timeValue(value interface{}, c *reader.Column) (parquet.Value, error) {
+	t, ok := value.(time.Time)
+	if !ok {
+		text, err := textValue(value, c)
+		if err != nil {
+			return parquet.Value{}, err
+		}
+		if t, ok = parseTime(text); !ok {
+			return parquet.Value{}, fmt.Errorf("invalid time %q", text)
+		}
+	}
+
+	if timeType(c) == timeDate {
+		days := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC).Unix() / 86400
+		return parquet.Int32Value(int32(days)), nil
+	}
+
+	return parquet.Int64Value(t.UnixMicro()), nil
+}
+
+func parseTime(text string) (time.Time, bool) {
+	for _, layout := range timeLayouts {
+		if t, err := time.Parse(layout, text); err == nil {
+			return t, true
+		}
+	}
+
+	return time.Time{}, false
+}
+
+// timeType returns whether the time column is a date, a timestamp or a time of day.
+func timeType(c *reader.Column) int {
+	typ := strings.ToLower(c.Type)
+	switch {
+	case typ == "date":
+		return timeDate
+	case strings.HasPrefix(typ, "time") && !strings.HasPrefix(typ, "timestamp"):
+		return timeOfDay
+	default:
+		return timeTimestamp
+	}
+}
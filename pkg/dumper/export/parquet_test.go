@@ -0,0 +1,183 @@
+package export
+
+import (
+	"bytes"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/parquet-go/parquet-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/hellofresh/klepto/pkg/database"
+	"github.com/hellofresh/klepto/pkg/reader"
+)
+
+func TestParquetEncoder(t *testing.T) {
+	t.Parallel()
+
+	columns := []*reader.Column{
+		{Name: "id", Type: "bigint"},
+		{Name: "name", Type: "varchar"},
+		{Name: "price", Type: "double"},
+		{Name: "active", Type: "boolean"},
+		{Name: "avatar", Type: "blob"},
+		{Name: "born_on", Type: "date"},
+		{Name: "created_at", Type: "datetime"},
+	}
+	rows := []database.Row{
+		{
+			"id":         []byte("1"),
+			"name":       "John",
+			"price":      []byte("10.5"),
+			"active":     true,
+			"avatar":     []byte{0xca, 0xfe},
+			"born_on":    []byte("1970-01-11"),
+			"created_at": []byte("2021-01-02 03:04:05"),
+		},
+		{"id": int64(2), "name": nil, "price": 0.5, "active": []byte("0"), "avatar": nil, "born_on": nil, "created_at": nil},
+		{"id": int64(3)},
+		{"id": int64(4)},
+		{"id": int64(5)},
+	}
+
+	var buf bytes.Buffer
+	enc := newEncoder(Options{Format: formatParquet, RowGroupSize: 2}, &buf, columns)
+	require.NoError(t, enc.WriteHeader())
+	for _, row := range rows {
+		require.NoError(t, enc.Encode(row))
+	}
+	require.NoError(t, enc.Flush())
+
+	f, err := parquet.OpenFile(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	require.NoError(t, err)
+	assert.Equal(t, int64(5), f.NumRows())
+	assert.Len(t, f.RowGroups(), 3, "the rows are written in groups of 2")
+
+	var read []parquet.Row
+	rdr := parquet.NewReader(bytes.NewReader(buf.Bytes()))
+	for {
+		batch := make([]parquet.Row, 5)
+		n, err := rdr.ReadRows(batch)
+		// the values reference the reader buffers, reused by the next read
+		for _, row := range batch[:n] {
+			read = append(read, row.Clone())
+		}
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+	}
+	require.Len(t, read, 5)
+
+	value := func(row parquet.Row, column string) parquet.Value {
+		leaf, ok := f.Schema().Lookup(column)
+		require.True(t, ok, column)
+		return row[leaf.ColumnIndex]
+	}
+
+	first := read[0]
+	assert.Equal(t, int64(1), value(first, "id").Int64())
+	assert.Equal(t, "John", value(first, "name").String())
+	assert.Equal(t, 10.5, value(first, "price").Double())
+	assert.True(t, value(first, "active").Boolean())
+	assert.Equal(t, []byte{0xca, 0xfe}, value(first, "avatar").ByteArray())
+	assert.Equal(t, int32(10), value(first, "born_on").Int32())
+	assert.Equal(t, time.Date(2021, 1, 2, 3, 4, 5, 0, time.UTC).UnixMicro(), value(first, "created_at").Int64())
+
+	second := read[1]
+	assert.Equal(t, int64(2), value(second, "id").Int64())
+	assert.True(t, value(second, "name").IsNull())
+	assert.False(t, value(second, "active").Boolean())
+	assert.True(t, value(second, "avatar").IsNull())
+	assert.True(t, value(second, "created_at").IsNull())
+}
+
+func TestParquetValueInvalid(t *testing.T) {
+	t.Parallel()
+
+	_, err := parquetValue("abc", &reader.Column{Type: "int"})
+	assert.Error(t, err)
+
+	_, err = parquetValue([]byte("yesterday"), &reader.Column{Type: "timestamp"})
+	assert.Error(t, err)
+}
+
+func TestParquetValue(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		scenario string
+		value    interface{}
+		column   *reader.Column
+		expected parquet.Value
+	}{
+		{
+			scenario: "when an unsigned bigint is above the max int64",
+			value:    []byte("18446744073709551615"),
+			column:   &reader.Column{Type: "bigint", Unsigned: true},
+			expected: parquet.Int64Value(-1),
+		},
+		{
+			scenario: "when an unsigned bigint is read as an uint64",
+			value:    uint64(9223372036854775808),
+			column:   &reader.Column{Type: "bigint", Unsigned: true},
+			expected: parquet.Int64Value(-9223372036854775808),
+		},
+		{
+			scenario: "when an unsigned int is read",
+			value:    []byte("4294967295"),
+			column:   &reader.Column{Type: "int", Unsigned: true},
+			expected: parquet.Int64Value(4294967295),
+		},
+		{
+			scenario: "when a datetime is a mysql zero date",
+			value:    []byte("0000-00-00 00:00:00"),
+			column:   &reader.Column{Type: "datetime"},
+			expected: parquet.NullValue(),
+		},
+		{
+			scenario: "when a date is a mysql zero date",
+			value:    "0000-00-00",
+			column:   &reader.Column{Type: "date"},
+			expected: parquet.NullValue(),
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.scenario, func(t *testing.T) {
+			t.Parallel()
+
+			value, err := parquetValue(test.value, test.column)
+			require.NoError(t, err)
+			assert.True(t, parquet.Equal(test.expected, value), "expected %v, got %v", test.expected, value)
+		})
+	}
+}
+
+func TestParquetUnsignedBigint(t *testing.T) {
+	t.Parallel()
+
+	columns := []*reader.Column{{Name: "id", Type: "bigint", Unsigned: true}}
+
+	var buf bytes.Buffer
+	enc := newEncoder(Options{Format: formatParquet}, &buf, columns)
+	require.NoError(t, enc.WriteHeader())
+	require.NoError(t, enc.Encode(database.Row{"id": []byte("18446744073709551615")}))
+	require.NoError(t, enc.Flush())
+
+	f, err := parquet.OpenFile(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	require.NoError(t, err)
+	leaf, ok := f.Schema().Lookup("id")
+	require.True(t, ok)
+	assert.Equal(t, "INT(64,false)", leaf.Node.Type().LogicalType().String(), "the values are unsigned")
+
+	rows := make([]parquet.Row, 1)
+	n, err := parquet.NewReader(bytes.NewReader(buf.Bytes())).ReadRows(rows)
+	if err != io.EOF {
+		require.NoError(t, err)
+	}
+	require.Equal(t, 1, n)
+	assert.Equal(t, uint64(18446744073709551615), rows[0][leaf.ColumnIndex].Uint64())
+}
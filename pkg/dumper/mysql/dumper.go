@@ -1,13 +1,17 @@
 package mysql
 
 import (
+	"context"
 	"database/sql"
 	"encoding/csv"
+	"encoding/hex"
 	"fmt"
 	"io"
+	"strconv"
 	"strings"
 	"sync"
 	"sync/atomic"
+	"time"
 
 	"github.com/go-sql-driver/mysql"
 	log "github.com/sirupsen/logrus"
@@ -15,7 +19,10 @@ import (
 	"github.com/hellofresh/klepto/pkg/database"
 	"github.com/hellofresh/klepto/pkg/dumper"
 	"github.com/hellofresh/klepto/pkg/dumper/engine"
+	"github.com/hellofresh/klepto/pkg/journal"
+	"github.com/hellofresh/klepto/pkg/progress"
 	"github.com/hellofresh/klepto/pkg/reader"
+	"github.com/hellofresh/klepto/pkg/schema"
 )
 
 const (
@@ -28,32 +35,68 @@ type (
 		reader              reader.Reader
 		setGlobalInline     sync.Once
 		disableGlobalInline bool
+		journal             *journal.Journal
+		progress            *progress.Tracker
+		// unchecked are the tables loaded without foreign key checks
+		unchecked map[string]bool
+		// source is the dialect of the source database, its structure is translated when it is not mysql
+		source string
 	}
 )
 
 // NewDumper returns a new mysql dumper.
-func NewDumper(conn *sql.DB, rdr reader.Reader) dumper.Dumper {
+func NewDumper(opts dumper.ConnOpts, conn *sql.DB, rdr reader.Reader) dumper.Dumper {
 	return engine.New(rdr, &myDumper{
-		conn:   conn,
-		reader: rdr,
-	})
+		conn:     conn,
+		reader:   rdr,
+		journal:  opts.Journal,
+		progress: opts.Progress,
+		source:   schema.DialectOf(opts.Source),
+	}, opts.Progress)
 }
 
-// DumpStructure dump the mysql database structure.
-func (d *myDumper) DumpStructure(sql string) error {
-	if _, err := d.conn.Exec(sql); err != nil {
+// DumpStructure dump the mysql database structure, the structure of other databases is translated.
+func (d *myDumper) DumpStructure(ctx context.Context, sql string) error {
+	if d.source != "" && d.source != schema.MySQL {
+		return d.dumpTranslatedStructure(ctx)
+	}
+
+	if _, err := d.conn.ExecContext(ctx, sql); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// dumpTranslatedStructure creates the tables from the structure of the source database.
+func (d *myDumper) dumpTranslatedStructure(ctx context.Context) error {
+	s, err := schema.Read(d.reader, d.source)
+	if err != nil {
+		return err
+	}
+
+	statements, err := schema.Render(s, schema.MySQL)
+	if err != nil {
 		return err
 	}
 
+	// mysql commits the DDL statements, they can't be run in a transaction
+	for _, stmt := range statements {
+		log.WithField("statement", stmt).Debug("creating translated structure")
+		if _, err := d.conn.ExecContext(ctx, stmt); err != nil {
+			return fmt.Errorf("failed to execute %q: %w", stmt, err)
+		}
+	}
+
 	return nil
 }
 
 // DumpTable dumps a mysql table.
-func (d *myDumper) DumpTable(tableName string, rowChan <-chan database.Row) error {
+func (d *myDumper) DumpTable(ctx context.Context, tableName string, rowChan <-chan database.Row) error {
 	var err error
 	d.setGlobalInline.Do(func() {
 		var allowLocalInline bool
-		r := d.conn.QueryRow("SELECT @@GLOBAL.local_infile")
+		r := d.conn.QueryRowContext(ctx, "SELECT @@GLOBAL.local_infile")
 		if err = r.Scan(&allowLocalInline); err != nil {
 			return
 		}
@@ -62,7 +105,11 @@ func (d *myDumper) DumpTable(tableName string, rowChan <-chan database.Row) erro
 			return
 		}
 
-		if _, err = d.conn.Exec("SET GLOBAL local_infile=1"); err != nil {
+		if err = d.journal.Record(journal.Entry{Action: journal.ActionEnableLocalInfile}); err != nil {
+			return
+		}
+
+		if _, err = d.conn.ExecContext(ctx, "SET GLOBAL local_infile=1"); err != nil {
 			return
 		}
 		d.disableGlobalInline = true
@@ -71,12 +118,13 @@ func (d *myDumper) DumpTable(tableName string, rowChan <-chan database.Row) erro
 		return err
 	}
 
-	txn, err := d.conn.Begin()
+	// the transaction is rolled back when the context is done
+	txn, err := d.conn.BeginTx(ctx, nil)
 	if err != nil {
 		return fmt.Errorf("failed to open transaction: %w", err)
 	}
 
-	insertedRows, err := d.insertIntoTable(txn, tableName, rowChan)
+	insertedRows, err := d.insertIntoTable(ctx, txn, tableName, rowChan)
 	if err != nil {
 		defer func() {
 			if err := txn.Rollback(); err != nil {
@@ -99,6 +147,21 @@ func (d *myDumper) DumpTable(tableName string, rowChan <-chan database.Row) erro
 	return nil
 }
 
+// PreDumpTables disables the foreign key checks while loading the given tables.
+func (d *myDumper) PreDumpTables(_ context.Context, tables []string) error {
+	d.unchecked = make(map[string]bool, len(tables))
+	for _, tbl := range tables {
+		d.unchecked[tbl] = true
+	}
+
+	return nil
+}
+
+// PostDumpTables does nothing, the foreign key checks are only disabled in the load transactions.
+func (d *myDumper) PostDumpTables(context.Context, []string) error {
+	return nil
+}
+
 // Close closes the mysql database connection.
 func (d *myDumper) Close() error {
 	var errGlobalInline error
@@ -119,18 +182,52 @@ func (d *myDumper) Close() error {
 		return fmt.Errorf("failed `SET GLOBAL local_infile=0` please do this manually! : %w", errGlobalInline)
 	}
 
+	return d.journal.Clear()
+}
+
+// Repair disables the local_infile global setting left enabled by an interrupted dump.
+func (d *myDumper) Repair(ctx context.Context, entries []journal.Entry) error {
+	for _, entry := range entries {
+		switch entry.Action {
+		case journal.ActionEnableLocalInfile:
+			log.Info("Disabling local_infile")
+			if _, err := d.conn.ExecContext(ctx, "SET GLOBAL local_infile=0"); err != nil {
+				return fmt.Errorf("failed to `SET GLOBAL local_infile=0`: %w", err)
+			}
+		default:
+			return fmt.Errorf("unsupported journal action %q", entry.Action)
+		}
+	}
+
 	return nil
 }
 
-func (d *myDumper) insertIntoTable(txn *sql.Tx, tableName string, rowChan <-chan database.Row) (int64, error) {
-	columns, err := d.reader.GetColumns(tableName)
+func (d *myDumper) insertIntoTable(ctx context.Context, txn *sql.Tx, tableName string, rowChan <-chan database.Row) (int64, error) {
+	allColumns, err := d.reader.GetColumns(tableName)
 	if err != nil {
 		return 0, fmt.Errorf("failed to get columns: %w", err)
 	}
 
+	// Generated columns can not be written, their values are computed by the database
+	var columns []*reader.Column
+	for _, column := range allColumns {
+		if !column.Generated {
+			columns = append(columns, column)
+		}
+	}
+
+	// Binary values are written hex encoded into a variable and decoded by the server
 	columnsQuoted := make([]string, len(columns))
+	var binarySets []string
 	for i, column := range columns {
-		columnsQuoted[i] = d.quoteIdentifier(column)
+		if column.Kind() != reader.KindBinary {
+			columnsQuoted[i] = d.quoteIdentifier(column.Name)
+			continue
+		}
+
+		variable := fmt.Sprintf("@binary%d", i)
+		columnsQuoted[i] = variable
+		binarySets = append(binarySets, fmt.Sprintf("%s = UNHEX(%s)", d.quoteIdentifier(column.Name), variable))
 	}
 
 	query := fmt.Sprintf(
@@ -139,10 +236,14 @@ func (d *myDumper) insertIntoTable(txn *sql.Tx, tableName string, rowChan <-chan
 		d.quoteIdentifier(tableName),
 		strings.Join(columnsQuoted, ","),
 	)
+	if len(binarySets) > 0 {
+		query += " SET " + strings.Join(binarySets, ",")
+	}
 
 	// Write all rows as csv to the pipe
 	rowReader, rowWriter := io.Pipe()
 	var inserted int64
+	table := d.progress.Table(tableName)
 	go func(writer *io.PipeWriter) {
 		defer writer.Close()
 
@@ -158,30 +259,21 @@ func (d *myDumper) insertIntoTable(txn *sql.Tx, tableName string, rowChan <-chan
 			// Put the data in the correct order and format
 			rowValues := make([]string, len(columns))
 			for i, col := range columns {
-				switch v := row[col].(type) {
-				case nil:
-					rowValues[i] = null
-				case string:
-					rowValues[i] = v
-				case []uint8:
-					rowValues[i] = string(v)
-				default:
-					log.WithField("type", v).Info("we have an unhandled type. attempting to convert to a string \n")
-					stringValue, ok := row[col].(string)
-					if ok {
-						rowValues[i] = stringValue
-					} else {
-						log.WithField("type", v).Errorf("failed to convert to string, writing as %v", v)
-						rowValues[i] = fmt.Sprintf("%v", v)
-					}
-				}
+				rowValues[i] = csvValue(row[col.Name], col)
+			}
+
+			var size int64
+			for _, v := range rowValues {
+				size += int64(len(v))
 			}
+			table.AddBytes(size)
 
 			if err := w.Write(rowValues); err != nil {
 				log.WithError(err).Error("error writing record to mysql")
 			}
 
 			atomic.AddInt64(&inserted, 1)
+			table.AddWritten(1)
 		}
 	}(rowWriter)
 
@@ -189,17 +281,65 @@ func (d *myDumper) insertIntoTable(txn *sql.Tx, tableName string, rowChan <-chan
 	mysql.RegisterReaderHandler(tableName, func() io.Reader { return rowReader })
 	defer mysql.DeregisterReaderHandler(tableName)
 
-	if _, err := txn.Exec("SET foreign_key_checks = 0;"); err != nil {
-		return 0, fmt.Errorf("failed to disable foreign key checks: %w", err)
+	// the pipe is closed when the load stops early, so the rows writer does not block
+	defer rowReader.Close()
+
+	// the setting is kept by the session, so it is always set as the connection may be reused
+	foreignKeyChecks := 1
+	if d.unchecked[tableName] {
+		foreignKeyChecks = 0
+	}
+	if _, err := txn.ExecContext(ctx, fmt.Sprintf("SET foreign_key_checks = %d;", foreignKeyChecks)); err != nil {
+		return 0, fmt.Errorf("failed to set foreign key checks: %w", err)
 	}
 
-	if _, err := txn.Exec(query); err != nil {
+	if _, err := txn.ExecContext(ctx, query); err != nil {
 		return 0, fmt.Errorf("failed to execute query: %w", err)
 	}
 
 	return inserted, nil
 }
 
+// csvValue formats the value as a field of the loaded csv. The values read from other databases than mysql
+// are typed, their booleans are written as numbers and their times in the mysql format and in UTC.
+func csvValue(value interface{}, col *reader.Column) string {
+	switch v := value.(type) {
+	case nil:
+		return null
+	case []byte:
+		if col.Kind() == reader.KindBinary {
+			return hex.EncodeToString(v)
+		}
+		return string(v)
+	case string:
+		if col.Kind() == reader.KindBinary {
+			return hex.EncodeToString([]byte(v))
+		}
+		return v
+	case bool:
+		if v {
+			return "1"
+		}
+		return "0"
+	case time.Time:
+		switch strings.ToLower(col.Type) {
+		case "date":
+			return v.Format(time.DateOnly)
+		case "time", "time without time zone", "time with time zone":
+			return v.Format("15:04:05.999999")
+		default:
+			return v.UTC().Format("2006-01-02 15:04:05.999999")
+		}
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case float64:
+		return strconv.FormatFloat(v, 'g', -1, 64)
+	default:
+		log.WithField("type", fmt.Sprintf("%T", v)).Debug("unhandled type, writing it with its default format")
+		return fmt.Sprintf("%v", v)
+	}
+}
+
 func (d *myDumper) quoteIdentifier(name string) string {
 	return fmt.Sprintf("`%s`", strings.Replace(name, "`", "``", -1))
 }
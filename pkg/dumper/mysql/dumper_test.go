@@ -0,0 +1,40 @@
+package mysql
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/hellofresh/klepto/pkg/reader"
+)
+
+func TestCSVValue(t *testing.T) {
+	t.Parallel()
+
+	createdAt := time.Date(2021, 1, 2, 3, 4, 5, 600000000, time.FixedZone("CET", 3600))
+
+	tests := []struct {
+		scenario string
+		value    interface{}
+		column   *reader.Column
+		expected string
+	}{
+		{scenario: "when the value is null", value: nil, column: &reader.Column{Type: "text"}, expected: null},
+		{scenario: "when the value is mysql text", value: []byte("john"), column: &reader.Column{Type: "varchar"}, expected: "john"},
+		{scenario: "when the value is binary", value: []byte{0xde, 0xad}, column: &reader.Column{Type: "bytea"}, expected: "dead"},
+		{scenario: "when the value is a boolean", value: true, column: &reader.Column{Type: "boolean"}, expected: "1"},
+		{scenario: "when the value is an integer", value: int64(-42), column: &reader.Column{Type: "bigint"}, expected: "-42"},
+		{scenario: "when the value is a float", value: 1.5, column: &reader.Column{Type: "double precision"}, expected: "1.5"},
+		{scenario: "when the value is a timestamp", value: createdAt, column: &reader.Column{Type: "timestamp with time zone"}, expected: "2021-01-02 02:04:05.6"},
+		{scenario: "when the value is a date", value: createdAt, column: &reader.Column{Type: "date"}, expected: "2021-01-02"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.scenario, func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, test.expected, csvValue(test.value, test.column))
+		})
+	}
+}
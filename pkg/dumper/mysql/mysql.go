@@ -23,6 +23,11 @@ func (m *driver) IsSupported(dsn string) bool {
 	return err == nil
 }
 
+// Journaled returns true, the local_infile setting enabled is recorded in the journal.
+func (m *driver) Journaled() bool {
+	return true
+}
+
 // NewConnection creates a new mysql connection and retrieves a new mysql dumper.
 func (m *driver) NewConnection(opts dumper.ConnOpts, rdr reader.Reader) (dumper.Dumper, error) {
 	dsnCfg, err := mysql.ParseDSN(opts.DSN)
@@ -45,7 +50,7 @@ func (m *driver) NewConnection(opts dumper.ConnOpts, rdr reader.Reader) (dumper.
 	conn.SetMaxIdleConns(opts.MaxIdleConns)
 	conn.SetConnMaxLifetime(opts.MaxConnLifetime)
 
-	return NewDumper(conn, rdr), nil
+	return NewDumper(opts, conn, rdr), nil
 }
 
 func init() {
@@ -1,8 +1,11 @@
 package postgres
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
+	"sort"
+	"strconv"
 	"strings"
 
 	"github.com/lib/pq"
@@ -11,7 +14,10 @@ import (
 	"github.com/hellofresh/klepto/pkg/database"
 	"github.com/hellofresh/klepto/pkg/dumper"
 	"github.com/hellofresh/klepto/pkg/dumper/engine"
+	"github.com/hellofresh/klepto/pkg/journal"
+	"github.com/hellofresh/klepto/pkg/progress"
 	"github.com/hellofresh/klepto/pkg/reader"
+	"github.com/hellofresh/klepto/pkg/schema"
 )
 
 type (
@@ -26,35 +32,106 @@ type (
 		reader      reader.Reader
 		isRDS       bool
 		foreignKeys []foreignKeyInfo
+		journal     *journal.Journal
+		progress    *progress.Tracker
+		// source is the dialect of the source database, its structure is translated when it is not postgres
+		source string
+		// schemas renames the schemas of the source tables on the target database
+		schemas map[string]string
 	}
 )
 
 // NewDumper returns a new postgres dumper.
 func NewDumper(opts dumper.ConnOpts, conn *sql.DB, rdr reader.Reader) dumper.Dumper {
 	return engine.New(rdr, &pgDumper{
-		conn:   conn,
-		reader: rdr,
-		isRDS:  opts.IsRDS,
-	})
+		conn:     conn,
+		reader:   rdr,
+		isRDS:    opts.IsRDS,
+		journal:  opts.Journal,
+		progress: opts.Progress,
+		source:   schema.DialectOf(opts.Source),
+		schemas:  opts.Schemas,
+	}, opts.Progress)
 }
 
-// DumpStructure dump the mysql database structure.
-func (d *pgDumper) DumpStructure(sql string) error {
-	if _, err := d.conn.Exec(sql); err != nil {
+// DumpStructure dump the postgres database structure, the structure of other databases is translated.
+// The schemas of the schema map are renamed once the structure is created.
+func (d *pgDumper) DumpStructure(ctx context.Context, sql string) error {
+	if d.source != "" && d.source != schema.Postgres {
+		if err := d.dumpTranslatedStructure(ctx); err != nil {
+			return err
+		}
+	} else if _, err := d.conn.ExecContext(ctx, sql); err != nil {
 		return err
 	}
 
+	return d.renameSchemas(ctx)
+}
+
+// renameSchemas renames the schemas on the target database, the objects of a schema follow it.
+func (d *pgDumper) renameSchemas(ctx context.Context) error {
+	names := make([]string, 0, len(d.schemas))
+	for name := range d.schemas {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		log.WithFields(log.Fields{"schema": name, "target": d.schemas[name]}).Debug("renaming schema")
+		query := fmt.Sprintf("ALTER SCHEMA %s RENAME TO %s", pq.QuoteIdentifier(name), pq.QuoteIdentifier(d.schemas[name]))
+		if _, err := d.conn.ExecContext(ctx, query); err != nil {
+			return fmt.Errorf("failed to rename schema %s: %w", name, err)
+		}
+	}
+
 	return nil
 }
 
+// targetTable returns the name of the table on the target database, in its renamed schema.
+func (d *pgDumper) targetTable(tableName string) string {
+	return dumper.TargetTable(d.schemas, tableName)
+}
+
+// dumpTranslatedStructure creates the tables from the structure of the source database in a transaction.
+func (d *pgDumper) dumpTranslatedStructure(ctx context.Context) error {
+	s, err := schema.Read(d.reader, d.source)
+	if err != nil {
+		return err
+	}
+
+	statements, err := schema.Render(s, schema.Postgres)
+	if err != nil {
+		return err
+	}
+
+	txn, err := d.conn.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to open transaction: %w", err)
+	}
+	defer txn.Rollback()
+
+	for _, stmt := range statements {
+		log.WithField("statement", stmt).Debug("creating translated structure")
+		if _, err := txn.ExecContext(ctx, stmt); err != nil {
+			return fmt.Errorf("failed to execute %q: %w", stmt, err)
+		}
+	}
+
+	return txn.Commit()
+}
+
 // DumpTable dumps a postgres table.
-func (d *pgDumper) DumpTable(tableName string, rowChan <-chan database.Row) error {
-	txn, err := d.conn.Begin()
+func (d *pgDumper) DumpTable(ctx context.Context, tableName string, rowChan <-chan database.Row) error {
+	// the transaction is rolled back when the context is done
+	txn, err := d.conn.BeginTx(ctx, nil)
 	if err != nil {
 		return fmt.Errorf("failed to open transaction: %w", err)
 	}
 
-	insertedRows, err := d.insertIntoTable(txn, tableName, rowChan)
+	insertedRows, identities, err := d.insertIntoTable(ctx, txn, tableName, rowChan)
+	if err == nil {
+		err = d.setIdentities(ctx, txn, tableName, identities)
+	}
 	if err != nil {
 		defer func() {
 			if err := txn.Rollback(); err != nil {
@@ -77,42 +154,96 @@ func (d *pgDumper) DumpTable(tableName string, rowChan <-chan database.Row) erro
 	return nil
 }
 
-// PreDumpTables Disable triggers on all tables to avoid foreign key constraints
-func (d *pgDumper) PreDumpTables(tables []string) error {
+// setIdentities sets the sequences of the identity columns after the largest values copied, so the rows inserted
+// later on the target database get new values. The created structures, translated or not, start the sequences
+// from their start value. The columns without copied values or without a sequence are left as they are.
+func (d *pgDumper) setIdentities(ctx context.Context, txn *sql.Tx, tableName string, identities []identityMax) error {
+	table := quoteTable(d.targetTable(tableName))
+	for _, identity := range identities {
+		if !identity.copied {
+			continue
+		}
+
+		query := "SELECT setval(pg_get_serial_sequence($1, $2), $3)"
+		if _, err := txn.ExecContext(ctx, query, table, identity.column, identity.max); err != nil {
+			return fmt.Errorf("failed to set the sequence of %s: %w", identity.column, err)
+		}
+	}
+
+	return nil
+}
+
+// PreDumpTables Disable triggers on the given tables to avoid foreign key constraints
+func (d *pgDumper) PreDumpTables(ctx context.Context, tables []string) error {
 	// We can't use `SET session_replication_role = replica` because multiple connections and stuff
 	// For RDS databases, the superuser does not have the required permission to call
 	// DISABLE TRIGGER ALL, so manually remove and re-add all Foreign Keys
 	if !d.isRDS {
 		log.Debug("Disabling triggers")
 		for _, tbl := range tables {
-			query := fmt.Sprintf("ALTER TABLE %q DISABLE TRIGGER ALL", strings.Trim(tbl, "\""))
-			if _, err := d.conn.Exec(query); err != nil {
+			tbl = d.targetTable(tbl)
+			if err := d.journal.Record(journal.Entry{Action: journal.ActionDisableTriggers, Table: tbl}); err != nil {
+				return err
+			}
+
+			query := fmt.Sprintf("ALTER TABLE %s DISABLE TRIGGER ALL", quoteTable(tbl))
+			if _, err := d.conn.ExecContext(ctx, query); err != nil {
 				return fmt.Errorf("failed to disable triggers for %s: %w", tbl, err)
 			}
 		}
 		return nil
 	}
 
+	if len(tables) == 0 {
+		return nil
+	}
+
+	dumped := make(map[string]bool, len(tables))
+	for _, tbl := range tables {
+		dumped[d.targetTable(tbl)] = true
+	}
+
 	log.Debug("Removing foreign keys")
-	query := `SELECT conrelid::regclass::varchar tableName,
+	query := `SELECT n.nspname schemaName,
+		c.relname tableName,
 		conname constraintName,
 		pg_catalog.pg_get_constraintdef(r.oid, true) constraintDefinition
 		FROM pg_catalog.pg_constraint r
+		JOIN pg_catalog.pg_class c ON c.oid = r.conrelid
+		JOIN pg_catalog.pg_namespace n ON n.oid = c.relnamespace
 		WHERE r.contype = 'f'
-		AND r.connamespace = (SELECT n.oid FROM pg_namespace n WHERE n.nspname = current_schema())
 		`
-	rows, err := d.conn.Query(query)
+	rows, err := d.conn.QueryContext(ctx, query)
 	if err != nil {
 		return fmt.Errorf("failed to query ForeignKeys: %w", err)
 	}
 	defer rows.Close()
 	for rows.Next() {
-		var fk foreignKeyInfo
-		if err := rows.Scan(&fk.tableName, &fk.constraintName, &fk.constraintDefinition); err != nil {
+		var (
+			fk         foreignKeyInfo
+			schemaName string
+		)
+		if err := rows.Scan(&schemaName, &fk.tableName, &fk.constraintName, &fk.constraintDefinition); err != nil {
 			return fmt.Errorf("failed to load ForeignKeyInfo: %w", err)
 		}
-		query := fmt.Sprintf("ALTER TABLE %q DROP CONSTRAINT %q", strings.Trim(fk.tableName, "\""), strings.Trim(fk.constraintName, "\""))
-		if _, err := d.conn.Exec(query); err != nil {
+		fk.tableName = reader.TableName(schemaName, fk.tableName)
+		if !dumped[fk.tableName] {
+			continue
+		}
+
+		// the foreign keys only live in memory once dropped, they are journaled to be restored after a crash
+		err := d.journal.Record(journal.Entry{
+			Action:     journal.ActionDropForeignKey,
+			Table:      fk.tableName,
+			Name:       fk.constraintName,
+			Definition: fk.constraintDefinition,
+		})
+		if err != nil {
+			return err
+		}
+
+		query := fmt.Sprintf("ALTER TABLE %s DROP CONSTRAINT %q", quoteTable(fk.tableName), strings.Trim(fk.constraintName, "\""))
+		if _, err := d.conn.ExecContext(ctx, query); err != nil {
 			return fmt.Errorf("failed to drop constraint %s.%s: %w", fk.tableName, fk.constraintName, err)
 		}
 		d.foreignKeys = append(d.foreignKeys, fk)
@@ -120,30 +251,101 @@ func (d *pgDumper) PreDumpTables(tables []string) error {
 	return nil
 }
 
-// PostDumpTables enable triggers on all tables to enforce foreign key constraints
-func (d *pgDumper) PostDumpTables(tables []string) error {
+// PostDumpTables enable triggers on the given tables to enforce foreign key constraints
+func (d *pgDumper) PostDumpTables(ctx context.Context, tables []string) error {
 	// We can't use `SET session_replication_role = DEFAULT` because multiple connections and stuff
 	if !d.isRDS {
 		log.Debug("Reenabling triggers")
 		for _, tbl := range tables {
-			query := fmt.Sprintf("ALTER TABLE %q ENABLE TRIGGER ALL", strings.Trim(tbl, "\""))
-			if _, err := d.conn.Exec(query); err != nil {
-				return fmt.Errorf("failed to enable triggers for %s: %w", tbl, err)
+			if err := d.enableTriggers(ctx, d.targetTable(tbl)); err != nil {
+				return err
 			}
 		}
-		return nil
+		return d.journal.Clear()
 	}
 
 	log.Debug("Recreating foreign keys")
 	for _, fk := range d.foreignKeys {
-		query := fmt.Sprintf("ALTER TABLE %q ADD CONSTRAINT %q %s", strings.Trim(fk.tableName, "\""), strings.Trim(fk.constraintName, "\""), fk.constraintDefinition)
-		if _, err := d.conn.Exec(query); err != nil {
-			return fmt.Errorf("failed to re-create ForeignKey %s.%s: %w", fk.tableName, fk.constraintName, err)
+		if err := d.addForeignKey(ctx, fk); err != nil {
+			return err
+		}
+	}
+	return d.journal.Clear()
+}
+
+// Repair enables the triggers and re-creates the foreign keys left by an interrupted dump.
+func (d *pgDumper) Repair(ctx context.Context, entries []journal.Entry) error {
+	for _, entry := range entries {
+		switch entry.Action {
+		case journal.ActionDisableTriggers:
+			log.WithField("table", entry.Table).Info("Enabling triggers")
+			if err := d.enableTriggers(ctx, entry.Table); err != nil {
+				return err
+			}
+		case journal.ActionDropForeignKey:
+			fk := foreignKeyInfo{tableName: entry.Table, constraintName: entry.Name, constraintDefinition: entry.Definition}
+			exists, err := d.foreignKeyExists(ctx, fk)
+			if err != nil {
+				return err
+			}
+			if exists {
+				log.WithFields(log.Fields{"table": fk.tableName, "constraint": fk.constraintName}).Debug("foreign key already exists")
+				continue
+			}
+
+			log.WithFields(log.Fields{"table": fk.tableName, "constraint": fk.constraintName}).Info("Re-creating foreign key")
+			if err := d.addForeignKey(ctx, fk); err != nil {
+				return err
+			}
+		default:
+			return fmt.Errorf("unsupported journal action %q", entry.Action)
 		}
 	}
+
+	return nil
+}
+
+func (d *pgDumper) enableTriggers(ctx context.Context, tableName string) error {
+	query := fmt.Sprintf("ALTER TABLE %s ENABLE TRIGGER ALL", quoteTable(tableName))
+	if _, err := d.conn.ExecContext(ctx, query); err != nil {
+		return fmt.Errorf("failed to enable triggers for %s: %w", tableName, err)
+	}
 	return nil
 }
 
+func (d *pgDumper) addForeignKey(ctx context.Context, fk foreignKeyInfo) error {
+	query := fmt.Sprintf("ALTER TABLE %s ADD CONSTRAINT %q %s", quoteTable(fk.tableName), strings.Trim(fk.constraintName, "\""), fk.constraintDefinition)
+	if _, err := d.conn.ExecContext(ctx, query); err != nil {
+		return fmt.Errorf("failed to re-create ForeignKey %s.%s: %w", fk.tableName, fk.constraintName, err)
+	}
+	return nil
+}
+
+func (d *pgDumper) foreignKeyExists(ctx context.Context, fk foreignKeyInfo) (bool, error) {
+	var exists bool
+	err := d.conn.QueryRowContext(
+		ctx,
+		"SELECT EXISTS (SELECT 1 FROM pg_catalog.pg_constraint WHERE conname = $1 AND conrelid = $2::regclass)",
+		strings.Trim(fk.constraintName, "\""),
+		quoteTable(fk.tableName),
+	).Scan(&exists)
+	if err != nil {
+		return false, fmt.Errorf("failed to check ForeignKey %s.%s: %w", fk.tableName, fk.constraintName, err)
+	}
+	return exists, nil
+}
+
+// quoteTable quotes a table name, the schema of a qualified table name is quoted apart, e.g. `"billing"."invoices"`.
+// The names quoted by older journals are unquoted first.
+func quoteTable(tableName string) string {
+	schemaName, name := reader.SplitTableName(tableName)
+	if schemaName == reader.DefaultSchema {
+		return pq.QuoteIdentifier(name)
+	}
+
+	return pq.QuoteIdentifier(schemaName) + "." + pq.QuoteIdentifier(name)
+}
+
 // Close closes the postgres database connection.
 func (d *pgDumper) Close() error {
 	err := d.conn.Close()
@@ -153,21 +355,41 @@ func (d *pgDumper) Close() error {
 	return nil
 }
 
-func (d *pgDumper) insertIntoTable(txn *sql.Tx, tableName string, rowChan <-chan database.Row) (int64, error) {
-	columns, err := d.reader.GetColumns(tableName)
+// insertIntoTable copies the rows in the table and returns the number of rows and the largest values copied in the
+// integer identity columns. COPY writes the identity columns generated always without OVERRIDING SYSTEM VALUE.
+func (d *pgDumper) insertIntoTable(ctx context.Context, txn *sql.Tx, tableName string, rowChan <-chan database.Row) (int64, []identityMax, error) {
+	allColumns, err := d.reader.GetColumns(tableName)
 	if err != nil {
-		return 0, fmt.Errorf("failed to get columns: %w", err)
+		return 0, nil, fmt.Errorf("failed to get columns: %w", err)
 	}
 
+	// Generated columns can not be written, their values are computed by the database
+	var (
+		columns    []*reader.Column
+		identities []identityMax
+	)
+	for _, column := range allColumns {
+		if column.Generated {
+			continue
+		}
+		if column.Identity && column.Kind() == reader.KindInteger {
+			identities = append(identities, identityMax{column: column.Name})
+		}
+		columns = append(columns, column)
+	}
+	columnNames := reader.ColumnNames(columns)
+
 	logger := log.WithFields(log.Fields{
 		"table":   tableName,
-		"columns": columns,
+		"columns": columnNames,
 	})
 	logger.Debug("preparing copy in")
 
-	stmt, err := txn.Prepare(pq.CopyIn(tableName, columns...))
+	targetSchema, targetName := reader.SplitTableName(d.targetTable(tableName))
+
+	stmt, err := txn.PrepareContext(ctx, pq.CopyInSchema(targetSchema, targetName, columnNames...))
 	if err != nil {
-		return 0, fmt.Errorf("failed to prepare copy in: %w", err)
+		return 0, nil, fmt.Errorf("failed to prepare copy in: %w", err)
 	}
 
 	defer func() {
@@ -177,6 +399,7 @@ func (d *pgDumper) insertIntoTable(txn *sql.Tx, tableName string, rowChan <-chan
 	}()
 
 	var inserted int64
+	table := d.progress.Table(tableName)
 	for {
 		row, more := <-rowChan
 		if !more {
@@ -187,27 +410,66 @@ func (d *pgDumper) insertIntoTable(txn *sql.Tx, tableName string, rowChan <-chan
 		// Put the data in the correct order
 		rowValues := make([]interface{}, len(columns))
 		for i, col := range columns {
-			val := row[col]
-			if bytesVal, ok := val.([]byte); ok {
+			val := row[col.Name]
+			// Binary columns are kept as bytes for pq to encode them, the drivers return other types as bytes too
+			if bytesVal, ok := val.([]byte); ok && col.Kind() != reader.KindBinary {
 				val = string(bytesVal)
 			}
+			// MySQL zero dates have no postgres equivalent, they are read as null by the mysql clients
+			if col.IsZeroDate(val) {
+				val = nil
+			}
 
 			rowValues[i] = val
+			table.AddBytes(progress.ValueSize(val))
+		}
+
+		for i := range identities {
+			identities[i].observe(row[identities[i].column])
 		}
 
 		// Insert
-		_, err := stmt.Exec(rowValues...)
+		_, err := stmt.ExecContext(ctx, rowValues...)
 		if err != nil {
-			return 0, fmt.Errorf("failed to copy in row: %w", err)
+			return 0, nil, fmt.Errorf("failed to copy in row: %w", err)
 		}
 
 		inserted++
+		table.AddWritten(1)
 	}
 
 	logger.Debug("executing copy in")
-	if _, err := stmt.Exec(); err != nil {
-		return 0, fmt.Errorf("failed to exec copy in: %w", err)
+	if _, err := stmt.ExecContext(ctx); err != nil {
+		return 0, nil, fmt.Errorf("failed to exec copy in: %w", err)
 	}
 
-	return inserted, nil
+	return inserted, identities, nil
+}
+
+// identityMax is the largest value copied in an identity column.
+type identityMax struct {
+	column string
+	max    int64
+	copied bool
+}
+
+// observe keeps the value when it is the largest copied so far, the values which are not integers are ignored.
+func (m *identityMax) observe(value interface{}) {
+	var v int64
+	switch value := value.(type) {
+	case int64:
+		v = value
+	case []byte, string:
+		parsed, err := strconv.ParseInt(fmt.Sprintf("%s", value), 10, 64)
+		if err != nil {
+			return
+		}
+		v = parsed
+	default:
+		return
+	}
+
+	if !m.copied || v > m.max {
+		m.max, m.copied = v, true
+	}
 }
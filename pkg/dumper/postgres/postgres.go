@@ -14,6 +14,11 @@ func (m *driver) IsSupported(dsn string) bool {
 	return strings.HasPrefix(strings.ToLower(dsn), "postgres://")
 }
 
+// Journaled returns true, the triggers disabled and the foreign keys dropped are recorded in the journal.
+func (m *driver) Journaled() bool {
+	return true
+}
+
 func (m *driver) NewConnection(opts dumper.ConnOpts, rdr reader.Reader) (dumper.Dumper, error) {
 	conn, err := sql.Open("postgres", opts.DSN)
 	if err != nil {
@@ -0,0 +1,359 @@
+package query
+
+import (
+	"encoding/hex"
+	"fmt"
+	"math"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hellofresh/klepto/pkg/reader"
+	"github.com/hellofresh/klepto/pkg/schema"
+)
+
+// Dialects of the written SQL.
+const (
+	dialectMySQL    = schema.MySQL
+	dialectPostgres = schema.Postgres
+)
+
+// Statements the rows are written with.
+const (
+	// statementsInsert writes the rows with multi-row INSERT statements.
+	statementsInsert = "insert"
+	// statementsCopy writes the rows of each table in a postgres `COPY ... FROM stdin` block.
+	statementsCopy = "copy"
+)
+
+// insertBatchSize is the number of rows inserted by a single INSERT statement.
+const insertBatchSize = 100
+
+type (
+	// dialect formats the identifiers and values of a SQL dialect.
+	dialect interface {
+		// QuoteIdentifier quotes a column or schema name.
+		QuoteIdentifier(name string) string
+		// QuoteTable quotes a table name.
+		QuoteTable(name string) string
+		// Literal formats a value of the column as a SQL literal.
+		Literal(value interface{}, column *reader.Column) (string, error)
+	}
+
+	// format is how the rows are written to the output.
+	format struct {
+		dialect    dialect
+		statements string
+		// name is the dialect name.
+		name string
+		// source is the dialect of the source database, its structure is translated when it is not the written dialect.
+		source string
+	}
+
+	mysqlDialect    struct{}
+	postgresDialect struct{}
+)
+
+var (
+	// numberRegexp matches the numbers that can be written unquoted in both dialects.
+	numberRegexp = regexp.MustCompile(`^[+-]?(\d+\.?\d*|\.\d+)([eE][+-]?\d+)?$`)
+
+	mysqlEscaper = strings.NewReplacer(
+		`\`, `\\`,
+		`'`, `\'`,
+		`"`, `\"`,
+		"\x00", `\0`,
+		"\n", `\n`,
+		"\r", `\r`,
+		"\x1a", `\Z`,
+	)
+	postgresEscaper = strings.NewReplacer(`\`, `\\`, `'`, `''`)
+	copyEscaper     = strings.NewReplacer(`\`, `\\`, "\t", `\t`, "\n", `\n`, "\r", `\r`)
+)
+
+// defaultFormat is the format of the dumpers created without a dsn.
+var defaultFormat = &format{dialect: mysqlDialect{}, statements: statementsInsert, name: dialectMySQL}
+
+// getFormat returns the format of the dsn. The dialect is set by the `dialect` param, or inferred from the source dsn
+// and mysql for the other databases, the statements by the `statements` param, postgres defaults to COPY blocks and
+// mysql to INSERT statements.
+func getFormat(dsn string, source string) (*format, error) {
+	_, rawParams, _ := strings.Cut(dsn, "?")
+	params, err := url.ParseQuery(rawParams)
+	if err != nil {
+		return nil, fmt.Errorf("invalid output params: %w", err)
+	}
+
+	sourceDialect := schema.DialectOf(source)
+	name := params.Get("dialect")
+	if name == "" {
+		name = sourceDialect
+		if name != dialectPostgres {
+			name = dialectMySQL
+		}
+	}
+
+	f := &format{statements: params.Get("statements"), name: name, source: sourceDialect}
+	switch name {
+	case dialectMySQL:
+		f.dialect = mysqlDialect{}
+		if f.statements == "" {
+			f.statements = statementsInsert
+		}
+	case dialectPostgres:
+		f.dialect = postgresDialect{}
+		if f.statements == "" {
+			f.statements = statementsCopy
+		}
+	default:
+		return nil, fmt.Errorf("unsupported dialect %q, expected %s or %s", name, dialectMySQL, dialectPostgres)
+	}
+
+	switch f.statements {
+	case statementsInsert:
+	case statementsCopy:
+		if name != dialectPostgres {
+			return nil, fmt.Errorf("%s statements are only supported by the %s dialect", statementsCopy, dialectPostgres)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported statements %q, expected %s or %s", f.statements, statementsInsert, statementsCopy)
+	}
+
+	return f, nil
+}
+
+// translates returns whether the structure of the source database is translated to the written dialect.
+func (f *format) translates() bool {
+	return f.source != "" && f.source != f.name
+}
+
+// QuoteIdentifier quotes the name with backticks.
+func (mysqlDialect) QuoteIdentifier(name string) string {
+	return "`" + strings.ReplaceAll(name, "`", "``") + "`"
+}
+
+// QuoteTable quotes the table name with backticks.
+func (d mysqlDialect) QuoteTable(name string) string {
+	return d.QuoteIdentifier(name)
+}
+
+// Literal formats the value as a mysql literal, binary values are written in hexadecimal.
+func (mysqlDialect) Literal(value interface{}, column *reader.Column) (string, error) {
+	switch v := deref(value).(type) {
+	case nil:
+		return "NULL", nil
+	case bool:
+		if v {
+			return "1", nil
+		}
+		return "0", nil
+	case float64:
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			return "", fmt.Errorf("mysql does not support the float value %v", v)
+		}
+		return strconv.FormatFloat(v, 'g', -1, 64), nil
+	case time.Time:
+		return mysqlQuote(formatTime(v, column, false)), nil
+	case []byte:
+		if column.Kind() == reader.KindBinary {
+			return "X'" + hex.EncodeToString(v) + "'", nil
+		}
+		return mysqlText(string(v), column), nil
+	case string:
+		if column.Kind() == reader.KindBinary {
+			return "X'" + hex.EncodeToString([]byte(v)) + "'", nil
+		}
+		return mysqlText(v, column), nil
+	default:
+		return formatInteger(v)
+	}
+}
+
+// QuoteIdentifier quotes the name with double quotes.
+func (postgresDialect) QuoteIdentifier(name string) string {
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
+
+// QuoteTable quotes the table name with double quotes, the schema of a qualified table name is quoted apart,
+// e.g. `"billing"."invoices"`.
+func (d postgresDialect) QuoteTable(name string) string {
+	schema, table := reader.SplitTableName(name)
+	if schema == reader.DefaultSchema {
+		return d.QuoteIdentifier(table)
+	}
+
+	return d.QuoteIdentifier(schema) + "." + d.QuoteIdentifier(table)
+}
+
+// Literal formats the value as a postgres literal, binary values are written as hexadecimal bytea.
+func (postgresDialect) Literal(value interface{}, column *reader.Column) (string, error) {
+	value = deref(value)
+	// MySQL zero dates have no postgres equivalent, they are read as null by the mysql clients
+	if column.IsZeroDate(value) {
+		value = nil
+	}
+
+	switch v := value.(type) {
+	case nil:
+		return "NULL", nil
+	case bool:
+		if v {
+			return "TRUE", nil
+		}
+		return "FALSE", nil
+	case float64:
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			return postgresQuote(formatFloat(v)), nil
+		}
+		return formatFloat(v), nil
+	case time.Time:
+		return postgresQuote(formatTime(v, column, true)), nil
+	case []byte:
+		if column.Kind() == reader.KindBinary {
+			return postgresQuote(`\x` + hex.EncodeToString(v)), nil
+		}
+		return postgresText(string(v), column), nil
+	case string:
+		if column.Kind() == reader.KindBinary {
+			return postgresQuote(`\x` + hex.EncodeToString([]byte(v))), nil
+		}
+		return postgresText(v, column), nil
+	default:
+		return formatInteger(v)
+	}
+}
+
+// CopyValue formats the value as a field of a postgres COPY text block.
+func (postgresDialect) CopyValue(value interface{}, column *reader.Column) (string, error) {
+	value = deref(value)
+	if column.IsZeroDate(value) {
+		value = nil
+	}
+
+	switch v := value.(type) {
+	case nil:
+		return `\N`, nil
+	case bool:
+		if v {
+			return "t", nil
+		}
+		return "f", nil
+	case float64:
+		return formatFloat(v), nil
+	case time.Time:
+		return formatTime(v, column, true), nil
+	case []byte:
+		if column.Kind() == reader.KindBinary {
+			return `\\x` + hex.EncodeToString(v), nil
+		}
+		return copyEscaper.Replace(string(v)), nil
+	case string:
+		if column.Kind() == reader.KindBinary {
+			return `\\x` + hex.EncodeToString([]byte(v)), nil
+		}
+		return copyEscaper.Replace(v), nil
+	default:
+		return formatInteger(v)
+	}
+}
+
+// deref returns the value the pointers point to.
+func deref(value interface{}) interface{} {
+	if v, ok := value.(*interface{}); ok {
+		if v == nil {
+			return nil
+		}
+		return deref(*v)
+	}
+
+	return value
+}
+
+// formatInteger formats the integer values, any other type is not supported.
+func formatInteger(value interface{}) (string, error) {
+	switch v := value.(type) {
+	case int64:
+		return strconv.FormatInt(v, 10), nil
+	case int:
+		return strconv.Itoa(v), nil
+	case int32:
+		return strconv.FormatInt(int64(v), 10), nil
+	case uint64:
+		return strconv.FormatUint(v, 10), nil
+	case float32:
+		return formatFloat(float64(v)), nil
+	default:
+		return "", fmt.Errorf("could not format value of type %T", value)
+	}
+}
+
+func formatFloat(v float64) string {
+	switch {
+	case math.IsNaN(v):
+		return "NaN"
+	case math.IsInf(v, 1):
+		return "Infinity"
+	case math.IsInf(v, -1):
+		return "-Infinity"
+	default:
+		return strconv.FormatFloat(v, 'g', -1, 64)
+	}
+}
+
+// formatTime formats the time for the column type, the time zone is only written to postgres columns with a time zone.
+func formatTime(t time.Time, column *reader.Column, withZone bool) string {
+	typ := strings.ToLower(column.Type)
+	withZone = withZone && (strings.Contains(typ, "with time zone") || strings.HasSuffix(typ, "tz"))
+
+	layout := "2006-01-02 15:04:05.999999"
+	switch {
+	case typ == "date":
+		return t.Format("2006-01-02")
+	case strings.HasPrefix(typ, "time") && !strings.HasPrefix(typ, "timestamp"):
+		layout = "15:04:05.999999"
+	}
+	if withZone {
+		layout += "-07:00"
+	}
+
+	return t.Format(layout)
+}
+
+// isNumeric returns true when the text can be written unquoted to the column.
+func isNumeric(s string, column *reader.Column) bool {
+	switch column.Kind() {
+	case reader.KindInteger, reader.KindFloat, reader.KindDecimal:
+		return numberRegexp.MatchString(s)
+	default:
+		return false
+	}
+}
+
+func mysqlText(s string, column *reader.Column) string {
+	if isNumeric(s, column) {
+		return s
+	}
+	return mysqlQuote(s)
+}
+
+func mysqlQuote(s string) string {
+	return "'" + mysqlEscaper.Replace(s) + "'"
+}
+
+func postgresText(s string, column *reader.Column) string {
+	if isNumeric(s, column) {
+		return s
+	}
+	return postgresQuote(s)
+}
+
+// postgresQuote quotes the string, strings with backslashes are written as escape strings
+// so they are read the same whatever the standard_conforming_strings setting.
+func postgresQuote(s string) string {
+	if strings.Contains(s, `\`) {
+		return "E'" + postgresEscaper.Replace(s) + "'"
+	}
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
@@ -0,0 +1,246 @@
+package query
+
+import (
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/hellofresh/klepto/pkg/database"
+	"github.com/hellofresh/klepto/pkg/reader"
+)
+
+func TestGetFormat(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		scenario   string
+		dsn        string
+		source     string
+		dialect    dialect
+		statements string
+		err        bool
+	}{
+		{scenario: "when the source is mysql", dsn: "os://stdout/", source: "root:root@tcp(localhost:3306)/klepto", dialect: mysqlDialect{}, statements: statementsInsert},
+		{scenario: "when the source is postgres", dsn: "os://stdout/", source: "postgres://localhost/klepto", dialect: postgresDialect{}, statements: statementsCopy},
+		{scenario: "when the dialect is set by param", dsn: "file://dump.sql?dialect=postgres&statements=insert", source: "mysql://root@tcp(localhost:3306)/klepto", dialect: postgresDialect{}, statements: statementsInsert},
+		{scenario: "when the source is sqlite", dsn: "os://stdout/", source: "sqlite:///tmp/klepto.db", dialect: mysqlDialect{}, statements: statementsInsert},
+		{scenario: "when the dialect is unknown", dsn: "os://stdout/?dialect=oracle", err: true},
+		{scenario: "when copying to mysql", dsn: "os://stdout/?dialect=mysql&statements=copy", err: true},
+		{scenario: "when the statements are unknown", dsn: "os://stdout/?statements=upsert", err: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.scenario, func(t *testing.T) {
+			t.Parallel()
+
+			f, err := getFormat(test.dsn, test.source)
+			if test.err {
+				assert.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, test.dialect, f.dialect)
+			assert.Equal(t, test.statements, f.statements)
+		})
+	}
+}
+
+func TestLiteral(t *testing.T) {
+	t.Parallel()
+
+	var null interface{}
+	text := &reader.Column{Name: "name", Type: "text"}
+	integer := &reader.Column{Name: "id", Type: "integer"}
+	binary := &reader.Column{Name: "data", Type: "bytea"}
+	createdAt := time.Date(2021, 1, 2, 3, 4, 5, 600000000, time.FixedZone("CET", 3600))
+
+	tests := []struct {
+		scenario string
+		value    interface{}
+		column   *reader.Column
+		mysql    string
+		postgres string
+		copy     string
+	}{
+		{scenario: "when the value is null", value: nil, column: text, mysql: "NULL", postgres: "NULL", copy: `\N`},
+		{scenario: "when the value is a null pointer", value: &null, column: integer, mysql: "NULL", postgres: "NULL", copy: `\N`},
+		{scenario: "when the value is an integer", value: int64(42), column: integer, mysql: "42", postgres: "42", copy: "42"},
+		{scenario: "when the value is numeric bytes", value: []byte("-1.5e3"), column: &reader.Column{Type: "numeric"}, mysql: "-1.5e3", postgres: "-1.5e3", copy: "-1.5e3"},
+		{scenario: "when the value is a float", value: 0.25, column: &reader.Column{Type: "double"}, mysql: "0.25", postgres: "0.25", copy: "0.25"},
+		{scenario: "when the value is a bool", value: true, column: &reader.Column{Type: "boolean"}, mysql: "1", postgres: "TRUE", copy: "t"},
+		{
+			scenario: "when the value is a string with quotes",
+			value:    "O'Brien \"Bob\"",
+			column:   text,
+			mysql:    `'O\'Brien \"Bob\"'`,
+			postgres: `'O''Brien "Bob"'`,
+			copy:     `O'Brien "Bob"`,
+		},
+		{
+			scenario: "when the value is a string with control characters",
+			value:    "a\\b\n\tc",
+			column:   text,
+			mysql:    `'a\\b\n` + "\t" + `c'`,
+			postgres: "E'a\\\\b\n\tc'",
+			copy:     `a\\b\n\tc`,
+		},
+		{scenario: "when a number is written to a text column", value: "1; DROP TABLE users", column: integer, mysql: "'1; DROP TABLE users'", postgres: "'1; DROP TABLE users'", copy: "1; DROP TABLE users"},
+		{scenario: "when the value is binary", value: []byte{0x00, 0xde, 0xad}, column: binary, mysql: "X'00dead'", postgres: `E'\\x00dead'`, copy: `\\x00dead`},
+		{scenario: "when the value is a text as bytes", value: []byte("hello"), column: text, mysql: "'hello'", postgres: "'hello'", copy: "hello"},
+		{scenario: "when the value is a timestamp", value: createdAt, column: &reader.Column{Type: "timestamp"}, mysql: "'2021-01-02 03:04:05.6'", postgres: "'2021-01-02 03:04:05.6'", copy: "2021-01-02 03:04:05.6"},
+		{
+			scenario: "when the value is a timestamp with time zone",
+			value:    createdAt,
+			column:   &reader.Column{Type: "timestamp with time zone"},
+			mysql:    "'2021-01-02 03:04:05.6'",
+			postgres: "'2021-01-02 03:04:05.6+01:00'",
+			copy:     "2021-01-02 03:04:05.6+01:00",
+		},
+		{scenario: "when the value is a date", value: createdAt, column: &reader.Column{Type: "date"}, mysql: "'2021-01-02'", postgres: "'2021-01-02'", copy: "2021-01-02"},
+		{
+			scenario: "when the value is a mysql zero datetime",
+			value:    []byte("0000-00-00 00:00:00"),
+			column:   &reader.Column{Type: "datetime"},
+			mysql:    "'0000-00-00 00:00:00'",
+			postgres: "NULL",
+			copy:     `\N`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.scenario, func(t *testing.T) {
+			t.Parallel()
+
+			value, err := mysqlDialect{}.Literal(test.value, test.column)
+			require.NoError(t, err)
+			assert.Equal(t, test.mysql, value, "mysql")
+
+			value, err = postgresDialect{}.Literal(test.value, test.column)
+			require.NoError(t, err)
+			assert.Equal(t, test.postgres, value, "postgres")
+
+			value, err = postgresDialect{}.CopyValue(test.value, test.column)
+			require.NoError(t, err)
+			assert.Equal(t, test.copy, value, "copy")
+		})
+	}
+}
+
+func TestLiteralUnsupported(t *testing.T) {
+	t.Parallel()
+
+	column := &reader.Column{Type: "double"}
+
+	_, err := mysqlDialect{}.Literal(math.Inf(1), column)
+	assert.Error(t, err)
+
+	value, err := postgresDialect{}.Literal(math.NaN(), column)
+	require.NoError(t, err)
+	assert.Equal(t, "'NaN'", value)
+
+	_, err = postgresDialect{}.Literal(struct{}{}, column)
+	assert.Error(t, err)
+}
+
+func TestQuoteIdentifier(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, "`odd``name`", mysqlDialect{}.QuoteIdentifier("odd`name"))
+	assert.Equal(t, `"odd""name"`, postgresDialect{}.QuoteIdentifier(`odd"name`))
+	assert.Equal(t, `"first.name"`, postgresDialect{}.QuoteIdentifier("first.name"))
+	assert.Equal(t, "`billing.invoices`", mysqlDialect{}.QuoteTable("billing.invoices"))
+	assert.Equal(t, `"billing"."invoices"`, postgresDialect{}.QuoteTable("billing.invoices"))
+	assert.Equal(t, `"audit.log"`, postgresDialect{}.QuoteTable(`"public"."audit.log"`))
+}
+
+func TestRowsWriter(t *testing.T) {
+	t.Parallel()
+
+	columns := []*reader.Column{{Name: "id", Type: "integer"}, {Name: "name", Type: "text"}}
+	rows := []database.Row{{"id": int64(1), "name": "Alice"}, {"id": int64(2), "name": nil}}
+
+	tests := []struct {
+		scenario string
+		format   *format
+		rows     []database.Row
+		expected string
+	}{
+		{
+			scenario: "when writing mysql inserts",
+			format:   &format{dialect: mysqlDialect{}, statements: statementsInsert},
+			rows:     rows,
+			expected: "INSERT INTO `users` (`id`, `name`) VALUES\n(1, 'Alice'),\n(2, NULL);\n",
+		},
+		{
+			scenario: "when writing postgres inserts",
+			format:   &format{dialect: postgresDialect{}, statements: statementsInsert},
+			rows:     rows,
+			expected: "INSERT INTO \"users\" (\"id\", \"name\") VALUES\n(1, 'Alice'),\n(2, NULL);\n",
+		},
+		{
+			scenario: "when writing a postgres copy block",
+			format:   &format{dialect: postgresDialect{}, statements: statementsCopy},
+			rows:     rows,
+			expected: "COPY \"users\" (\"id\", \"name\") FROM stdin;\n1\tAlice\n2\t\\N\n\\.\n",
+		},
+		{
+			scenario: "when there are no rows",
+			format:   &format{dialect: postgresDialect{}, statements: statementsCopy},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.scenario, func(t *testing.T) {
+			t.Parallel()
+
+			var buf strings.Builder
+			rw := test.format.newRowsWriter(&buf, "users", columns)
+			for _, row := range test.rows {
+				require.NoError(t, rw.WriteRow(row))
+			}
+			require.NoError(t, rw.Flush())
+
+			assert.Equal(t, test.expected, buf.String())
+		})
+	}
+}
+
+func TestIdentityAlways(t *testing.T) {
+	t.Parallel()
+
+	columns := []*reader.Column{{Name: "id", Type: "integer", Identity: true, IdentityAlways: true}, {Name: "name", Type: "text"}}
+	f := &format{dialect: postgresDialect{}, statements: statementsInsert, name: dialectPostgres}
+
+	var buf strings.Builder
+	rw := f.newRowsWriter(&buf, "users", columns)
+	require.NoError(t, rw.WriteRow(database.Row{"id": int64(7), "name": "Alice"}))
+	require.NoError(t, rw.Flush())
+
+	assert.Equal(t, "INSERT INTO \"users\" (\"id\", \"name\") OVERRIDING SYSTEM VALUE VALUES\n(7, 'Alice');\n", buf.String())
+	assert.Equal(
+		t,
+		"SELECT setval(pg_get_serial_sequence('\"users\"', 'id'), COALESCE(MAX(\"id\"), 0) + 1, false) FROM \"users\";\n",
+		f.sequenceStatements("users", columns),
+	)
+	assert.Empty(t, defaultFormat.sequenceStatements("users", columns))
+}
+
+func TestInsertBatches(t *testing.T) {
+	t.Parallel()
+
+	var buf strings.Builder
+	f := &format{dialect: mysqlDialect{}, statements: statementsInsert}
+	rw := f.newRowsWriter(&buf, "users", []*reader.Column{{Name: "id", Type: "int"}})
+	for i := 0; i < insertBatchSize+1; i++ {
+		require.NoError(t, rw.WriteRow(database.Row{"id": int64(i)}))
+	}
+	require.NoError(t, rw.Flush())
+
+	assert.Equal(t, 2, strings.Count(buf.String(), "INSERT INTO"))
+	assert.True(t, strings.HasSuffix(buf.String(), "VALUES\n(100);\n"))
+}
@@ -1,163 +1,240 @@
 package query
 
 import (
-	"errors"
+	"context"
 	"fmt"
 	"io"
-	"strconv"
+	"strings"
 	"sync"
-	"time"
 
-	sq "github.com/Masterminds/squirrel"
 	log "github.com/sirupsen/logrus"
 
-	"github.com/hellofresh/klepto/pkg/config"
 	"github.com/hellofresh/klepto/pkg/database"
 	"github.com/hellofresh/klepto/pkg/dumper"
+	"github.com/hellofresh/klepto/pkg/dumper/engine"
+	"github.com/hellofresh/klepto/pkg/progress"
 	"github.com/hellofresh/klepto/pkg/reader"
+	"github.com/hellofresh/klepto/pkg/schema"
 )
 
 type (
 	textDumper struct {
-		reader reader.Reader
-		output io.Writer
+		reader   reader.Reader
+		output   output
+		format   *format
+		progress *progress.Tracker
+		// schemas renames the postgres schemas of the written tables
+		schemas map[string]string
+
+		mu sync.Mutex
+		// spools hold the rows of the dumped tables until they are written to the shared output
+		spools map[string]*spool
+	}
+
+	// countingWriter counts the bytes written to a table output.
+	countingWriter struct {
+		w     io.Writer
+		table *progress.Table
 	}
 )
 
-// NewDumper returns a new text dumper implementation.
-func NewDumper(output io.Writer, rdr reader.Reader) dumper.Dumper {
-	return &textDumper{
-		reader: rdr,
-		output: output,
+// NewDumper returns a new text dumper implementation writing mysql INSERT statements,
+// the progress of the tables is tracked when the tracker is not nil.
+func NewDumper(w io.Writer, rdr reader.Reader, tracker *progress.Tracker) dumper.Dumper {
+	return newDumper(newStreamOutput(w), defaultFormat, rdr, tracker, nil)
+}
+
+// newDumper returns a text dumper run by the dump engine. The tables are dumped concurrently and written sorted by
+// name or in foreign key order, the rows of a table written to a shared stream are spooled so they are not
+// interleaved with other tables.
+func newDumper(out output, f *format, rdr reader.Reader, tracker *progress.Tracker, schemas map[string]string) dumper.Dumper {
+	return engine.New(rdr, &textDumper{
+		reader:   rdr,
+		output:   out,
+		format:   f,
+		progress: tracker,
+		schemas:  schemas,
+		spools:   make(map[string]*spool),
+	}, tracker)
+}
+
+// DumpStructure writes the database structure, the structure of another dialect is translated.
+func (d *textDumper) DumpStructure(_ context.Context, structure string) error {
+	if d.format.translates() {
+		var err error
+		if structure, err = d.translateStructure(); err != nil {
+			return fmt.Errorf("could not translate database structure: %w", err)
+		}
+	}
+
+	if err := d.writeStructure(structure); err != nil {
+		return fmt.Errorf("could not write structure to output: %w", err)
 	}
+
+	return nil
 }
 
-// Dump executes the dump stream process.
-func (d *textDumper) Dump(done chan<- struct{}, cfgTables config.Tables, concurrency int, dataOnly bool) error {
-	tables, err := d.reader.GetTables()
+// DumpTable writes the rows of the table to the table output, or to a spool written by WriteTable when the output
+// is shared. The spooled rows are discarded when the context is done.
+func (d *textDumper) DumpTable(ctx context.Context, tableName string, rowChan <-chan database.Row) error {
+	columns, err := d.reader.GetColumns(tableName)
 	if err != nil {
-		return fmt.Errorf("failed to get tables: %w", err)
+		return fmt.Errorf("failed to get columns: %w", err)
 	}
 
-	if !dataOnly {
-		structure, err := d.reader.GetStructure()
-		if err != nil {
-			return fmt.Errorf("could not get database structure: %w", err)
-		}
-		if _, err := io.WriteString(d.output, structure); err != nil {
-			return fmt.Errorf("could not write structure to output: %w", err)
+	// Generated columns can not be written, their values are computed by the database
+	writable := make([]*reader.Column, 0, len(columns))
+	for _, c := range columns {
+		if !c.Generated {
+			writable = append(writable, c)
 		}
 	}
 
-	var wg sync.WaitGroup
-	for _, tbl := range tables {
-		var opts reader.ReadTableOpt
-		logger := log.WithField("table", tbl)
-
-		tableConfig := cfgTables.FindByName(tbl)
-		if tableConfig == nil {
-			logger.Debug("no configuration found for table")
-		} else {
-			if tableConfig.IgnoreData {
-				logger.Debug("ignoring data to dump")
-				continue
-			}
-			opts = reader.NewReadTableOpt(tableConfig)
+	if !d.output.Shared() {
+		w, err := d.output.Table(tableName)
+		if err != nil {
+			return fmt.Errorf("failed to open output: %w", err)
 		}
 
-		// Create read/write chanel
-		rowChan := make(chan database.Row)
-
-		wg.Add(1)
-		go func(tableName string) {
-			defer wg.Done()
-
-			for {
-				row, more := <-rowChan
-				if !more {
-					return
-				}
-
-				columnMap, err := d.toSQLColumnMap(row)
-				if err != nil {
-					logger.WithError(err).Fatal("could not convert value to string")
-				}
-
-				insert := sq.Insert(tableName).SetMap(columnMap)
-				if _, err := io.WriteString(d.output, sq.DebugSqlizer(insert)); err != nil {
-					logger.WithError(err).Error("could not write insert statement to output")
-				}
-				if _, err := io.WriteString(d.output, "\n"); err != nil {
-					logger.WithError(err).Error("could not write new line to output")
-				}
-			}
-		}(tbl)
-
-		if err := d.reader.ReadTable(tbl, rowChan, opts); err != nil {
-			log.WithError(err).WithField("table", tbl).Error("error while reading table")
-		}
+		return d.writeRows(w, tableName, rowChan, writable, d.progress.Table(tableName))
 	}
 
-	go func() {
-		wg.Wait()
-		done <- struct{}{}
-	}()
+	s, err := newSpool()
+	if err != nil {
+		return fmt.Errorf("failed to open output: %w", err)
+	}
+
+	err = d.writeRows(s, tableName, rowChan, writable, d.progress.Table(tableName))
+	if err == nil {
+		err = ctx.Err()
+	}
+	if err != nil {
+		removeSpool(tableName, s)
+		return err
+	}
+
+	d.mu.Lock()
+	d.spools[tableName] = s
+	d.mu.Unlock()
 
 	return nil
 }
 
-// Close closes the output stream.
-func (d *textDumper) Close() error {
-	closer, ok := d.output.(io.WriteCloser)
-	if ok {
-		if err := closer.Close(); err != nil {
-			return fmt.Errorf("failed to close output stream: %w", err)
-		}
+// WriteTable writes the spooled rows of the table to the shared output.
+func (d *textDumper) WriteTable(_ context.Context, tableName string) error {
+	d.mu.Lock()
+	s, ok := d.spools[tableName]
+	delete(d.spools, tableName)
+	d.mu.Unlock()
+
+	if !ok {
 		return nil
 	}
+	defer removeSpool(tableName, s)
+
+	w, err := d.output.Table(tableName)
+	if err != nil {
+		return fmt.Errorf("failed to open output: %w", err)
+	}
 
-	return errors.New("unable to close output: wrong closer type")
+	if err := s.CopyTo(w); err != nil {
+		w.Close()
+		return fmt.Errorf("could not write table to output: %w", err)
+	}
+
+	return w.Close()
 }
 
-func (d *textDumper) toSQLColumnMap(row database.Row) (map[string]interface{}, error) {
-	sqlColumnMap := make(map[string]interface{})
+// Close removes the spools of the tables which were not written and closes the output.
+func (d *textDumper) Close() error {
+	d.mu.Lock()
+	for tableName, s := range d.spools {
+		removeSpool(tableName, s)
+	}
+	clear(d.spools)
+	d.mu.Unlock()
+
+	return d.output.Close()
+}
+
+// translateStructure returns the structure of the source database translated to the written dialect.
+func (d *textDumper) translateStructure() (string, error) {
+	s, err := schema.Read(d.reader, d.format.source)
+	if err != nil {
+		return "", err
+	}
+
+	statements, err := schema.Render(s, d.format.name)
+	if err != nil {
+		return "", err
+	}
+
+	return strings.Join(statements, ";\n\n") + ";\n", nil
+}
+
+// writeStructure writes the database structure to the output, followed by the renaming of its schemas.
+func (d *textDumper) writeStructure(structure string) error {
+	w, err := d.output.Structure()
+	if err != nil {
+		return err
+	}
+
+	if _, err := io.WriteString(w, structure+d.format.schemaStatements(d.schemas)); err != nil {
+		w.Close()
+		return err
+	}
+
+	return w.Close()
+}
+
+// writeRows writes the statements of the rows to the table output and closes it, the rows are written to the table in
+// its renamed schema. The rows are drained after a write failure, so the reader is not blocked.
+func (d *textDumper) writeRows(w io.WriteCloser, tableName string, rowChan <-chan database.Row, columns []*reader.Column, table *progress.Table) error {
+	targetTable := dumper.TargetTable(d.schemas, tableName)
+	cw := &countingWriter{w: w, table: table}
+	rw := d.format.newRowsWriter(cw, targetTable, columns)
 
-	for column, value := range row {
-		strValue, err := d.toSQLStringValue(value)
+	var err error
+	for row := range rowChan {
 		if err != nil {
-			return sqlColumnMap, err
+			continue
 		}
 
-		sqlColumnMap[column] = fmt.Sprintf("%v", strValue)
+		if err = rw.WriteRow(row); err != nil {
+			err = fmt.Errorf("could not write row to output: %w", err)
+			continue
+		}
+		table.AddWritten(1)
 	}
 
-	return sqlColumnMap, nil
+	if err == nil {
+		if flushErr := rw.Flush(); flushErr != nil {
+			err = fmt.Errorf("could not write rows to output: %w", flushErr)
+		}
+	}
+	if statements := d.format.sequenceStatements(targetTable, columns); err == nil && statements != "" {
+		if _, writeErr := io.WriteString(cw, statements); writeErr != nil {
+			err = fmt.Errorf("could not write sequences to output: %w", writeErr)
+		}
+	}
+	if closeErr := w.Close(); closeErr != nil && err == nil {
+		err = fmt.Errorf("could not close table output: %w", closeErr)
+	}
+
+	return err
 }
 
-// ResolveType accepts a value and attempts to determine its type
-func (d *textDumper) toSQLStringValue(src interface{}) (string, error) {
-	switch value := src.(type) {
-	case int64:
-		return strconv.FormatInt(value, 10), nil
-	case float64:
-		return fmt.Sprintf("%v", value), nil
-	case bool:
-		return strconv.FormatBool(value), nil
-	case string:
-		return value, nil
-	case []byte:
-		// TODO handle blobs?
-		return string(value), nil
-	case time.Time:
-		return value.String(), nil
-	case nil:
-		return "NULL", nil
-	case *interface{}:
-		if value == nil {
-			return "NULL", nil
-		}
-		return d.toSQLStringValue(*value)
-	default:
-		return "", errors.New("could not parse type")
+// removeSpool removes the spool file of the table.
+func removeSpool(tableName string, s *spool) {
+	if err := s.Remove(); err != nil {
+		log.WithError(err).WithField("table", tableName).Warn("failed to remove spool file")
 	}
 }
+
+// Write writes to the table output and counts the bytes written.
+func (w *countingWriter) Write(p []byte) (int, error) {
+	n, err := w.w.Write(p)
+	w.table.AddBytes(int64(n))
+
+	return n, err
+}
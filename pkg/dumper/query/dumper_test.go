@@ -0,0 +1,160 @@
+package query
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/hellofresh/klepto/pkg/config"
+	"github.com/hellofresh/klepto/pkg/database"
+	"github.com/hellofresh/klepto/pkg/dumper"
+	"github.com/hellofresh/klepto/pkg/reader"
+)
+
+func TestDumpOrder(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		scenario string
+		opts     dumper.DumpOpts
+		failing  map[string]error
+		expected string
+		failed   []string
+	}{
+		{
+			scenario: "when the tables are dumped concurrently",
+			opts:     dumper.DumpOpts{Concurrency: 4, DataOnly: true},
+			expected: "INSERT INTO `addresses` (`id`) VALUES\n(0),\n(1);\n" +
+				"INSERT INTO `orders` (`id`) VALUES\n(0),\n(1);\n" +
+				"INSERT INTO `payments` (`id`) VALUES\n(0),\n(1);\n" +
+				"INSERT INTO `users` (`id`) VALUES\n(0),\n(1);\n",
+		},
+		{
+			scenario: "when the tables are dumped in foreign key order",
+			opts:     dumper.DumpOpts{Concurrency: 4, DataOnly: true, ForeignKeyOrder: true},
+			expected: "INSERT INTO `addresses` (`id`) VALUES\n(0),\n(1);\n" +
+				"INSERT INTO `users` (`id`) VALUES\n(0),\n(1);\n" +
+				"INSERT INTO `orders` (`id`) VALUES\n(0),\n(1);\n" +
+				"INSERT INTO `payments` (`id`) VALUES\n(0),\n(1);\n",
+		},
+		{
+			scenario: "when a table fails",
+			opts:     dumper.DumpOpts{Concurrency: 4},
+			failing:  map[string]error{"orders": errors.New("connection reset")},
+			expected: "CREATE TABLE structure;\n" +
+				"INSERT INTO `addresses` (`id`) VALUES\n(0),\n(1);\n" +
+				"INSERT INTO `payments` (`id`) VALUES\n(0),\n(1);\n" +
+				"INSERT INTO `users` (`id`) VALUES\n(0),\n(1);\n",
+			failed: []string{"orders"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.scenario, func(t *testing.T) {
+			t.Parallel()
+
+			rdr := &mockReader{
+				tables:     []string{"users", "payments", "orders", "addresses"},
+				references: map[string][]string{"payments": {"orders"}, "orders": {"users"}},
+				failing:    test.failing,
+			}
+
+			var buf strings.Builder
+			err := NewDumper(&buf, rdr, nil).Dump(context.Background(), config.Tables{}, test.opts)
+
+			assert.Equal(t, test.failed, dumper.FailedTables(err))
+			assert.Equal(t, test.expected, buf.String())
+		})
+	}
+}
+
+func TestDumpTranslatedStructure(t *testing.T) {
+	t.Parallel()
+
+	f, err := getFormat("os://stdout/?dialect=postgres&statements=insert", "root:root@tcp(localhost:3306)/klepto")
+	assert.NoError(t, err)
+
+	var buf strings.Builder
+	rdr := &mockReader{tables: []string{"users"}}
+	err = newDumper(newStreamOutput(&buf), f, rdr, nil, nil).Dump(context.Background(), config.Tables{}, dumper.DumpOpts{})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "CREATE TABLE \"users\" (\n  \"id\" integer NOT NULL\n);\n"+
+		"INSERT INTO \"users\" (\"id\") VALUES\n(0),\n(1);\n", buf.String())
+}
+
+func TestDumpRenamedSchemas(t *testing.T) {
+	t.Parallel()
+
+	f, err := getFormat("os://stdout/?dialect=postgres&statements=insert", "postgres://localhost/klepto")
+	assert.NoError(t, err)
+
+	var buf strings.Builder
+	rdr := &mockReader{tables: []string{"billing.invoices"}}
+	schemas := map[string]string{"billing": "archive"}
+	err = newDumper(newStreamOutput(&buf), f, rdr, nil, schemas).Dump(context.Background(), config.Tables{}, dumper.DumpOpts{})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "CREATE TABLE structure;\n"+
+		"ALTER SCHEMA \"billing\" RENAME TO \"archive\";\n"+
+		"INSERT INTO \"archive\".\"invoices\" (\"id\") VALUES\n(0),\n(1);\n", buf.String())
+}
+
+type mockReader struct {
+	reader.Reader
+	tables     []string
+	references map[string][]string
+	failing    map[string]error
+}
+
+func (m *mockReader) GetStructure() (string, error) {
+	return "CREATE TABLE structure;\n", nil
+}
+
+func (m *mockReader) GetTables() ([]string, error) {
+	return m.tables, nil
+}
+
+func (m *mockReader) GetColumns(string) ([]*reader.Column, error) {
+	return []*reader.Column{{Name: "id", Type: "int"}, {Name: "total", Type: "int", Generated: true}}, nil
+}
+
+func (m *mockReader) GetPrimaryKey(string) (*reader.Key, error) {
+	return nil, nil
+}
+
+func (m *mockReader) GetUniqueKeys(string) ([]*reader.Key, error) {
+	return nil, nil
+}
+
+func (m *mockReader) GetForeignKeys(tableName string) ([]*reader.ForeignKey, error) {
+	var foreignKeys []*reader.ForeignKey
+	for _, ref := range m.references[tableName] {
+		foreignKeys = append(foreignKeys, &reader.ForeignKey{Table: tableName, ReferencedTable: ref})
+	}
+	return foreignKeys, nil
+}
+
+func (m *mockReader) ReadTable(ctx context.Context, tableName string, rowChan chan<- database.Row, _ reader.ReadTableOpt) error {
+	defer close(rowChan)
+
+	if err, ok := m.failing[tableName]; ok {
+		return err
+	}
+
+	// the tables sorted first are the slowest, so the tables are not read in the order they are written
+	time.Sleep(time.Duration('z'-tableName[0]) * time.Millisecond)
+	for i := 0; i < 2; i++ {
+		select {
+		case rowChan <- database.Row{"id": int64(i), "total": int64(i)}:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	return nil
+}
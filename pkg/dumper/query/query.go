@@ -1,6 +1,9 @@
 package query
 
 import (
+	"fmt"
+	"strings"
+
 	parser "github.com/hellofresh/klepto/pkg/dsn"
 	"github.com/hellofresh/klepto/pkg/dumper"
 	"github.com/hellofresh/klepto/pkg/reader"
@@ -9,6 +12,10 @@ import (
 type driver struct{}
 
 func (m *driver) IsSupported(dsn string) bool {
+	if strings.HasPrefix(dsn, fileScheme) {
+		return true
+	}
+
 	d, err := parser.Parse(dsn)
 	if err != nil {
 		return false
@@ -17,11 +24,19 @@ func (m *driver) IsSupported(dsn string) bool {
 }
 
 func (m *driver) NewConnection(opts dumper.ConnOpts, rdr reader.Reader) (dumper.Dumper, error) {
-	writer, err := getOutputWriter(opts.DSN)
+	f, err := getFormat(opts.DSN, opts.Source)
+	if err != nil {
+		return nil, err
+	}
+	if len(opts.Schemas) > 0 && f.name != dialectPostgres {
+		return nil, fmt.Errorf("the schemas can only be renamed in the %s dialect", dialectPostgres)
+	}
+
+	out, err := getOutput(opts.DSN)
 	if err != nil {
 		return nil, err
 	}
-	return NewDumper(writer, rdr), nil
+	return newDumper(out, f, rdr, opts.Progress, opts.Schemas), nil
 }
 
 func init() {
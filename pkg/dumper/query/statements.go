@@ -0,0 +1,181 @@
+package query
+
+import (
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/hellofresh/klepto/pkg/database"
+	"github.com/hellofresh/klepto/pkg/reader"
+)
+
+type (
+	// rowsWriter writes the rows of a table as SQL statements.
+	rowsWriter interface {
+		// WriteRow writes the row, it may be buffered until the statement is complete.
+		WriteRow(row database.Row) error
+		// Flush writes the buffered rows.
+		Flush() error
+	}
+
+	// insertWriter writes the rows with multi-row INSERT statements.
+	insertWriter struct {
+		w       io.Writer
+		dialect dialect
+		columns []*reader.Column
+		prefix  string
+		buf     strings.Builder
+		rows    int
+	}
+
+	// copyWriter writes the rows in a postgres `COPY ... FROM stdin` block.
+	copyWriter struct {
+		w       io.Writer
+		dialect postgresDialect
+		columns []*reader.Column
+		header  string
+		started bool
+		buf     strings.Builder
+	}
+)
+
+// newRowsWriter returns the writer of the table rows in the format.
+func (f *format) newRowsWriter(w io.Writer, tableName string, columns []*reader.Column) rowsWriter {
+	names := make([]string, len(columns))
+	for i, c := range columns {
+		names[i] = f.dialect.QuoteIdentifier(c.Name)
+	}
+	table := f.dialect.QuoteTable(tableName)
+
+	if f.statements == statementsCopy {
+		return &copyWriter{
+			w:       w,
+			columns: columns,
+			header:  "COPY " + table + " (" + strings.Join(names, ", ") + ") FROM stdin;\n",
+		}
+	}
+
+	// the postgres identity columns generated always are only written when the values override them
+	values := "VALUES"
+	if f.name == dialectPostgres && slices.ContainsFunc(columns, func(c *reader.Column) bool { return c.IdentityAlways }) {
+		values = "OVERRIDING SYSTEM VALUE VALUES"
+	}
+
+	return &insertWriter{
+		w:       w,
+		dialect: f.dialect,
+		columns: columns,
+		prefix:  "INSERT INTO " + table + " (" + strings.Join(names, ", ") + ") " + values + "\n",
+	}
+}
+
+// sequenceStatements returns the statements setting the sequences of the postgres identity columns after the written
+// values, so the rows inserted later on get new values. Mysql auto increments follow the written values.
+func (f *format) sequenceStatements(tableName string, columns []*reader.Column) string {
+	if f.name != dialectPostgres {
+		return ""
+	}
+
+	var b strings.Builder
+	table := f.dialect.QuoteTable(tableName)
+	for _, c := range columns {
+		if !c.Identity {
+			continue
+		}
+		fmt.Fprintf(
+			&b, "SELECT setval(pg_get_serial_sequence(%s, %s), COALESCE(MAX(%s), 0) + 1, false) FROM %s;\n",
+			postgresQuote(table), postgresQuote(c.Name), f.dialect.QuoteIdentifier(c.Name), table,
+		)
+	}
+
+	return b.String()
+}
+
+// schemaStatements returns the statements renaming the postgres schemas of the written structure, sorted by name.
+func (f *format) schemaStatements(schemas map[string]string) string {
+	var b strings.Builder
+	for _, name := range slices.Sorted(maps.Keys(schemas)) {
+		fmt.Fprintf(&b, "ALTER SCHEMA %s RENAME TO %s;\n", f.dialect.QuoteIdentifier(name), f.dialect.QuoteIdentifier(schemas[name]))
+	}
+
+	return b.String()
+}
+
+// WriteRow adds the row to the statement, the statement is written once it has insertBatchSize rows.
+func (iw *insertWriter) WriteRow(row database.Row) error {
+	if iw.rows == 0 {
+		iw.buf.WriteString(iw.prefix)
+	} else {
+		iw.buf.WriteString(",\n")
+	}
+
+	iw.buf.WriteString("(")
+	for i, c := range iw.columns {
+		value, err := iw.dialect.Literal(row[c.Name], c)
+		if err != nil {
+			return fmt.Errorf("could not format column %s: %w", c.Name, err)
+		}
+		if i > 0 {
+			iw.buf.WriteString(", ")
+		}
+		iw.buf.WriteString(value)
+	}
+	iw.buf.WriteString(")")
+
+	iw.rows++
+	if iw.rows < insertBatchSize {
+		return nil
+	}
+	return iw.Flush()
+}
+
+// Flush writes the statement of the buffered rows.
+func (iw *insertWriter) Flush() error {
+	if iw.rows == 0 {
+		return nil
+	}
+
+	iw.buf.WriteString(";\n")
+	_, err := io.WriteString(iw.w, iw.buf.String())
+	iw.buf.Reset()
+	iw.rows = 0
+
+	return err
+}
+
+// WriteRow writes the row as a line of the COPY block, the block is started by the first row.
+func (cw *copyWriter) WriteRow(row database.Row) error {
+	cw.buf.Reset()
+	if !cw.started {
+		cw.buf.WriteString(cw.header)
+		cw.started = true
+	}
+
+	for i, c := range cw.columns {
+		value, err := cw.dialect.CopyValue(row[c.Name], c)
+		if err != nil {
+			return fmt.Errorf("could not format column %s: %w", c.Name, err)
+		}
+		if i > 0 {
+			cw.buf.WriteString("\t")
+		}
+		cw.buf.WriteString(value)
+	}
+	cw.buf.WriteString("\n")
+
+	_, err := io.WriteString(cw.w, cw.buf.String())
+	return err
+}
+
+// Flush ends the COPY block.
+func (cw *copyWriter) Flush() error {
+	if !cw.started {
+		return nil
+	}
+
+	_, err := io.WriteString(cw.w, "\\.\n")
+	cw.started = false
+	return err
+}
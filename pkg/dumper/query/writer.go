@@ -1,13 +1,88 @@
 package query
 
 import (
+	"bufio"
+	"compress/gzip"
+	"errors"
 	"fmt"
 	"io"
+	"net/url"
 	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/klauspost/compress/zstd"
 
 	parser "github.com/hellofresh/klepto/pkg/dsn"
 )
 
+const (
+	// fileScheme is the scheme of the dsn writing to files.
+	fileScheme = "file://"
+	// schemaFile is the file the structure is written to in a directory.
+	schemaFile = "schema.sql"
+)
+
+// Compressions of the files.
+const (
+	compressionNone = "none"
+	compressionGzip = "gzip"
+	compressionZstd = "zstd"
+)
+
+// extensions maps the compressions to their file extension.
+var extensions = map[string]string{
+	compressionNone: "",
+	compressionGzip: ".gz",
+	compressionZstd: ".zst",
+}
+
+type (
+	// output opens the writers the dump is written to.
+	output interface {
+		// Structure returns the writer of the database structure.
+		Structure() (io.WriteCloser, error)
+		// Table returns the writer of a table rows.
+		Table(name string) (io.WriteCloser, error)
+		// Shared returns true when the tables are written to the same stream, they are spooled
+		// so their rows are written one table after the other.
+		Shared() bool
+		// Close flushes and closes the output.
+		Close() error
+	}
+
+	// streamOutput writes the structure and all the tables to a single stream.
+	streamOutput struct {
+		mu     sync.Mutex
+		w      io.Writer
+		closer io.Closer
+	}
+
+	// dirOutput writes the structure and each table to their own file in a directory.
+	dirOutput struct {
+		dir         string
+		compression string
+	}
+
+	// lockedWriter serialises the writes of the tables to a shared stream.
+	lockedWriter struct {
+		output *streamOutput
+	}
+
+	// compressedFile closes the compressor before the file.
+	compressedFile struct {
+		io.WriteCloser
+		file *os.File
+	}
+
+	// spool is the temporary file a table is written to before being copied to a shared stream.
+	spool struct {
+		file *os.File
+		buf  *bufio.Writer
+	}
+)
+
 func getOsWriter(address string) io.Writer {
 	if address == "stderr" {
 		return os.Stderr
@@ -18,16 +93,210 @@ func getOsWriter(address string) io.Writer {
 	return nil
 }
 
-// TODO: Implement writer interface for file.
-func getOutputWriter(dsn string) (io.Writer, error) {
+// getOutput returns the output of the dsn, either `os://stdout/`, `os://stderr/` or a `file://` path.
+func getOutput(dsn string) (output, error) {
+	if strings.HasPrefix(dsn, fileScheme) {
+		return getFileOutput(strings.TrimPrefix(dsn, fileScheme))
+	}
+
 	config, err := parser.Parse(dsn)
 	if err != nil {
 		return nil, err
 	}
 	switch config.Type {
 	case "os":
-		return getOsWriter(config.Address), nil
+		w := getOsWriter(config.Address)
+		if w == nil {
+			return nil, fmt.Errorf("unknown os output %q, expected stdout or stderr", config.Address)
+		}
+		return newStreamOutput(w), nil
 	default:
 		return nil, fmt.Errorf("unknown output writer type: %v", config.Type)
 	}
 }
+
+// getFileOutput returns the output of a file path. The compression is chosen by the `compress` param,
+// or by the file extension. A path ending with a slash, or the `split=true` param, writes each table
+// to its own file in the directory.
+func getFileOutput(location string) (output, error) {
+	path, rawParams, _ := strings.Cut(location, "?")
+	params, err := url.ParseQuery(rawParams)
+	if err != nil {
+		return nil, fmt.Errorf("invalid file output params: %w", err)
+	}
+	if path == "" {
+		return nil, errors.New("file output path can not be empty")
+	}
+
+	split := strings.HasSuffix(path, "/") || params.Get("split") == "true"
+
+	compression := params.Get("compress")
+	if compression == "" {
+		compression = compressionByExtension(path)
+	}
+	if _, ok := extensions[compression]; !ok {
+		return nil, fmt.Errorf("unsupported compression %q, expected %s, %s or %s", compression, compressionNone, compressionGzip, compressionZstd)
+	}
+
+	if split {
+		if err := os.MkdirAll(path, 0755); err != nil {
+			return nil, fmt.Errorf("failed to create output directory: %w", err)
+		}
+		return &dirOutput{dir: path, compression: compression}, nil
+	}
+
+	f, err := createFile(path, compression)
+	if err != nil {
+		return nil, err
+	}
+	return newStreamOutput(f), nil
+}
+
+// compressionByExtension returns the compression matching the file extension.
+func compressionByExtension(path string) string {
+	switch filepath.Ext(path) {
+	case ".gz", ".gzip":
+		return compressionGzip
+	case ".zst", ".zstd":
+		return compressionZstd
+	default:
+		return compressionNone
+	}
+}
+
+// createFile creates the file, the writes are compressed with the given compression.
+func createFile(path string, compression string) (io.WriteCloser, error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create output file: %w", err)
+	}
+
+	switch compression {
+	case compressionGzip:
+		return &compressedFile{WriteCloser: gzip.NewWriter(f), file: f}, nil
+	case compressionZstd:
+		w, err := zstd.NewWriter(f)
+		if err != nil {
+			f.Close()
+			return nil, fmt.Errorf("failed to create zstd writer: %w", err)
+		}
+		return &compressedFile{WriteCloser: w, file: f}, nil
+	default:
+		return f, nil
+	}
+}
+
+func newStreamOutput(w io.Writer) *streamOutput {
+	o := &streamOutput{w: w}
+	if closer, ok := w.(io.Closer); ok {
+		o.closer = closer
+	}
+
+	return o
+}
+
+// Structure returns the stream.
+func (o *streamOutput) Structure() (io.WriteCloser, error) {
+	return &lockedWriter{output: o}, nil
+}
+
+// Table returns the stream.
+func (o *streamOutput) Table(string) (io.WriteCloser, error) {
+	return &lockedWriter{output: o}, nil
+}
+
+// Shared returns true, the tables are written to the stream.
+func (o *streamOutput) Shared() bool {
+	return true
+}
+
+// Close closes the stream.
+func (o *streamOutput) Close() error {
+	if o.closer == nil {
+		return errors.New("unable to close output: wrong closer type")
+	}
+
+	if err := o.closer.Close(); err != nil {
+		return fmt.Errorf("failed to close output stream: %w", err)
+	}
+	return nil
+}
+
+// Structure creates the schema file.
+func (o *dirOutput) Structure() (io.WriteCloser, error) {
+	return createFile(filepath.Join(o.dir, schemaFile+extensions[o.compression]), o.compression)
+}
+
+// Table creates the table file.
+func (o *dirOutput) Table(name string) (io.WriteCloser, error) {
+	name = strings.NewReplacer("/", "_", "\\", "_").Replace(name)
+	return createFile(filepath.Join(o.dir, name+".sql"+extensions[o.compression]), o.compression)
+}
+
+// Shared returns false, each table is written to its own file.
+func (o *dirOutput) Shared() bool {
+	return false
+}
+
+// Close does nothing, the files are closed once written.
+func (o *dirOutput) Close() error {
+	return nil
+}
+
+// Write writes to the stream.
+func (w *lockedWriter) Write(p []byte) (int, error) {
+	w.output.mu.Lock()
+	defer w.output.mu.Unlock()
+
+	return w.output.w.Write(p)
+}
+
+// Close does nothing, the stream is closed with the output.
+func (w *lockedWriter) Close() error {
+	return nil
+}
+
+// Close flushes the compressor and closes the file.
+func (f *compressedFile) Close() error {
+	if err := f.WriteCloser.Close(); err != nil {
+		f.file.Close()
+		return err
+	}
+
+	return f.file.Close()
+}
+
+func newSpool() (*spool, error) {
+	f, err := os.CreateTemp("", "klepto-*.sql")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create spool file: %w", err)
+	}
+
+	return &spool{file: f, buf: bufio.NewWriter(f)}, nil
+}
+
+// Write writes to the spool file.
+func (s *spool) Write(p []byte) (int, error) {
+	return s.buf.Write(p)
+}
+
+// Close flushes the writes, the file is kept until it is copied.
+func (s *spool) Close() error {
+	return s.buf.Flush()
+}
+
+// CopyTo copies the spooled table to the writer.
+func (s *spool) CopyTo(w io.Writer) error {
+	if _, err := s.file.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+
+	_, err := io.Copy(w, s.file)
+	return err
+}
+
+// Remove closes and removes the spool file.
+func (s *spool) Remove() error {
+	s.file.Close()
+	return os.Remove(s.file.Name())
+}
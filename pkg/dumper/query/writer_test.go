@@ -1,11 +1,15 @@
 package query
 
 import (
+	"compress/gzip"
 	"io"
 	"os"
+	"path/filepath"
 	"testing"
 
+	"github.com/klauspost/compress/zstd"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 var tests = []struct {
@@ -18,13 +22,128 @@ var tests = []struct {
 
 func TestWriter(t *testing.T) {
 	for _, test := range tests {
-		w, err := getOutputWriter(test.Dsn)
+		out, err := getOutput(test.Dsn)
 		if err != nil {
 			t.Error("Encountered error when getting output writer",
 				err)
 		}
 		if test.Dsn != "" {
-			assert.Equal(t, test.Writer, w)
+			require.IsType(t, &streamOutput{}, out)
+			assert.Equal(t, test.Writer, out.(*streamOutput).w)
 		}
 	}
 }
+
+func TestWriterUnknownOutput(t *testing.T) {
+	t.Parallel()
+
+	for _, dsn := range []string{"os://stdin/", "file://", "file://dump.sql?compress=lz4"} {
+		_, err := getOutput(dsn)
+		assert.Error(t, err, dsn)
+	}
+}
+
+func TestFileOutput(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		scenario string
+		path     string
+		params   string
+		files    map[string]string
+	}{
+		{
+			scenario: "when writing to a file",
+			path:     "dump.sql",
+			files:    map[string]string{"dump.sql": "structure;\nusers;\norders;\n"},
+		},
+		{
+			scenario: "when the compression is chosen by extension",
+			path:     "dump.sql.gz",
+			files:    map[string]string{"dump.sql.gz": "structure;\nusers;\norders;\n"},
+		},
+		{
+			scenario: "when the compression is chosen by param",
+			path:     "dump.sql",
+			params:   "?compress=zstd",
+			files:    map[string]string{"dump.sql": "structure;\nusers;\norders;\n"},
+		},
+		{
+			scenario: "when splitting tables in a directory",
+			path:     "dump/",
+			params:   "?compress=gzip",
+			files: map[string]string{
+				"dump/schema.sql.gz": "structure;\n",
+				"dump/users.sql.gz":  "users;\n",
+				"dump/orders.sql.gz": "orders;\n",
+			},
+		},
+		{
+			scenario: "when splitting tables by param",
+			path:     "dump",
+			params:   "?split=true",
+			files: map[string]string{
+				"dump/schema.sql": "structure;\n",
+				"dump/users.sql":  "users;\n",
+				"dump/orders.sql": "orders;\n",
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.scenario, func(t *testing.T) {
+			dir := t.TempDir()
+
+			out, err := getOutput(fileScheme + filepath.Join(dir, test.path) + suffix(test.path) + test.params)
+			require.NoError(t, err)
+
+			write(t, out.Structure, "structure;\n")
+			write(t, func() (io.WriteCloser, error) { return out.Table("users") }, "users;\n")
+			write(t, func() (io.WriteCloser, error) { return out.Table("orders") }, "orders;\n")
+			require.NoError(t, out.Close())
+
+			for name, expected := range test.files {
+				assert.Equal(t, expected, read(t, filepath.Join(dir, name), test.params), name)
+			}
+		})
+	}
+}
+
+// suffix keeps the trailing slash removed by filepath.Join.
+func suffix(path string) string {
+	if path[len(path)-1] == '/' {
+		return "/"
+	}
+	return ""
+}
+
+func write(t *testing.T, open func() (io.WriteCloser, error), content string) {
+	w, err := open()
+	require.NoError(t, err)
+	_, err = io.WriteString(w, content)
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+}
+
+func read(t *testing.T, path string, params string) string {
+	f, err := os.Open(path)
+	require.NoError(t, err)
+	defer f.Close()
+
+	var r io.Reader = f
+	switch {
+	case filepath.Ext(path) == ".gz":
+		gz, err := gzip.NewReader(f)
+		require.NoError(t, err)
+		r = gz
+	case params == "?compress=zstd":
+		zr, err := zstd.NewReader(f)
+		require.NoError(t, err)
+		defer zr.Close()
+		r = zr
+	}
+
+	content, err := io.ReadAll(r)
+	require.NoError(t, err)
+	return string(content)
+}
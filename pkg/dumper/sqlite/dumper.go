@@ -0,0 +1,229 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/hellofresh/klepto/pkg/config"
+	"github.com/hellofresh/klepto/pkg/database"
+	"github.com/hellofresh/klepto/pkg/dumper"
+	"github.com/hellofresh/klepto/pkg/dumper/engine"
+	"github.com/hellofresh/klepto/pkg/progress"
+	"github.com/hellofresh/klepto/pkg/reader"
+	"github.com/hellofresh/klepto/pkg/schema"
+)
+
+// maxVariables is the maximum number of parameters of an insert statement, the lowest limit of the sqlite versions.
+const maxVariables = 999
+
+type (
+	sqliteDumper struct {
+		conn     *sql.DB
+		reader   reader.Reader
+		progress *progress.Tracker
+		// source is the dialect of the source database, its structure is translated when it is not sqlite
+		source string
+	}
+
+	// serialDumper dumps a table at a time. Sqlite has a single writer, so a table waiting for the transaction
+	// of another one would hold its read connection, which the other table may be waiting for.
+	serialDumper struct {
+		dumper.Dumper
+	}
+)
+
+// NewDumper returns a new sqlite dumper.
+func NewDumper(opts dumper.ConnOpts, conn *sql.DB, rdr reader.Reader) dumper.Dumper {
+	return &serialDumper{engine.New(rdr, &sqliteDumper{
+		conn:     conn,
+		reader:   rdr,
+		progress: opts.Progress,
+		source:   schema.DialectOf(opts.Source),
+	}, opts.Progress)}
+}
+
+// Dump executes the dump process a table at a time.
+func (d *serialDumper) Dump(ctx context.Context, cfgTables config.Tables, opts dumper.DumpOpts) error {
+	if opts.Concurrency > 1 {
+		log.WithField("concurrency", opts.Concurrency).Debug("sqlite has a single writer, the tables are dumped one at a time")
+	}
+	opts.Concurrency = 1
+
+	return d.Dumper.Dump(ctx, cfgTables, opts)
+}
+
+// DumpStructure creates the tables. The structure of other databases is not sqlite SQL, so it is translated:
+// the tables are created from the source columns and keys, with their types translated to sqlite types.
+func (d *sqliteDumper) DumpStructure(ctx context.Context, sql string) error {
+	statements := []string{sql}
+	if d.source != schema.SQLite {
+		s, err := schema.Read(d.reader, d.source)
+		if err != nil {
+			return err
+		}
+
+		if statements, err = schema.Render(s, schema.SQLite); err != nil {
+			return err
+		}
+	}
+
+	txn, err := d.conn.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to open transaction: %w", err)
+	}
+	defer txn.Rollback()
+
+	for _, stmt := range statements {
+		if _, err := txn.ExecContext(ctx, stmt); err != nil {
+			return fmt.Errorf("failed to execute %q: %w", stmt, err)
+		}
+	}
+
+	return txn.Commit()
+}
+
+// DumpTable dumps a sqlite table in a transaction.
+func (d *sqliteDumper) DumpTable(ctx context.Context, tableName string, rowChan <-chan database.Row) error {
+	// the transaction is rolled back when the context is done
+	txn, err := d.conn.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to open transaction: %w", err)
+	}
+	defer txn.Rollback()
+
+	insertedRows, err := d.insertIntoTable(ctx, txn, tableName, rowChan)
+	if err != nil {
+		return fmt.Errorf("failed to insert rows: %w", err)
+	}
+
+	log.WithFields(log.Fields{
+		"table":    tableName,
+		"inserted": insertedRows,
+	}).Debug("inserted rows")
+
+	if err := txn.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+// insertIntoTable inserts the rows with multi-row insert statements.
+func (d *sqliteDumper) insertIntoTable(ctx context.Context, txn *sql.Tx, tableName string, rowChan <-chan database.Row) (int64, error) {
+	allColumns, err := d.reader.GetColumns(tableName)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get columns: %w", err)
+	}
+
+	// Generated columns can not be written, their values are computed by the database
+	var columns []*reader.Column
+	for _, column := range allColumns {
+		if !column.Generated {
+			columns = append(columns, column)
+		}
+	}
+
+	batchSize := max(1, maxVariables/max(1, len(columns)))
+	batch := make([]interface{}, 0, batchSize*len(columns))
+	table := d.progress.Table(tableName)
+
+	var (
+		inserted int64
+		stmt     *sql.Stmt
+	)
+	flush := func(rows int) error {
+		if rows == 0 {
+			return nil
+		}
+
+		var err error
+		if rows == batchSize {
+			// the statement of a full batch is prepared once
+			if stmt == nil {
+				stmt, err = txn.PrepareContext(ctx, insertStatement(tableName, columns, batchSize))
+				if err != nil {
+					return fmt.Errorf("failed to prepare insert: %w", err)
+				}
+			}
+			_, err = stmt.ExecContext(ctx, batch...)
+		} else {
+			_, err = txn.ExecContext(ctx, insertStatement(tableName, columns, rows), batch...)
+		}
+		if err != nil {
+			return err
+		}
+
+		inserted += int64(rows)
+		table.AddWritten(int64(rows))
+		batch = batch[:0]
+		return nil
+	}
+	defer func() {
+		if stmt != nil {
+			stmt.Close()
+		}
+	}()
+
+	rows := 0
+	for row := range rowChan {
+		for _, c := range columns {
+			val := row[c.Name]
+			// Binary columns are kept as bytes to be stored as blobs, the drivers return other types as bytes too
+			if bytesVal, ok := val.([]byte); ok && c.Kind() != reader.KindBinary {
+				val = string(bytesVal)
+			}
+
+			batch = append(batch, val)
+			table.AddBytes(progress.ValueSize(val))
+		}
+
+		rows++
+		if rows == batchSize {
+			if err := flush(rows); err != nil {
+				return inserted, err
+			}
+			rows = 0
+		}
+	}
+
+	if err := flush(rows); err != nil {
+		return inserted, err
+	}
+
+	return inserted, ctx.Err()
+}
+
+// Close closes the sqlite database connection.
+func (d *sqliteDumper) Close() error {
+	if err := d.conn.Close(); err != nil {
+		return fmt.Errorf("failed to close sqlite dumper database connection: %w", err)
+	}
+	return nil
+}
+
+// insertStatement returns the statement inserting the given number of rows.
+func insertStatement(tableName string, columns []*reader.Column, rows int) string {
+	values := "(" + strings.TrimSuffix(strings.Repeat("?, ", len(columns)), ", ") + ")"
+
+	return fmt.Sprintf(
+		"INSERT INTO %s (%s) VALUES %s",
+		quoteIdentifier(tableName), quoteIdentifiers(reader.ColumnNames(columns)), strings.TrimSuffix(strings.Repeat(values+", ", rows), ", "),
+	)
+}
+
+func quoteIdentifier(name string) string {
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
+
+func quoteIdentifiers(names []string) string {
+	quoted := make([]string, len(names))
+	for i, name := range names {
+		quoted[i] = quoteIdentifier(name)
+	}
+
+	return strings.Join(quoted, ", ")
+}
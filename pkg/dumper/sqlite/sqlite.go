@@ -0,0 +1,52 @@
+package sqlite
+
+import (
+	"database/sql"
+	"errors"
+	"strings"
+
+	// registers the pure go sqlite database/sql driver
+	_ "modernc.org/sqlite"
+
+	"github.com/hellofresh/klepto/pkg/dumper"
+	"github.com/hellofresh/klepto/pkg/reader"
+)
+
+// scheme is the scheme of the sqlite dsn, e.g. `sqlite:///path/to/file.db`.
+const scheme = "sqlite://"
+
+type driver struct{}
+
+// IsSupported checks if the given dsn connection string is supported.
+func (m *driver) IsSupported(dsn string) bool {
+	return strings.HasPrefix(strings.ToLower(dsn), scheme)
+}
+
+// NewConnection opens the sqlite database file, created when it does not exist, and retrieves a new sqlite dumper.
+func (m *driver) NewConnection(opts dumper.ConnOpts, rdr reader.Reader) (dumper.Dumper, error) {
+	path, params, _ := strings.Cut(opts.DSN[len(scheme):], "?")
+	if path == "" {
+		return nil, errors.New("sqlite database path can not be empty")
+	}
+
+	// times are written in the sqlite format, e.g. `2006-01-02 15:04:05-07:00`, instead of the go one
+	dsn := "file:" + path + "?_pragma=busy_timeout(10000)&_time_format=sqlite"
+	if params != "" {
+		dsn += "&" + params
+	}
+
+	conn, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		return nil, err
+	}
+
+	// sqlite has a single writer, the tables transactions wait for the connection instead of failing as busy
+	conn.SetMaxOpenConns(1)
+	conn.SetConnMaxLifetime(opts.MaxConnLifetime)
+
+	return NewDumper(opts, conn, rdr), nil
+}
+
+func init() {
+	dumper.Register("sqlite", &driver{})
+}
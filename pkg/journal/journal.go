@@ -0,0 +1,179 @@
+package journal
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// DefaultPath returns the default journal file path of the target database dsn. The journals are kept in the klepto
+// directory of the user state directory, `$XDG_STATE_HOME` or `~/.local/state`, and named after a hash of the dsn,
+// so the steals of a target share the same journal wherever they are run from, and the dsn credentials are not written.
+func DefaultPath(dsn string) (string, error) {
+	dir := os.Getenv("XDG_STATE_HOME")
+	if dir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", fmt.Errorf("failed to find the user state directory: %w", err)
+		}
+		dir = filepath.Join(home, ".local", "state")
+	}
+
+	sum := sha256.Sum256([]byte(dsn))
+	return filepath.Join(dir, "klepto", "journal-"+hex.EncodeToString(sum[:8])+".jsonl"), nil
+}
+
+// Actions recorded in the journal.
+const (
+	// ActionDisableTriggers is recorded before the triggers of a table are disabled.
+	ActionDisableTriggers = "disable_triggers"
+	// ActionDropForeignKey is recorded before a foreign key is dropped, with the definition to re-create it.
+	ActionDropForeignKey = "drop_foreign_key"
+	// ActionEnableLocalInfile is recorded before the local_infile global setting is enabled.
+	ActionEnableLocalInfile = "enable_local_infile"
+)
+
+type (
+	// Journal records the changes made on the target database which must be reverted once the steal ends,
+	// so they can be replayed by the repair command when the steal dies midway.
+	// A nil journal records nothing.
+	Journal struct {
+		path string
+		mu   sync.Mutex
+		file *os.File
+	}
+
+	// Entry is a change made on the target database.
+	Entry struct {
+		// Action is the kind of change.
+		Action string `json:"action"`
+		// Table is the table changed.
+		Table string `json:"table,omitempty"`
+		// Name is the name of the changed object, e.g. the constraint name.
+		Name string `json:"name,omitempty"`
+		// Definition is the definition needed to restore the changed object.
+		Definition string `json:"definition,omitempty"`
+	}
+)
+
+// New creates a journal writing to the given path, the file is only created once a change is recorded.
+func New(path string) *Journal {
+	return &Journal{path: path}
+}
+
+// Path returns the journal file path.
+func (j *Journal) Path() string {
+	return j.path
+}
+
+// Record appends the entry to the journal and flushes it to the disk before the change is made.
+func (j *Journal) Record(entry Entry) error {
+	if j == nil {
+		return nil
+	}
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if j.file == nil {
+		if err := os.MkdirAll(filepath.Dir(j.path), 0700); err != nil {
+			return fmt.Errorf("failed to create journal directory: %w", err)
+		}
+
+		f, err := os.OpenFile(j.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+		if err != nil {
+			return fmt.Errorf("failed to open journal: %w", err)
+		}
+		j.file = f
+	}
+
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("failed to encode journal entry: %w", err)
+	}
+
+	if _, err := j.file.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("failed to write journal: %w", err)
+	}
+
+	return j.file.Sync()
+}
+
+// Clear removes the journal once all the recorded changes are reverted.
+func (j *Journal) Clear() error {
+	if j == nil {
+		return nil
+	}
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if j.file != nil {
+		if err := j.file.Close(); err != nil {
+			return fmt.Errorf("failed to close journal: %w", err)
+		}
+		j.file = nil
+	}
+
+	if err := os.Remove(j.path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to remove journal: %w", err)
+	}
+
+	return nil
+}
+
+// Exists checks if a journal was left at the given path.
+func Exists(path string) (bool, error) {
+	_, err := os.Stat(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return false, nil
+	}
+
+	return err == nil, err
+}
+
+// Read reads the entries recorded in the journal at the given path.
+func Read(path string) ([]Entry, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open journal: %w", err)
+	}
+	defer f.Close()
+
+	var (
+		entries   []Entry
+		decodeErr error
+	)
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		if decodeErr != nil {
+			return nil, decodeErr
+		}
+
+		var entry Entry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			decodeErr = fmt.Errorf("failed to decode journal entry %q: %w", scanner.Text(), err)
+			continue
+		}
+		entries = append(entries, entry)
+	}
+
+	// the entries are recorded before the changes are made, so a truncated last entry
+	// written while the process died was never applied and is ignored
+	if decodeErr != nil {
+		log.WithError(decodeErr).Warn("ignoring truncated journal entry")
+	}
+
+	return entries, scanner.Err()
+}
@@ -0,0 +1,108 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/hellofresh/klepto/pkg/progress"
+)
+
+const namespace = "klepto"
+
+var phases = []string{
+	progress.PhaseConnecting,
+	progress.PhaseSubset,
+	progress.PhaseStructure,
+	progress.PhasePreDumpHooks,
+	progress.PhaseTables,
+	progress.PhasePostDumpHooks,
+	progress.PhaseDone,
+}
+
+// Collector exports the progress of a steal as Prometheus metrics, the values are read from the tracker when scraped.
+type Collector struct {
+	tracker *progress.Tracker
+
+	rowsRead      *prometheus.Desc
+	rowsWritten   *prometheus.Desc
+	bytesWritten  *prometheus.Desc
+	rowsEstimated *prometheus.Desc
+	tableErrors   *prometheus.Desc
+	queryDuration *prometheus.Desc
+	tablesTotal   *prometheus.Desc
+	tablesDone    *prometheus.Desc
+	tablesRunning *prometheus.Desc
+	phase         *prometheus.Desc
+}
+
+// NewCollector creates a collector of the tracked steal.
+func NewCollector(tracker *progress.Tracker) *Collector {
+	table := []string{"table"}
+
+	return &Collector{
+		tracker:       tracker,
+		rowsRead:      prometheus.NewDesc(prometheus.BuildFQName(namespace, "", "rows_read_total"), "Rows read from the source database.", table, nil),
+		rowsWritten:   prometheus.NewDesc(prometheus.BuildFQName(namespace, "", "rows_written_total"), "Rows written to the target.", table, nil),
+		bytesWritten:  prometheus.NewDesc(prometheus.BuildFQName(namespace, "", "bytes_written_total"), "Size of the values written to the target.", table, nil),
+		rowsEstimated: prometheus.NewDesc(prometheus.BuildFQName(namespace, "", "rows_estimated"), "Rows the table is expected to have, estimated from the source database statistics.", table, nil),
+		tableErrors:   prometheus.NewDesc(prometheus.BuildFQName(namespace, "", "table_errors_total"), "Tables which failed to be read or dumped.", table, nil),
+		queryDuration: prometheus.NewDesc(prometheus.BuildFQName(namespace, "", "query_duration_seconds"), "Duration of the read queries until the first row is returned.", table, nil),
+		tablesTotal:   prometheus.NewDesc(prometheus.BuildFQName(namespace, "", "tables_total"), "Tables to steal.", nil, nil),
+		tablesDone:    prometheus.NewDesc(prometheus.BuildFQName(namespace, "", "tables_done"), "Tables stolen, or failed to be.", nil, nil),
+		tablesRunning: prometheus.NewDesc(prometheus.BuildFQName(namespace, "", "tables_running"), "Tables being stolen concurrently.", nil, nil),
+		phase:         prometheus.NewDesc(prometheus.BuildFQName(namespace, "", "phase"), "Current phase of the steal, 1 for the current one.", []string{"phase"}, nil),
+	}
+}
+
+// Describe sends the metrics descriptions.
+func (c *Collector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.rowsRead
+	ch <- c.rowsWritten
+	ch <- c.bytesWritten
+	ch <- c.rowsEstimated
+	ch <- c.tableErrors
+	ch <- c.queryDuration
+	ch <- c.tablesTotal
+	ch <- c.tablesDone
+	ch <- c.tablesRunning
+	ch <- c.phase
+}
+
+// Collect sends the current metrics values.
+func (c *Collector) Collect(ch chan<- prometheus.Metric) {
+	var done, running int
+	for _, table := range c.tracker.Tables() {
+		s := table.Stats()
+		if s.Done {
+			done++
+		} else {
+			running++
+		}
+
+		var failed float64
+		if s.Failed {
+			failed = 1
+		}
+
+		ch <- prometheus.MustNewConstMetric(c.rowsRead, prometheus.CounterValue, float64(s.Read), s.Table)
+		ch <- prometheus.MustNewConstMetric(c.rowsWritten, prometheus.CounterValue, float64(s.Written), s.Table)
+		ch <- prometheus.MustNewConstMetric(c.bytesWritten, prometheus.CounterValue, float64(s.Bytes), s.Table)
+		ch <- prometheus.MustNewConstMetric(c.rowsEstimated, prometheus.GaugeValue, float64(s.Estimate), s.Table)
+		ch <- prometheus.MustNewConstMetric(c.tableErrors, prometheus.CounterValue, failed, s.Table)
+
+		count, sum, buckets := table.Queries()
+		ch <- prometheus.MustNewConstHistogram(c.queryDuration, count, sum, buckets, s.Table)
+	}
+
+	ch <- prometheus.MustNewConstMetric(c.tablesTotal, prometheus.GaugeValue, float64(c.tracker.Total()))
+	ch <- prometheus.MustNewConstMetric(c.tablesDone, prometheus.GaugeValue, float64(done))
+	ch <- prometheus.MustNewConstMetric(c.tablesRunning, prometheus.GaugeValue, float64(running))
+
+	current := c.tracker.Phase()
+	for _, phase := range phases {
+		var value float64
+		if phase == current {
+			value = 1
+		}
+		ch <- prometheus.MustNewConstMetric(c.phase, prometheus.GaugeValue, value, phase)
+	}
+}
@@ -0,0 +1,146 @@
+package metrics
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/hellofresh/klepto/pkg/progress"
+)
+
+// shutdownTimeout is the time given to the running requests once the steal is done.
+const shutdownTimeout = 5 * time.Second
+
+type (
+	// Status is the current status of a steal.
+	Status struct {
+		// Phase is the current phase.
+		Phase string `json:"phase"`
+		// StartedAt is the time the steal started.
+		StartedAt time.Time `json:"started_at"`
+		// TablesTotal is the number of tables to steal.
+		TablesTotal int `json:"tables_total"`
+		// TablesDone is the number of tables stolen, or failed to be.
+		TablesDone int `json:"tables_done"`
+		// TablesFailed is the number of tables which failed to be stolen.
+		TablesFailed int `json:"tables_failed"`
+		// TablesRunning is the number of tables being stolen.
+		TablesRunning int `json:"tables_running"`
+		// Tables are the started tables.
+		Tables []*TableStatus `json:"tables"`
+	}
+
+	// TableStatus is the current status of a table.
+	TableStatus struct {
+		Name         string  `json:"name"`
+		Status       string  `json:"status"`
+		RowsRead     int64   `json:"rows_read"`
+		RowsWritten  int64   `json:"rows_written"`
+		BytesWritten int64   `json:"bytes_written"`
+		RowsEstimate int64   `json:"rows_estimate,omitempty"`
+		Duration     float64 `json:"duration_seconds"`
+		ETA          float64 `json:"eta_seconds,omitempty"`
+	}
+)
+
+// NewHandler returns the handler serving the Prometheus metrics on /metrics and the steal status on /status.
+func NewHandler(tracker *progress.Tracker) http.Handler {
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(
+		NewCollector(tracker),
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.HandlerFor(registry, promhttp.HandlerOpts{}))
+	mux.HandleFunc("/status", func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(NewStatus(tracker)); err != nil {
+			log.WithError(err).Warn("failed to write status")
+		}
+	})
+
+	return mux
+}
+
+// Serve serves the metrics and status of the tracked steal on the address until the context is done.
+func Serve(ctx context.Context, addr string, tracker *progress.Tracker) error {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+
+	server := &http.Server{
+		Handler:           NewHandler(tracker),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	go func() {
+		<-ctx.Done()
+
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+		if err := server.Shutdown(shutdownCtx); err != nil {
+			log.WithError(err).Warn("failed to shutdown metrics server")
+		}
+	}()
+
+	log.WithField("addr", listener.Addr().String()).Info("Serving metrics")
+	go func() {
+		if err := server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.WithError(err).Error("Metrics server failed")
+		}
+	}()
+
+	return nil
+}
+
+// NewStatus returns the current status of the tracked steal.
+func NewStatus(tracker *progress.Tracker) *Status {
+	status := &Status{
+		Phase:       tracker.Phase(),
+		StartedAt:   tracker.StartedAt(),
+		TablesTotal: tracker.Total(),
+		Tables:      []*TableStatus{},
+	}
+
+	for _, s := range tracker.Stats() {
+		table := &TableStatus{
+			Name:         s.Table,
+			Status:       "running",
+			RowsRead:     s.Read,
+			RowsWritten:  s.Written,
+			BytesWritten: s.Bytes,
+			RowsEstimate: s.Estimate,
+			Duration:     s.Elapsed.Seconds(),
+		}
+
+		switch {
+		case s.Failed:
+			table.Status = "failed"
+			status.TablesDone++
+			status.TablesFailed++
+		case s.Done:
+			table.Status = "stolen"
+			status.TablesDone++
+		default:
+			status.TablesRunning++
+			if eta, ok := s.ETA(); ok {
+				table.ETA = eta.Seconds()
+			}
+		}
+
+		status.Tables = append(status.Tables, table)
+	}
+
+	return status
+}
@@ -0,0 +1,86 @@
+package metrics
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/hellofresh/klepto/pkg/progress"
+)
+
+func newTracker() *progress.Tracker {
+	tracker := progress.NewTracker()
+	tracker.SetTotal(3)
+	tracker.SetPhase(progress.PhaseTables)
+
+	users := tracker.Start("users")
+	users.AddRead(10)
+	users.AddWritten(8)
+	users.AddBytes(128)
+	users.ObserveQuery(20 * time.Millisecond)
+
+	orders := tracker.Start("orders")
+	orders.Fail()
+
+	return tracker
+}
+
+func TestMetrics(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(NewHandler(newTracker()))
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "/metrics")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+
+	for _, expected := range []string{
+		`klepto_rows_read_total{table="users"} 10`,
+		`klepto_rows_written_total{table="users"} 8`,
+		`klepto_bytes_written_total{table="users"} 128`,
+		`klepto_table_errors_total{table="orders"} 1`,
+		`klepto_query_duration_seconds_bucket{table="users",le="0.025"} 1`,
+		`klepto_query_duration_seconds_count{table="users"} 1`,
+		`klepto_tables_total 3`,
+		`klepto_tables_done 1`,
+		`klepto_tables_running 1`,
+		`klepto_phase{phase="tables"} 1`,
+		`klepto_phase{phase="done"} 0`,
+	} {
+		assert.Contains(t, string(body), expected)
+	}
+}
+
+func TestStatus(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(NewHandler(newTracker()))
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "/status")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	var status Status
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&status))
+
+	assert.Equal(t, progress.PhaseTables, status.Phase)
+	assert.Equal(t, 3, status.TablesTotal)
+	assert.Equal(t, 1, status.TablesDone)
+	assert.Equal(t, 1, status.TablesFailed)
+	assert.Equal(t, 1, status.TablesRunning)
+	require.Len(t, status.Tables, 2)
+	assert.Equal(t, "running", status.Tables[0].Status)
+	assert.Equal(t, int64(8), status.Tables[0].RowsWritten)
+	assert.Equal(t, "failed", status.Tables[1].Status)
+}
@@ -0,0 +1,344 @@
+package progress
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Phases of a steal.
+const (
+	PhaseConnecting    = "connecting"
+	PhaseSubset        = "subset"
+	PhaseStructure     = "structure"
+	PhasePreDumpHooks  = "pre_dump_hooks"
+	PhaseTables        = "tables"
+	PhasePostDumpHooks = "post_dump_hooks"
+	PhaseDone          = "done"
+)
+
+// QueryBuckets are the upper bounds, in seconds, of the query duration buckets.
+var QueryBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10, 30, 60, 300}
+
+type (
+	// Tracker counts the rows read and written per table while stealing.
+	// A nil tracker tracks nothing.
+	Tracker struct {
+		mu      sync.Mutex
+		tables  map[string]*Table
+		order   []string
+		total   int
+		phase   string
+		started time.Time
+	}
+
+	// Table counts the rows read and written of a table, a nil table counts nothing.
+	Table struct {
+		name     string
+		started  time.Time
+		read     atomic.Int64
+		written  atomic.Int64
+		estimate atomic.Int64
+		finished atomic.Int64
+		failed   atomic.Bool
+		bytes    atomic.Int64
+		queries  Histogram
+	}
+
+	// Histogram counts observations in the QueryBuckets.
+	Histogram struct {
+		mu      sync.Mutex
+		count   uint64
+		sum     float64
+		buckets []uint64
+	}
+
+	// Stats are the counters of a table at a given time.
+	Stats struct {
+		// Table is the table name.
+		Table string
+		// Read is the number of rows read from the source.
+		Read int64
+		// Written is the number of rows written to the target.
+		Written int64
+		// Bytes is the size of the values written to the target.
+		Bytes int64
+		// Estimate is the number of rows the table is expected to have, 0 when unknown.
+		Estimate int64
+		// Elapsed is the time spent stealing the table.
+		Elapsed time.Duration
+		// Done is true once the table is stolen, or failed to be.
+		Done bool
+		// Failed is true when the table failed to be stolen.
+		Failed bool
+	}
+)
+
+// NewTracker creates a new tracker.
+func NewTracker() *Tracker {
+	return &Tracker{
+		tables:  make(map[string]*Table),
+		phase:   PhaseConnecting,
+		started: time.Now(),
+	}
+}
+
+// SetPhase sets the current phase of the steal.
+func (t *Tracker) SetPhase(phase string) {
+	if t == nil {
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.phase = phase
+}
+
+// Phase returns the current phase of the steal.
+func (t *Tracker) Phase() string {
+	if t == nil {
+		return ""
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.phase
+}
+
+// StartedAt returns the time the tracking started.
+func (t *Tracker) StartedAt() time.Time {
+	if t == nil {
+		return time.Time{}
+	}
+
+	return t.started
+}
+
+// SetTotal sets the number of tables to steal.
+func (t *Tracker) SetTotal(total int) {
+	if t == nil {
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.total = total
+}
+
+// Total returns the number of tables to steal.
+func (t *Tracker) Total() int {
+	if t == nil {
+		return 0
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.total
+}
+
+// Start starts tracking a table, the rows of the tables not started are not counted.
+func (t *Tracker) Start(name string) *Table {
+	if t == nil {
+		return nil
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	table := &Table{name: name, started: time.Now(), queries: Histogram{buckets: make([]uint64, len(QueryBuckets))}}
+	if _, ok := t.tables[name]; !ok {
+		t.order = append(t.order, name)
+	}
+	t.tables[name] = table
+
+	return table
+}
+
+// Table returns the tracked table, nil when the table is not started.
+func (t *Tracker) Table(name string) *Table {
+	if t == nil {
+		return nil
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.tables[name]
+}
+
+// Stats returns the counters of the started tables, in the order they were started.
+func (t *Tracker) Stats() []Stats {
+	if t == nil {
+		return nil
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	stats := make([]Stats, len(t.order))
+	for i, name := range t.order {
+		stats[i] = t.tables[name].Stats()
+	}
+
+	return stats
+}
+
+// Tables returns the started tables, in the order they were started.
+func (t *Tracker) Tables() []*Table {
+	if t == nil {
+		return nil
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	tables := make([]*Table, len(t.order))
+	for i, name := range t.order {
+		tables[i] = t.tables[name]
+	}
+
+	return tables
+}
+
+// AddRead counts rows read from the source.
+func (t *Table) AddRead(n int64) {
+	if t != nil {
+		t.read.Add(n)
+	}
+}
+
+// AddWritten counts rows written to the target.
+func (t *Table) AddWritten(n int64) {
+	if t != nil {
+		t.written.Add(n)
+	}
+}
+
+// AddBytes counts the size of the values written to the target.
+func (t *Table) AddBytes(n int64) {
+	if t != nil {
+		t.bytes.Add(n)
+	}
+}
+
+// ObserveQuery records the duration of a read query.
+func (t *Table) ObserveQuery(d time.Duration) {
+	if t != nil {
+		t.queries.Observe(d.Seconds())
+	}
+}
+
+// Queries returns the read queries durations.
+func (t *Table) Queries() (count uint64, sum float64, buckets map[float64]uint64) {
+	return t.queries.Snapshot()
+}
+
+// SetEstimate sets the number of rows the table is expected to have.
+func (t *Table) SetEstimate(n int64) {
+	if t != nil {
+		t.estimate.Store(n)
+	}
+}
+
+// Done marks the table as stolen.
+func (t *Table) Done() {
+	if t != nil {
+		t.finished.CompareAndSwap(0, max(1, int64(time.Since(t.started))))
+	}
+}
+
+// Fail marks the table as failed to be stolen.
+func (t *Table) Fail() {
+	if t != nil {
+		t.failed.Store(true)
+		t.Done()
+	}
+}
+
+// Stats returns the table counters.
+func (t *Table) Stats() Stats {
+	elapsed := time.Duration(t.finished.Load())
+	if elapsed == 0 {
+		elapsed = time.Since(t.started)
+	}
+
+	return Stats{
+		Table:    t.name,
+		Read:     t.read.Load(),
+		Written:  t.written.Load(),
+		Bytes:    t.bytes.Load(),
+		Estimate: t.estimate.Load(),
+		Elapsed:  elapsed,
+		Done:     t.finished.Load() != 0,
+		Failed:   t.failed.Load(),
+	}
+}
+
+// Rate returns the number of rows written per second.
+func (s Stats) Rate() float64 {
+	if s.Elapsed <= 0 {
+		return 0
+	}
+
+	return float64(s.Written) / s.Elapsed.Seconds()
+}
+
+// ETA returns the estimated time left to steal the table, false when it can't be estimated.
+func (s Stats) ETA() (time.Duration, bool) {
+	rate := s.Rate()
+	if s.Done || s.Estimate <= 0 || rate <= 0 {
+		return 0, false
+	}
+
+	left := s.Estimate - s.Written
+	if left < 0 {
+		left = 0
+	}
+
+	return time.Duration(float64(left) / rate * float64(time.Second)), true
+}
+
+// Observe records a value in the histogram.
+func (h *Histogram) Observe(v float64) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.count++
+	h.sum += v
+	for i, bound := range QueryBuckets {
+		if v <= bound {
+			h.buckets[i]++
+		}
+	}
+}
+
+// Snapshot returns the number of observations, their sum and the cumulative counts per bucket upper bound.
+func (h *Histogram) Snapshot() (count uint64, sum float64, buckets map[float64]uint64) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	buckets = make(map[float64]uint64, len(QueryBuckets))
+	for i, bound := range QueryBuckets {
+		buckets[bound] = h.buckets[i]
+	}
+
+	return h.count, h.sum, buckets
+}
+
+// ValueSize returns the size in bytes of a row value.
+func ValueSize(v interface{}) int64 {
+	switch value := v.(type) {
+	case nil:
+		return 0
+	case []byte:
+		return int64(len(value))
+	case string:
+		return int64(len(value))
+	case bool:
+		return 1
+	case int64, uint64, float64, time.Time:
+		return 8
+	default:
+		return int64(len(fmt.Sprint(value)))
+	}
+}
@@ -0,0 +1,137 @@
+package progress
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTracker(t *testing.T) {
+	t.Parallel()
+
+	tracker := NewTracker()
+	tracker.SetTotal(2)
+	assert.Nil(t, tracker.Table("users"), "tables are only tracked once started")
+
+	users := tracker.Start("users")
+	users.SetEstimate(100)
+	users.AddRead(10)
+	users.AddWritten(8)
+	tracker.Table("users").AddRead(2)
+
+	orders := tracker.Start("orders")
+	orders.Fail()
+
+	stats := tracker.Stats()
+	require.Len(t, stats, 2)
+	assert.Equal(t, 2, tracker.Total())
+
+	assert.Equal(t, "users", stats[0].Table)
+	assert.Equal(t, int64(12), stats[0].Read)
+	assert.Equal(t, int64(8), stats[0].Written)
+	assert.Equal(t, int64(100), stats[0].Estimate)
+	assert.False(t, stats[0].Done)
+
+	assert.Equal(t, "orders", stats[1].Table)
+	assert.True(t, stats[1].Done)
+	assert.True(t, stats[1].Failed)
+}
+
+func TestNilTracker(t *testing.T) {
+	t.Parallel()
+
+	var tracker *Tracker
+	tracker.SetTotal(1)
+
+	table := tracker.Start("users")
+	assert.Nil(t, table)
+	table.AddRead(1)
+	table.AddWritten(1)
+	table.SetEstimate(1)
+	table.Done()
+
+	assert.Nil(t, tracker.Table("users"))
+	assert.Empty(t, tracker.Stats())
+	assert.Zero(t, tracker.Total())
+}
+
+func TestStatsETA(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		scenario string
+		stats    Stats
+		eta      time.Duration
+		ok       bool
+	}{
+		{
+			scenario: "when the estimate is known",
+			stats:    Stats{Written: 100, Estimate: 400, Elapsed: 10 * time.Second},
+			eta:      30 * time.Second,
+			ok:       true,
+		},
+		{
+			scenario: "when more rows than estimated are written",
+			stats:    Stats{Written: 500, Estimate: 400, Elapsed: 10 * time.Second},
+			eta:      0,
+			ok:       true,
+		},
+		{
+			scenario: "when the estimate is unknown",
+			stats:    Stats{Written: 100, Elapsed: 10 * time.Second},
+		},
+		{
+			scenario: "when no row is written",
+			stats:    Stats{Estimate: 400, Elapsed: 10 * time.Second},
+		},
+		{
+			scenario: "when the table is done",
+			stats:    Stats{Written: 100, Estimate: 400, Elapsed: 10 * time.Second, Done: true},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.scenario, func(t *testing.T) {
+			eta, ok := test.stats.ETA()
+			assert.Equal(t, test.ok, ok)
+			assert.Equal(t, test.eta, eta)
+		})
+	}
+}
+
+func TestHistogram(t *testing.T) {
+	t.Parallel()
+
+	table := NewTracker().Start("users")
+	table.ObserveQuery(20 * time.Millisecond)
+	table.ObserveQuery(2 * time.Second)
+
+	count, sum, buckets := table.Queries()
+	assert.Equal(t, uint64(2), count)
+	assert.InDelta(t, 2.02, sum, 0.0001)
+	assert.Equal(t, uint64(0), buckets[0.01])
+	assert.Equal(t, uint64(1), buckets[0.025])
+	assert.Equal(t, uint64(2), buckets[2.5])
+}
+
+func TestValueSize(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		value    interface{}
+		expected int64
+	}{
+		{value: nil, expected: 0},
+		{value: []byte("abc"), expected: 3},
+		{value: "abcd", expected: 4},
+		{value: int64(1), expected: 8},
+		{value: true, expected: 1},
+		{value: int32(12345), expected: 5},
+	}
+
+	for _, test := range tests {
+		assert.Equal(t, test.expected, ValueSize(test.value))
+	}
+}
@@ -0,0 +1,206 @@
+package progress
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// terminalRefresh is the refresh interval of the progress displayed on terminals.
+const terminalRefresh = 500 * time.Millisecond
+
+// Reporter reports the progress of the tracked tables. On terminals the progress of the running tables
+// is displayed below the log lines and refreshed in place, otherwise it is logged periodically.
+type Reporter struct {
+	tracker  *Tracker
+	out      io.Writer
+	interval time.Duration
+	terminal bool
+
+	mu sync.Mutex
+	// block are the progress lines displayed on the terminal
+	block []string
+	// reported are the finished tables already logged
+	reported map[string]bool
+}
+
+// NewReporter creates a reporter writing to out, the progress is logged every interval when out is not a terminal.
+func NewReporter(tracker *Tracker, out io.Writer, interval time.Duration, terminal bool) *Reporter {
+	return &Reporter{
+		tracker:  tracker,
+		out:      out,
+		interval: interval,
+		terminal: terminal,
+		reported: make(map[string]bool),
+	}
+}
+
+// IsTerminal checks if the file is a terminal.
+func IsTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// Run reports the progress until the context is done, it fails when the interval is not positive.
+func (r *Reporter) Run(ctx context.Context) error {
+	if r.interval <= 0 {
+		return fmt.Errorf("invalid progress interval %s, it must be positive", r.interval)
+	}
+
+	refresh := r.interval
+	if r.terminal {
+		refresh = terminalRefresh
+	}
+
+	ticker := time.NewTicker(refresh)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			r.logFinished()
+			r.draw(nil)
+			return nil
+		case <-ticker.C:
+			r.logFinished()
+			if r.terminal {
+				r.draw(r.render())
+			} else {
+				r.logRunning()
+			}
+		}
+	}
+}
+
+// Write writes the log output above the progress displayed on the terminal.
+func (r *Reporter) Write(p []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.clear()
+	n, err := r.out.Write(p)
+	r.print()
+
+	return n, err
+}
+
+// logFinished logs the tables finished since the last report.
+func (r *Reporter) logFinished() {
+	for _, s := range r.tracker.Stats() {
+		if !s.Done || r.reported[s.Table] {
+			continue
+		}
+		r.reported[s.Table] = true
+
+		// the failures are reported by the dumpers
+		if s.Failed {
+			continue
+		}
+
+		log.WithFields(log.Fields{
+			"table":    s.Table,
+			"read":     s.Read,
+			"written":  s.Written,
+			"rows/s":   fmt.Sprintf("%.0f", s.Rate()),
+			"duration": s.Elapsed.Round(time.Millisecond),
+		}).Info("Table stolen")
+	}
+}
+
+// logRunning logs the progress of the running tables.
+func (r *Reporter) logRunning() {
+	for _, s := range r.tracker.Stats() {
+		if s.Done {
+			continue
+		}
+
+		fields := log.Fields{
+			"table":   s.Table,
+			"read":    s.Read,
+			"written": s.Written,
+			"rows/s":  fmt.Sprintf("%.0f", s.Rate()),
+		}
+		if eta, ok := s.ETA(); ok {
+			fields["eta"] = eta.Round(time.Second)
+			fields["percent"] = percent(s)
+		}
+
+		log.WithFields(fields).Info("Stealing table")
+	}
+}
+
+// render returns the progress lines of the running tables followed by a summary line.
+func (r *Reporter) render() []string {
+	stats := r.tracker.Stats()
+
+	var (
+		width   int
+		done    int
+		written int64
+	)
+	for _, s := range stats {
+		width = max(width, len(s.Table))
+		written += s.Written
+		if s.Done {
+			done++
+		}
+	}
+
+	var lines []string
+	for _, s := range stats {
+		if !s.Done {
+			lines = append(lines, formatLine(s, width))
+		}
+	}
+
+	return append(lines, fmt.Sprintf("%d/%d tables stolen, %d rows written", done, r.tracker.Total(), written))
+}
+
+// draw replaces the progress displayed on the terminal with the given lines.
+func (r *Reporter) draw(lines []string) {
+	if !r.terminal {
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.clear()
+	r.block = lines
+	r.print()
+}
+
+// clear erases the progress displayed on the terminal.
+func (r *Reporter) clear() {
+	if len(r.block) > 0 {
+		fmt.Fprintf(r.out, "\033[%dA\033[J", len(r.block))
+	}
+}
+
+// print displays the progress on the terminal.
+func (r *Reporter) print() {
+	for _, line := range r.block {
+		fmt.Fprintln(r.out, line)
+	}
+}
+
+// formatLine formats the progress of a running table.
+func formatLine(s Stats, width int) string {
+	line := fmt.Sprintf("%-*s %10d read %10d written %8.0f rows/s", width, s.Table, s.Read, s.Written, s.Rate())
+	if eta, ok := s.ETA(); ok {
+		line += fmt.Sprintf("  ETA %s (%d%%)", eta.Round(time.Second), percent(s))
+	}
+
+	return strings.TrimRight(line, " ")
+}
+
+// percent returns the percentage of the estimated rows written.
+func percent(s Stats) int64 {
+	return min(100, s.Written*100/s.Estimate)
+}
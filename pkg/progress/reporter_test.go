@@ -0,0 +1,59 @@
+package progress
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFormatLine(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		scenario string
+		stats    Stats
+		expected string
+	}{
+		{
+			scenario: "when the estimate is known",
+			stats:    Stats{Table: "users", Read: 120, Written: 100, Estimate: 400, Elapsed: 10 * time.Second},
+			expected: "users         120 read        100 written       10 rows/s  ETA 30s (25%)",
+		},
+		{
+			scenario: "when the estimate is unknown",
+			stats:    Stats{Table: "users", Read: 120, Written: 100, Elapsed: 10 * time.Second},
+			expected: "users         120 read        100 written       10 rows/s",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.scenario, func(t *testing.T) {
+			assert.Equal(t, test.expected, formatLine(test.stats, 6))
+		})
+	}
+}
+
+func TestReporterWrite(t *testing.T) {
+	t.Parallel()
+
+	var out bytes.Buffer
+	r := NewReporter(NewTracker(), &out, time.Second, true)
+
+	r.draw([]string{"users", "0/1 tables stolen, 0 rows written"})
+	out.Reset()
+
+	_, err := r.Write([]byte("log line\n"))
+	assert.NoError(t, err)
+	assert.Equal(t, "\033[2A\033[Jlog line\nusers\n0/1 tables stolen, 0 rows written\n", out.String())
+}
+
+func TestReporterRunInvalidInterval(t *testing.T) {
+	t.Parallel()
+
+	r := NewReporter(NewTracker(), io.Discard, 0, false)
+	assert.Error(t, r.Run(context.Background()))
+}
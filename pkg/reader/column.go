@@ -0,0 +1,141 @@
+package reader
+
+import "strings"
+
+// zeroDate is the date of the mysql zero dates and datetimes, e.g. `0000-00-00 00:00:00`.
+const zeroDate = "0000-00-00"
+
+// Column kinds, a database independent classification of the column types.
+const (
+	// KindOther is the kind of the types that are not classified, their values are handled as strings.
+	KindOther ColumnKind = iota
+	// KindString is the kind of the character types.
+	KindString
+	// KindInteger is the kind of the integer types.
+	KindInteger
+	// KindFloat is the kind of the floating point types.
+	KindFloat
+	// KindDecimal is the kind of the fixed point types.
+	KindDecimal
+	// KindBool is the kind of the boolean types.
+	KindBool
+	// KindBinary is the kind of the binary types.
+	KindBinary
+	// KindTime is the kind of the date and time types.
+	KindTime
+	// KindJSON is the kind of the JSON document types.
+	KindJSON
+)
+
+type (
+	// ColumnKind is the database independent kind of a column type.
+	ColumnKind int
+
+	// Column describes a table column.
+	Column struct {
+		// Name is the column name.
+		Name string
+		// Type is the database type name of the column, e.g. `varchar`, `integer` or `bytea`.
+		Type string
+		// Nullable is true when the column accepts null values.
+		Nullable bool
+		// Default is the column default expression, nil when the column has none.
+		Default *string
+		// Generated is true when the column value is computed by the database from the other columns and can not be
+		// written, the identity columns are not generated.
+		Generated bool
+		// Identity is true when the column value is generated by a sequence or an auto increment.
+		Identity bool
+		// IdentityAlways is true for the postgres identity columns generated always, their values are only written
+		// by COPY or with OVERRIDING SYSTEM VALUE.
+		IdentityAlways bool
+		// MaxLength is the maximum length of character and binary columns, 0 when unknown or unlimited.
+		MaxLength int64
+		// Precision is the number of digits of decimal columns, 0 when unknown or unlimited.
+		Precision int64
+		// Scale is the number of digits after the decimal point of decimal columns.
+		Scale int64
+		// Unsigned is true when the integer or decimal column only accepts positive values.
+		Unsigned bool
+	}
+)
+
+var kinds = map[string]ColumnKind{
+	"char":                        KindString,
+	"character":                   KindString,
+	"varchar":                     KindString,
+	"character varying":           KindString,
+	"bpchar":                      KindString,
+	"text":                        KindString,
+	"tinytext":                    KindString,
+	"mediumtext":                  KindString,
+	"longtext":                    KindString,
+	"enum":                        KindString,
+	"set":                         KindString,
+	"citext":                      KindString,
+	"uuid":                        KindString,
+	"tinyint":                     KindInteger,
+	"smallint":                    KindInteger,
+	"mediumint":                   KindInteger,
+	"int":                         KindInteger,
+	"integer":                     KindInteger,
+	"bigint":                      KindInteger,
+	"year":                        KindInteger,
+	"float":                       KindFloat,
+	"double":                      KindFloat,
+	"real":                        KindFloat,
+	"double precision":            KindFloat,
+	"decimal":                     KindDecimal,
+	"numeric":                     KindDecimal,
+	"boolean":                     KindBool,
+	"bool":                        KindBool,
+	"binary":                      KindBinary,
+	"varbinary":                   KindBinary,
+	"tinyblob":                    KindBinary,
+	"blob":                        KindBinary,
+	"mediumblob":                  KindBinary,
+	"longblob":                    KindBinary,
+	"bytea":                       KindBinary,
+	"date":                        KindTime,
+	"time":                        KindTime,
+	"datetime":                    KindTime,
+	"timestamp":                   KindTime,
+	"time without time zone":      KindTime,
+	"time with time zone":         KindTime,
+	"timestamp without time zone": KindTime,
+	"timestamp with time zone":    KindTime,
+	"json":                        KindJSON,
+	"jsonb":                       KindJSON,
+}
+
+// Kind returns the database independent kind of the column type.
+func (c *Column) Kind() ColumnKind {
+	return kinds[strings.ToLower(c.Type)]
+}
+
+// IsZeroDate returns whether the value of the column is a mysql zero date or datetime, which the other databases can
+// not store, the mysql clients read them as null.
+func (c *Column) IsZeroDate(value interface{}) bool {
+	if c.Kind() != KindTime {
+		return false
+	}
+
+	switch v := value.(type) {
+	case string:
+		return strings.HasPrefix(v, zeroDate)
+	case []byte:
+		return strings.HasPrefix(string(v), zeroDate)
+	default:
+		return false
+	}
+}
+
+// ColumnNames returns the names of the given columns.
+func ColumnNames(columns []*Column) []string {
+	names := make([]string, len(columns))
+	for i, c := range columns {
+		names[i] = c.Name
+	}
+
+	return names
+}
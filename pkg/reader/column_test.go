@@ -0,0 +1,51 @@
+package reader
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestColumnKind(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		columnType string
+		kind       ColumnKind
+	}{
+		{columnType: "varchar", kind: KindString},
+		{columnType: "character varying", kind: KindString},
+		{columnType: "BIGINT", kind: KindInteger},
+		{columnType: "double precision", kind: KindFloat},
+		{columnType: "numeric", kind: KindDecimal},
+		{columnType: "boolean", kind: KindBool},
+		{columnType: "bytea", kind: KindBinary},
+		{columnType: "longblob", kind: KindBinary},
+		{columnType: "timestamp with time zone", kind: KindTime},
+		{columnType: "jsonb", kind: KindJSON},
+		{columnType: "int4[]", kind: KindOther},
+	}
+
+	for _, test := range tests {
+		t.Run(test.columnType, func(t *testing.T) {
+			assert.Equal(t, test.kind, (&Column{Type: test.columnType}).Kind())
+		})
+	}
+}
+
+func TestColumnNames(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, []string{"id", "email"}, ColumnNames([]*Column{{Name: "id"}, {Name: "email"}}))
+}
+
+func TestColumnIsZeroDate(t *testing.T) {
+	t.Parallel()
+
+	datetime := &Column{Name: "created_at", Type: "datetime"}
+	assert.True(t, datetime.IsZeroDate("0000-00-00 00:00:00"))
+	assert.True(t, datetime.IsZeroDate([]byte("0000-00-00")))
+	assert.False(t, datetime.IsZeroDate("2021-01-02 03:04:05"))
+	assert.False(t, datetime.IsZeroDate(nil))
+	assert.False(t, (&Column{Name: "code", Type: "varchar"}).IsZeroDate("0000-00-00"))
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"strings"
 	"sync"
 	"time"
 
@@ -11,6 +12,7 @@ import (
 	log "github.com/sirupsen/logrus"
 
 	"github.com/hellofresh/klepto/pkg/database"
+	"github.com/hellofresh/klepto/pkg/progress"
 	"github.com/hellofresh/klepto/pkg/reader"
 )
 
@@ -22,8 +24,46 @@ type (
 		tables []string
 		// columns is a cache variable for tables and there columns in the db
 		columns sync.Map
+		// primaryKeys is a cache variable for tables and there primary key in the db
+		primaryKeys sync.Map
+		// uniqueKeys is a cache variable for tables and there unique keys in the db
+		uniqueKeys sync.Map
+		// foreignKeys is a cache variable for tables and there foreign keys in the db
+		foreignKeys sync.Map
 		// timeout is the sql read operation timeout
 		timeout time.Duration
+		// chunkSize is the number of rows read by a single query, 0 reads the tables with a single query
+		chunkSize uint64
+		// chunkWorkers is the number of chunks of a table read in parallel
+		chunkWorkers int
+		// snapshot is true when the tables are read from a consistent snapshot
+		snapshot bool
+		// snapshotSize is the number of connections sharing the snapshot
+		snapshotSize  int
+		snapshotOnce  sync.Once
+		snapshotErr   error
+		snapshotConns chan *sql.Conn
+		// snapshotMu guards the snapshot connections released after the snapshot is closed
+		snapshotMu     sync.Mutex
+		snapshotClosed bool
+		// progress counts the rows read and the queries duration per table
+		progress *progress.Tracker
+	}
+
+	// Opts are the engine read options.
+	Opts struct {
+		// Timeout is the timeout of a read query, when the tables are read in chunks it applies to each chunk.
+		Timeout time.Duration
+		// ChunkSize is the number of rows read by a single query, 0 reads the tables with a single query.
+		ChunkSize uint64
+		// ChunkWorkers is the number of chunks of a table read in parallel.
+		ChunkWorkers int
+		// Snapshot if set to true, all the tables are read from the same consistent snapshot of the database.
+		Snapshot bool
+		// SnapshotConns is the number of connections sharing the snapshot.
+		SnapshotConns int
+		// Progress counts the rows read per table, nil counts nothing.
+		Progress *progress.Tracker
 	}
 
 	// Storage is the read storage database interface.
@@ -33,19 +73,66 @@ type (
 		// GetTables return a list of all database tables
 		GetTables() ([]string, error)
 		// GetColumns return a list of all columns for a given table
-		GetColumns(string) ([]string, error)
+		GetColumns(string) ([]*reader.Column, error)
+		// GetPrimaryKey returns the primary key of a given table, nil if the table has none
+		GetPrimaryKey(string) (*reader.Key, error)
+		// GetUniqueKeys returns the unique keys, other than the primary key, of a given table
+		GetUniqueKeys(string) ([]*reader.Key, error)
+		// GetForeignKeys returns the foreign keys defined on a given table
+		GetForeignKeys(string) ([]*reader.ForeignKey, error)
 		// QuoteIdentifier returns a quoted instance of a identifier (table, column etc.)
 		QuoteIdentifier(string) string
+		// PlaceholderFormat returns the format of the query parameters placeholders
+		PlaceholderFormat() sq.PlaceholderFormat
 		// Conn return the sql.DB connection
 		Conn() *sql.DB
 		// Close closes the reader resources and releases them.
 		Close() error
 	}
+
+	// TableQuoter is implemented by the storages which table names can be qualified with their schema.
+	TableQuoter interface {
+		// QuoteTable returns a quoted table name, its schema quoted apart
+		QuoteTable(string) string
+	}
 )
 
 // New creates a new sql reader engine.
-func New(s Storage, timeout time.Duration) *Engine {
-	return &Engine{Storage: s, timeout: timeout}
+func New(s Storage, opts Opts) *Engine {
+	if opts.ChunkWorkers < 1 {
+		opts.ChunkWorkers = 1
+	}
+	if opts.SnapshotConns < 1 {
+		opts.SnapshotConns = 1
+	}
+
+	return &Engine{
+		Storage:      s,
+		timeout:      opts.Timeout,
+		chunkSize:    opts.ChunkSize,
+		chunkWorkers: opts.ChunkWorkers,
+		snapshot:     opts.Snapshot,
+		snapshotSize: opts.SnapshotConns,
+		progress:     opts.Progress,
+	}
+}
+
+// NewOpts builds the engine options from the reader connection options.
+// The snapshot connections leave one connection of the pool free for the metadata queries.
+func NewOpts(opts reader.ConnOpts) Opts {
+	snapshotConns := opts.MaxConns - 1
+	if opts.MaxConns <= 0 {
+		snapshotConns = defaultSnapshotConns
+	}
+
+	return Opts{
+		Timeout:       opts.Timeout,
+		ChunkSize:     opts.ChunkSize,
+		ChunkWorkers:  opts.ChunkWorkers,
+		Snapshot:      opts.Snapshot,
+		SnapshotConns: snapshotConns,
+		Progress:      opts.Progress,
+	}
 }
 
 // GetTables gets a list of all tables in the database
@@ -63,7 +150,7 @@ func (e *Engine) GetTables() ([]string, error) {
 }
 
 // GetColumns returns the columns in the specified database table
-func (e *Engine) GetColumns(tableName string) ([]string, error) {
+func (e *Engine) GetColumns(tableName string) ([]*reader.Column, error) {
 	columns, ok := e.columns.Load(tableName)
 	if !ok {
 		var err error
@@ -75,68 +162,347 @@ func (e *Engine) GetColumns(tableName string) ([]string, error) {
 		e.columns.Store(tableName, columns)
 	}
 
-	return columns.([]string), nil
+	return columns.([]*reader.Column), nil
+}
+
+// GetPrimaryKey returns the primary key of the specified database table
+func (e *Engine) GetPrimaryKey(tableName string) (*reader.Key, error) {
+	primaryKey, ok := e.primaryKeys.Load(tableName)
+	if !ok {
+		var err error
+		primaryKey, err = e.Storage.GetPrimaryKey(tableName)
+		if err != nil {
+			return nil, err
+		}
+
+		e.primaryKeys.Store(tableName, primaryKey)
+	}
+
+	return primaryKey.(*reader.Key), nil
+}
+
+// GetUniqueKeys returns the unique keys of the specified database table
+func (e *Engine) GetUniqueKeys(tableName string) ([]*reader.Key, error) {
+	uniqueKeys, ok := e.uniqueKeys.Load(tableName)
+	if !ok {
+		var err error
+		uniqueKeys, err = e.Storage.GetUniqueKeys(tableName)
+		if err != nil {
+			return nil, err
+		}
+
+		e.uniqueKeys.Store(tableName, uniqueKeys)
+	}
+
+	return uniqueKeys.([]*reader.Key), nil
+}
+
+// GetForeignKeys returns the foreign keys defined on the specified database table
+func (e *Engine) GetForeignKeys(tableName string) ([]*reader.ForeignKey, error) {
+	foreignKeys, ok := e.foreignKeys.Load(tableName)
+	if !ok {
+		var err error
+		foreignKeys, err = e.Storage.GetForeignKeys(tableName)
+		if err != nil {
+			return nil, err
+		}
+
+		e.foreignKeys.Store(tableName, foreignKeys)
+	}
+
+	return foreignKeys.([]*reader.ForeignKey), nil
+}
+
+// GetIndexes returns the non unique indexes of the specified database table, none when the storage can't list them
+func (e *Engine) GetIndexes(tableName string) ([]*reader.Key, error) {
+	indexer, ok := e.Storage.(reader.IndexReader)
+	if !ok {
+		return nil, nil
+	}
+
+	return indexer.GetIndexes(tableName)
 }
 
 // ReadTable returns a list of all rows in a table
-func (e *Engine) ReadTable(tableName string, rowChan chan<- database.Row, opts reader.ReadTableOpt) error {
+func (e *Engine) ReadTable(ctx context.Context, tableName string, rowChan chan<- database.Row, opts reader.ReadTableOpt) error {
 	defer close(rowChan)
 
 	logger := log.WithField("table", tableName)
 	logger.Debug("reading table data")
 
-	if len(opts.Columns) == 0 {
-		columns, err := e.GetColumns(tableName)
+	query, err := e.prepareQuery(tableName, opts)
+	if err != nil {
+		return err
+	}
+
+	e.estimateRows(tableName, opts)
+
+	key, err := e.chunkKey(tableName, opts)
+	if err != nil {
+		return err
+	}
+
+	if key == nil {
+		_, _, err := e.readChunk(ctx, tableName, query, rowChan, nil)
+		return err
+	}
+
+	logger.WithField("key", reader.ColumnNames(key)).Debug("reading table data in chunks")
+	if len(key) == 1 && key[0].Kind() == reader.KindInteger {
+		return e.readRanges(ctx, tableName, query, key[0], rowChan)
+	}
+
+	return e.readKeyset(ctx, tableName, query, key, rowChan)
+}
+
+// chunkKey returns the columns used to split the table reads in chunks, nil when the table is read with a single query.
+// Tables are only split when they have a primary key and the read is not limited, sorted or joined.
+func (e *Engine) chunkKey(tableName string, opts reader.ReadTableOpt) ([]*reader.Column, error) {
+	if e.chunkSize == 0 || opts.Limit > 0 || len(opts.Sorts) > 0 || len(opts.Relationships) > 0 ||
+		opts.Keys != nil || len(opts.Columns) > 0 {
+		return nil, nil
+	}
+
+	primaryKey, err := e.GetPrimaryKey(tableName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get primary key: %w", err)
+	}
+	if primaryKey == nil {
+		return nil, nil
+	}
+
+	columns, err := e.GetColumns(tableName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get columns: %w", err)
+	}
+
+	key := make([]*reader.Column, len(primaryKey.Columns))
+	for i, name := range primaryKey.Columns {
+		for _, c := range columns {
+			if c.Name == name {
+				key[i] = c
+			}
+		}
+		if key[i] == nil {
+			return nil, fmt.Errorf("primary key column %s not found", name)
+		}
+	}
+
+	return key, nil
+}
+
+// readRanges reads the table in ranges of its integer primary key, the ranges are read in parallel by the chunk workers.
+func (e *Engine) readRanges(ctx context.Context, tableName string, query sq.SelectBuilder, key *reader.Column, rowChan chan<- database.Row) error {
+	column := e.FormatColumn(tableName, key.Name)
+
+	var lower, upper sql.NullInt64
+	boundsQuery := query.RemoveColumns().Columns(fmt.Sprintf("MIN(%s)", column), fmt.Sprintf("MAX(%s)", column))
+	if err := e.queryRow(ctx, boundsQuery, &lower, &upper); err != nil {
+		return fmt.Errorf("failed to query %s primary key bounds: %w", tableName, err)
+	}
+	if !lower.Valid {
+		return nil
+	}
+
+	ranges := make(chan int64)
+	stop := make(chan struct{})
+	var stopOnce sync.Once
+	errChan := make(chan error, e.chunkWorkers+1)
+
+	var wg sync.WaitGroup
+	for i := 0; i < e.chunkWorkers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for start := range ranges {
+				chunk := query.Where(rangeCondition(column, start, e.chunkEnd(start, upper.Int64)))
+				if _, _, err := e.readChunk(ctx, tableName, chunk, rowChan, nil); err != nil {
+					errChan <- err
+					stopOnce.Do(func() { close(stop) })
+					return
+				}
+			}
+		}()
+	}
+
+dispatch:
+	for start := lower.Int64; ; start = e.chunkEnd(start, upper.Int64) + 1 {
+		select {
+		case ranges <- start:
+		case <-stop:
+			break dispatch
+		case <-ctx.Done():
+			errChan <- ctx.Err()
+			break dispatch
+		}
+
+		if e.chunkEnd(start, upper.Int64) >= upper.Int64 {
+			break
+		}
+	}
+	close(ranges)
+	wg.Wait()
+	close(errChan)
+
+	return <-errChan
+}
+
+// chunkEnd returns the inclusive end of the range starting at the given key.
+func (e *Engine) chunkEnd(start, upper int64) int64 {
+	if upper-start < int64(e.chunkSize) {
+		return upper
+	}
+
+	return start + int64(e.chunkSize) - 1
+}
+
+// readKeyset reads the table in chunks ordered by its primary key, each chunk starts after the last key of the previous one.
+func (e *Engine) readKeyset(ctx context.Context, tableName string, query sq.SelectBuilder, key []*reader.Column, rowChan chan<- database.Row) error {
+	columns := e.formatColumns(tableName, reader.ColumnNames(key))
+	query = query.OrderBy(columns...).Limit(e.chunkSize)
+
+	var last []interface{}
+	for {
+		chunk := query
+		if last != nil {
+			chunk = chunk.Where(keysetCondition(columns, last))
+		}
+
+		count, lastKey, err := e.readChunk(ctx, tableName, chunk, rowChan, reader.ColumnNames(key))
 		if err != nil {
-			return fmt.Errorf("failed to get columns: %w", err)
+			return err
 		}
-		opts.Columns = e.formatColumns(tableName, columns)
+		if uint64(count) < e.chunkSize {
+			return nil
+		}
+
+		last = lastKey
 	}
+}
 
-	var (
-		query sq.SelectBuilder
-		err   error
-	)
-	query, err = e.buildQuery(tableName, opts)
+// readChunk executes the query under the read timeout and publishes its rows,
+// it returns the number of rows published and the values of the key columns of the last one.
+func (e *Engine) readChunk(ctx context.Context, tableName string, query sq.SelectBuilder, rowChan chan<- database.Row, key []string) (int, []interface{}, error) {
+	logger := log.WithField("table", tableName)
+
+	runner, release, err := e.runner(ctx)
 	if err != nil {
-		return fmt.Errorf("failed to build query for %s: %w", tableName, err)
+		return 0, nil, err
 	}
 
-	var rows *sql.Rows
-	ctx, cancel := context.WithTimeout(context.Background(), e.timeout)
+	queryCtx, cancel := context.WithTimeout(ctx, e.timeout)
 	defer cancel()
 
-	errChan := make(chan error)
+	type queryResult struct {
+		rows *sql.Rows
+		err  error
+	}
+
+	start := time.Now()
+	results := make(chan queryResult, 1)
 	go func() {
-		defer close(errChan)
-		rows, err = query.RunWith(e.Conn()).QueryContext(ctx)
-		errChan <- err
+		rows, err := query.RunWith(runner).QueryContext(queryCtx)
+		results <- queryResult{rows: rows, err: err}
 	}()
 
+	var rows *sql.Rows
 	select {
-	case <-ctx.Done():
-		return fmt.Errorf("timeout during read %s table: %w", tableName, ctx.Err())
-	case err := <-errChan:
-		if err != nil {
+	case <-queryCtx.Done():
+		// the abandoned query returns once its context is cancelled, its connection is only released then
+		go func() {
+			if result := <-results; result.rows != nil {
+				result.rows.Close()
+			}
+			release()
+		}()
+
+		if ctx.Err() != nil {
+			return 0, nil, ctx.Err()
+		}
+		return 0, nil, fmt.Errorf("timeout during read %s table: %w", tableName, queryCtx.Err())
+	case result := <-results:
+		if result.err != nil {
+			release()
 			querySQL, queryParams, _ := query.ToSql()
-			logger.WithError(err).
+			logger.WithError(result.err).
 				WithFields(log.Fields{
 					"query":  querySQL,
 					"params": queryParams,
 				}).Warn("failed to query rows")
-			return fmt.Errorf("failed to query rows: %w", err)
+			return 0, nil, fmt.Errorf("failed to query rows: %w", result.err)
 		}
-		break
+		rows = result.rows
+		e.progress.Table(tableName).ObserveQuery(time.Since(start))
 	}
+	defer release()
 
-	return e.publishRows(rows, rowChan, tableName)
+	return e.publishRows(queryCtx, rows, rowChan, tableName, key)
+}
+
+// queryRow executes a single row query under the read timeout.
+func (e *Engine) queryRow(ctx context.Context, query sq.SelectBuilder, dest ...interface{}) error {
+	runner, release, err := e.runner(ctx)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	ctx, cancel := context.WithTimeout(ctx, e.timeout)
+	defer cancel()
+
+	return query.RunWith(runner).QueryRowContext(ctx).Scan(dest...)
+}
+
+// Close ends the snapshot if any and closes the storage.
+func (e *Engine) Close() error {
+	if err := e.closeSnapshot(); err != nil {
+		log.WithError(err).Warn("failed to end the snapshot transactions")
+	}
+
+	return e.Storage.Close()
+}
+
+// ValidateQuery executes the query used to read the table without fetching any row.
+func (e *Engine) ValidateQuery(ctx context.Context, tableName string, opts reader.ReadTableOpt) error {
+	query, err := e.prepareQuery(tableName, opts)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, e.timeout)
+	defer cancel()
+
+	rows, err := query.Limit(0).RunWith(e.Conn()).QueryContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	return rows.Close()
+}
+
+// prepareQuery fills the table columns if needed and builds the read query.
+func (e *Engine) prepareQuery(tableName string, opts reader.ReadTableOpt) (sq.SelectBuilder, error) {
+	if len(opts.Columns) == 0 {
+		columns, err := e.GetColumns(tableName)
+		if err != nil {
+			return sq.SelectBuilder{}, fmt.Errorf("failed to get columns: %w", err)
+		}
+		opts.Columns = e.formatColumns(tableName, reader.ColumnNames(columns))
+	}
+
+	query, err := e.buildQuery(tableName, opts)
+	if err != nil {
+		return query, fmt.Errorf("failed to build query: %w", err)
+	}
+
+	return query, nil
 }
 
 // BuildQuery builds the query that will be used to read the table
 func (e *Engine) buildQuery(tableName string, opts reader.ReadTableOpt) (sq.SelectBuilder, error) {
 	var query sq.SelectBuilder
 
-	query = sq.Select(opts.Columns...).From(e.QuoteIdentifier(tableName))
+	query = sq.Select(opts.Columns...).From(e.quoteTable(tableName)).PlaceholderFormat(e.PlaceholderFormat())
 	for _, r := range opts.Relationships {
 		if r.Table == "" {
 			r.Table = tableName
@@ -152,7 +518,11 @@ func (e *Engine) buildQuery(tableName string, opts reader.ReadTableOpt) (sq.Sele
 	}
 
 	if opts.Match != "" {
-		query = query.Where(opts.Match)
+		query = query.Where(e.escapePlaceholders(opts.Match))
+	}
+
+	if opts.Keys != nil {
+		query = query.Where(e.keysCondition(tableName, opts.Keys))
 	}
 
 	for k, v := range opts.Sorts {
@@ -166,21 +536,83 @@ func (e *Engine) buildQuery(tableName string, opts reader.ReadTableOpt) (sq.Sele
 	return query, nil
 }
 
+// keysCondition builds the condition matching the rows with one of the given key values.
+func (e *Engine) keysCondition(tableName string, keys *reader.KeysOpt) sq.Sqlizer {
+	columns := e.formatColumns(tableName, keys.Columns)
+	if len(columns) == 1 {
+		values := make([]interface{}, len(keys.Values))
+		for i, v := range keys.Values {
+			values[i] = v[0]
+		}
+		return sq.Eq{columns[0]: values}
+	}
+
+	tuple := "(" + strings.TrimSuffix(strings.Repeat("?,", len(columns)), ",") + ")"
+	tuples := make([]string, len(keys.Values))
+	args := make([]interface{}, 0, len(keys.Values)*len(columns))
+	for i, v := range keys.Values {
+		tuples[i] = tuple
+		args = append(args, v...)
+	}
+
+	return sq.Expr(
+		fmt.Sprintf("(%s) IN (%s)", strings.Join(columns, ", "), strings.Join(tuples, ", ")),
+		args...,
+	)
+}
+
+// escapePlaceholders escapes the question marks of a raw sql condition,
+// so they are not replaced when the storage does not use them as placeholders.
+func (e *Engine) escapePlaceholders(condition string) string {
+	if e.PlaceholderFormat() == sq.Question {
+		return condition
+	}
+
+	return strings.ReplaceAll(condition, "?", "??")
+}
+
 // FormatColumn returns a escaped table+column string
 func (e *Engine) FormatColumn(tableName string, columnName string) string {
 	return fmt.Sprintf(
 		"%s.%s",
-		e.QuoteIdentifier(tableName),
+		e.quoteTable(tableName),
 		e.QuoteIdentifier(columnName),
 	)
 }
 
-func (e *Engine) publishRows(rows *sql.Rows, rowChan chan<- database.Row, tableName string) error {
+// quoteTable returns the quoted table name, the storages which can not qualify the table names quote it as an identifier.
+func (e *Engine) quoteTable(tableName string) string {
+	if quoter, ok := e.Storage.(TableQuoter); ok {
+		return quoter.QuoteTable(tableName)
+	}
+
+	return e.QuoteIdentifier(tableName)
+}
+
+// rangeCondition builds the condition matching the rows which key is in the inclusive range.
+func rangeCondition(column string, start, end int64) sq.Sqlizer {
+	return sq.And{sq.GtOrEq{column: start}, sq.LtOrEq{column: end}}
+}
+
+// keysetCondition builds the condition matching the rows which key is after the given key values.
+func keysetCondition(columns []string, values []interface{}) sq.Sqlizer {
+	if len(columns) == 1 {
+		return sq.Gt{columns[0]: values[0]}
+	}
+
+	return sq.Expr(
+		fmt.Sprintf("(%s) > (%s)", strings.Join(columns, ", "), strings.TrimSuffix(strings.Repeat("?,", len(columns)), ",")),
+		values...,
+	)
+}
+
+// publishRows sends the rows to the channel, it returns the number of rows sent and the key values of the last one.
+func (e *Engine) publishRows(ctx context.Context, rows *sql.Rows, rowChan chan<- database.Row, tableName string, key []string) (int, []interface{}, error) {
 	defer rows.Close()
 
 	columnTypes, err := rows.ColumnTypes()
 	if err != nil {
-		return fmt.Errorf("failed to get column types: %w", err)
+		return 0, nil, fmt.Errorf("failed to get column types: %w", err)
 	}
 
 	columnCount := len(columnTypes)
@@ -191,6 +623,10 @@ func (e *Engine) publishRows(rows *sql.Rows, rowChan chan<- database.Row, tableN
 
 	fieldPointers := make([]interface{}, columnCount)
 
+	var (
+		count int
+		last  []interface{}
+	)
 	for rows.Next() {
 		row := make(database.Row, columnCount)
 		fields := make([]interface{}, columnCount)
@@ -200,7 +636,9 @@ func (e *Engine) publishRows(rows *sql.Rows, rowChan chan<- database.Row, tableN
 		}
 
 		if err := rows.Scan(fieldPointers...); err != nil {
-			log.WithError(err).WithField("table", tableName).Warn("failed to fetch row")
+			if err := fetchError(err, tableName, key); err != nil {
+				return count, last, err
+			}
 			continue
 		}
 
@@ -208,9 +646,33 @@ func (e *Engine) publishRows(rows *sql.Rows, rowChan chan<- database.Row, tableN
 			row[column] = fields[idx]
 		}
 
-		rowChan <- row
+		// the key values are copied before publishing, the row may be modified by the consumers
+		if key != nil {
+			last = make([]interface{}, len(key))
+			for i, k := range key {
+				last[i] = row[k]
+			}
+		}
+
+		select {
+		case rowChan <- row:
+			count++
+		case <-ctx.Done():
+			return count, last, ctx.Err()
+		}
+	}
+
+	return count, last, rows.Err()
+}
+
+// fetchError returns the error failing the read of a row which could not be fetched, nil when the row is skipped.
+// The keyset reads fail as a skipped row would end their chunks early, the other reads skip the row.
+func fetchError(err error, tableName string, key []string) error {
+	if key != nil {
+		return fmt.Errorf("failed to fetch row: %w", err)
 	}
 
+	log.WithError(err).WithField("table", tableName).Warn("failed to fetch row")
 	return nil
 }
 
@@ -0,0 +1,289 @@
+package engine
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math"
+	"path/filepath"
+	"testing"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	_ "modernc.org/sqlite"
+
+	"github.com/hellofresh/klepto/pkg/reader"
+)
+
+func TestBuildQuery(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		scenario    string
+		placeholder sq.PlaceholderFormat
+		opts        reader.ReadTableOpt
+		sql         string
+		args        []interface{}
+	}{
+		{
+			scenario:    "when match is set",
+			placeholder: sq.Question,
+			opts:        reader.ReadTableOpt{Columns: []string{"*"}, Match: "name = 'what?'", Limit: 10},
+			sql:         `SELECT * FROM "users" WHERE name = 'what?' LIMIT 10`,
+		},
+		{
+			scenario:    "when match is set with dollar placeholders",
+			placeholder: sq.Dollar,
+			opts:        reader.ReadTableOpt{Columns: []string{"*"}, Match: "data ? 'key'"},
+			sql:         `SELECT * FROM "users" WHERE data ? 'key'`,
+		},
+		{
+			scenario:    "when single column keys are set",
+			placeholder: sq.Dollar,
+			opts: reader.ReadTableOpt{Columns: []string{"*"}, Keys: &reader.KeysOpt{
+				Columns: []string{"id"},
+				Values:  [][]interface{}{{1}, {2}},
+			}},
+			sql:  `SELECT * FROM "users" WHERE "users"."id" IN ($1,$2)`,
+			args: []interface{}{1, 2},
+		},
+		{
+			scenario:    "when multiple columns keys are set",
+			placeholder: sq.Question,
+			opts: reader.ReadTableOpt{Columns: []string{"*"}, Keys: &reader.KeysOpt{
+				Columns: []string{"a", "b"},
+				Values:  [][]interface{}{{1, "x"}, {2, "y"}},
+			}},
+			sql:  `SELECT * FROM "users" WHERE ("users"."a", "users"."b") IN ((?,?), (?,?))`,
+			args: []interface{}{1, "x", 2, "y"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.scenario, func(t *testing.T) {
+			e := New(&mockStorage{placeholder: test.placeholder}, Opts{})
+
+			query, err := e.buildQuery("users", test.opts)
+			require.NoError(t, err)
+
+			sql, args, err := query.ToSql()
+			require.NoError(t, err)
+			assert.Equal(t, test.sql, sql)
+			assert.Equal(t, test.args, args)
+		})
+	}
+}
+
+func TestChunkConditions(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		scenario  string
+		condition sq.Sqlizer
+		sql       string
+		args      []interface{}
+	}{
+		{
+			scenario:  "when range is set",
+			condition: rangeCondition(`"users"."id"`, 1, 100),
+			sql:       `("users"."id" >= ? AND "users"."id" <= ?)`,
+			args:      []interface{}{int64(1), int64(100)},
+		},
+		{
+			scenario:  "when single column keyset is set",
+			condition: keysetCondition([]string{`"users"."id"`}, []interface{}{"abc"}),
+			sql:       `"users"."id" > ?`,
+			args:      []interface{}{"abc"},
+		},
+		{
+			scenario:  "when multiple columns keyset is set",
+			condition: keysetCondition([]string{`"users"."a"`, `"users"."b"`}, []interface{}{1, "x"}),
+			sql:       `("users"."a", "users"."b") > (?,?)`,
+			args:      []interface{}{1, "x"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.scenario, func(t *testing.T) {
+			sql, args, err := test.condition.ToSql()
+			require.NoError(t, err)
+			assert.Equal(t, test.sql, sql)
+			assert.Equal(t, test.args, args)
+		})
+	}
+}
+
+func TestChunkEnd(t *testing.T) {
+	t.Parallel()
+
+	e := New(&mockStorage{}, Opts{ChunkSize: 10})
+
+	assert.Equal(t, int64(10), e.chunkEnd(1, 100))
+	assert.Equal(t, int64(100), e.chunkEnd(95, 100))
+	assert.Equal(t, int64(math.MaxInt64), e.chunkEnd(math.MaxInt64-5, math.MaxInt64))
+}
+
+func TestFetchError(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		scenario string
+		key      []string
+		err      string
+	}{
+		{scenario: "when the table is read with a single query", key: nil},
+		{scenario: "when the table is read in keyset chunks", key: []string{"id"}, err: "failed to fetch row: bad value"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.scenario, func(t *testing.T) {
+			t.Parallel()
+
+			err := fetchError(errors.New("bad value"), "users", test.key)
+			if test.err == "" {
+				assert.NoError(t, err, "the row is skipped")
+				return
+			}
+			assert.EqualError(t, err, test.err)
+		})
+	}
+}
+
+func TestChunkKey(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		scenario  string
+		chunkSize uint64
+		opts      reader.ReadTableOpt
+		key       []string
+	}{
+		{scenario: "when chunks are disabled"},
+		{scenario: "when chunks are enabled", chunkSize: 10, key: []string{"id"}},
+		{scenario: "when read is limited", chunkSize: 10, opts: reader.ReadTableOpt{Limit: 5}},
+		{scenario: "when read is sorted", chunkSize: 10, opts: reader.ReadTableOpt{Sorts: map[string]string{"id": "desc"}}},
+		{scenario: "when read is joined", chunkSize: 10, opts: reader.ReadTableOpt{Relationships: []*reader.RelationshipOpt{{}}}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.scenario, func(t *testing.T) {
+			e := New(&mockStorage{}, Opts{ChunkSize: test.chunkSize})
+
+			key, err := e.chunkKey("users", test.opts)
+			require.NoError(t, err)
+			if test.key == nil {
+				assert.Nil(t, key)
+				return
+			}
+			assert.Equal(t, test.key, reader.ColumnNames(key))
+		})
+	}
+}
+
+func TestNewOpts(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		scenario      string
+		maxConns      int
+		snapshotConns int
+	}{
+		{scenario: "when connections are limited", maxConns: 5, snapshotConns: 4},
+		{scenario: "when connections are unlimited", maxConns: 0, snapshotConns: defaultSnapshotConns},
+	}
+
+	for _, test := range tests {
+		t.Run(test.scenario, func(t *testing.T) {
+			opts := NewOpts(reader.ConnOpts{MaxConns: test.maxConns, Snapshot: true})
+
+			assert.True(t, opts.Snapshot)
+			assert.Equal(t, test.snapshotConns, opts.SnapshotConns)
+		})
+	}
+}
+
+func TestSnapshotNotSupported(t *testing.T) {
+	t.Parallel()
+
+	e := New(&mockStorage{}, Opts{Snapshot: true})
+
+	_, _, err := e.runner(context.Background())
+	assert.EqualError(t, err, "the reader does not support snapshots")
+	assert.NoError(t, e.Close())
+}
+
+func TestCloseSnapshotInUse(t *testing.T) {
+	t.Parallel()
+
+	db, err := sql.Open("sqlite", filepath.Join(t.TempDir(), "snapshot.db"))
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+
+	e := New(&snapshotStorage{db: db}, Opts{Snapshot: true, SnapshotConns: 2})
+
+	_, release, err := e.runner(context.Background())
+	require.NoError(t, err)
+
+	// the close does not wait for the connection in use, it is ended once released
+	closed := make(chan error, 1)
+	go func() { closed <- e.closeSnapshot() }()
+	select {
+	case err := <-closed:
+		require.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("the snapshot close waits for the connection in use")
+	}
+
+	assert.Equal(t, 1, db.Stats().InUse, "the connection in use is left open")
+
+	release()
+	assert.Equal(t, 0, db.Stats().InUse, "the released connection is returned to the pool")
+
+	_, _, err = e.runner(context.Background())
+	assert.EqualError(t, err, "the snapshot is closed")
+}
+
+type snapshotStorage struct {
+	mockStorage
+	db *sql.DB
+}
+
+func (s *snapshotStorage) Snapshot(ctx context.Context, n int) ([]*sql.Conn, error) {
+	conns := make([]*sql.Conn, n)
+	for i := range conns {
+		conn, err := s.db.Conn(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := conn.ExecContext(ctx, "BEGIN"); err != nil {
+			return nil, err
+		}
+		conns[i] = conn
+	}
+
+	return conns, nil
+}
+
+type mockStorage struct {
+	placeholder sq.PlaceholderFormat
+}
+
+func (m *mockStorage) GetStructure() (string, error) { return "", nil }
+func (m *mockStorage) GetTables() ([]string, error)  { return nil, nil }
+func (m *mockStorage) GetColumns(string) ([]*reader.Column, error) {
+	return []*reader.Column{{Name: "id", Type: "bigint"}, {Name: "email", Type: "varchar"}}, nil
+}
+func (m *mockStorage) QuoteIdentifier(name string) string      { return fmt.Sprintf("%q", name) }
+func (m *mockStorage) PlaceholderFormat() sq.PlaceholderFormat { return m.placeholder }
+func (m *mockStorage) Conn() *sql.DB                           { return nil }
+func (m *mockStorage) Close() error                            { return nil }
+func (m *mockStorage) GetPrimaryKey(string) (*reader.Key, error) {
+	return &reader.Key{Name: "users_pkey", Columns: []string{"id"}}, nil
+}
+func (m *mockStorage) GetUniqueKeys(string) ([]*reader.Key, error) { return nil, nil }
+func (m *mockStorage) GetForeignKeys(string) ([]*reader.ForeignKey, error) {
+	return nil, nil
+}
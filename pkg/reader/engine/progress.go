@@ -0,0 +1,35 @@
+package engine
+
+import (
+	log "github.com/sirupsen/logrus"
+
+	"github.com/hellofresh/klepto/pkg/reader"
+)
+
+// RowEstimator is implemented by the storages able to estimate the number of rows of a table from the catalog.
+type RowEstimator interface {
+	// EstimateRows returns the estimated number of rows of the table, 0 when unknown.
+	EstimateRows(string) (int64, error)
+}
+
+// estimateRows sets the expected number of rows of a tracked table. Only the reads of the whole table,
+// or of its first rows, can be estimated from the catalog.
+func (e *Engine) estimateRows(tableName string, opts reader.ReadTableOpt) {
+	table := e.progress.Table(tableName)
+	estimator, ok := e.Storage.(RowEstimator)
+	if table == nil || !ok || opts.Match != "" || len(opts.Relationships) > 0 || opts.Keys != nil {
+		return
+	}
+
+	estimate, err := estimator.EstimateRows(tableName)
+	if err != nil {
+		log.WithError(err).WithField("table", tableName).Debug("failed to estimate rows")
+		return
+	}
+
+	if opts.Limit > 0 && uint64(estimate) > opts.Limit {
+		estimate = int64(opts.Limit)
+	}
+
+	table.SetEstimate(estimate)
+}
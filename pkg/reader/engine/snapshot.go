@@ -0,0 +1,138 @@
+package engine
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	sq "github.com/Masterminds/squirrel"
+	log "github.com/sirupsen/logrus"
+)
+
+// defaultSnapshotConns is the number of snapshot connections when the number of read connections is unlimited.
+const defaultSnapshotConns = 4
+
+type (
+	// Snapshotter is implemented by the storages able to read all the tables from a consistent snapshot.
+	Snapshotter interface {
+		// Snapshot opens the given number of connections, each one in a read only transaction
+		// seeing the same snapshot of the database.
+		Snapshot(ctx context.Context, n int) ([]*sql.Conn, error)
+	}
+
+	// connRunner runs the squirrel queries on a single connection.
+	connRunner struct {
+		*sql.Conn
+	}
+)
+
+func (r connRunner) Exec(query string, args ...interface{}) (sql.Result, error) {
+	return r.ExecContext(context.Background(), query, args...)
+}
+
+func (r connRunner) Query(query string, args ...interface{}) (*sql.Rows, error) {
+	return r.QueryContext(context.Background(), query, args...)
+}
+
+func (r connRunner) QueryRow(query string, args ...interface{}) *sql.Row {
+	return r.QueryRowContext(context.Background(), query, args...)
+}
+
+// runner returns the runner of the read queries and the function releasing it.
+// When the snapshot is enabled, the queries are run on one of the snapshot connections.
+func (e *Engine) runner(ctx context.Context) (sq.BaseRunner, func(), error) {
+	if !e.snapshot {
+		return e.Conn(), func() {}, nil
+	}
+
+	// the snapshot is shared by all the reads, it is not bound to the context of the first one
+	e.snapshotOnce.Do(func() {
+		e.snapshotErr = e.openSnapshot()
+	})
+	if e.snapshotErr != nil {
+		return nil, nil, e.snapshotErr
+	}
+
+	e.snapshotMu.Lock()
+	closed := e.snapshotClosed
+	e.snapshotMu.Unlock()
+	if closed {
+		return nil, nil, errors.New("the snapshot is closed")
+	}
+
+	select {
+	case conn := <-e.snapshotConns:
+		return connRunner{conn}, func() { e.releaseSnapshotConn(conn) }, nil
+	case <-ctx.Done():
+		return nil, nil, ctx.Err()
+	}
+}
+
+// releaseSnapshotConn returns the connection to the snapshot, the connections still in use when the snapshot
+// is closed, e.g. by a query abandoned after a timeout, are ended once they are released.
+func (e *Engine) releaseSnapshotConn(conn *sql.Conn) {
+	e.snapshotMu.Lock()
+	defer e.snapshotMu.Unlock()
+
+	if !e.snapshotClosed {
+		e.snapshotConns <- conn
+		return
+	}
+
+	if err := endSnapshotConn(conn); err != nil {
+		log.WithError(err).Warn("failed to end the snapshot transaction")
+	}
+}
+
+// openSnapshot opens the connections sharing the snapshot all the tables are read from.
+func (e *Engine) openSnapshot() error {
+	snapshotter, ok := e.Storage.(Snapshotter)
+	if !ok {
+		return errors.New("the reader does not support snapshots")
+	}
+
+	log.WithField("connections", e.snapshotSize).Debug("opening snapshot connections")
+
+	conns, err := snapshotter.Snapshot(context.Background(), e.snapshotSize)
+	if err != nil {
+		return fmt.Errorf("failed to open snapshot: %w", err)
+	}
+
+	e.snapshotConns = make(chan *sql.Conn, len(conns))
+	for _, conn := range conns {
+		e.snapshotConns <- conn
+	}
+
+	return nil
+}
+
+// closeSnapshot ends the snapshot transactions of the released connections, the ones still in use
+// are ended once they are released, so the close does not wait for them.
+func (e *Engine) closeSnapshot() error {
+	if e.snapshotConns == nil {
+		return nil
+	}
+
+	e.snapshotMu.Lock()
+	defer e.snapshotMu.Unlock()
+
+	e.snapshotClosed = true
+
+	var errs []error
+	for {
+		select {
+		case conn := <-e.snapshotConns:
+			errs = append(errs, endSnapshotConn(conn))
+		default:
+			return errors.Join(errs...)
+		}
+	}
+}
+
+// endSnapshotConn ends the snapshot transaction of the connection and closes it.
+func endSnapshotConn(conn *sql.Conn) error {
+	_, err := conn.ExecContext(context.Background(), "ROLLBACK")
+
+	return errors.Join(err, conn.Close())
+}
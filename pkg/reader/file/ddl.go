@@ -0,0 +1,690 @@
+package file
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/hellofresh/klepto/pkg/reader"
+	"github.com/hellofresh/klepto/pkg/schema"
+)
+
+// primaryKey is the name of the mysql and sqlite primary keys.
+const primaryKey = "PRIMARY"
+
+type (
+	// table is the structure of a table defined in the dump file, and the position of its rows.
+	table struct {
+		name        string
+		columns     []*reader.Column
+		primaryKey  *reader.Key
+		uniqueKeys  []*reader.Key
+		indexes     []*reader.Key
+		foreignKeys []*reader.ForeignKey
+		// data are the sections of the file holding the table rows, in file order.
+		data []*data
+	}
+
+	// data is a section of the file holding table rows.
+	data struct {
+		section section
+		// copy is true when the rows are the lines of a postgres COPY statement, they are INSERT statements otherwise.
+		copy bool
+		// columns are the columns of the COPY rows, nil when the rows have a value for each table column.
+		columns []string
+	}
+
+	// ddlParser parses the statements defining the tables.
+	ddlParser struct {
+		*parser
+		dialect string
+	}
+)
+
+var (
+	// postgresTypes are the postgres type aliases, the types are named as in the information schema.
+	postgresTypes = map[string]string{
+		"int2":        "smallint",
+		"int":         "integer",
+		"int4":        "integer",
+		"int8":        "bigint",
+		"float4":      "real",
+		"float8":      "double precision",
+		"decimal":     "numeric",
+		"bool":        "boolean",
+		"varchar":     "character varying",
+		"char":        "character",
+		"bpchar":      "character",
+		"timestamp":   "timestamp without time zone",
+		"timestamptz": "timestamp with time zone",
+		"time":        "time without time zone",
+		"timetz":      "time with time zone",
+		"smallserial": "smallint",
+		"serial2":     "smallint",
+		"serial":      "integer",
+		"serial4":     "integer",
+		"bigserial":   "bigint",
+		"serial8":     "bigint",
+	}
+
+	// typeWords are the words which are part of the type name after its first word, e.g. `double precision`.
+	typeWords = []string{"varying", "precision", "with", "without", "time", "zone", "unsigned", "signed", "zerofill"}
+
+	// defaultStops are the words ending a column default expression.
+	defaultStops = []string{
+		"NOT", "NULL", "PRIMARY", "UNIQUE", "KEY", "REFERENCES", "CHECK", "CONSTRAINT", "COLLATE", "COMMENT",
+		"AUTO_INCREMENT", "ON", "GENERATED", "INVISIBLE", "VISIBLE", "COLUMN_FORMAT", "STORAGE",
+	}
+)
+
+// createTable parses a CREATE TABLE statement, it returns nil for the tables without columns definitions,
+// e.g. `CREATE TABLE ... AS SELECT` or the postgres partitions.
+func (p *ddlParser) createTable() (*table, error) {
+	for !p.acceptWords("TABLE") {
+		if p.done() {
+			return nil, p.unexpected("TABLE")
+		}
+		p.next()
+	}
+	p.acceptWords("IF", "NOT", "EXISTS")
+
+	name, err := p.qualifiedName()
+	if err != nil {
+		return nil, err
+	}
+	if !p.acceptSymbol("(") {
+		return nil, nil
+	}
+
+	t := &table{name: name}
+	for !p.acceptSymbol(")") {
+		if err := p.definition(t); err != nil {
+			return nil, fmt.Errorf("invalid definition of table %s: %w", name, err)
+		}
+		p.skipUntil(",", ")")
+		p.acceptSymbol(",")
+		if p.done() {
+			return nil, fmt.Errorf("invalid definition of table %s: %w", name, p.unexpected(`")"`))
+		}
+	}
+
+	if err := p.err; err != nil {
+		return nil, err
+	}
+
+	return t, nil
+}
+
+// definition parses a column or a constraint definition of a CREATE TABLE statement.
+func (p *ddlParser) definition(t *table) error {
+	if p.isConstraint() {
+		return p.constraint(t)
+	}
+
+	c, err := p.column(t)
+	if err != nil {
+		return err
+	}
+
+	t.columns = append(t.columns, c)
+	return nil
+}
+
+// isConstraint returns whether the definition is a table constraint rather than a column,
+// the unreserved keywords are valid column names, e.g. `key` in postgres.
+func (p *ddlParser) isConstraint() bool {
+	switch {
+	case p.isWord(0, "CONSTRAINT", "PRIMARY", "UNIQUE", "FOREIGN", "CHECK", "LIKE"):
+		return true
+	case p.dialect == schema.MySQL:
+		return p.isWord(0, "KEY", "INDEX", "FULLTEXT", "SPATIAL")
+	case p.dialect == schema.Postgres:
+		return p.isWord(0, "EXCLUDE") && (p.isWord(1, "USING") || p.peekAt(1).kind == tokenSymbol)
+	}
+
+	return false
+}
+
+// constraint parses a table constraint, the checks and the full text and spatial indexes are skipped.
+func (p *ddlParser) constraint(t *table) error {
+	var name string
+	if p.acceptWords("CONSTRAINT") && !p.isWord(0, "PRIMARY", "UNIQUE", "FOREIGN", "CHECK", "EXCLUDE") {
+		var err error
+		if name, err = p.name(); err != nil {
+			return err
+		}
+	}
+
+	switch {
+	case p.acceptWords("PRIMARY", "KEY"):
+		if p.acceptWords("USING") {
+			p.next()
+		}
+		columns, ok, err := p.keyColumns()
+		if err != nil || !ok {
+			return err
+		}
+		t.setPrimaryKey(p.dialect, name, columns)
+	case p.isWord(0, "UNIQUE"), p.isWord(0, "KEY", "INDEX"):
+		unique := p.acceptWords("UNIQUE")
+		if !p.acceptWords("KEY") {
+			p.acceptWords("INDEX")
+		}
+		p.acceptWords("NULLS", "NOT", "DISTINCT")
+		if !p.isSymbol("(") && !p.isWord(0, "USING") {
+			var err error
+			if name, err = p.name(); err != nil {
+				return err
+			}
+		}
+		if p.acceptWords("USING") {
+			p.next()
+		}
+
+		columns, ok, err := p.keyColumns()
+		if err != nil || !ok {
+			return err
+		}
+		if unique {
+			t.addUniqueKey(p.dialect, name, columns)
+		} else {
+			t.addIndex(p.dialect, name, columns)
+		}
+	case p.acceptWords("FOREIGN", "KEY"):
+		if !p.isSymbol("(") {
+			if _, err := p.name(); err != nil {
+				return err
+			}
+		}
+
+		columns, err := p.names()
+		if err != nil {
+			return err
+		}
+		if !p.acceptWords("REFERENCES") {
+			return p.unexpected("REFERENCES")
+		}
+		return p.references(t, name, columns)
+	}
+
+	return nil
+}
+
+// column parses a column definition and its inline constraints.
+func (p *ddlParser) column(t *table) (*reader.Column, error) {
+	name, err := p.name()
+	if err != nil {
+		return nil, err
+	}
+
+	c := &reader.Column{Name: name, Nullable: true}
+	if err := p.columnType(c); err != nil {
+		return nil, fmt.Errorf("invalid type of column %s: %w", name, err)
+	}
+
+	var constraint string
+	for !p.done() && !p.isSymbol(",") && !p.isSymbol(")") {
+		switch {
+		case p.acceptWords("NOT", "NULL"):
+			c.Nullable = false
+		case p.acceptWords("NULL"):
+			c.Nullable = true
+		case p.acceptWords("DEFAULT"):
+			c.Default = p.defaultValue()
+			c.Identity = c.Identity || c.Default != nil && strings.HasPrefix(*c.Default, "nextval(")
+		case p.acceptWords("AUTO_INCREMENT"), p.acceptWords("AUTOINCREMENT"):
+			c.Identity = true
+		case p.acceptWords("PRIMARY", "KEY"), p.acceptWords("KEY"):
+			t.setPrimaryKey(p.dialect, constraint, []string{name})
+			// the column is not in the table yet, it is made not null here
+			c.Nullable = c.Nullable && p.dialect == schema.SQLite
+		case p.acceptWords("UNIQUE"):
+			if !p.acceptWords("KEY") {
+				p.acceptWords("INDEX")
+			}
+			t.addUniqueKey(p.dialect, constraint, []string{name})
+		case p.acceptWords("REFERENCES"):
+			if err := p.references(t, constraint, []string{name}); err != nil {
+				return nil, err
+			}
+		case p.acceptWords("GENERATED", "ALWAYS", "AS", "IDENTITY"):
+			// the identity columns are implicitly not null
+			c.Identity, c.IdentityAlways, c.Nullable = true, true, false
+			p.skipGroup()
+		case p.acceptWords("GENERATED", "BY", "DEFAULT", "AS", "IDENTITY"):
+			c.Identity, c.Nullable = true, false
+			p.skipGroup()
+		case p.acceptWords("GENERATED", "ALWAYS", "AS"), p.acceptWords("AS"):
+			c.Generated = true
+			p.skipGroup()
+		case p.acceptWords("CONSTRAINT"):
+			constraint, _ = p.name()
+			continue
+		case p.acceptWords("COLLATE"), p.acceptWords("CHARACTER", "SET"), p.acceptWords("CHARSET"), p.acceptWords("COMMENT"),
+			p.acceptWords("COLUMN_FORMAT"), p.acceptWords("STORAGE"), p.acceptWords("SRID"):
+			p.next()
+		case p.acceptWords("ON", "UPDATE"):
+			p.next()
+			p.skipGroup()
+		default:
+			if p.isSymbol("(") {
+				p.skipGroup()
+			} else {
+				p.next()
+			}
+		}
+		constraint = ""
+	}
+
+	return c, nil
+}
+
+// columnType parses the column type and its size, the postgres types are named as in the information schema,
+// e.g. `character varying` instead of `varchar`.
+func (p *ddlParser) columnType(c *reader.Column) error {
+	first, err := p.qualifiedName()
+	if err != nil {
+		return err
+	}
+
+	words := []string{strings.ToLower(first)}
+	var size []int64
+	array := false
+	for {
+		switch {
+		case p.isSymbol("("):
+			var err error
+			if size, err = p.typeSize(); err != nil {
+				return err
+			}
+		case p.isSymbol("["):
+			p.skipUntil("]")
+			p.next()
+			array = true
+		case p.isWord(0, typeWords...):
+			word := strings.ToLower(p.next().text)
+			switch word {
+			case "unsigned":
+				c.Unsigned = true
+			case "signed", "zerofill":
+			default:
+				words = append(words, word)
+			}
+		default:
+			c.Type = strings.Join(words, " ")
+			if p.dialect == schema.Postgres {
+				if name, ok := postgresTypes[c.Type]; ok {
+					// the serial types are integers with a sequence default
+					if strings.Contains(c.Type, "serial") {
+						c.Identity, c.Nullable = true, false
+					}
+					c.Type = name
+				}
+			}
+			if array {
+				c.Type += "[]"
+				return nil
+			}
+
+			switch c.Kind() {
+			case reader.KindString, reader.KindBinary:
+				if len(size) > 0 {
+					c.MaxLength = size[0]
+				}
+			case reader.KindDecimal:
+				if len(size) > 0 {
+					c.Precision = size[0]
+				}
+				if len(size) > 1 {
+					c.Scale = size[1]
+				}
+			}
+			return nil
+		}
+	}
+}
+
+// typeSize parses the parenthesized size of a type, the arguments which are not numbers are skipped, e.g. the enum values.
+func (p *ddlParser) typeSize() ([]int64, error) {
+	p.next()
+
+	var size []int64
+	for !p.acceptSymbol(")") {
+		tok := p.next()
+		switch tok.kind {
+		case tokenEOF:
+			return nil, p.unexpected(`")"`)
+		case tokenNumber:
+			if n, err := strconv.ParseInt(tok.text, 10, 64); err == nil {
+				size = append(size, n)
+			}
+		}
+	}
+
+	return size, nil
+}
+
+// defaultValue parses a column default expression. The mysql literal strings are unquoted, as in the information schema.
+func (p *ddlParser) defaultValue() *string {
+	first := p.peek()
+	if p.acceptWords("NULL") && !p.isSymbol("::") {
+		return nil
+	}
+
+	end := first.end
+	for p.isSymbol("(") || !p.done() && !p.isWord(0, defaultStops...) && !p.isSymbol(",") && !p.isSymbol(")") {
+		if p.isSymbol("(") {
+			p.skipGroup()
+		} else {
+			p.next()
+		}
+		end = p.previous.end
+	}
+
+	value := p.lexer.input[first.start:end]
+	if p.dialect == schema.MySQL && first.kind == tokenString && first.end == end {
+		value = first.text
+	}
+
+	return &value
+}
+
+// references parses the table and columns referenced by a foreign key, after the REFERENCES keyword.
+// The referenced columns are set to the referenced table primary key once all the tables are read when they are omitted.
+func (p *ddlParser) references(t *table, name string, columns []string) error {
+	referencedTable, err := p.qualifiedName()
+	if err != nil {
+		return err
+	}
+
+	var referencedColumns []string
+	if p.isSymbol("(") {
+		if referencedColumns, err = p.names(); err != nil {
+			return err
+		}
+	}
+
+	if name == "" {
+		switch p.dialect {
+		case schema.MySQL:
+			name = fmt.Sprintf("%s_ibfk_%d", t.name, len(t.foreignKeys)+1)
+		default:
+			name = fmt.Sprintf("%s_%s_fkey", t.name, strings.Join(columns, "_"))
+		}
+	}
+
+	t.foreignKeys = append(t.foreignKeys, &reader.ForeignKey{
+		Name:              name,
+		Table:             t.name,
+		Columns:           columns,
+		ReferencedTable:   referencedTable,
+		ReferencedColumns: referencedColumns,
+	})
+	return nil
+}
+
+// keyColumns parses the parenthesized columns of a key or an index, ok is false when the key has expressions.
+func (p *ddlParser) keyColumns() (columns []string, ok bool, err error) {
+	if err := p.expectSymbol("("); err != nil {
+		return nil, false, err
+	}
+
+	ok = true
+	for {
+		if p.isSymbol("(") {
+			ok = false
+		} else {
+			name, err := p.name()
+			if err != nil {
+				return nil, false, err
+			}
+			columns = append(columns, name)
+
+			// the mysql prefix lengths, e.g. `name(10)`, are skipped, the other groups are function calls
+			if p.isSymbol("(") && p.dialect != schema.MySQL {
+				ok = false
+			}
+		}
+
+		// the sort orders, collations and operator classes only apply to the index
+		for !p.done() && !p.isSymbol(",") && !p.isSymbol(")") {
+			if p.isSymbol("(") {
+				p.skipGroup()
+				continue
+			}
+			if tok := p.next(); tok.kind != tokenWord && tok.kind != tokenIdentifier {
+				ok = false
+			}
+		}
+
+		if p.acceptSymbol(")") {
+			return columns, ok, nil
+		}
+		if err := p.expectSymbol(","); err != nil {
+			return nil, false, err
+		}
+	}
+}
+
+// createIndex parses a CREATE INDEX statement, the partial and full text indexes are skipped.
+func (p *ddlParser) createIndex(tables map[string]*table) error {
+	p.acceptWords("CREATE")
+	unique := p.acceptWords("UNIQUE")
+	if p.isWord(0, "FULLTEXT", "SPATIAL") {
+		return nil
+	}
+	if !p.acceptWords("INDEX") {
+		return p.unexpected("INDEX")
+	}
+	p.acceptWords("CONCURRENTLY")
+	p.acceptWords("IF", "NOT", "EXISTS")
+
+	var name string
+	if !p.isWord(0, "ON") {
+		var err error
+		if name, err = p.qualifiedName(); err != nil {
+			return err
+		}
+	}
+	p.skipUntil("ON")
+	if !p.acceptWords("ON") {
+		return p.unexpected("ON")
+	}
+	p.acceptWords("ONLY")
+
+	tableName, err := p.qualifiedName()
+	if err != nil {
+		return err
+	}
+	t, ok := tables[tableName]
+	if !ok {
+		return fmt.Errorf("index %s is defined on the unknown table %s", name, tableName)
+	}
+
+	if p.acceptWords("USING") {
+		p.next()
+	}
+	columns, ok, err := p.keyColumns()
+	if err != nil || !ok {
+		return err
+	}
+
+	p.skipUntil("WHERE")
+	if p.isWord(0, "WHERE") {
+		return nil
+	}
+
+	if unique {
+		t.addUniqueKey(p.dialect, name, columns)
+	} else {
+		t.addIndex(p.dialect, name, columns)
+	}
+	return nil
+}
+
+// alterTable parses the ALTER TABLE statements adding constraints or changing the columns, e.g. the pg_dump keys
+// or the phpMyAdmin auto increments.
+func (p *ddlParser) alterTable(tables map[string]*table) error {
+	p.acceptWords("ALTER", "TABLE")
+	p.acceptWords("IF", "EXISTS")
+	p.acceptWords("ONLY")
+
+	name, err := p.qualifiedName()
+	if err != nil {
+		return err
+	}
+	t, ok := tables[name]
+	if !ok {
+		return nil
+	}
+
+	for !p.done() {
+		if err := p.alteration(t); err != nil {
+			return fmt.Errorf("invalid alteration of table %s: %w", name, err)
+		}
+		p.skipUntil(",")
+		p.acceptSymbol(",")
+	}
+
+	return p.err
+}
+
+// alteration parses an action of an ALTER TABLE statement, the actions which do not change the structure read are skipped.
+func (p *ddlParser) alteration(t *table) error {
+	switch {
+	case p.acceptWords("ADD"):
+		if !p.isConstraint() {
+			p.acceptWords("COLUMN")
+			p.acceptWords("IF", "NOT", "EXISTS")
+		}
+		return p.definition(t)
+	case p.acceptWords("MODIFY"):
+		p.acceptWords("COLUMN")
+		c, err := p.column(t)
+		if err != nil {
+			return err
+		}
+		t.replaceColumn(c.Name, c)
+	case p.acceptWords("CHANGE"):
+		// the column is renamed, its old name is followed by the new definition
+		p.acceptWords("COLUMN")
+		previous, err := p.name()
+		if err != nil {
+			return err
+		}
+		c, err := p.column(t)
+		if err != nil {
+			return err
+		}
+		t.replaceColumn(previous, c)
+	case p.acceptWords("ALTER"):
+		p.acceptWords("COLUMN")
+		name, err := p.name()
+		if err != nil {
+			return err
+		}
+		c, ok := t.column(name)
+		if !ok {
+			return nil
+		}
+
+		switch {
+		case p.acceptWords("SET", "DEFAULT"):
+			c.Default = p.defaultValue()
+			c.Identity = c.Identity || c.Default != nil && strings.HasPrefix(*c.Default, "nextval(")
+		case p.acceptWords("DROP", "DEFAULT"):
+			c.Default = nil
+		case p.acceptWords("SET", "NOT", "NULL"):
+			c.Nullable = false
+		case p.acceptWords("DROP", "NOT", "NULL"):
+			c.Nullable = true
+		case p.acceptWords("ADD", "GENERATED", "ALWAYS", "AS", "IDENTITY"):
+			c.Identity, c.IdentityAlways = true, true
+		case p.acceptWords("ADD", "GENERATED", "BY", "DEFAULT", "AS", "IDENTITY"):
+			c.Identity = true
+		}
+	}
+
+	return nil
+}
+
+// column returns the column of the given name.
+func (t *table) column(name string) (*reader.Column, bool) {
+	for _, c := range t.columns {
+		if c.Name == name {
+			return c, true
+		}
+	}
+
+	return nil, false
+}
+
+// replaceColumn replaces the column of the given name by the new definition, it is added when it does not exist.
+func (t *table) replaceColumn(name string, c *reader.Column) {
+	for i, existing := range t.columns {
+		if existing.Name == name {
+			t.columns[i] = c
+			return
+		}
+	}
+
+	t.columns = append(t.columns, c)
+}
+
+// setPrimaryKey sets the table primary key, its columns can't be null.
+func (t *table) setPrimaryKey(dialect string, name string, columns []string) {
+	switch {
+	case dialect != schema.Postgres:
+		name = primaryKey
+	case name == "":
+		name = t.name + "_pkey"
+	}
+
+	t.primaryKey = &reader.Key{Name: name, Columns: columns}
+	if dialect == schema.SQLite {
+		return
+	}
+	for _, name := range columns {
+		if c, ok := t.column(name); ok {
+			c.Nullable = false
+		}
+	}
+}
+
+// addUniqueKey adds a unique key, the unnamed keys are named as their database would.
+func (t *table) addUniqueKey(dialect string, name string, columns []string) {
+	if name == "" {
+		name = t.keyName(dialect, columns, "key")
+	}
+
+	t.uniqueKeys = append(t.uniqueKeys, &reader.Key{Name: name, Columns: columns})
+}
+
+// addIndex adds an index, the unnamed indexes are named as their database would.
+func (t *table) addIndex(dialect string, name string, columns []string) {
+	if name == "" {
+		name = t.keyName(dialect, columns, "idx")
+	}
+
+	t.indexes = append(t.indexes, &reader.Key{Name: name, Columns: columns})
+}
+
+// keyName returns the name of an unnamed key, mysql names them after their first column and postgres after the table
+// and the columns, e.g. `users_email_key`.
+func (t *table) keyName(dialect string, columns []string, suffix string) string {
+	if dialect != schema.MySQL {
+		return fmt.Sprintf("%s_%s_%s", t.name, strings.Join(columns, "_"), suffix)
+	}
+
+	used := make(map[string]bool)
+	for _, key := range append(t.uniqueKeys, t.indexes...) {
+		used[key.Name] = true
+	}
+
+	name := columns[0]
+	for i := 2; used[name]; i++ {
+		name = fmt.Sprintf("%s_%d", columns[0], i)
+	}
+
+	return name
+}
@@ -0,0 +1,48 @@
+// Package file reads the tables of a SQL dump file, e.g. the output of mysqldump or a plain text pg_dump,
+// so a database can be stolen without a connection to it. The filters are applied in process.
+package file
+
+import (
+	"errors"
+	"net/url"
+	"strings"
+
+	"github.com/hellofresh/klepto/pkg/reader"
+	"github.com/hellofresh/klepto/pkg/schema"
+)
+
+// scheme is the scheme of the dump file dsn, e.g. `file:///backups/prod.sql?dialect=mysql`.
+const scheme = "file://"
+
+type driver struct{}
+
+// IsSupported checks if the given dsn connection string is supported.
+func (m *driver) IsSupported(dsn string) bool {
+	return strings.HasPrefix(strings.ToLower(dsn), scheme)
+}
+
+// NewConnection scans the dump file and retrieves a new dump file reader, the dialect of the file defaults to mysql.
+func (m *driver) NewConnection(opts reader.ConnOpts) (reader.Reader, error) {
+	path, params, _ := strings.Cut(opts.DSN[len(scheme):], "?")
+	if path == "" {
+		return nil, errors.New("dump file path can not be empty")
+	}
+
+	values, err := url.ParseQuery(params)
+	if err != nil {
+		return nil, err
+	}
+
+	dialect := schema.MySQL
+	if name := values.Get("dialect"); name != "" {
+		if dialect, err = schema.ParseDialect(name); err != nil {
+			return nil, err
+		}
+	}
+
+	return NewReader(path, dialect)
+}
+
+func init() {
+	reader.Register("file", &driver{})
+}
@@ -0,0 +1,936 @@
+package file
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hellofresh/klepto/pkg/database"
+	"github.com/hellofresh/klepto/pkg/reader"
+	"github.com/hellofresh/klepto/pkg/schema"
+)
+
+// errLimitReached stops the read of the table rows once the query limit is reached.
+var errLimitReached = errors.New("limit reached")
+
+type (
+	// query reads the rows of a table as the read table query of the database engines, the joins, the filter,
+	// the sorts and the limit are applied in process.
+	query struct {
+		r     *dumpReader
+		table *table
+		joins []*join
+		// match is the filter condition, nil matches every row.
+		match expression
+		sorts []*sortColumn
+		limit uint64
+		// keys are the values of the keys option, nil matches every row.
+		keys       map[string]struct{}
+		keyColumns []*columnRef
+		// columns are the columns of the published rows.
+		columns []*columnRef
+	}
+
+	// join is an inner join of the rows of a table, the referenced rows are loaded in memory.
+	join struct {
+		table      *table
+		key        *columnRef
+		foreignKey *columnRef
+		rows       map[string][]database.Row
+	}
+
+	// sortColumn is a column of the ORDER BY clause.
+	sortColumn struct {
+		column     *columnRef
+		descending bool
+	}
+
+	// columnRef is a column of a table of the query.
+	columnRef struct {
+		table  string
+		column string
+	}
+
+	// scope holds the row of each table of the query, by table name.
+	scope map[string]database.Row
+
+	// expression is a compiled condition or value, the conditions are true, false or nil when unknown.
+	expression func(scope) interface{}
+
+	// filterParser compiles a condition on the tables of a query.
+	filterParser struct {
+		*parser
+		query *query
+	}
+)
+
+// newQuery compiles the read table options of the table.
+func (r *dumpReader) newQuery(tableName string, opts reader.ReadTableOpt) (*query, error) {
+	t, err := r.table(tableName)
+	if err != nil {
+		return nil, err
+	}
+
+	q := &query{r: r, table: t, limit: opts.Limit}
+	for _, rel := range opts.Relationships {
+		if err := q.addJoin(rel); err != nil {
+			return nil, fmt.Errorf("invalid relationship of %s: %w", tableName, err)
+		}
+	}
+
+	if strings.TrimSpace(opts.Match) != "" {
+		if q.match, err = q.compile(opts.Match); err != nil {
+			return nil, fmt.Errorf("invalid match condition of %s: %w", tableName, err)
+		}
+	}
+
+	names := make([]string, 0, len(opts.Sorts))
+	for name := range opts.Sorts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		column, err := q.parseColumn(name)
+		if err != nil {
+			return nil, fmt.Errorf("invalid sort of %s: %w", tableName, err)
+		}
+
+		switch direction := strings.ToLower(strings.TrimSpace(opts.Sorts[name])); direction {
+		case "", "asc", "desc":
+			q.sorts = append(q.sorts, &sortColumn{column: column, descending: direction == "desc"})
+		default:
+			return nil, fmt.Errorf("invalid sort of %s: unknown direction %q", tableName, direction)
+		}
+	}
+
+	if opts.Keys != nil {
+		q.keys = make(map[string]struct{}, len(opts.Keys.Values))
+		for _, values := range opts.Keys.Values {
+			q.keys[valuesKey(values)] = struct{}{}
+		}
+		for _, name := range opts.Keys.Columns {
+			column, err := q.resolve(t.name, name)
+			if err != nil {
+				return nil, fmt.Errorf("invalid keys of %s: %w", tableName, err)
+			}
+			q.keyColumns = append(q.keyColumns, column)
+		}
+	}
+
+	for _, name := range opts.Columns {
+		column, err := q.parseColumn(name)
+		if err != nil {
+			return nil, fmt.Errorf("invalid column of %s: %w", tableName, err)
+		}
+		q.columns = append(q.columns, column)
+	}
+	if len(q.columns) == 0 {
+		for _, c := range t.columns {
+			q.columns = append(q.columns, &columnRef{table: t.name, column: c.Name})
+		}
+	}
+
+	return q, nil
+}
+
+// addJoin adds the join of a relationship, `JOIN referenced ON referenced.key = table.foreign_key`.
+func (q *query) addJoin(rel *reader.RelationshipOpt) error {
+	referenced, err := q.parseName(rel.ReferencedTable)
+	if err != nil {
+		return err
+	}
+	t, err := q.r.table(referenced)
+	if err != nil {
+		return err
+	}
+	for _, j := range q.joins {
+		if j.table.name == t.name {
+			return fmt.Errorf("table %s is joined more than once", t.name)
+		}
+	}
+
+	j := &join{table: t}
+	q.joins = append(q.joins, j)
+
+	if j.key, err = q.parseColumn(rel.ReferencedTable + "." + rel.ReferencedKey); err != nil {
+		return err
+	}
+
+	foreignTable := rel.Table
+	if foreignTable == "" {
+		foreignTable = q.table.name
+	}
+	if j.foreignKey, err = q.parseColumn(foreignTable + "." + rel.ForeignKey); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// ValidateQuery compiles the read table options without reading any row.
+func (r *dumpReader) ValidateQuery(_ context.Context, tableName string, opts reader.ReadTableOpt) error {
+	_, err := r.newQuery(tableName, opts)
+	return err
+}
+
+// ReadTable publishes the table rows to the channel, the filter is applied in process.
+func (r *dumpReader) ReadTable(ctx context.Context, tableName string, rowChan chan<- database.Row, opts reader.ReadTableOpt) error {
+	defer close(rowChan)
+
+	q, err := r.newQuery(tableName, opts)
+	if err != nil {
+		return err
+	}
+
+	return q.run(ctx, rowChan)
+}
+
+// run reads the table rows and publishes the ones matching the query.
+func (q *query) run(ctx context.Context, rowChan chan<- database.Row) error {
+	for _, j := range q.joins {
+		if err := q.load(j); err != nil {
+			return err
+		}
+	}
+
+	var (
+		published uint64
+		sorted    []scope
+	)
+	err := q.r.readRows(q.table, func(row database.Row) error {
+		for _, s := range q.matches(row) {
+			if q.sorts != nil {
+				sorted = append(sorted, s)
+				continue
+			}
+
+			if err := q.publish(ctx, s, rowChan); err != nil {
+				return err
+			}
+			published++
+			if q.limit > 0 && published >= q.limit {
+				return errLimitReached
+			}
+		}
+		return nil
+	})
+	if errors.Is(err, errLimitReached) {
+		return nil
+	}
+	if err != nil || q.sorts == nil {
+		return err
+	}
+
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return q.less(sorted[i], sorted[j])
+	})
+	if q.limit > 0 && uint64(len(sorted)) > q.limit {
+		sorted = sorted[:q.limit]
+	}
+	for _, s := range sorted {
+		if err := q.publish(ctx, s, rowChan); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// load reads the rows of a joined table, indexed by their key value.
+func (q *query) load(j *join) error {
+	j.rows = make(map[string][]database.Row)
+	return q.r.readRows(j.table, func(row database.Row) error {
+		value := row[j.key.column]
+		if value != nil {
+			key := valuesKey([]interface{}{value})
+			j.rows[key] = append(j.rows[key], row)
+		}
+		return nil
+	})
+}
+
+// matches returns the scopes of the joined rows matching the query.
+func (q *query) matches(row database.Row) []scope {
+	if q.keys != nil {
+		values := make([]interface{}, len(q.keyColumns))
+		for i, c := range q.keyColumns {
+			values[i] = row[c.column]
+		}
+		if _, ok := q.keys[valuesKey(values)]; !ok {
+			return nil
+		}
+	}
+
+	scopes := []scope{{q.table.name: row}}
+	for _, j := range q.joins {
+		var joined []scope
+		for _, s := range scopes {
+			value := s[j.foreignKey.table][j.foreignKey.column]
+			if value == nil {
+				continue
+			}
+			for _, referenced := range j.rows[valuesKey([]interface{}{value})] {
+				next := make(scope, len(s)+1)
+				for name, r := range s {
+					next[name] = r
+				}
+				next[j.table.name] = referenced
+				joined = append(joined, next)
+			}
+		}
+		scopes = joined
+	}
+
+	if q.match == nil {
+		return scopes
+	}
+
+	matching := scopes[:0]
+	for _, s := range scopes {
+		if truth(q.match(s)) == true {
+			matching = append(matching, s)
+		}
+	}
+	return matching
+}
+
+// publish sends the query columns of the scope to the channel.
+func (q *query) publish(ctx context.Context, s scope, rowChan chan<- database.Row) error {
+	row := make(database.Row, len(q.columns))
+	for _, c := range q.columns {
+		row[c.column] = s[c.table][c.column]
+	}
+
+	select {
+	case rowChan <- row:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// less returns whether the scope is sorted before the other one, mysql and sqlite sort the nulls first
+// and postgres last.
+func (q *query) less(a, b scope) bool {
+	for _, s := range q.sorts {
+		x, y := a[s.column.table][s.column.column], b[s.column.table][s.column.column]
+
+		var c int
+		switch {
+		case x == nil && y == nil:
+			continue
+		case x == nil || y == nil:
+			c = 1
+			if (x == nil) == (q.r.dialect != schema.Postgres) {
+				c = -1
+			}
+		default:
+			c = q.compare(x, y)
+		}
+
+		if c == 0 {
+			continue
+		}
+		if s.descending {
+			return c > 0
+		}
+		return c < 0
+	}
+
+	return false
+}
+
+// compile compiles a SQL condition.
+func (q *query) compile(condition string) (expression, error) {
+	p := &filterParser{parser: newParser(condition, q.r.dialect), query: q}
+	e, err := p.or()
+	if err != nil {
+		return nil, err
+	}
+	if !p.done() {
+		return nil, p.unexpected("the end of the condition")
+	}
+
+	return e, nil
+}
+
+// parseName parses a table name, e.g. `users` or `"users"`.
+func (q *query) parseName(text string) (string, error) {
+	p := newParser(text, q.r.dialect)
+	name, err := p.qualifiedName()
+	if err == nil && !p.done() {
+		err = p.unexpected("the end of the name")
+	}
+
+	return name, err
+}
+
+// parseColumn parses a column reference, e.g. `users.id` or "`users`.`id`".
+func (q *query) parseColumn(text string) (*columnRef, error) {
+	p := &filterParser{parser: newParser(text, q.r.dialect), query: q}
+	column, err := p.column()
+	if err == nil && !p.done() {
+		err = p.unexpected("the end of the column")
+	}
+
+	return column, err
+}
+
+// resolve returns the column of the table, the unqualified columns belong to the first table of the query having them.
+// The mysql and sqlite names are case insensitive.
+func (q *query) resolve(tableName string, columnName string) (*columnRef, error) {
+	tables := []*table{q.table}
+	for _, j := range q.joins {
+		tables = append(tables, j.table)
+	}
+
+	for _, t := range tables {
+		if tableName != "" && !q.sameName(t.name, tableName) {
+			continue
+		}
+		for _, c := range t.columns {
+			if q.sameName(c.Name, columnName) {
+				return &columnRef{table: t.name, column: c.Name}, nil
+			}
+		}
+		if tableName != "" {
+			return nil, fmt.Errorf("unknown column %s.%s", tableName, columnName)
+		}
+	}
+
+	if tableName != "" {
+		return nil, fmt.Errorf("unknown table %s", tableName)
+	}
+	return nil, fmt.Errorf("unknown column %s", columnName)
+}
+
+func (q *query) sameName(a, b string) bool {
+	if q.r.dialect == schema.Postgres {
+		return a == b
+	}
+
+	return strings.EqualFold(a, b)
+}
+
+// or parses `a OR b`.
+func (p *filterParser) or() (expression, error) {
+	left, err := p.and()
+	for err == nil && p.acceptWords("OR") {
+		var right expression
+		if right, err = p.and(); err == nil {
+			left = orExpression(left, right)
+		}
+	}
+
+	return left, err
+}
+
+// and parses `a AND b`.
+func (p *filterParser) and() (expression, error) {
+	left, err := p.not()
+	for err == nil && p.acceptWords("AND") {
+		var right expression
+		if right, err = p.not(); err == nil {
+			left = andExpression(left, right)
+		}
+	}
+
+	return left, err
+}
+
+// not parses `NOT a`.
+func (p *filterParser) not() (expression, error) {
+	if !p.acceptWords("NOT") {
+		return p.predicate()
+	}
+
+	e, err := p.not()
+	if err != nil {
+		return nil, err
+	}
+	return notExpression(e), nil
+}
+
+// predicate parses a comparison, e.g. `a = b`, `a IS NULL`, `a IN (b, c)`, `a LIKE b` or `a BETWEEN b AND c`.
+func (p *filterParser) predicate() (expression, error) {
+	left, err := p.operand()
+	if err != nil {
+		return nil, err
+	}
+
+	if p.acceptWords("IS") {
+		negate := p.acceptWords("NOT")
+		var e expression
+		switch {
+		case p.acceptWords("NULL"):
+			e = func(s scope) interface{} { return left(s) == nil }
+		case p.acceptWords("TRUE"):
+			e = func(s scope) interface{} { return truth(left(s)) == true }
+		case p.acceptWords("FALSE"):
+			e = func(s scope) interface{} { return truth(left(s)) == false }
+		default:
+			return nil, p.unexpected("NULL, TRUE or FALSE")
+		}
+		if negate {
+			e = notExpression(e)
+		}
+		return e, nil
+	}
+
+	negate := false
+	if p.isWord(0, "NOT") && p.isWord(1, "IN", "LIKE", "ILIKE", "BETWEEN") {
+		p.next()
+		negate = true
+	}
+
+	var e expression
+	switch {
+	case p.acceptWords("IN"):
+		e, err = p.in(left)
+	case p.isWord(0, "LIKE", "ILIKE"):
+		insensitive := strings.EqualFold(p.next().text, "ILIKE") || p.query.r.dialect == schema.MySQL
+		e, err = p.like(left, insensitive)
+	case p.acceptWords("BETWEEN"):
+		e, err = p.between(left)
+	case negate:
+		return nil, p.unexpected("IN, LIKE or BETWEEN")
+	case p.peek().kind == tokenSymbol:
+		e, err = p.comparison(left)
+	default:
+		return left, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if negate {
+		e = notExpression(e)
+	}
+	return e, nil
+}
+
+// comparison parses the operator and the right operand of a comparison.
+func (p *filterParser) comparison(left expression) (expression, error) {
+	operator := p.peek().text
+	switch operator {
+	case "=", "==", "!=", "<>", "<", "<=", ">", ">=", "<=>":
+		p.next()
+	default:
+		return left, nil
+	}
+
+	right, err := p.operand()
+	if err != nil {
+		return nil, err
+	}
+
+	compare := p.query.compare
+	if operator == "<=>" {
+		return func(s scope) interface{} {
+			x, y := left(s), right(s)
+			if x == nil || y == nil {
+				return x == nil && y == nil
+			}
+			return compare(x, y) == 0
+		}, nil
+	}
+
+	return func(s scope) interface{} {
+		x, y := left(s), right(s)
+		if x == nil || y == nil {
+			return nil
+		}
+
+		c := compare(x, y)
+		switch operator {
+		case "=", "==":
+			return c == 0
+		case "!=", "<>":
+			return c != 0
+		case "<":
+			return c < 0
+		case "<=":
+			return c <= 0
+		case ">":
+			return c > 0
+		default:
+			return c >= 0
+		}
+	}, nil
+}
+
+// in parses the values of `a IN (b, c)`.
+func (p *filterParser) in(left expression) (expression, error) {
+	if err := p.expectSymbol("("); err != nil {
+		return nil, err
+	}
+
+	var values []expression
+	for {
+		value, err := p.operand()
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, value)
+
+		if !p.acceptSymbol(",") {
+			break
+		}
+	}
+	if err := p.expectSymbol(")"); err != nil {
+		return nil, err
+	}
+
+	compare := p.query.compare
+	return func(s scope) interface{} {
+		x := left(s)
+		if x == nil {
+			return nil
+		}
+
+		var result interface{} = false
+		for _, value := range values {
+			y := value(s)
+			switch {
+			case y == nil:
+				result = nil
+			case compare(x, y) == 0:
+				return true
+			}
+		}
+		return result
+	}, nil
+}
+
+// like parses the pattern of `a LIKE b`, the mysql patterns and the ILIKE patterns are case insensitive.
+func (p *filterParser) like(left expression, insensitive bool) (expression, error) {
+	pattern, err := p.operand()
+	if err != nil {
+		return nil, err
+	}
+
+	// the patterns are usually constant, the last one is kept compiled
+	var (
+		last     string
+		compiled *regexp.Regexp
+	)
+	return func(s scope) interface{} {
+		x, y := left(s), pattern(s)
+		if x == nil || y == nil {
+			return nil
+		}
+		if compiled == nil || text(y) != last {
+			last = text(y)
+			compiled = likePattern(last, insensitive)
+		}
+		return compiled.MatchString(text(x))
+	}, nil
+}
+
+// between parses the bounds of `a BETWEEN b AND c`.
+func (p *filterParser) between(left expression) (expression, error) {
+	lower, err := p.operand()
+	if err != nil {
+		return nil, err
+	}
+	if !p.acceptWords("AND") {
+		return nil, p.unexpected("AND")
+	}
+	upper, err := p.operand()
+	if err != nil {
+		return nil, err
+	}
+
+	compare := p.query.compare
+	bound := func(bound expression, valid func(int) bool) expression {
+		return func(s scope) interface{} {
+			x, y := left(s), bound(s)
+			if x == nil || y == nil {
+				return nil
+			}
+			return valid(compare(x, y))
+		}
+	}
+
+	return andExpression(
+		bound(lower, func(c int) bool { return c >= 0 }),
+		bound(upper, func(c int) bool { return c <= 0 }),
+	), nil
+}
+
+// operand parses a parenthesized condition, a literal, the current time functions or a column.
+func (p *filterParser) operand() (expression, error) {
+	if p.acceptSymbol("(") {
+		e, err := p.or()
+		if err != nil {
+			return nil, err
+		}
+		return e, p.expectSymbol(")")
+	}
+
+	value, ok, err := p.constant()
+	if err != nil {
+		return nil, err
+	}
+	if ok {
+		return func(scope) interface{} { return value }, nil
+	}
+
+	tok := p.peek()
+	if tok.kind == tokenWord && p.peekAt(1).kind == tokenSymbol && p.peekAt(1).text == "(" {
+		return nil, fmt.Errorf("function %s is not supported on dump files", tok.text)
+	}
+
+	column, err := p.column()
+	if err != nil {
+		return nil, err
+	}
+	return func(s scope) interface{} { return s[column.table][column.column] }, nil
+}
+
+// constant parses a literal or a current time function.
+func (p *filterParser) constant() (interface{}, bool, error) {
+	negative := false
+	if p.isSymbol("-") || p.isSymbol("+") {
+		negative = p.next().text == "-"
+		if p.peek().kind != tokenNumber {
+			return nil, false, p.unexpected("a number")
+		}
+	}
+
+	tok := p.peek()
+	switch {
+	case tok.kind == tokenNumber:
+		p.next()
+		text := tok.text
+		if negative {
+			text = "-" + text
+		}
+		if n, err := strconv.ParseInt(text, 10, 64); err == nil {
+			return n, true, nil
+		}
+		f, err := strconv.ParseFloat(text, 64)
+		if err != nil {
+			return nil, false, fmt.Errorf("invalid number %s: %w", text, err)
+		}
+		return f, true, nil
+	case tok.kind == tokenString:
+		p.next()
+		return tok.text, true, nil
+	case tok.kind == tokenBlob:
+		p.next()
+		return []byte(tok.text), true, nil
+	case p.acceptWords("NULL"):
+		return nil, true, nil
+	case p.acceptWords("TRUE"):
+		return true, true, nil
+	case p.acceptWords("FALSE"):
+		return false, true, nil
+	case p.isWord(0, "NOW", "CURRENT_TIMESTAMP", "LOCALTIMESTAMP", "CURRENT_DATE"):
+		date := p.next().text
+		if p.acceptSymbol("(") {
+			if err := p.expectSymbol(")"); err != nil {
+				return nil, false, err
+			}
+		}
+
+		now := time.Now().UTC()
+		if strings.EqualFold(date, "CURRENT_DATE") {
+			now = now.Truncate(24 * time.Hour)
+		}
+		return now, true, nil
+	}
+
+	return nil, false, nil
+}
+
+// column parses a column reference, optionally qualified by its table.
+func (p *filterParser) column() (*columnRef, error) {
+	name, err := p.name()
+	if err != nil {
+		return nil, err
+	}
+
+	tableName := ""
+	if p.acceptSymbol(".") {
+		tableName = name
+		if name, err = p.name(); err != nil {
+			return nil, err
+		}
+	}
+
+	return p.query.resolve(tableName, name)
+}
+
+// compare compares two non null values, the numbers are compared numerically and the times chronologically.
+// The mysql strings comparison is case insensitive.
+func (q *query) compare(x, y interface{}) int {
+	if a, ok := number(x); ok {
+		if b, ok := number(y); ok {
+			return compareFloats(a, b)
+		}
+		if b, err := strconv.ParseFloat(strings.TrimSpace(text(y)), 64); err == nil {
+			return compareFloats(a, b)
+		}
+	} else if b, ok := number(y); ok {
+		if a, err := strconv.ParseFloat(strings.TrimSpace(text(x)), 64); err == nil {
+			return compareFloats(a, b)
+		}
+	}
+
+	a, aTime := x.(time.Time)
+	b, bTime := y.(time.Time)
+	switch {
+	case aTime && !bTime:
+		b, bTime = parseTime(text(y))
+	case bTime && !aTime:
+		a, aTime = parseTime(text(x))
+	}
+	if aTime && bTime {
+		return a.Compare(b)
+	}
+
+	if q.r.dialect == schema.MySQL {
+		return strings.Compare(strings.ToLower(text(x)), strings.ToLower(text(y)))
+	}
+	return strings.Compare(text(x), text(y))
+}
+
+func compareFloats(a, b float64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+
+	return 0
+}
+
+// number returns the numeric value of the numbers and the booleans.
+func number(v interface{}) (float64, bool) {
+	switch v := v.(type) {
+	case int64:
+		return float64(v), true
+	case float64:
+		return v, !math.IsNaN(v)
+	case bool:
+		if v {
+			return 1, true
+		}
+		return 0, true
+	}
+
+	return 0, false
+}
+
+// text returns the string representation of a value.
+func text(v interface{}) string {
+	switch v := v.(type) {
+	case string:
+		return v
+	case []byte:
+		return string(v)
+	case time.Time:
+		return v.Format("2006-01-02 15:04:05.999999999")
+	}
+
+	return fmt.Sprint(v)
+}
+
+// truth returns the truth value of a condition or a value, nil when it is unknown.
+func truth(v interface{}) interface{} {
+	switch v := v.(type) {
+	case nil:
+		return nil
+	case bool:
+		return v
+	}
+
+	if n, ok := number(v); ok {
+		return n != 0
+	}
+	n, err := strconv.ParseFloat(strings.TrimSpace(text(v)), 64)
+	return err == nil && n != 0
+}
+
+func orExpression(left, right expression) expression {
+	return func(s scope) interface{} {
+		x, y := truth(left(s)), truth(right(s))
+		if x == true || y == true {
+			return true
+		}
+		if x == nil || y == nil {
+			return nil
+		}
+		return false
+	}
+}
+
+func andExpression(left, right expression) expression {
+	return func(s scope) interface{} {
+		x, y := truth(left(s)), truth(right(s))
+		if x == false || y == false {
+			return false
+		}
+		if x == nil || y == nil {
+			return nil
+		}
+		return true
+	}
+}
+
+func notExpression(e expression) expression {
+	return func(s scope) interface{} {
+		if x := truth(e(s)); x != nil {
+			return x == false
+		}
+		return nil
+	}
+}
+
+// likePattern converts a LIKE pattern to a regular expression, `%` matches any characters and `_` a single one.
+func likePattern(pattern string, insensitive bool) *regexp.Regexp {
+	var b strings.Builder
+	if insensitive {
+		b.WriteString("(?is)^")
+	} else {
+		b.WriteString("(?s)^")
+	}
+
+	escaped := false
+	for _, c := range pattern {
+		switch {
+		case escaped:
+			escaped = false
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		case c == '\\':
+			escaped = true
+		case c == '%':
+			b.WriteString(".*")
+		case c == '_':
+			b.WriteString(".")
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	b.WriteString("$")
+
+	return regexp.MustCompile(b.String())
+}
+
+// valuesKey returns a key identifying the values, the values are compared by their string representation.
+func valuesKey(values []interface{}) string {
+	parts := make([]string, len(values))
+	for i, v := range values {
+		parts[i] = text(v)
+	}
+
+	return strings.Join(parts, "\x00")
+}
@@ -0,0 +1,142 @@
+package file
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/hellofresh/klepto/pkg/database"
+	"github.com/hellofresh/klepto/pkg/reader"
+	"github.com/hellofresh/klepto/pkg/schema"
+)
+
+func TestReadTableFilter(t *testing.T) {
+	t.Parallel()
+
+	r := newTestReader(t, schema.MySQL, mysqlDump)
+
+	tests := []struct {
+		scenario string
+		table    string
+		opts     reader.ReadTableOpt
+		ids      []interface{}
+	}{
+		{
+			scenario: "when the rows are matched by a condition",
+			table:    "users",
+			opts:     reader.ReadTableOpt{Match: "users.score > 0 OR `name` IS NULL"},
+			ids:      []interface{}{int64(1), int64(2)},
+		},
+		{
+			scenario: "when the mysql strings are compared case insensitively",
+			table:    "users",
+			opts:     reader.ReadTableOpt{Match: "email LIKE 'b%' AND NOT id IN (1, 3)"},
+			ids:      []interface{}{int64(2)},
+		},
+		{
+			scenario: "when the rows are matched on their dates",
+			table:    "users",
+			opts:     reader.ReadTableOpt{Match: "created_at BETWEEN '2020-01-01' AND NOW()"},
+			ids:      []interface{}{int64(1), int64(3)},
+		},
+		{
+			scenario: "when the condition is unknown for null values",
+			table:    "users",
+			opts:     reader.ReadTableOpt{Match: "NOT (score < 0)"},
+			ids:      []interface{}{int64(1)},
+		},
+		{
+			scenario: "when the rows are sorted and limited",
+			table:    "users",
+			opts:     reader.ReadTableOpt{Sorts: map[string]string{"users.name": "desc"}, Limit: 2},
+			ids:      []interface{}{int64(1), int64(3)},
+		},
+		{
+			scenario: "when the nulls are sorted first",
+			table:    "users",
+			opts:     reader.ReadTableOpt{Sorts: map[string]string{"users.score": "asc"}},
+			ids:      []interface{}{int64(2), int64(3), int64(1)},
+		},
+		{
+			scenario: "when the rows are limited without sort",
+			table:    "orders",
+			opts:     reader.ReadTableOpt{Limit: 1},
+			ids:      []interface{}{int64(10)},
+		},
+		{
+			scenario: "when the rows are restricted to keys",
+			table:    "orders",
+			opts:     reader.ReadTableOpt{Keys: &reader.KeysOpt{Columns: []string{"user_id"}, Values: [][]interface{}{{int64(3)}, {int64(4)}}}},
+			ids:      []interface{}{int64(11)},
+		},
+		{
+			scenario: "when the rows are matched on a related table",
+			table:    "orders",
+			opts: reader.ReadTableOpt{
+				Relationships: []*reader.RelationshipOpt{{ForeignKey: "user_id", ReferencedTable: "users", ReferencedKey: "id"}},
+				Match:         "users.email = 'c@example.com'",
+			},
+			ids: []interface{}{int64(11)},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.scenario, func(t *testing.T) {
+			t.Parallel()
+
+			var ids []interface{}
+			for _, row := range readRows(t, r, test.table, test.opts) {
+				ids = append(ids, row["id"])
+			}
+			assert.Equal(t, test.ids, ids)
+		})
+	}
+}
+
+func TestReadTableColumns(t *testing.T) {
+	t.Parallel()
+
+	r := newTestReader(t, schema.MySQL, mysqlDump)
+
+	rows := readRows(t, r, "orders", reader.ReadTableOpt{
+		Columns: []string{r.FormatColumn("orders", "id")},
+		Limit:   1,
+	})
+	assert.Equal(t, []database.Row{{"id": int64(10)}}, rows)
+}
+
+func TestValidateQuery(t *testing.T) {
+	t.Parallel()
+
+	r := newTestReader(t, schema.MySQL, mysqlDump)
+
+	tests := []struct {
+		scenario string
+		opts     reader.ReadTableOpt
+		valid    bool
+	}{
+		{scenario: "when the condition is valid", opts: reader.ReadTableOpt{Match: "id >= 2 AND email <> ''"}, valid: true},
+		{scenario: "when the column does not exist", opts: reader.ReadTableOpt{Match: "missing = 1"}},
+		{scenario: "when the condition calls a function", opts: reader.ReadTableOpt{Match: "LOWER(email) = 'a'"}},
+		{scenario: "when the condition is incomplete", opts: reader.ReadTableOpt{Match: "id ="}},
+		{scenario: "when the sort direction is invalid", opts: reader.ReadTableOpt{Sorts: map[string]string{"id": "up"}}},
+		{scenario: "when the related table does not exist", opts: reader.ReadTableOpt{
+			Relationships: []*reader.RelationshipOpt{{ForeignKey: "id", ReferencedTable: "missing", ReferencedKey: "id"}},
+		}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.scenario, func(t *testing.T) {
+			t.Parallel()
+
+			err := r.ValidateQuery(context.Background(), "users", test.opts)
+			if test.valid {
+				require.NoError(t, err)
+			} else {
+				require.Error(t, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,503 @@
+package file
+
+import (
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/hellofresh/klepto/pkg/schema"
+)
+
+// Token kinds of the SQL lexer.
+const (
+	tokenEOF tokenKind = iota
+	// tokenWord is a keyword or an unquoted identifier.
+	tokenWord
+	// tokenIdentifier is a quoted identifier, its text is unquoted.
+	tokenIdentifier
+	// tokenString is a string literal, its text is unquoted and unescaped.
+	tokenString
+	// tokenNumber is a number literal.
+	tokenNumber
+	// tokenBlob is a hexadecimal or bit literal, its text holds the decoded bytes.
+	tokenBlob
+	// tokenSymbol is an operator or a punctuation.
+	tokenSymbol
+)
+
+type (
+	tokenKind int
+
+	token struct {
+		kind tokenKind
+		text string
+		// start and end are the position of the token in the lexer input.
+		start, end int
+	}
+
+	// lexer splits a statement in tokens, the comments are skipped.
+	lexer struct {
+		input   string
+		pos     int
+		dialect string
+	}
+
+	// parser reads the tokens of a statement with any number of lookahead tokens.
+	parser struct {
+		lexer *lexer
+		ahead []token
+		// previous is the last consumed token.
+		previous token
+		err      error
+	}
+)
+
+var (
+	// mysqlEscapes are the characters of the mysql backslash escape sequences.
+	mysqlEscapes = map[byte]string{'0': "\x00", 'b': "\b", 'n': "\n", 'r': "\r", 't': "\t", 'Z': "\x1a", '%': `\%`, '_': `\_`}
+	// postgresEscapes are the characters of the postgres backslash escape sequences.
+	postgresEscapes = map[byte]string{'b': "\b", 'f': "\f", 'n': "\n", 'r': "\r", 't': "\t"}
+)
+
+// next returns the next token, tokenEOF at the end of the input.
+func (l *lexer) next() (token, error) {
+	if err := l.skipSpaceAndComments(); err != nil {
+		return token{}, err
+	}
+
+	start := l.pos
+	if start >= len(l.input) {
+		return token{kind: tokenEOF, start: start, end: start}, nil
+	}
+
+	c := l.input[start]
+	switch {
+	case c == '\'':
+		return l.quoted(start, tokenString, '\'', l.dialect == schema.MySQL)
+	case c == '"' && l.dialect == schema.MySQL:
+		return l.quoted(start, tokenString, '"', true)
+	case c == '"':
+		return l.quoted(start, tokenIdentifier, '"', false)
+	case c == '`':
+		return l.quoted(start, tokenIdentifier, '`', false)
+	case c == '$' && l.dialect == schema.Postgres:
+		if tag, ok := l.dollarTag(start); ok {
+			return l.dollarQuoted(start, tag)
+		}
+	case c == '0' && l.dialect == schema.MySQL && strings.HasPrefix(strings.ToLower(l.input[start:]), "0x"):
+		l.pos += 2
+		for l.pos < len(l.input) && isHexDigit(l.input[l.pos]) {
+			l.pos++
+		}
+		return l.blob(start, l.input[start+2:l.pos], 16)
+	case isDigit(c) || c == '.' && start+1 < len(l.input) && isDigit(l.input[start+1]):
+		return l.number(start), nil
+	case isWordStart(c):
+		return l.word(start)
+	}
+
+	for _, symbol := range []string{"<=>", "<=", ">=", "<>", "!=", "::", "||", "&&"} {
+		if strings.HasPrefix(l.input[start:], symbol) {
+			l.pos += len(symbol)
+			return token{kind: tokenSymbol, text: symbol, start: start, end: l.pos}, nil
+		}
+	}
+
+	l.pos++
+	return token{kind: tokenSymbol, text: string(c), start: start, end: l.pos}, nil
+}
+
+// skipSpaceAndComments moves the lexer to the start of the next token.
+func (l *lexer) skipSpaceAndComments() error {
+	for l.pos < len(l.input) {
+		rest := l.input[l.pos:]
+		switch {
+		case isSpace(rest[0]):
+			l.pos++
+		case strings.HasPrefix(rest, "--") || rest[0] == '#' && l.dialect == schema.MySQL:
+			end := strings.IndexByte(rest, '\n')
+			if end < 0 {
+				end = len(rest) - 1
+			}
+			l.pos += end + 1
+		case strings.HasPrefix(rest, "/*"):
+			end := strings.Index(rest[2:], "*/")
+			if end < 0 {
+				return fmt.Errorf("unterminated comment at position %d", l.pos)
+			}
+			l.pos += end + 4
+		default:
+			return nil
+		}
+	}
+
+	return nil
+}
+
+// quoted reads a quoted string or identifier, the quote is escaped by doubling it and, when enabled, by a backslash.
+func (l *lexer) quoted(start int, kind tokenKind, quote byte, backslash bool) (token, error) {
+	var text strings.Builder
+	for i := start + 1; i < len(l.input); i++ {
+		c := l.input[i]
+		switch {
+		case c == '\\' && backslash && i+1 < len(l.input):
+			i++
+			if escaped, ok := mysqlEscapes[l.input[i]]; ok {
+				text.WriteString(escaped)
+			} else {
+				text.WriteByte(l.input[i])
+			}
+		case c == quote && i+1 < len(l.input) && l.input[i+1] == quote:
+			text.WriteByte(quote)
+			i++
+		case c == quote:
+			l.pos = i + 1
+			return token{kind: kind, text: text.String(), start: start, end: l.pos}, nil
+		default:
+			text.WriteByte(c)
+		}
+	}
+
+	return token{}, fmt.Errorf("unterminated quote at position %d", start)
+}
+
+// escaped reads a postgres escape string, e.g. `E'it\'s'`, which supports the C-style backslash escapes.
+func (l *lexer) escaped(start int) (token, error) {
+	var text strings.Builder
+	for i := start + 2; i < len(l.input); i++ {
+		c := l.input[i]
+		switch {
+		case c == '\\' && i+1 < len(l.input):
+			i++
+			if escaped, ok := postgresEscapes[l.input[i]]; ok {
+				text.WriteString(escaped)
+			} else {
+				text.WriteByte(l.input[i])
+			}
+		case c == '\'' && i+1 < len(l.input) && l.input[i+1] == '\'':
+			text.WriteByte('\'')
+			i++
+		case c == '\'':
+			l.pos = i + 1
+			return token{kind: tokenString, text: text.String(), start: start, end: l.pos}, nil
+		default:
+			text.WriteByte(c)
+		}
+	}
+
+	return token{}, fmt.Errorf("unterminated quote at position %d", start)
+}
+
+// dollarTag returns the tag of the postgres dollar quote starting at the position, e.g. `$body$`.
+func (l *lexer) dollarTag(start int) (string, bool) {
+	end := strings.IndexByte(l.input[start+1:], '$')
+	if end < 0 {
+		return "", false
+	}
+
+	tag := l.input[start : start+end+2]
+	for i := 1; i < len(tag)-1; i++ {
+		if !isWordStart(tag[i]) && !(i > 1 && isDigit(tag[i])) {
+			return "", false
+		}
+	}
+
+	return tag, true
+}
+
+func (l *lexer) dollarQuoted(start int, tag string) (token, error) {
+	body := start + len(tag)
+	end := strings.Index(l.input[body:], tag)
+	if end < 0 {
+		return token{}, fmt.Errorf("unterminated dollar quote at position %d", start)
+	}
+
+	l.pos = body + end + len(tag)
+	return token{kind: tokenString, text: l.input[body : body+end], start: start, end: l.pos}, nil
+}
+
+func (l *lexer) number(start int) token {
+	for l.pos < len(l.input) && (isDigit(l.input[l.pos]) || l.input[l.pos] == '.') {
+		l.pos++
+	}
+
+	if l.pos < len(l.input) && (l.input[l.pos] == 'e' || l.input[l.pos] == 'E') {
+		exponent := l.pos + 1
+		if exponent < len(l.input) && (l.input[exponent] == '+' || l.input[exponent] == '-') {
+			exponent++
+		}
+		if exponent < len(l.input) && isDigit(l.input[exponent]) {
+			l.pos = exponent
+			for l.pos < len(l.input) && isDigit(l.input[l.pos]) {
+				l.pos++
+			}
+		}
+	}
+
+	return token{kind: tokenNumber, text: l.input[start:l.pos], start: start, end: l.pos}
+}
+
+// word reads a keyword or an identifier, and the literals starting with a prefix, e.g. `X'0F'` or `_utf8mb4'text'`.
+func (l *lexer) word(start int) (token, error) {
+	for l.pos < len(l.input) && (isWordStart(l.input[l.pos]) || isDigit(l.input[l.pos]) || l.input[l.pos] == '$') {
+		l.pos++
+	}
+
+	word := l.input[start:l.pos]
+	quote := l.pos < len(l.input) && l.input[l.pos] == '\''
+	switch lower := strings.ToLower(word); {
+	case quote && (lower == "x" || lower == "b"):
+		tok, err := l.quoted(l.pos, tokenString, '\'', false)
+		if err != nil {
+			return tok, err
+		}
+		base := 16
+		if lower == "b" {
+			base = 2
+		}
+		return l.blob(start, tok.text, base)
+	case quote && lower == "e" && l.dialect == schema.Postgres:
+		return l.escaped(start)
+	case quote && lower == "n":
+		tok, err := l.quoted(l.pos, tokenString, '\'', l.dialect == schema.MySQL)
+		tok.start = start
+		return tok, err
+	case strings.HasPrefix(word, "_") && l.dialect == schema.MySQL:
+		// the character set introducers, e.g. `_binary 'data'`, only change the charset of the string that follows
+		next := l.pos
+		for next < len(l.input) && isSpace(l.input[next]) {
+			next++
+		}
+		if next < len(l.input) && (l.input[next] == '\'' || l.input[next] == '"') {
+			tok, err := l.quoted(next, tokenString, l.input[next], true)
+			tok.start = start
+			return tok, err
+		}
+	}
+
+	return token{kind: tokenWord, text: word, start: start, end: l.pos}, nil
+}
+
+// blob decodes a hexadecimal or bit literal.
+func (l *lexer) blob(start int, digits string, base int) (token, error) {
+	if base == 16 {
+		if len(digits)%2 == 1 {
+			digits = "0" + digits
+		}
+		b, err := hex.DecodeString(digits)
+		if err != nil {
+			return token{}, fmt.Errorf("invalid hexadecimal literal at position %d: %w", start, err)
+		}
+		return token{kind: tokenBlob, text: string(b), start: start, end: l.pos}, nil
+	}
+
+	n, ok := new(big.Int).SetString(digits, base)
+	if !ok {
+		return token{}, fmt.Errorf("invalid bit literal at position %d", start)
+	}
+	return token{kind: tokenBlob, text: string(n.Bytes()), start: start, end: l.pos}, nil
+}
+
+func newParser(input string, dialect string) *parser {
+	return &parser{lexer: &lexer{input: input, dialect: dialect}}
+}
+
+// peekAt returns the i-th token after the current position without consuming it.
+func (p *parser) peekAt(i int) token {
+	for len(p.ahead) <= i {
+		tok, err := p.lexer.next()
+		if err != nil {
+			if p.err == nil {
+				p.err = err
+			}
+			tok = token{kind: tokenEOF, start: len(p.lexer.input), end: len(p.lexer.input)}
+		}
+		p.ahead = append(p.ahead, tok)
+	}
+
+	return p.ahead[i]
+}
+
+func (p *parser) peek() token {
+	return p.peekAt(0)
+}
+
+func (p *parser) next() token {
+	tok := p.peek()
+	p.ahead = p.ahead[1:]
+	p.previous = tok
+	return tok
+}
+
+// done returns whether all the tokens were read.
+func (p *parser) done() bool {
+	return p.peek().kind == tokenEOF
+}
+
+// isWord returns whether the i-th next token is one of the words.
+func (p *parser) isWord(i int, words ...string) bool {
+	tok := p.peekAt(i)
+	if tok.kind != tokenWord {
+		return false
+	}
+
+	for _, word := range words {
+		if strings.EqualFold(tok.text, word) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// acceptWords consumes the next tokens when they are the sequence of words.
+func (p *parser) acceptWords(words ...string) bool {
+	for i, word := range words {
+		if !p.isWord(i, word) {
+			return false
+		}
+	}
+
+	for range words {
+		p.next()
+	}
+	return true
+}
+
+// isSymbol returns whether the next token is the symbol.
+func (p *parser) isSymbol(symbol string) bool {
+	tok := p.peek()
+	return tok.kind == tokenSymbol && tok.text == symbol
+}
+
+// acceptSymbol consumes the next token when it is the symbol.
+func (p *parser) acceptSymbol(symbol string) bool {
+	if !p.isSymbol(symbol) {
+		return false
+	}
+
+	p.next()
+	return true
+}
+
+func (p *parser) expectSymbol(symbol string) error {
+	if !p.acceptSymbol(symbol) {
+		return p.unexpected(fmt.Sprintf("%q", symbol))
+	}
+
+	return nil
+}
+
+// name reads an identifier.
+func (p *parser) name() (string, error) {
+	tok := p.peek()
+	if tok.kind != tokenWord && tok.kind != tokenIdentifier {
+		return "", p.unexpected("a name")
+	}
+
+	p.next()
+	return tok.text, nil
+}
+
+// qualifiedName reads a name and its qualifiers, e.g. `public.users`, and returns its last part.
+func (p *parser) qualifiedName() (string, error) {
+	name, err := p.name()
+	for err == nil && p.acceptSymbol(".") {
+		name, err = p.name()
+	}
+
+	return name, err
+}
+
+// names reads a parenthesized list of names.
+func (p *parser) names() ([]string, error) {
+	if err := p.expectSymbol("("); err != nil {
+		return nil, err
+	}
+
+	var names []string
+	for {
+		name, err := p.name()
+		if err != nil {
+			return nil, err
+		}
+		names = append(names, name)
+
+		if !p.acceptSymbol(",") {
+			return names, p.expectSymbol(")")
+		}
+	}
+}
+
+// skipGroup consumes a parenthesized group when it is the next token, with the groups it contains.
+func (p *parser) skipGroup() {
+	if !p.isSymbol("(") {
+		return
+	}
+
+	depth := 0
+	for !p.done() {
+		tok := p.next()
+		if tok.kind != tokenSymbol {
+			continue
+		}
+		switch tok.text {
+		case "(":
+			depth++
+		case ")":
+			depth--
+			if depth == 0 {
+				return
+			}
+		}
+	}
+}
+
+// skipUntil consumes the tokens until one of the symbols or words outside of a group, which is not consumed.
+func (p *parser) skipUntil(stops ...string) {
+	for !p.done() {
+		tok := p.peek()
+		for _, stop := range stops {
+			if (tok.kind == tokenSymbol || tok.kind == tokenWord) && strings.EqualFold(tok.text, stop) {
+				return
+			}
+		}
+
+		if p.isSymbol("(") {
+			p.skipGroup()
+			continue
+		}
+		p.next()
+	}
+}
+
+// unexpected returns the error of an unexpected token, or the lexer error.
+func (p *parser) unexpected(expected string) error {
+	if p.err != nil {
+		return p.err
+	}
+
+	tok := p.peek()
+	if tok.kind == tokenEOF {
+		return fmt.Errorf("expected %s, got the end of the statement", expected)
+	}
+	return fmt.Errorf("expected %s at position %d, got %q", expected, tok.start, p.lexer.input[tok.start:tok.end])
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f' || c == '\v'
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isHexDigit(c byte) bool {
+	return isDigit(c) || c >= 'a' && c <= 'f' || c >= 'A' && c <= 'F'
+}
+
+// isWordStart returns whether the character can start an unquoted identifier, the multi-byte characters included.
+func isWordStart(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c == '_' || c >= 0x80
+}
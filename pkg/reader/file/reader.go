@@ -0,0 +1,309 @@
+package file
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/hellofresh/klepto/pkg/reader"
+	"github.com/hellofresh/klepto/pkg/schema"
+)
+
+type (
+	// dumpReader reads the tables of a SQL dump file. The file is scanned once for the structure of the tables
+	// and the position of their rows, the rows of a table are read from the file each time the table is read.
+	dumpReader struct {
+		file    *os.File
+		dialect string
+		// structure are the statements which do not insert rows, in file order.
+		structure []string
+		// tables are the tables defined in the file, in file order.
+		tables []*table
+		byName map[string]*table
+	}
+)
+
+// NewReader scans the dump file written in the dialect and retrieves a reader of its tables.
+func NewReader(path string, dialect string) (reader.Reader, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open dump file: %w", err)
+	}
+
+	r := &dumpReader{file: file, dialect: dialect, byName: make(map[string]*table)}
+	if err := r.scan(); err != nil {
+		file.Close()
+		return nil, fmt.Errorf("failed to scan dump file %s: %w", path, err)
+	}
+
+	return r, nil
+}
+
+// scan reads the statements of the file, the tables rows are only located.
+func (r *dumpReader) scan() error {
+	s := newScanner(r.file, r.dialect, maxStatementText)
+
+	// the consecutive INSERT statements of a table are read as a single section
+	var previous *data
+	for {
+		stmt, err := s.next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return err
+		}
+
+		previous, err = r.handle(stmt, previous)
+		if err != nil {
+			return err
+		}
+	}
+
+	r.resolve()
+
+	log.WithField("tables", len(r.tables)).Debug("scanned dump file")
+	return nil
+}
+
+// handle reads a statement of the file, it returns the data of the rows it inserts, nil when it does not insert rows.
+func (r *dumpReader) handle(stmt *statement, previous *data) (*data, error) {
+	p := &ddlParser{parser: newParser(stmt.text, r.dialect), dialect: r.dialect}
+	logger := log.WithField("statement", abbreviate(stmt.text))
+
+	switch {
+	case p.isWord(0, "INSERT", "REPLACE"):
+		t, err := r.dataTable(p.insertTable())
+		if err != nil || t == nil {
+			return nil, err
+		}
+
+		if previous != nil && len(t.data) > 0 && t.data[len(t.data)-1] == previous {
+			previous.section.size = stmt.section.offset + stmt.section.size - previous.section.offset
+			return previous, nil
+		}
+		d := &data{section: stmt.section}
+		t.data = append(t.data, d)
+		return d, nil
+	case p.acceptWords("COPY"):
+		t, err := r.dataTable(p.qualifiedName())
+		if err != nil || t == nil {
+			return nil, err
+		}
+
+		d := &data{section: stmt.copyData, copy: true}
+		if p.isSymbol("(") {
+			if d.columns, err = p.names(); err != nil {
+				return nil, fmt.Errorf("invalid COPY statement: %w", err)
+			}
+		}
+		t.data = append(t.data, d)
+		return nil, nil
+	case p.isWord(0, "LOCK", "UNLOCK") && p.isWord(1, "TABLES"):
+		return nil, nil
+	case p.isWord(0, "SELECT") && strings.Contains(strings.ToLower(stmt.text), "setval("):
+		// the sequences values are data, they are set by the dumpers after the rows are loaded
+		return nil, nil
+	case p.isCreateTable():
+		t, err := p.createTable()
+		switch {
+		case err != nil:
+			logger.WithError(err).Warn("failed to read the table definition, the table is skipped")
+		case t != nil:
+			if _, ok := r.byName[t.name]; !ok {
+				r.tables = append(r.tables, t)
+			}
+			r.byName[t.name] = t
+		}
+	case p.isCreateIndex():
+		if err := p.createIndex(r.byName); err != nil {
+			logger.WithError(err).Warn("failed to read the index definition, the index is skipped")
+		}
+	case p.isWord(0, "ALTER") && p.isWord(1, "TABLE"):
+		if err := p.alterTable(r.byName); err != nil {
+			logger.WithError(err).Warn("failed to read the table alteration, it is skipped")
+		}
+	}
+
+	r.structure = append(r.structure, stmt.text)
+	return nil, nil
+}
+
+// dataTable returns the table the rows are inserted in, nil when it is not defined in the file.
+func (r *dumpReader) dataTable(name string, err error) (*table, error) {
+	if err != nil {
+		return nil, fmt.Errorf("invalid data statement: %w", err)
+	}
+
+	t, ok := r.byName[name]
+	if !ok {
+		log.WithField("table", name).Warn("the rows of a table which is not defined in the dump file are skipped")
+	}
+
+	return t, nil
+}
+
+// resolve completes the structure once all the tables are read, the foreign keys referencing a table without
+// naming its columns reference its primary key, and the sqlite integer primary keys are aliases of the rowids.
+func (r *dumpReader) resolve() {
+	for _, t := range r.tables {
+		for _, fk := range t.foreignKeys {
+			referenced, ok := r.byName[fk.ReferencedTable]
+			if len(fk.ReferencedColumns) == 0 && ok && referenced.primaryKey != nil {
+				fk.ReferencedColumns = referenced.primaryKey.Columns
+			}
+		}
+
+		if r.dialect == schema.SQLite && t.primaryKey != nil && len(t.primaryKey.Columns) == 1 {
+			if c, ok := t.column(t.primaryKey.Columns[0]); ok && strings.EqualFold(c.Type, "integer") {
+				c.Identity = true
+			}
+		}
+	}
+}
+
+// GetStructure returns the statements of the file which do not insert rows.
+func (r *dumpReader) GetStructure() (string, error) {
+	var buf strings.Builder
+	for _, stmt := range r.structure {
+		buf.WriteString(stmt)
+		buf.WriteString(";\n")
+	}
+
+	return buf.String(), nil
+}
+
+// GetTables returns the tables defined in the file, in file order.
+func (r *dumpReader) GetTables() ([]string, error) {
+	tables := make([]string, len(r.tables))
+	for i, t := range r.tables {
+		tables[i] = t.name
+	}
+
+	return tables, nil
+}
+
+// GetColumns returns the columns of the specified table
+func (r *dumpReader) GetColumns(tableName string) ([]*reader.Column, error) {
+	t, err := r.table(tableName)
+	if err != nil {
+		return nil, err
+	}
+
+	return t.columns, nil
+}
+
+// GetPrimaryKey returns the primary key of the specified table
+func (r *dumpReader) GetPrimaryKey(tableName string) (*reader.Key, error) {
+	t, err := r.table(tableName)
+	if err != nil {
+		return nil, err
+	}
+
+	return t.primaryKey, nil
+}
+
+// GetUniqueKeys returns the unique keys of the specified table
+func (r *dumpReader) GetUniqueKeys(tableName string) ([]*reader.Key, error) {
+	t, err := r.table(tableName)
+	if err != nil {
+		return nil, err
+	}
+
+	return t.uniqueKeys, nil
+}
+
+// GetIndexes returns the non unique indexes of the specified table
+func (r *dumpReader) GetIndexes(tableName string) ([]*reader.Key, error) {
+	t, err := r.table(tableName)
+	if err != nil {
+		return nil, err
+	}
+
+	return t.indexes, nil
+}
+
+// GetForeignKeys returns the foreign keys defined on the specified table
+func (r *dumpReader) GetForeignKeys(tableName string) ([]*reader.ForeignKey, error) {
+	t, err := r.table(tableName)
+	if err != nil {
+		return nil, err
+	}
+
+	return t.foreignKeys, nil
+}
+
+// FormatColumn returns a escaped table+column string
+func (r *dumpReader) FormatColumn(tableName string, columnName string) string {
+	return r.quoteIdentifier(tableName) + "." + r.quoteIdentifier(columnName)
+}
+
+// quoteIdentifier quotes the name with the quotes of the file dialect.
+func (r *dumpReader) quoteIdentifier(name string) string {
+	if r.dialect == schema.MySQL {
+		return "`" + strings.ReplaceAll(name, "`", "``") + "`"
+	}
+
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
+
+// Close closes the dump file.
+func (r *dumpReader) Close() error {
+	if err := r.file.Close(); err != nil {
+		return fmt.Errorf("failed to close dump file: %w", err)
+	}
+
+	return nil
+}
+
+func (r *dumpReader) table(name string) (*table, error) {
+	t, ok := r.byName[name]
+	if !ok {
+		return nil, fmt.Errorf("table %s is not defined in the dump file", name)
+	}
+
+	return t, nil
+}
+
+// isCreateTable returns whether the statement is a CREATE TABLE statement.
+func (p *ddlParser) isCreateTable() bool {
+	if !p.isWord(0, "CREATE") {
+		return false
+	}
+
+	i := 1
+	for p.isWord(i, "OR", "REPLACE", "GLOBAL", "LOCAL", "TEMPORARY", "TEMP", "UNLOGGED") {
+		i++
+	}
+	return p.isWord(i, "TABLE")
+}
+
+// isCreateIndex returns whether the statement is a CREATE INDEX statement.
+func (p *ddlParser) isCreateIndex() bool {
+	return p.isWord(0, "CREATE") && (p.isWord(1, "INDEX") || p.isWord(1, "UNIQUE", "FULLTEXT", "SPATIAL") && p.isWord(2, "INDEX"))
+}
+
+// insertTable returns the table of an INSERT statement.
+func (p *ddlParser) insertTable() (string, error) {
+	p.next()
+	for p.isWord(0, "LOW_PRIORITY", "DELAYED", "HIGH_PRIORITY", "IGNORE", "OR", "ROLLBACK", "ABORT", "FAIL", "REPLACE") {
+		p.next()
+	}
+	p.acceptWords("INTO")
+
+	return p.qualifiedName()
+}
+
+// abbreviate returns the start of the statement, for the logs.
+func abbreviate(text string) string {
+	const length = 100
+	if len(text) <= length {
+		return text
+	}
+
+	return text[:length] + "..."
+}
@@ -0,0 +1,333 @@
+package file
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/hellofresh/klepto/pkg/database"
+	"github.com/hellofresh/klepto/pkg/reader"
+	"github.com/hellofresh/klepto/pkg/schema"
+)
+
+const mysqlDump = "-- MySQL dump 10.13  Distrib 8.0.36\n" +
+	"/*!40101 SET NAMES utf8mb4 */;\n" +
+	"DROP TABLE IF EXISTS `users`;\n" +
+	"CREATE TABLE `users` (\n" +
+	"  `id` int unsigned NOT NULL AUTO_INCREMENT,\n" +
+	"  `email` varchar(255) NOT NULL,\n" +
+	"  `name` varchar(100) DEFAULT 'anonymous',\n" +
+	"  `score` decimal(10,2) DEFAULT NULL,\n" +
+	"  `avatar` blob,\n" +
+	"  `created_at` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,\n" +
+	"  PRIMARY KEY (`id`),\n" +
+	"  UNIQUE KEY `email` (`email`),\n" +
+	"  KEY `name_idx` (`name`)\n" +
+	") ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;\n" +
+	"CREATE TABLE `orders` (\n" +
+	"  `id` bigint NOT NULL AUTO_INCREMENT,\n" +
+	"  `user_id` int unsigned NOT NULL,\n" +
+	"  PRIMARY KEY (`id`),\n" +
+	"  KEY `fk_user` (`user_id`),\n" +
+	"  CONSTRAINT `fk_user` FOREIGN KEY (`user_id`) REFERENCES `users` (`id`)\n" +
+	") ENGINE=InnoDB;\n" +
+	"LOCK TABLES `users` WRITE;\n" +
+	"/*!40000 ALTER TABLE `users` DISABLE KEYS */;\n" +
+	"INSERT INTO `users` VALUES (1,'a@example.com','it\\'s; me',1.50,0x0102,'2020-01-02 03:04:05'),(2,'B@example.com',NULL,NULL,NULL,'0000-00-00 00:00:00');\n" +
+	"INSERT INTO `users` VALUES (3,'c@example.com','c',-2.00,_binary 'xy','2021-06-07 08:09:10');\n" +
+	"/*!40000 ALTER TABLE `users` ENABLE KEYS */;\n" +
+	"UNLOCK TABLES;\n" +
+	"INSERT INTO `orders` (`id`, `user_id`) VALUES (10,1),(11,3),(12,1);\n" +
+	"INSERT INTO `missing` VALUES (1);\n"
+
+const postgresDump = `--
+-- PostgreSQL database dump
+--
+SET statement_timeout = 0;
+SELECT pg_catalog.set_config('search_path', '', false);
+
+CREATE TABLE public.users (
+    id integer NOT NULL,
+    email character varying(255) NOT NULL,
+    active boolean DEFAULT true NOT NULL,
+    data bytea,
+    created_at timestamp with time zone
+);
+
+CREATE SEQUENCE public.users_id_seq AS integer START WITH 1 INCREMENT BY 1 NO MINVALUE NO MAXVALUE CACHE 1;
+ALTER SEQUENCE public.users_id_seq OWNED BY public.users.id;
+ALTER TABLE ONLY public.users ALTER COLUMN id SET DEFAULT nextval('public.users_id_seq'::regclass);
+
+COPY public.users (id, email, active, data, created_at) FROM stdin;
+1	a@example.com	t	\\x0102	2020-01-02 03:04:05+02
+2	tab\there@example.com	f	\N	\N
+\.
+
+SELECT pg_catalog.setval('public.users_id_seq', 2, true);
+
+ALTER TABLE ONLY public.users
+    ADD CONSTRAINT users_pkey PRIMARY KEY (id);
+CREATE UNIQUE INDEX users_email_key ON public.users USING btree (email);
+`
+
+func newTestReader(t *testing.T, dialect string, dump string) *dumpReader {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "dump.sql")
+	require.NoError(t, os.WriteFile(path, []byte(dump), 0o600))
+
+	r, err := NewReader(path, dialect)
+	require.NoError(t, err)
+	t.Cleanup(func() { r.Close() })
+
+	return r.(*dumpReader)
+}
+
+func readRows(t *testing.T, r reader.Reader, table string, opts reader.ReadTableOpt) []database.Row {
+	t.Helper()
+
+	rowChan := make(chan database.Row)
+	errChan := make(chan error, 1)
+	go func() {
+		errChan <- r.ReadTable(context.Background(), table, rowChan, opts)
+	}()
+
+	var rows []database.Row
+	for row := range rowChan {
+		rows = append(rows, row)
+	}
+	require.NoError(t, <-errChan)
+
+	return rows
+}
+
+func TestStructure(t *testing.T) {
+	t.Parallel()
+
+	anonymous := "anonymous"
+	currentTimestamp := "CURRENT_TIMESTAMP"
+	nextval := "nextval('public.users_id_seq'::regclass)"
+	active := "true"
+
+	tests := []struct {
+		scenario    string
+		dialect     string
+		dump        string
+		tables      []string
+		columns     []*reader.Column
+		primaryKey  *reader.Key
+		uniqueKeys  []*reader.Key
+		indexes     []*reader.Key
+		foreignKeys map[string][]*reader.ForeignKey
+	}{
+		{
+			scenario: "when the dump is a mysqldump",
+			dialect:  schema.MySQL,
+			dump:     mysqlDump,
+			tables:   []string{"users", "orders"},
+			columns: []*reader.Column{
+				{Name: "id", Type: "int", Identity: true, Unsigned: true},
+				{Name: "email", Type: "varchar", MaxLength: 255},
+				{Name: "name", Type: "varchar", Nullable: true, Default: &anonymous, MaxLength: 100},
+				{Name: "score", Type: "decimal", Nullable: true, Precision: 10, Scale: 2},
+				{Name: "avatar", Type: "blob", Nullable: true},
+				{Name: "created_at", Type: "datetime", Default: &currentTimestamp},
+			},
+			primaryKey: &reader.Key{Name: "PRIMARY", Columns: []string{"id"}},
+			uniqueKeys: []*reader.Key{{Name: "email", Columns: []string{"email"}}},
+			indexes:    []*reader.Key{{Name: "name_idx", Columns: []string{"name"}}},
+			foreignKeys: map[string][]*reader.ForeignKey{
+				"orders": {{Name: "fk_user", Table: "orders", Columns: []string{"user_id"}, ReferencedTable: "users", ReferencedColumns: []string{"id"}}},
+			},
+		},
+		{
+			scenario: "when the dump is a plain pg_dump",
+			dialect:  schema.Postgres,
+			dump:     postgresDump,
+			tables:   []string{"users"},
+			columns: []*reader.Column{
+				{Name: "id", Type: "integer", Identity: true, Default: &nextval},
+				{Name: "email", Type: "character varying", MaxLength: 255},
+				{Name: "active", Type: "boolean", Default: &active},
+				{Name: "data", Type: "bytea", Nullable: true},
+				{Name: "created_at", Type: "timestamp with time zone", Nullable: true},
+			},
+			primaryKey: &reader.Key{Name: "users_pkey", Columns: []string{"id"}},
+			uniqueKeys: []*reader.Key{{Name: "users_email_key", Columns: []string{"email"}}},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.scenario, func(t *testing.T) {
+			t.Parallel()
+
+			r := newTestReader(t, test.dialect, test.dump)
+
+			tables, err := r.GetTables()
+			require.NoError(t, err)
+			assert.Equal(t, test.tables, tables)
+
+			columns, err := r.GetColumns("users")
+			require.NoError(t, err)
+			assert.Equal(t, test.columns, columns)
+
+			primaryKey, err := r.GetPrimaryKey("users")
+			require.NoError(t, err)
+			assert.Equal(t, test.primaryKey, primaryKey)
+
+			uniqueKeys, err := r.GetUniqueKeys("users")
+			require.NoError(t, err)
+			assert.Equal(t, test.uniqueKeys, uniqueKeys)
+
+			indexes, err := r.GetIndexes("users")
+			require.NoError(t, err)
+			assert.Equal(t, test.indexes, indexes)
+
+			for table, expected := range test.foreignKeys {
+				foreignKeys, err := r.GetForeignKeys(table)
+				require.NoError(t, err)
+				assert.Equal(t, expected, foreignKeys)
+			}
+
+			structure, err := r.GetStructure()
+			require.NoError(t, err)
+			assert.NotContains(t, structure, "INSERT")
+			assert.NotContains(t, structure, "setval")
+			assert.NotContains(t, structure, "LOCK TABLES")
+			assert.Contains(t, structure, "CREATE TABLE")
+
+			_, err = r.GetColumns("missing")
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestIdentityColumns(t *testing.T) {
+	t.Parallel()
+
+	r := newTestReader(t, schema.Postgres, `CREATE TABLE public.events (
+    id bigint GENERATED ALWAYS AS IDENTITY,
+    seq bigint GENERATED BY DEFAULT AS IDENTITY,
+    total bigint GENERATED ALWAYS AS (seq * 2) STORED
+);
+`)
+
+	columns, err := r.GetColumns("events")
+	require.NoError(t, err)
+	assert.Equal(t, []*reader.Column{
+		{Name: "id", Type: "bigint", Identity: true, IdentityAlways: true},
+		{Name: "seq", Type: "bigint", Identity: true},
+		{Name: "total", Type: "bigint", Nullable: true, Generated: true},
+	}, columns)
+}
+
+func TestReadTable(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		scenario string
+		dialect  string
+		dump     string
+		table    string
+		rows     []database.Row
+	}{
+		{
+			scenario: "when the rows are mysql INSERT statements",
+			dialect:  schema.MySQL,
+			dump:     mysqlDump,
+			table:    "users",
+			rows: []database.Row{
+				{"id": int64(1), "email": "a@example.com", "name": "it's; me", "score": "1.50", "avatar": []byte{1, 2}, "created_at": time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)},
+				{"id": int64(2), "email": "B@example.com", "name": nil, "score": nil, "avatar": nil, "created_at": "0000-00-00 00:00:00"},
+				{"id": int64(3), "email": "c@example.com", "name": "c", "score": "-2.00", "avatar": []byte("xy"), "created_at": time.Date(2021, 6, 7, 8, 9, 10, 0, time.UTC)},
+			},
+		},
+		{
+			scenario: "when the INSERT statements name their columns",
+			dialect:  schema.MySQL,
+			dump:     mysqlDump,
+			table:    "orders",
+			rows: []database.Row{
+				{"id": int64(10), "user_id": int64(1)},
+				{"id": int64(11), "user_id": int64(3)},
+				{"id": int64(12), "user_id": int64(1)},
+			},
+		},
+		{
+			scenario: "when the rows are postgres COPY lines",
+			dialect:  schema.Postgres,
+			dump:     postgresDump,
+			table:    "users",
+			rows: []database.Row{
+				{"id": int64(1), "email": "a@example.com", "active": true, "data": []byte{1, 2}, "created_at": time.Date(2020, 1, 2, 1, 4, 5, 0, time.UTC)},
+				{"id": int64(2), "email": "tab\there@example.com", "active": false, "data": nil, "created_at": nil},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.scenario, func(t *testing.T) {
+			t.Parallel()
+
+			r := newTestReader(t, test.dialect, test.dump)
+			assert.Equal(t, test.rows, readRows(t, r, test.table, reader.ReadTableOpt{}))
+		})
+	}
+}
+
+func TestScanner(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		scenario   string
+		dialect    string
+		dump       string
+		statements []string
+	}{
+		{
+			scenario:   "when the delimiters are quoted or commented",
+			dialect:    schema.MySQL,
+			dump:       "# comment;\nSELECT 'a;b', \"c;d\", `e;f`; -- g;\nSELECT /* h; */ 1",
+			statements: []string{"SELECT 'a;b', \"c;d\", `e;f`", "SELECT   1"},
+		},
+		{
+			scenario:   "when the delimiter is changed",
+			dialect:    schema.MySQL,
+			dump:       "DELIMITER ;;\nCREATE TRIGGER t BEFORE INSERT ON users FOR EACH ROW BEGIN SET NEW.a = 1; END ;;\nDELIMITER ;\nSELECT 1;",
+			statements: []string{"CREATE TRIGGER t BEFORE INSERT ON users FOR EACH ROW BEGIN SET NEW.a = 1; END", "SELECT 1"},
+		},
+		{
+			scenario:   "when the postgres strings are dollar quoted or escaped",
+			dialect:    schema.Postgres,
+			dump:       "\\connect klepto\nCREATE FUNCTION f() RETURNS int AS $fn$ SELECT 1; $fn$ LANGUAGE sql;\nSELECT E'it\\'s;', 'a\\';",
+			statements: []string{"CREATE FUNCTION f() RETURNS int AS $fn$ SELECT 1; $fn$ LANGUAGE sql", "SELECT E'it\\'s;', 'a\\'"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.scenario, func(t *testing.T) {
+			t.Parallel()
+
+			s := newScanner(strings.NewReader(test.dump), test.dialect, 0)
+
+			var statements []string
+			for {
+				stmt, err := s.next()
+				if errors.Is(err, io.EOF) {
+					break
+				}
+				require.NoError(t, err)
+				statements = append(statements, stmt.text)
+			}
+			assert.Equal(t, test.statements, statements)
+		})
+	}
+}
@@ -0,0 +1,353 @@
+package file
+
+import (
+	"bufio"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hellofresh/klepto/pkg/database"
+	"github.com/hellofresh/klepto/pkg/reader"
+	"github.com/hellofresh/klepto/pkg/schema"
+)
+
+// timeLayouts are the layouts of the date and time values written by the databases dumps.
+var timeLayouts = []string{
+	"2006-01-02 15:04:05.999999999Z07:00",
+	"2006-01-02 15:04:05.999999999Z07",
+	"2006-01-02T15:04:05.999999999Z07:00",
+	"2006-01-02 15:04:05.999999999",
+	"2006-01-02T15:04:05.999999999",
+	time.DateOnly,
+}
+
+// readRows reads the rows of the table from the file, in file order. Each row holds a value for every table column,
+// the values are converted to the go types returned by the databases drivers.
+func (r *dumpReader) readRows(t *table, fn func(database.Row) error) error {
+	for _, d := range t.data {
+		section := io.NewSectionReader(r.file, d.section.offset, d.section.size)
+
+		var err error
+		if d.copy {
+			err = r.copyRows(section, t, d.columns, fn)
+		} else {
+			err = r.insertRows(section, t, fn)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// insertRows reads the rows of the INSERT statements.
+func (r *dumpReader) insertRows(section io.Reader, t *table, fn func(database.Row) error) error {
+	s := newScanner(section, r.dialect, 0)
+	for {
+		stmt, err := s.next()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read the rows of %s: %w", t.name, err)
+		}
+
+		if err := r.insertStatement(stmt.text, t, fn); err != nil {
+			return fmt.Errorf("failed to read the rows of %s: %w", t.name, err)
+		}
+	}
+}
+
+// insertStatement reads the rows of an INSERT statement, the ON DUPLICATE KEY and ON CONFLICT clauses are ignored.
+func (r *dumpReader) insertStatement(text string, t *table, fn func(database.Row) error) error {
+	p := &ddlParser{parser: newParser(text, r.dialect), dialect: r.dialect}
+	if _, err := p.insertTable(); err != nil {
+		return err
+	}
+
+	columns := t.columns
+	if p.isSymbol("(") {
+		names, err := p.names()
+		if err != nil {
+			return err
+		}
+		if columns, err = t.namedColumns(names); err != nil {
+			return err
+		}
+	}
+
+	if p.acceptWords("OVERRIDING") {
+		p.next()
+		p.acceptWords("VALUE")
+	}
+	if !p.acceptWords("VALUES") && !p.acceptWords("VALUE") {
+		return p.unexpected("VALUES")
+	}
+
+	for {
+		if err := p.expectSymbol("("); err != nil {
+			return err
+		}
+
+		row := t.emptyRow()
+		for i := 0; ; i++ {
+			if i >= len(columns) {
+				return fmt.Errorf("the row has more values than the %d columns", len(columns))
+			}
+
+			v, err := p.literal()
+			if err != nil {
+				return err
+			}
+			row[columns[i].Name] = r.convert(columns[i], v)
+
+			if !p.acceptSymbol(",") {
+				break
+			}
+		}
+		if err := p.expectSymbol(")"); err != nil {
+			return err
+		}
+
+		if err := fn(row); err != nil {
+			return err
+		}
+
+		if !p.acceptSymbol(",") {
+			return nil
+		}
+	}
+}
+
+// literal reads a value of an INSERT statement, it returns nil, a string, a bool or the bytes of a blob.
+func (p *ddlParser) literal() (interface{}, error) {
+	sign := ""
+	if p.isSymbol("-") || p.isSymbol("+") {
+		sign = strings.TrimPrefix(p.next().text, "+")
+	}
+
+	var v interface{}
+	tok := p.peek()
+	switch {
+	case tok.kind == tokenNumber:
+		v = sign + tok.text
+	case sign != "":
+		return nil, p.unexpected("a number")
+	case tok.kind == tokenString:
+		v = tok.text
+	case tok.kind == tokenBlob:
+		v = []byte(tok.text)
+	case p.isWord(0, "NULL", "DEFAULT"):
+		v = nil
+	case p.isWord(0, "TRUE"):
+		v = true
+	case p.isWord(0, "FALSE"):
+		v = false
+	default:
+		return nil, p.unexpected("a literal value")
+	}
+	p.next()
+
+	// the postgres casts, e.g. `'{}'::jsonb` or `'a'::character varying(10)`
+	for p.acceptSymbol("::") {
+		if _, err := p.qualifiedName(); err != nil {
+			return nil, err
+		}
+		for p.isWord(0, typeWords...) {
+			p.next()
+		}
+		p.skipGroup()
+		for p.acceptSymbol("[") {
+			if err := p.expectSymbol("]"); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return v, nil
+}
+
+// copyRows reads the lines of a postgres COPY statement, the values are separated by tabs and escaped with backslashes.
+func (r *dumpReader) copyRows(section io.Reader, t *table, names []string, fn func(database.Row) error) error {
+	columns := t.columns
+	if names != nil {
+		var err error
+		if columns, err = t.namedColumns(names); err != nil {
+			return fmt.Errorf("failed to read the rows of %s: %w", t.name, err)
+		}
+	}
+
+	lines := bufio.NewReaderSize(section, 1024*1024)
+	for {
+		line, err := lines.ReadString('\n')
+		if errors.Is(err, io.EOF) && line == "" {
+			return nil
+		}
+		if err != nil && !errors.Is(err, io.EOF) {
+			return fmt.Errorf("failed to read the rows of %s: %w", t.name, err)
+		}
+
+		fields := strings.Split(strings.TrimSuffix(line, "\n"), "\t")
+		if len(fields) != len(columns) {
+			return fmt.Errorf("failed to read the rows of %s: the row has %d values for %d columns", t.name, len(fields), len(columns))
+		}
+
+		row := t.emptyRow()
+		for i, field := range fields {
+			if field == `\N` {
+				continue
+			}
+			row[columns[i].Name] = r.convert(columns[i], unescapeCopy(field))
+		}
+
+		if err := fn(row); err != nil {
+			return err
+		}
+	}
+}
+
+// unescapeCopy decodes the backslash escape sequences of a COPY value.
+func unescapeCopy(field string) string {
+	if !strings.Contains(field, `\`) {
+		return field
+	}
+
+	var b strings.Builder
+	for i := 0; i < len(field); i++ {
+		c := field[i]
+		if c != '\\' || i+1 == len(field) {
+			b.WriteByte(c)
+			continue
+		}
+
+		i++
+		switch c = field[i]; {
+		case c >= '0' && c <= '7':
+			end := i + 1
+			for end < len(field) && end < i+3 && field[end] >= '0' && field[end] <= '7' {
+				end++
+			}
+			n, _ := strconv.ParseUint(field[i:end], 8, 8)
+			b.WriteByte(byte(n))
+			i = end - 1
+		case c == 'x' && i+1 < len(field) && isHexDigit(field[i+1]):
+			end := i + 2
+			if end < len(field) && isHexDigit(field[end]) {
+				end++
+			}
+			n, _ := strconv.ParseUint(field[i+1:end], 16, 8)
+			b.WriteByte(byte(n))
+			i = end - 1
+		case c == 'v':
+			b.WriteByte('\v')
+		default:
+			if escaped, ok := postgresEscapes[c]; ok {
+				b.WriteString(escaped)
+			} else {
+				b.WriteByte(c)
+			}
+		}
+	}
+
+	return b.String()
+}
+
+// convert converts a value of the dump to the go type of the column kind, the values which can't be converted
+// are kept as strings.
+func (r *dumpReader) convert(c *reader.Column, v interface{}) interface{} {
+	kind := c.Kind()
+
+	var s string
+	switch v := v.(type) {
+	case nil:
+		return nil
+	case bool:
+		if kind == reader.KindBool {
+			return v
+		}
+		s = "0"
+		if v {
+			s = "1"
+		}
+	case []byte:
+		if kind == reader.KindBinary || kind == reader.KindOther {
+			return v
+		}
+		s = string(v)
+	case string:
+		s = v
+	}
+
+	switch kind {
+	case reader.KindInteger:
+		if n, err := strconv.ParseInt(s, 10, 64); err == nil {
+			return n
+		}
+	case reader.KindFloat:
+		if f, err := strconv.ParseFloat(s, 64); err == nil {
+			return f
+		}
+	case reader.KindBool:
+		switch strings.ToLower(s) {
+		case "t", "true", "y", "yes", "on", "1":
+			return true
+		case "f", "false", "n", "no", "off", "0":
+			return false
+		}
+	case reader.KindBinary:
+		// the postgres bytea values are written in hexadecimal, e.g. `\x0102`
+		if r.dialect == schema.Postgres && strings.HasPrefix(s, `\x`) {
+			if b, err := hex.DecodeString(s[2:]); err == nil {
+				return b
+			}
+		}
+		return []byte(s)
+	case reader.KindTime:
+		if t, ok := parseTime(s); ok {
+			return t
+		}
+	}
+
+	return s
+}
+
+// parseTime parses a date and time value, the times without a date, the zero dates and the infinite timestamps
+// are not parsed.
+func parseTime(s string) (time.Time, bool) {
+	for _, layout := range timeLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t.UTC(), true
+		}
+	}
+
+	return time.Time{}, false
+}
+
+// namedColumns returns the table columns with the names.
+func (t *table) namedColumns(names []string) ([]*reader.Column, error) {
+	columns := make([]*reader.Column, len(names))
+	for i, name := range names {
+		c, ok := t.column(name)
+		if !ok {
+			return nil, fmt.Errorf("unknown column %s", name)
+		}
+		columns[i] = c
+	}
+
+	return columns, nil
+}
+
+// emptyRow returns a row with a null value for every column.
+func (t *table) emptyRow() database.Row {
+	row := make(database.Row, len(t.columns))
+	for _, c := range t.columns {
+		row[c.Name] = nil
+	}
+
+	return row
+}
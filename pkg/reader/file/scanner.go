@@ -0,0 +1,350 @@
+package file
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/hellofresh/klepto/pkg/schema"
+)
+
+// maxStatementText is the length of the INSERT statements text kept by the scanner, their rows are read again
+// from the file when their table is read, only their start is needed to find their table.
+const maxStatementText = 64 * 1024
+
+type (
+	// section is a part of the dump file.
+	section struct {
+		offset int64
+		size   int64
+	}
+
+	// statement is a statement of the dump file.
+	statement struct {
+		// text is the statement, without its delimiter and comments, the INSERT statements are truncated to the scanner limit.
+		text string
+		// section is the position of the statement in the file.
+		section section
+		// copyData is the section of the rows following a `COPY ... FROM stdin` statement.
+		copyData section
+	}
+
+	// scanner splits a dump file in statements, it only keeps track of the quotes and comments so the
+	// delimiters inside them are skipped.
+	scanner struct {
+		r         *bufio.Reader
+		dialect   string
+		offset    int64
+		delimiter string
+		text      bytes.Buffer
+		// limit is the length of the INSERT statements text kept, 0 keeps the whole text.
+		limit     int
+		truncated bool
+		// recent are the last characters read.
+		recent [3]byte
+	}
+)
+
+func newScanner(r io.Reader, dialect string, limit int) *scanner {
+	return &scanner{r: bufio.NewReaderSize(r, 1024*1024), dialect: dialect, delimiter: ";", limit: limit}
+}
+
+// next returns the next statement, io.EOF when the file has no more statements.
+func (s *scanner) next() (*statement, error) {
+	s.text.Reset()
+	s.truncated = false
+
+	start := int64(-1)
+	for {
+		c, err := s.readByte()
+		if errors.Is(err, io.EOF) {
+			if start < 0 || strings.TrimSpace(s.text.String()) == "" {
+				return nil, io.EOF
+			}
+			return s.statement(start, s.offset)
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if isSpace(c) {
+			if start >= 0 {
+				s.write(c)
+			}
+			continue
+		}
+
+		comment, err := s.comment(c)
+		if err != nil {
+			return nil, err
+		}
+		if comment {
+			if start >= 0 {
+				s.write(' ')
+			}
+			continue
+		}
+
+		if start < 0 {
+			start = s.offset - 1
+			// psql meta-commands, e.g. `\connect`, and the mysql client DELIMITER command are not statements
+			if c == '\\' || s.dialect == schema.MySQL && (c == 'd' || c == 'D') && s.isDelimiterCommand() {
+				line, err := s.readLine()
+				if err != nil && !errors.Is(err, io.EOF) {
+					return nil, err
+				}
+				if c != '\\' {
+					s.delimiter = strings.TrimSpace(string(line[len("elimiter"):]))
+				}
+				start = -1
+				continue
+			}
+		}
+
+		if c == s.delimiter[0] {
+			end := s.offset - 1
+			if s.acceptDelimiter() {
+				return s.statement(start, end)
+			}
+		}
+
+		s.write(c)
+		if err := s.quote(c); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// statement builds the statement read from the start offset, and reads the rows following it if it is a COPY statement.
+func (s *scanner) statement(start int64, end int64) (*statement, error) {
+	stmt := &statement{text: strings.TrimSpace(s.text.String()), section: section{offset: start, size: end - start}}
+
+	fields := strings.Fields(strings.ToLower(stmt.text))
+	if len(fields) < 3 || fields[0] != "copy" || fields[len(fields)-2] != "from" || fields[len(fields)-1] != "stdin" {
+		return stmt, nil
+	}
+
+	// the rows start on the line following the statement and end with a `\.` line
+	if _, err := s.readLine(); err != nil {
+		return nil, fmt.Errorf("failed to read the rows of %q: %w", stmt.text, err)
+	}
+	stmt.copyData.offset = s.offset
+	for {
+		lineStart := s.offset
+		line, err := s.readLine()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read the rows of %q: %w", stmt.text, err)
+		}
+		if string(bytes.TrimRight(line, "\r\n")) == `\.` {
+			stmt.copyData.size = lineStart - stmt.copyData.offset
+			return stmt, nil
+		}
+	}
+}
+
+// comment skips the comment starting with the character, the mysql conditional comments, e.g. `/*!40101 SET NAMES utf8 */`,
+// are executed by mysql and are kept.
+func (s *scanner) comment(c byte) (bool, error) {
+	next, _ := s.r.Peek(1)
+	switch {
+	case c == '-' && bytes.Equal(next, []byte("-")) || c == '#' && s.dialect == schema.MySQL:
+		_, err := s.readLine()
+		if errors.Is(err, io.EOF) {
+			err = nil
+		}
+		return true, err
+	case c == '/' && bytes.Equal(next, []byte("*")):
+		if conditional, _ := s.r.Peek(2); s.dialect == schema.MySQL && string(conditional) == "*!" {
+			return false, nil
+		}
+
+		var previous byte
+		for {
+			c, err := s.readByte()
+			if err != nil {
+				return true, fmt.Errorf("unterminated comment: %w", err)
+			}
+			if previous == '*' && c == '/' {
+				return true, nil
+			}
+			previous = c
+		}
+	}
+
+	return false, nil
+}
+
+// quote copies the quoted string or identifier starting with the character.
+func (s *scanner) quote(c byte) error {
+	switch {
+	case c == '\'' || c == '"' || c == '`':
+		// mysql escapes the quotes with backslashes in its strings, postgres in its escape strings, e.g. `E'it\'s'`
+		backslash := s.dialect == schema.MySQL && c != '`' ||
+			s.dialect == schema.Postgres && c == '\'' && s.isEscapeString()
+		return s.copyQuoted(c, backslash)
+	case c == '$' && s.dialect == schema.Postgres:
+		return s.copyDollarQuoted()
+	case c == '/' && s.dialect == schema.MySQL:
+		// the mysql conditional comments are part of the statement
+		if next, _ := s.r.Peek(1); string(next) != "*" {
+			return nil
+		}
+		var previous byte
+		for {
+			c, err := s.readByte()
+			if err != nil {
+				return fmt.Errorf("unterminated comment: %w", err)
+			}
+			s.write(c)
+			if previous == '*' && c == '/' {
+				return nil
+			}
+			previous = c
+		}
+	}
+
+	return nil
+}
+
+func (s *scanner) copyQuoted(quote byte, backslash bool) error {
+	for {
+		c, err := s.readByte()
+		if err != nil {
+			return fmt.Errorf("unterminated quote: %w", err)
+		}
+		s.write(c)
+
+		switch {
+		case c == '\\' && backslash:
+			escaped, err := s.readByte()
+			if err != nil {
+				return fmt.Errorf("unterminated quote: %w", err)
+			}
+			s.write(escaped)
+		case c == quote:
+			// a doubled quote is an escaped quote
+			if next, _ := s.r.Peek(1); len(next) == 0 || next[0] != quote {
+				return nil
+			}
+			c, _ = s.readByte()
+			s.write(c)
+		}
+	}
+}
+
+// copyDollarQuoted copies a postgres dollar quoted string, e.g. `$$body$$` or `$fn$body$fn$`.
+func (s *scanner) copyDollarQuoted() error {
+	var tag []byte
+	for i := 1; ; i++ {
+		next, err := s.r.Peek(i)
+		if err != nil || len(next) < i {
+			return nil
+		}
+		c := next[i-1]
+		if c == '$' {
+			tag = append([]byte("$"), next...)
+			break
+		}
+		if !isWordStart(c) && !(i > 1 && isDigit(c)) {
+			return nil
+		}
+	}
+
+	for range len(tag) - 1 {
+		c, _ := s.readByte()
+		s.write(c)
+	}
+
+	var body []byte
+	for {
+		c, err := s.readByte()
+		if err != nil {
+			return fmt.Errorf("unterminated dollar quote: %w", err)
+		}
+		s.write(c)
+
+		body = append(body, c)
+		if bytes.HasSuffix(body, tag) {
+			return nil
+		}
+		if len(body) > len(tag) {
+			body = body[1:]
+		}
+	}
+}
+
+// isEscapeString returns whether the quote just read starts a postgres escape string, e.g. `E'it\'s'`.
+func (s *scanner) isEscapeString() bool {
+	return (s.recent[1] == 'E' || s.recent[1] == 'e') && !isWordStart(s.recent[0]) && !isDigit(s.recent[0])
+}
+
+// isDelimiterCommand returns whether the `d` just read starts a mysql DELIMITER command.
+func (s *scanner) isDelimiterCommand() bool {
+	next, _ := s.r.Peek(len("elimiter "))
+	return len(next) == len("elimiter ") && strings.EqualFold(string(next[:len("elimiter")]), "elimiter") &&
+		(next[len("elimiter")] == ' ' || next[len("elimiter")] == '\t')
+}
+
+// acceptDelimiter consumes the rest of the delimiter which first character was just read.
+func (s *scanner) acceptDelimiter() bool {
+	if len(s.delimiter) == 1 {
+		return true
+	}
+
+	next, _ := s.r.Peek(len(s.delimiter) - 1)
+	if string(next) != s.delimiter[1:] {
+		return false
+	}
+
+	for range len(next) {
+		_, _ = s.readByte()
+	}
+	return true
+}
+
+func (s *scanner) readByte() (byte, error) {
+	c, err := s.r.ReadByte()
+	if err == nil {
+		s.offset++
+		s.recent = [3]byte{s.recent[1], s.recent[2], c}
+	}
+
+	return c, err
+}
+
+// readLine reads until the end of the line, the line feed included.
+func (s *scanner) readLine() ([]byte, error) {
+	var line []byte
+	for {
+		chunk, err := s.r.ReadSlice('\n')
+		s.offset += int64(len(chunk))
+		line = append(line, chunk...)
+		if errors.Is(err, bufio.ErrBufferFull) {
+			continue
+		}
+
+		return line, err
+	}
+}
+
+// write keeps the character in the statement text, the text of the long INSERT statements is truncated.
+func (s *scanner) write(c byte) {
+	if s.truncated {
+		return
+	}
+	if s.limit > 0 && s.text.Len() >= s.limit && isInsert(s.text.String()) {
+		s.truncated = true
+		return
+	}
+
+	s.text.WriteByte(c)
+}
+
+// isInsert returns whether the statement inserts rows.
+func isInsert(text string) bool {
+	word, _, _ := strings.Cut(strings.TrimLeft(text, "( \t\r\n"), " ")
+	return strings.EqualFold(word, "insert") || strings.EqualFold(word, "replace")
+}
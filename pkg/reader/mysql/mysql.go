@@ -6,6 +6,7 @@ import (
 	"github.com/go-sql-driver/mysql"
 
 	"github.com/hellofresh/klepto/pkg/reader"
+	"github.com/hellofresh/klepto/pkg/reader/engine"
 )
 
 type driver struct{}
@@ -31,7 +32,7 @@ func (m *driver) NewConnection(opts reader.ConnOpts) (reader.Reader, error) {
 	conn.SetMaxIdleConns(opts.MaxIdleConns)
 	conn.SetConnMaxLifetime(opts.MaxConnLifetime)
 
-	return NewStorage(conn, opts.Timeout), nil
+	return NewStorage(conn, engine.NewOpts(opts)), nil
 }
 
 func init() {
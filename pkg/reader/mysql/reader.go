@@ -2,11 +2,13 @@ package mysql
 
 import (
 	"bytes"
+	"context"
 	"database/sql"
 	"fmt"
 	"strings"
 	"time"
 
+	sq "github.com/Masterminds/squirrel"
 	log "github.com/sirupsen/logrus"
 
 	"github.com/hellofresh/klepto/pkg/reader"
@@ -14,7 +16,8 @@ import (
 )
 
 const (
-	baseTable = "BASE TABLE"
+	baseTable  = "BASE TABLE"
+	primaryKey = "PRIMARY"
 )
 
 type (
@@ -24,10 +27,10 @@ type (
 )
 
 // NewStorage creates a new mysql reader.
-func NewStorage(conn *sql.DB, timeout time.Duration) reader.Reader {
+func NewStorage(conn *sql.DB, opts engine.Opts) reader.Reader {
 	return engine.New(&storage{
 		conn: conn,
-	}, timeout)
+	}, opts)
 }
 
 // GetTables gets a list of all tables in the database.
@@ -57,9 +60,12 @@ func (s *storage) GetTables() ([]string, error) {
 }
 
 // GetColumns returns the columns in the specified database table
-func (s *storage) GetColumns(tableName string) ([]string, error) {
+func (s *storage) GetColumns(tableName string) ([]*reader.Column, error) {
 	rows, err := s.conn.Query(
-		"SELECT `column_name` FROM `information_schema`.`columns` WHERE table_schema=DATABASE() AND table_name=?",
+		"SELECT `column_name`, `data_type`, `column_type`, `is_nullable`, `column_default`, `extra`, "+
+			"`character_maximum_length`, `numeric_precision`, `numeric_scale` "+
+			"FROM `information_schema`.`columns` WHERE table_schema=DATABASE() AND table_name=? "+
+			"ORDER BY `ordinal_position`",
 		tableName,
 	)
 	if err != nil {
@@ -67,17 +73,191 @@ func (s *storage) GetColumns(tableName string) ([]string, error) {
 	}
 	defer rows.Close()
 
-	var columns []string
+	var columns []*reader.Column
 	for rows.Next() {
-		var column string
-		if err := rows.Scan(&column); err != nil {
+		var (
+			column                         reader.Column
+			columnType, nullable, extra    string
+			columnDefault                  sql.NullString
+			characterMaxLength             sql.NullInt64
+			numericPrecision, numericScale sql.NullInt64
+		)
+		err := rows.Scan(
+			&column.Name, &column.Type, &columnType, &nullable, &columnDefault, &extra,
+			&characterMaxLength, &numericPrecision, &numericScale,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		column.Nullable = nullable == "YES"
+		if columnDefault.Valid {
+			column.Default = &columnDefault.String
+		}
+		extra = strings.ToUpper(extra)
+		column.Generated = strings.Contains(extra, "VIRTUAL GENERATED") || strings.Contains(extra, "STORED GENERATED")
+		column.Identity = strings.Contains(extra, "AUTO_INCREMENT")
+		column.MaxLength = characterMaxLength.Int64
+		if column.Kind() == reader.KindDecimal {
+			column.Precision, column.Scale = numericPrecision.Int64, numericScale.Int64
+		}
+		column.Unsigned = strings.Contains(strings.ToLower(columnType), "unsigned")
+
+		columns = append(columns, &column)
+	}
+
+	return columns, rows.Err()
+}
+
+// GetPrimaryKey returns the primary key of the specified database table
+func (s *storage) GetPrimaryKey(tableName string) (*reader.Key, error) {
+	keys, err := s.getUniqueIndexes(tableName)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, key := range keys {
+		if key.Name == primaryKey {
+			return key, nil
+		}
+	}
+
+	return nil, nil
+}
+
+// GetUniqueKeys returns the unique keys, other than the primary key, of the specified database table
+func (s *storage) GetUniqueKeys(tableName string) ([]*reader.Key, error) {
+	keys, err := s.getUniqueIndexes(tableName)
+	if err != nil {
+		return nil, err
+	}
+
+	var uniqueKeys []*reader.Key
+	for _, key := range keys {
+		if key.Name != primaryKey {
+			uniqueKeys = append(uniqueKeys, key)
+		}
+	}
+
+	return uniqueKeys, nil
+}
+
+// getUniqueIndexes returns the unique indexes, including the primary key, of the specified database table
+func (s *storage) getUniqueIndexes(tableName string) ([]*reader.Key, error) {
+	rows, err := s.conn.Query(
+		"SELECT `index_name`, `column_name` FROM `information_schema`.`statistics` "+
+			"WHERE table_schema=DATABASE() AND table_name=? AND non_unique=0 "+
+			"ORDER BY `index_name`, `seq_in_index`",
+		tableName,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanKeys(rows)
+}
+
+// scanKeys groups the index name and column rows in keys, the indexes with functional key parts are left out
+func scanKeys(rows *sql.Rows) ([]*reader.Key, error) {
+	var (
+		keys        []*reader.Key
+		expressions = make(map[string]bool)
+	)
+	for rows.Next() {
+		var (
+			name   string
+			column sql.NullString
+		)
+		if err := rows.Scan(&name, &column); err != nil {
 			return nil, err
 		}
 
-		columns = append(columns, column)
+		// functional key parts have no column, such indexes can't be used as a row key
+		if !column.Valid {
+			expressions[name] = true
+			continue
+		}
+
+		if len(keys) == 0 || keys[len(keys)-1].Name != name {
+			keys = append(keys, &reader.Key{Name: name})
+		}
+
+		key := keys[len(keys)-1]
+		key.Columns = append(key.Columns, column.String)
+	}
+
+	var indexes []*reader.Key
+	for _, key := range keys {
+		if !expressions[key.Name] {
+			indexes = append(indexes, key)
+		}
 	}
 
-	return columns, nil
+	return indexes, rows.Err()
+}
+
+// GetIndexes returns the non unique indexes of the specified database table, the indexes on expressions are left out
+func (s *storage) GetIndexes(tableName string) ([]*reader.Key, error) {
+	rows, err := s.conn.Query(
+		"SELECT `index_name`, `column_name` FROM `information_schema`.`statistics` "+
+			"WHERE table_schema=DATABASE() AND table_name=? AND non_unique=1 AND index_type NOT IN ('FULLTEXT', 'SPATIAL') "+
+			"ORDER BY `index_name`, `seq_in_index`",
+		tableName,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanKeys(rows)
+}
+
+// EstimateRows returns the number of rows of the specified database table estimated by the storage engine
+func (s *storage) EstimateRows(tableName string) (int64, error) {
+	var rows sql.NullInt64
+	err := s.conn.QueryRow(
+		"SELECT `table_rows` FROM `information_schema`.`tables` WHERE table_schema=DATABASE() AND table_name=?",
+		tableName,
+	).Scan(&rows)
+	if err != nil {
+		return 0, err
+	}
+
+	return rows.Int64, nil
+}
+
+// GetForeignKeys returns the foreign keys defined on the specified database table
+func (s *storage) GetForeignKeys(tableName string) ([]*reader.ForeignKey, error) {
+	rows, err := s.conn.Query(
+		"SELECT `constraint_name`, `column_name`, `referenced_table_name`, `referenced_column_name` "+
+			"FROM `information_schema`.`key_column_usage` "+
+			"WHERE table_schema=DATABASE() AND table_name=? AND referenced_table_schema=DATABASE() "+
+			"ORDER BY `constraint_name`, `ordinal_position`",
+		tableName,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var foreignKeys []*reader.ForeignKey
+	for rows.Next() {
+		var name, column, referencedTable, referencedColumn string
+		if err := rows.Scan(&name, &column, &referencedTable, &referencedColumn); err != nil {
+			return nil, err
+		}
+
+		if len(foreignKeys) == 0 || foreignKeys[len(foreignKeys)-1].Name != name {
+			foreignKeys = append(foreignKeys, &reader.ForeignKey{Name: name, Table: tableName, ReferencedTable: referencedTable})
+		}
+
+		fk := foreignKeys[len(foreignKeys)-1]
+		fk.Columns = append(fk.Columns, column)
+		fk.ReferencedColumns = append(fk.ReferencedColumns, referencedColumn)
+	}
+
+	return foreignKeys, rows.Err()
 }
 
 // GetStructure dumps the mysql database structure.
@@ -115,6 +295,63 @@ func (s *storage) QuoteIdentifier(name string) string {
 	return fmt.Sprintf("`%s`", strings.Replace(name, "`", "``", -1))
 }
 
+// PlaceholderFormat returns the mysql query placeholder format.
+func (s *storage) PlaceholderFormat() sq.PlaceholderFormat { return sq.Question }
+
+// Snapshot opens the given number of connections in transactions with a consistent snapshot.
+// The tables are locked while the transactions start, so all of them see the same state of the database.
+// When the tables can not be locked, a single connection is opened so all the tables are still read from one snapshot.
+func (s *storage) Snapshot(ctx context.Context, n int) ([]*sql.Conn, error) {
+	lock, err := s.conn.Conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer lock.Close()
+
+	// FLUSH TABLES WITH READ LOCK requires the RELOAD privilege, which managed databases (e.g. AWS RDS) do not grant
+	if _, err := lock.ExecContext(ctx, "FLUSH TABLES WITH READ LOCK"); err != nil {
+		log.WithError(err).Warn("failed to lock tables, all the tables are read through a single snapshot connection")
+		n = 1
+	} else {
+		defer lock.ExecContext(ctx, "UNLOCK TABLES")
+	}
+
+	conns := make([]*sql.Conn, 0, n)
+	for i := 0; i < n; i++ {
+		conn, err := s.startSnapshot(ctx)
+		if err != nil {
+			for _, c := range conns {
+				c.Close()
+			}
+			return nil, err
+		}
+
+		conns = append(conns, conn)
+	}
+
+	return conns, nil
+}
+
+// startSnapshot opens a connection in a read only transaction with a consistent snapshot.
+func (s *storage) startSnapshot(ctx context.Context) (*sql.Conn, error) {
+	conn, err := s.conn.Conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := conn.ExecContext(ctx, "SET SESSION TRANSACTION ISOLATION LEVEL REPEATABLE READ"); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to set isolation level: %w", err)
+	}
+
+	if _, err := conn.ExecContext(ctx, "START TRANSACTION WITH CONSISTENT SNAPSHOT, READ ONLY"); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to start transaction: %w", err)
+	}
+
+	return conn, nil
+}
+
 // Close closes the mysql database connection.
 func (s *storage) Close() error {
 	err := s.conn.Close()
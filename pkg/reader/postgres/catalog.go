@@ -0,0 +1,628 @@
+package postgres
+
+import (
+	"database/sql"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/lib/pq"
+	log "github.com/sirupsen/logrus"
+)
+
+// notExtensionMember is the condition excluding the objects created by an extension, they are created with it.
+const notExtensionMember = `NOT EXISTS (SELECT 1 FROM pg_catalog.pg_depend d WHERE d.objid = %s AND d.deptype = 'e')`
+
+type (
+	// Catalog generates the structure from the postgres catalog, it does not need the pg_dump binary.
+	// It covers the schemas, extensions, enums, sequences, tables, views, constraints and indexes,
+	// the functions, triggers and privileges are not generated.
+	Catalog struct {
+		conn *sql.DB
+		// namespace is the condition selecting the schemas of the structure, the system schemas are excluded.
+		namespace string
+	}
+
+	catalogTable struct {
+		oid     int64
+		schema  string
+		name    string
+		columns []*catalogColumn
+		// partitionKey is the partition key of a partitioned table, e.g. `RANGE (created_at)`.
+		partitionKey string
+		// parentSchema and parent are the partitioned table of a partition.
+		parentSchema string
+		parent       string
+		// bound is the partition bound of a partition, e.g. `FOR VALUES IN ('de')`.
+		bound string
+	}
+
+	catalogColumn struct {
+		name      string
+		dataType  string
+		collation string
+		notNull   bool
+		// expression is the default or the generation expression of the column.
+		expression string
+		// identity is `a` for the identities generated always and `d` for the identities generated by default.
+		identity string
+		// generated is `s` for the stored generated columns.
+		generated string
+	}
+
+	catalogSequence struct {
+		schema    string
+		name      string
+		dataType  string
+		start     int64
+		increment int64
+		min       int64
+		max       int64
+		cache     int64
+		cycle     bool
+	}
+
+	catalogView struct {
+		oid       int64
+		statement string
+		// uses are the oids of the views and materialized views the view reads from.
+		uses []int64
+	}
+
+	catalogConstraint struct {
+		schema      string
+		table       string
+		partitioned bool
+		name        string
+		definition  string
+	}
+)
+
+// NewCatalog creates a new Catalog generating the structure of the included schemas, all the schemas when none is
+// included, other than the excluded ones.
+func NewCatalog(conn *sql.DB, include, exclude []string) *Catalog {
+	return &Catalog{
+		conn:      conn,
+		namespace: schemaFilter{include: include, exclude: exclude}.condition("ns.nspname"),
+	}
+}
+
+// GetStructure generates the statements creating the database structure, in dependency order.
+func (c *Catalog) GetStructure() (string, error) {
+	var version string
+	if err := c.conn.QueryRow("SHOW server_version_num").Scan(&version); err != nil {
+		return "", fmt.Errorf("failed to read the server version: %w", err)
+	}
+	serverVersion, err := strconv.Atoi(version)
+	if err != nil {
+		return "", fmt.Errorf("failed to read the server version: %w", err)
+	}
+
+	log.Debug("generating the structure from the catalog")
+
+	var statements []string
+	steps := []struct {
+		name string
+		read func(int) ([]string, error)
+	}{
+		{name: "schemas", read: c.schemas},
+		{name: "extensions", read: c.extensions},
+		{name: "enums", read: c.enums},
+		{name: "sequences", read: c.sequences},
+		{name: "tables", read: c.tables},
+		{name: "sequence owners", read: c.sequenceOwners},
+		{name: "constraints", read: func(int) ([]string, error) { return c.constraints(false) }},
+		{name: "views", read: c.views},
+		{name: "indexes", read: c.indexes},
+		{name: "foreign keys", read: func(int) ([]string, error) { return c.constraints(true) }},
+	}
+	for _, step := range steps {
+		stmts, err := step.read(serverVersion)
+		if err != nil {
+			return "", fmt.Errorf("failed to read the %s: %w", step.name, err)
+		}
+		statements = append(statements, stmts...)
+	}
+
+	var buf strings.Builder
+	for _, stmt := range statements {
+		buf.WriteString(stmt)
+		buf.WriteString(";\n\n")
+	}
+
+	return buf.String(), nil
+}
+
+// schemas creates the schemas other than public.
+func (c *Catalog) schemas(int) ([]string, error) {
+	return c.queryStatements(
+		`SELECT 'CREATE SCHEMA IF NOT EXISTS ' || quote_ident(ns.nspname)
+		 FROM pg_catalog.pg_namespace ns
+		 WHERE ns.nspname <> 'public' AND ` + c.namespace + ` AND ` + fmt.Sprintf(notExtensionMember, "ns.oid") + `
+		 ORDER BY ns.nspname`,
+	)
+}
+
+// extensions creates the extensions of the selected schemas and the ones installed in pg_catalog,
+// plpgsql is installed by default.
+func (c *Catalog) extensions(int) ([]string, error) {
+	return c.queryStatements(
+		`SELECT 'CREATE EXTENSION IF NOT EXISTS ' || quote_ident(e.extname) || ' WITH SCHEMA ' || quote_ident(ns.nspname)
+		 FROM pg_catalog.pg_extension e
+		 JOIN pg_catalog.pg_namespace ns ON ns.oid = e.extnamespace
+		 WHERE e.extname <> 'plpgsql' AND (ns.nspname = 'pg_catalog' OR ` + c.namespace + `)
+		 ORDER BY e.extname`,
+	)
+}
+
+// enums creates the enum types.
+func (c *Catalog) enums(int) ([]string, error) {
+	rows, err := c.conn.Query(
+		`SELECT ns.nspname, t.typname, e.enumlabel
+		 FROM pg_catalog.pg_type t
+		 JOIN pg_catalog.pg_namespace ns ON ns.oid = t.typnamespace
+		 JOIN pg_catalog.pg_enum e ON e.enumtypid = t.oid
+		 WHERE ` + c.namespace + ` AND ` + fmt.Sprintf(notExtensionMember, "t.oid") + `
+		 ORDER BY ns.nspname, t.typname, e.enumsortorder`,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var (
+		statements []string
+		name       string
+		labels     []string
+	)
+	for rows.Next() {
+		var schema, typeName, label string
+		if err := rows.Scan(&schema, &typeName, &label); err != nil {
+			return nil, err
+		}
+
+		if qualified := qualifiedName(schema, typeName); qualified != name {
+			if name != "" {
+				statements = append(statements, renderEnum(name, labels))
+			}
+			name, labels = qualified, nil
+		}
+		labels = append(labels, label)
+	}
+	if name != "" {
+		statements = append(statements, renderEnum(name, labels))
+	}
+
+	return statements, rows.Err()
+}
+
+// sequences creates the sequences which are not identities, the pg_sequence catalog was added in postgres 10.
+func (c *Catalog) sequences(version int) ([]string, error) {
+	query := `SELECT ns.nspname, cl.relname, format_type(s.seqtypid, NULL), s.seqstart, s.seqincrement, s.seqmin, s.seqmax, s.seqcache, s.seqcycle
+		 FROM pg_catalog.pg_sequence s
+		 JOIN pg_catalog.pg_class cl ON cl.oid = s.seqrelid
+		 JOIN pg_catalog.pg_namespace ns ON ns.oid = cl.relnamespace
+		 WHERE ` + c.namespace + `
+		 AND NOT EXISTS (SELECT 1 FROM pg_catalog.pg_depend d WHERE d.objid = cl.oid AND d.deptype IN ('i', 'e'))
+		 ORDER BY ns.nspname, cl.relname`
+	if version < 100000 {
+		query = `SELECT sequence_schema, sequence_name, '', start_value::bigint, increment::bigint, minimum_value::bigint, maximum_value::bigint, 1, cycle_option = 'YES'
+			 FROM information_schema.sequences seq
+			 JOIN pg_catalog.pg_namespace ns ON ns.nspname = seq.sequence_schema
+			 WHERE ` + c.namespace + `
+			 ORDER BY sequence_schema, sequence_name`
+	}
+
+	rows, err := c.conn.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var statements []string
+	for rows.Next() {
+		var s catalogSequence
+		if err := rows.Scan(&s.schema, &s.name, &s.dataType, &s.start, &s.increment, &s.min, &s.max, &s.cache, &s.cycle); err != nil {
+			return nil, err
+		}
+		statements = append(statements, renderSequence(&s))
+	}
+
+	return statements, rows.Err()
+}
+
+// tables creates the tables, the partitioned tables before their partitions. The partitions were added in postgres 10,
+// the identity columns in postgres 10 and the generated columns in postgres 12.
+func (c *Catalog) tables(version int) ([]string, error) {
+	partitionKey, parentSchema, parent, bound := "NULL", "NULL", "NULL", "NULL"
+	partitionJoin := ""
+	if version >= 100000 {
+		partitionKey = "CASE WHEN cl.relkind = 'p' THEN pg_catalog.pg_get_partkeydef(cl.oid) END"
+		parentSchema, parent, bound = "pns.nspname", "pcl.relname", "pg_catalog.pg_get_expr(cl.relpartbound, cl.oid)"
+		partitionJoin = `LEFT JOIN pg_catalog.pg_inherits i ON cl.relispartition AND i.inhrelid = cl.oid
+			 LEFT JOIN pg_catalog.pg_class pcl ON pcl.oid = i.inhparent
+			 LEFT JOIN pg_catalog.pg_namespace pns ON pns.oid = pcl.relnamespace`
+	}
+
+	rows, err := c.conn.Query(fmt.Sprintf(
+		`SELECT cl.oid, ns.nspname, cl.relname, %s, %s, %s, %s
+		 FROM pg_catalog.pg_class cl
+		 JOIN pg_catalog.pg_namespace ns ON ns.oid = cl.relnamespace
+		 %s
+		 WHERE cl.relkind IN ('r', 'p') AND %s AND `+fmt.Sprintf(notExtensionMember, "cl.oid")+`
+		 ORDER BY ns.nspname, cl.relname`,
+		partitionKey, parentSchema, parent, bound, partitionJoin, c.namespace,
+	))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tables []*catalogTable
+	byOID := make(map[int64]*catalogTable)
+	for rows.Next() {
+		var (
+			t                                  catalogTable
+			partitionKey, parentSchema, parent sql.NullString
+			bound                              sql.NullString
+		)
+		if err := rows.Scan(&t.oid, &t.schema, &t.name, &partitionKey, &parentSchema, &parent, &bound); err != nil {
+			return nil, err
+		}
+		t.partitionKey, t.parentSchema, t.parent, t.bound = partitionKey.String, parentSchema.String, parent.String, bound.String
+
+		tables = append(tables, &t)
+		byOID[t.oid] = &t
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if err := c.columns(version, byOID); err != nil {
+		return nil, err
+	}
+
+	return renderTables(tables), nil
+}
+
+// columns reads the columns of the tables.
+func (c *Catalog) columns(version int, tables map[int64]*catalogTable) error {
+	identity, generated := "''", "''"
+	if version >= 100000 {
+		identity = "a.attidentity"
+	}
+	if version >= 120000 {
+		generated = "a.attgenerated"
+	}
+
+	rows, err := c.conn.Query(fmt.Sprintf(
+		`SELECT a.attrelid, a.attname, pg_catalog.format_type(a.atttypid, a.atttypmod), a.attnotnull,
+		 pg_catalog.pg_get_expr(ad.adbin, ad.adrelid), %s, %s,
+		 CASE WHEN a.attcollation <> t.typcollation THEN quote_ident(cns.nspname) || '.' || quote_ident(co.collname) END
+		 FROM pg_catalog.pg_attribute a
+		 JOIN pg_catalog.pg_class cl ON cl.oid = a.attrelid
+		 JOIN pg_catalog.pg_namespace ns ON ns.oid = cl.relnamespace
+		 JOIN pg_catalog.pg_type t ON t.oid = a.atttypid
+		 LEFT JOIN pg_catalog.pg_attrdef ad ON ad.adrelid = a.attrelid AND ad.adnum = a.attnum
+		 LEFT JOIN pg_catalog.pg_collation co ON co.oid = a.attcollation
+		 LEFT JOIN pg_catalog.pg_namespace cns ON cns.oid = co.collnamespace
+		 WHERE cl.relkind IN ('r', 'p') AND a.attnum > 0 AND NOT a.attisdropped AND %s
+		 ORDER BY a.attrelid, a.attnum`,
+		identity, generated, c.namespace,
+	))
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			oid                   int64
+			column                catalogColumn
+			expression, collation sql.NullString
+		)
+		err := rows.Scan(
+			&oid, &column.name, &column.dataType, &column.notNull,
+			&expression, &column.identity, &column.generated, &collation,
+		)
+		if err != nil {
+			return err
+		}
+		column.expression, column.collation = expression.String, collation.String
+
+		if t, ok := tables[oid]; ok {
+			t.columns = append(t.columns, &column)
+		}
+	}
+
+	return rows.Err()
+}
+
+// sequenceOwners links the sequences to the columns they belong to, e.g. the serial columns sequences.
+func (c *Catalog) sequenceOwners(int) ([]string, error) {
+	return c.queryStatements(
+		`SELECT 'ALTER SEQUENCE ' || quote_ident(ns.nspname) || '.' || quote_ident(s.relname) ||
+		 ' OWNED BY ' || quote_ident(tns.nspname) || '.' || quote_ident(t.relname) || '.' || quote_ident(a.attname)
+		 FROM pg_catalog.pg_depend d
+		 JOIN pg_catalog.pg_class s ON s.oid = d.objid AND s.relkind = 'S'
+		 JOIN pg_catalog.pg_namespace ns ON ns.oid = s.relnamespace
+		 JOIN pg_catalog.pg_class t ON t.oid = d.refobjid
+		 JOIN pg_catalog.pg_namespace tns ON tns.oid = t.relnamespace
+		 JOIN pg_catalog.pg_attribute a ON a.attrelid = t.oid AND a.attnum = d.refobjsubid
+		 WHERE d.classid = 'pg_catalog.pg_class'::regclass AND d.refclassid = 'pg_catalog.pg_class'::regclass
+		 AND d.deptype = 'a' AND ` + c.namespace + `
+		 ORDER BY ns.nspname, s.relname`,
+	)
+}
+
+// views creates the views and the materialized views after the views they use, read from the dependencies of their
+// rewrite rules, as a view may be replaced by one using views created later. The views are created after the
+// constraints, a view grouping by a primary key may select the other columns of its table.
+// The materialized views are created without their data.
+func (c *Catalog) views(int) ([]string, error) {
+	rows, err := c.conn.Query(
+		`SELECT cl.oid, CASE WHEN cl.relkind = 'm' THEN 'CREATE MATERIALIZED VIEW ' ELSE 'CREATE VIEW ' END ||
+		 quote_ident(ns.nspname) || '.' || quote_ident(cl.relname) || ' AS' || chr(10) ||
+		 rtrim(pg_catalog.pg_get_viewdef(cl.oid), ';') ||
+		 CASE WHEN cl.relkind = 'm' THEN chr(10) || ' WITH NO DATA' ELSE '' END,
+		 ARRAY(SELECT DISTINCT d.refobjid::bigint
+		       FROM pg_catalog.pg_rewrite r
+		       JOIN pg_catalog.pg_depend d ON d.classid = 'pg_catalog.pg_rewrite'::regclass AND d.objid = r.oid
+		       JOIN pg_catalog.pg_class ref ON ref.oid = d.refobjid
+		       WHERE r.ev_class = cl.oid AND d.refclassid = 'pg_catalog.pg_class'::regclass
+		       AND d.refobjid <> cl.oid AND ref.relkind IN ('v', 'm'))
+		 FROM pg_catalog.pg_class cl
+		 JOIN pg_catalog.pg_namespace ns ON ns.oid = cl.relnamespace
+		 WHERE cl.relkind IN ('v', 'm') AND ` + c.namespace + ` AND ` + fmt.Sprintf(notExtensionMember, "cl.oid") + `
+		 ORDER BY cl.oid`,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var views []*catalogView
+	for rows.Next() {
+		var v catalogView
+		if err := rows.Scan(&v.oid, &v.statement, (*pq.Int64Array)(&v.uses)); err != nil {
+			return nil, err
+		}
+		views = append(views, &v)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return renderViews(views), nil
+}
+
+// constraints creates the primary keys, unique, check and exclusion constraints, or the foreign keys. The foreign keys
+// are created after the indexes, they may reference the columns of a unique index. The constraints inherited by the
+// partitions are created with the ones of their partitioned table.
+func (c *Catalog) constraints(foreign bool) ([]string, error) {
+	kinds := "'p', 'u', 'c', 'x'"
+	if foreign {
+		kinds = "'f'"
+	}
+
+	rows, err := c.conn.Query(
+		`SELECT ns.nspname, cl.relname, cl.relkind = 'p', con.conname, pg_catalog.pg_get_constraintdef(con.oid)
+		 FROM pg_catalog.pg_constraint con
+		 JOIN pg_catalog.pg_class cl ON cl.oid = con.conrelid
+		 JOIN pg_catalog.pg_namespace ns ON ns.oid = cl.relnamespace
+		 WHERE con.contype IN (` + kinds + `) AND con.conislocal
+		 AND cl.relkind IN ('r', 'p') AND ` + c.namespace + `
+		 ORDER BY ns.nspname, cl.relname, con.conname`,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var statements []string
+	for rows.Next() {
+		var con catalogConstraint
+		if err := rows.Scan(&con.schema, &con.table, &con.partitioned, &con.name, &con.definition); err != nil {
+			return nil, err
+		}
+		statements = append(statements, renderConstraint(&con))
+	}
+
+	return statements, rows.Err()
+}
+
+// indexes creates the indexes which are not created by a constraint, the indexes inherited by the partitions are
+// created with the ones of their partitioned table.
+func (c *Catalog) indexes(int) ([]string, error) {
+	return c.queryStatements(
+		`SELECT pg_catalog.pg_get_indexdef(i.indexrelid)
+		 FROM pg_catalog.pg_index i
+		 JOIN pg_catalog.pg_class cl ON cl.oid = i.indrelid
+		 JOIN pg_catalog.pg_class ic ON ic.oid = i.indexrelid
+		 JOIN pg_catalog.pg_namespace ns ON ns.oid = cl.relnamespace
+		 WHERE cl.relkind IN ('r', 'p', 'm') AND ` + c.namespace + `
+		 AND NOT EXISTS (SELECT 1 FROM pg_catalog.pg_constraint con WHERE con.conindid = i.indexrelid AND con.contype IN ('p', 'u', 'x'))
+		 AND NOT EXISTS (SELECT 1 FROM pg_catalog.pg_inherits inh WHERE inh.inhrelid = i.indexrelid)
+		 ORDER BY ns.nspname, cl.relname, ic.relname`,
+	)
+}
+
+// queryStatements returns the statements built by the query.
+func (c *Catalog) queryStatements(query string) ([]string, error) {
+	rows, err := c.conn.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var statements []string
+	for rows.Next() {
+		var stmt string
+		if err := rows.Scan(&stmt); err != nil {
+			return nil, err
+		}
+		statements = append(statements, stmt)
+	}
+
+	return statements, rows.Err()
+}
+
+func renderEnum(name string, labels []string) string {
+	quoted := make([]string, len(labels))
+	for i, label := range labels {
+		quoted[i] = pq.QuoteLiteral(label)
+	}
+
+	return fmt.Sprintf("CREATE TYPE %s AS ENUM (%s)", name, strings.Join(quoted, ", "))
+}
+
+func renderSequence(s *catalogSequence) string {
+	var b strings.Builder
+	b.WriteString("CREATE SEQUENCE " + qualifiedName(s.schema, s.name))
+	if s.dataType != "" {
+		b.WriteString(" AS " + s.dataType)
+	}
+	fmt.Fprintf(&b, " START WITH %d INCREMENT BY %d MINVALUE %d MAXVALUE %d CACHE %d", s.start, s.increment, s.min, s.max, s.cache)
+	if s.cycle {
+		b.WriteString(" CYCLE")
+	}
+
+	return b.String()
+}
+
+// renderTables creates the tables, the partitions are created once their partitioned table is.
+func renderTables(tables []*catalogTable) []string {
+	var statements []string
+	created := make(map[string]bool)
+	for len(tables) > 0 {
+		var pending []*catalogTable
+		for _, t := range tables {
+			if t.parent != "" && !created[qualifiedName(t.parentSchema, t.parent)] {
+				pending = append(pending, t)
+				continue
+			}
+
+			statements = append(statements, renderTable(t))
+			created[qualifiedName(t.schema, t.name)] = true
+		}
+
+		// the partitions of a table left out, e.g. in a system schema, are created as they are read
+		if len(pending) == len(tables) {
+			for _, t := range pending {
+				t.parent = ""
+			}
+		}
+		tables = pending
+	}
+
+	return statements
+}
+
+// renderViews creates the views once the views they use are created.
+func renderViews(views []*catalogView) []string {
+	selected := make(map[int64]bool, len(views))
+	for _, v := range views {
+		selected[v.oid] = true
+	}
+
+	var statements []string
+	created := make(map[int64]bool, len(views))
+	for len(views) > 0 {
+		var pending []*catalogView
+		for _, v := range views {
+			if !usesCreated(v, selected, created) {
+				pending = append(pending, v)
+				continue
+			}
+
+			statements = append(statements, v.statement)
+			created[v.oid] = true
+		}
+
+		// the views can not use each other, a cycle is only left by a broken catalog and created as it is read
+		if len(pending) == len(views) {
+			for _, v := range pending {
+				statements = append(statements, v.statement)
+			}
+			break
+		}
+		views = pending
+	}
+
+	return statements
+}
+
+// usesCreated checks if the views used by the view are created, the views left out, e.g. in a system schema,
+// are not waited for.
+func usesCreated(v *catalogView, selected, created map[int64]bool) bool {
+	for _, oid := range v.uses {
+		if selected[oid] && !created[oid] {
+			return false
+		}
+	}
+
+	return true
+}
+
+func renderTable(t *catalogTable) string {
+	name := qualifiedName(t.schema, t.name)
+	if t.parent != "" {
+		return fmt.Sprintf("CREATE TABLE %s PARTITION OF %s %s", name, qualifiedName(t.parentSchema, t.parent), t.bound)
+	}
+
+	definitions := make([]string, len(t.columns))
+	for i, c := range t.columns {
+		definitions[i] = "    " + renderColumn(c)
+	}
+
+	stmt := fmt.Sprintf("CREATE TABLE %s (\n%s\n)", name, strings.Join(definitions, ",\n"))
+	if t.partitionKey != "" {
+		stmt += " PARTITION BY " + t.partitionKey
+	}
+
+	return stmt
+}
+
+func renderColumn(c *catalogColumn) string {
+	definition := pq.QuoteIdentifier(c.name) + " " + c.dataType
+	if c.collation != "" {
+		definition += " COLLATE " + c.collation
+	}
+
+	switch {
+	case c.generated == "s":
+		definition += " GENERATED ALWAYS AS (" + c.expression + ") STORED"
+	case c.expression != "":
+		definition += " DEFAULT " + c.expression
+	}
+
+	if c.notNull {
+		definition += " NOT NULL"
+	}
+
+	switch c.identity {
+	case "a":
+		definition += " GENERATED ALWAYS AS IDENTITY"
+	case "d":
+		definition += " GENERATED BY DEFAULT AS IDENTITY"
+	}
+
+	return definition
+}
+
+// renderConstraint adds a constraint to a table, the constraints of the partitioned tables are added to their partitions too.
+func renderConstraint(con *catalogConstraint) string {
+	only := "ONLY "
+	if con.partitioned {
+		only = ""
+	}
+
+	return fmt.Sprintf(
+		"ALTER TABLE %s%s ADD CONSTRAINT %s %s",
+		only, qualifiedName(con.schema, con.table), pq.QuoteIdentifier(con.name), con.definition,
+	)
+}
+
+func qualifiedName(schema, name string) string {
+	return pq.QuoteIdentifier(schema) + "." + pq.QuoteIdentifier(name)
+}
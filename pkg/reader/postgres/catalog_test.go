@@ -0,0 +1,104 @@
+package postgres
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRenderTables(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		scenario   string
+		tables     []*catalogTable
+		statements []string
+	}{
+		{
+			scenario: "when the columns have defaults, identities and generated expressions",
+			tables: []*catalogTable{{
+				schema: "public",
+				name:   "users",
+				columns: []*catalogColumn{
+					{name: "id", dataType: "bigint", notNull: true, identity: "d"},
+					{name: "email", dataType: "character varying(255)", collation: `"pg_catalog"."C"`, notNull: true},
+					{name: "active", dataType: "boolean", expression: "true", notNull: true},
+					{name: "lower_email", dataType: "text", expression: "lower((email)::text)", generated: "s"},
+				},
+			}},
+			statements: []string{`CREATE TABLE "public"."users" (
+    "id" bigint NOT NULL GENERATED BY DEFAULT AS IDENTITY,
+    "email" character varying(255) COLLATE "pg_catalog"."C" NOT NULL,
+    "active" boolean DEFAULT true NOT NULL,
+    "lower_email" text GENERATED ALWAYS AS (lower((email)::text)) STORED
+)`},
+		},
+		{
+			scenario: "when the partitions are read before their partitioned table",
+			tables: []*catalogTable{
+				{schema: "public", name: "orders_de", parentSchema: "public", parent: "orders", bound: "FOR VALUES IN ('de')"},
+				{schema: "public", name: "orders", partitionKey: "LIST (country)", columns: []*catalogColumn{{name: "country", dataType: "text"}}},
+			},
+			statements: []string{
+				"CREATE TABLE \"public\".\"orders\" (\n    \"country\" text\n) PARTITION BY LIST (country)",
+				`CREATE TABLE "public"."orders_de" PARTITION OF "public"."orders" FOR VALUES IN ('de')`,
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.scenario, func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, test.statements, renderTables(test.tables))
+		})
+	}
+}
+
+func TestRenderViews(t *testing.T) {
+	t.Parallel()
+
+	views := []*catalogView{
+		{oid: 10, statement: "CREATE VIEW top_customers", uses: []int64{30}},
+		{oid: 20, statement: "CREATE VIEW active_users"},
+		{oid: 30, statement: "CREATE MATERIALIZED VIEW customer_totals", uses: []int64{20, 99}},
+	}
+
+	assert.Equal(t, []string{
+		"CREATE VIEW active_users",
+		"CREATE MATERIALIZED VIEW customer_totals",
+		"CREATE VIEW top_customers",
+	}, renderViews(views), "the views are created after the views they use, the views left out are not waited for")
+}
+
+func TestRenderSequence(t *testing.T) {
+	t.Parallel()
+
+	s := &catalogSequence{schema: "billing", name: "invoice_number", dataType: "integer", start: 1000, increment: 1, min: 1, max: 2147483647, cache: 1, cycle: true}
+	assert.Equal(
+		t,
+		`CREATE SEQUENCE "billing"."invoice_number" AS integer START WITH 1000 INCREMENT BY 1 MINVALUE 1 MAXVALUE 2147483647 CACHE 1 CYCLE`,
+		renderSequence(s),
+	)
+}
+
+func TestRenderEnum(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, `CREATE TYPE "public"."mood" AS ENUM ('sad', 'it''s ok')`, renderEnum(qualifiedName("public", "mood"), []string{"sad", "it's ok"}))
+}
+
+func TestRenderConstraint(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(
+		t,
+		`ALTER TABLE ONLY "public"."orders" ADD CONSTRAINT "orders_user_id_fkey" FOREIGN KEY (user_id) REFERENCES users(id)`,
+		renderConstraint(&catalogConstraint{schema: "public", table: "orders", name: "orders_user_id_fkey", definition: "FOREIGN KEY (user_id) REFERENCES users(id)"}),
+	)
+	assert.Equal(
+		t,
+		`ALTER TABLE "public"."orders" ADD CONSTRAINT "orders_pkey" PRIMARY KEY (id, country)`,
+		renderConstraint(&catalogConstraint{schema: "public", table: "orders", partitioned: true, name: "orders_pkey", definition: "PRIMARY KEY (id, country)"}),
+	)
+}
@@ -2,6 +2,7 @@ package postgres
 
 import (
 	"bytes"
+	"fmt"
 	"os/exec"
 
 	log "github.com/sirupsen/logrus"
@@ -12,11 +13,13 @@ type (
 	PgDump struct {
 		command string
 		dsn     string
+		schemas schemaFilter
 	}
 )
 
-// NewPgDump creates a new PgDump.
-func NewPgDump(dsn string) (*PgDump, error) {
+// NewPgDump creates a new PgDump dumping the included schemas, all the schemas when none is included,
+// other than the excluded ones.
+func NewPgDump(dsn string, include, exclude []string) (*PgDump, error) {
 	path, err := exec.LookPath("pg_dump")
 	if err != nil {
 		return nil, err
@@ -25,6 +28,7 @@ func NewPgDump(dsn string) (*PgDump, error) {
 	return &PgDump{
 		command: path,
 		dsn:     dsn,
+		schemas: schemaFilter{include: include, exclude: exclude},
 	}, nil
 }
 
@@ -32,14 +36,14 @@ func NewPgDump(dsn string) (*PgDump, error) {
 func (p *PgDump) GetStructure() (string, error) {
 	logger := log.WithField("command", p.command)
 
-	cmd := exec.Command(
-		p.command,
+	args := []string{
 		"--dbname", p.dsn,
 		"--schema-only",
 		"--no-privileges",
 		"--no-owner",
 		"--no-comments",
-	)
+	}
+	cmd := exec.Command(p.command, append(args, p.schemas.pgDumpArgs()...)...)
 
 	logger.Debug("loading schema for table")
 	cmdErr := logger.WriterLevel(log.WarnLevel)
@@ -52,7 +56,7 @@ func (p *PgDump) GetStructure() (string, error) {
 	cmd.Stdout = buf
 
 	if err := cmd.Run(); err != nil {
-		logger.Error("failed to load schema for table")
+		return "", fmt.Errorf("failed to run pg_dump: %w", err)
 	}
 
 	return buf.String(), nil
@@ -5,8 +5,10 @@ import (
 	"strings"
 
 	_ "github.com/lib/pq" // import postgres driver
+	log "github.com/sirupsen/logrus"
 
 	"github.com/hellofresh/klepto/pkg/reader"
+	"github.com/hellofresh/klepto/pkg/reader/engine"
 )
 
 type driver struct{}
@@ -27,12 +29,22 @@ func (m *driver) NewConnection(opts reader.ConnOpts) (reader.Reader, error) {
 	conn.SetMaxIdleConns(opts.MaxIdleConns)
 	conn.SetConnMaxLifetime(opts.MaxConnLifetime)
 
-	dumper, err := NewPgDump(opts.DSN)
+	return NewStorage(conn, newDumper(conn, opts), opts.Schemas, opts.ExcludeSchemas, engine.NewOpts(opts)), nil
+}
+
+// newDumper returns the pg_dump command, or the catalog when it is preferred or pg_dump is not installed.
+func newDumper(conn *sql.DB, opts reader.ConnOpts) PgDumper {
+	if opts.NativeStructure {
+		return NewCatalog(conn, opts.Schemas, opts.ExcludeSchemas)
+	}
+
+	dumper, err := NewPgDump(opts.DSN, opts.Schemas, opts.ExcludeSchemas)
 	if err != nil {
-		return nil, err
+		log.WithError(err).Info("pg_dump is not available, the structure is generated from the catalog")
+		return NewCatalog(conn, opts.Schemas, opts.ExcludeSchemas)
 	}
 
-	return NewStorage(conn, dumper, opts.Timeout), nil
+	return dumper
 }
 
 func init() {
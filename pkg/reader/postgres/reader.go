@@ -1,11 +1,15 @@
 package postgres
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	"strconv"
-	"time"
+	"strings"
+	"sync"
 
+	sq "github.com/Masterminds/squirrel"
+	"github.com/lib/pq"
 	log "github.com/sirupsen/logrus"
 
 	"github.com/hellofresh/klepto/pkg/reader"
@@ -15,31 +19,40 @@ import (
 type (
 	storage struct {
 		PgDumper
-		conn *sql.DB
+		conn    *sql.DB
+		schemas schemaFilter
+
+		versionOnce sync.Once
+		version     int
+		versionErr  error
 	}
 
-	// PgDumper executes the pg dump command.
+	// PgDumper dumps the database structure, with the pg_dump command or from the catalog.
 	PgDumper interface {
 		GetStructure() (stmt string, err error)
 	}
 )
 
-// NewStorage creates a new postgres storage reader.
-func NewStorage(conn *sql.DB, dumper PgDumper, timeout time.Duration) reader.Reader {
+// NewStorage creates a new postgres storage reader of the tables in the included schemas, all the schemas when none
+// is included, other than the excluded ones.
+func NewStorage(conn *sql.DB, dumper PgDumper, include, exclude []string, opts engine.Opts) reader.Reader {
 	return engine.New(&storage{
 		PgDumper: dumper,
 		conn:     conn,
-	}, timeout)
+		schemas:  schemaFilter{include: include, exclude: exclude},
+	}, opts)
 }
 
-// GetTables gets a list of all tables in the database
+// GetTables gets a list of all tables in the database, the tables outside of the public schema are qualified
+// with their schema, e.g. `billing.invoices`.
 func (s *storage) GetTables() ([]string, error) {
 	log.Debug("fetching table list")
 	rows, err := s.conn.Query(
-		`SELECT table_name FROM information_schema.tables
+		`SELECT table_schema, table_name FROM information_schema.tables
 		 WHERE table_catalog=current_database()
 		 AND table_type = 'BASE TABLE'
-		 AND table_schema NOT IN ('pg_catalog', 'information_schema')`,
+		 AND ` + s.schemas.condition("table_schema") + `
+		 ORDER BY table_schema, table_name`,
 	)
 	if err != nil {
 		return nil, err
@@ -48,12 +61,12 @@ func (s *storage) GetTables() ([]string, error) {
 
 	tables := make([]string, 0)
 	for rows.Next() {
-		var tableName string
-		if err := rows.Scan(&tableName); err != nil {
+		var schema, name string
+		if err := rows.Scan(&schema, &name); err != nil {
 			return nil, err
 		}
 
-		tables = append(tables, tableName)
+		tables = append(tables, reader.TableName(schema, name))
 	}
 
 	log.WithField("tables", tables).Debug("fetched table list")
@@ -61,28 +74,253 @@ func (s *storage) GetTables() ([]string, error) {
 	return tables, nil
 }
 
-func (s *storage) GetColumns(table string) ([]string, error) {
+// GetColumns returns the columns in the specified database table
+func (s *storage) GetColumns(table string) ([]*reader.Column, error) {
 	log.WithField("table", table).Debug("fetching table columns")
+	schema, name := reader.SplitTableName(table)
+
+	version, err := s.serverVersion()
+	if err != nil {
+		return nil, err
+	}
+
+	// identity columns were added in postgres 10 and generated columns in postgres 12
+	isIdentity, identityGeneration, isGenerated := "'NO'", "NULL", "'NEVER'"
+	if version >= 100000 {
+		isIdentity, identityGeneration = "is_identity", "identity_generation"
+	}
+	if version >= 120000 {
+		isGenerated = "is_generated"
+	}
+
+	rows, err := s.conn.Query(
+		fmt.Sprintf(
+			`SELECT column_name, data_type, udt_name, is_nullable, column_default, %s, %s, %s,
+			 character_maximum_length, numeric_precision, numeric_scale
+			 FROM information_schema.columns
+			 WHERE table_catalog=current_database() AND table_schema=$1 AND table_name=$2
+			 ORDER BY ordinal_position`,
+			isIdentity, identityGeneration, isGenerated,
+		),
+		schema, name,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var columns []*reader.Column
+	for rows.Next() {
+		var (
+			column                                 reader.Column
+			udtName, nullable, identity, generated string
+			columnDefault, generation              sql.NullString
+			characterMaxLength                     sql.NullInt64
+			numericPrecision, numericScale         sql.NullInt64
+		)
+		err := rows.Scan(
+			&column.Name, &column.Type, &udtName, &nullable, &columnDefault,
+			&identity, &generation, &generated, &characterMaxLength, &numericPrecision, &numericScale,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		switch column.Type {
+		case "USER-DEFINED":
+			column.Type = udtName
+		case "ARRAY":
+			column.Type = strings.TrimPrefix(udtName, "_") + "[]"
+		}
+
+		column.Nullable = nullable == "YES"
+		if columnDefault.Valid {
+			column.Default = &columnDefault.String
+		}
+		column.Identity = identity == "YES" || strings.HasPrefix(columnDefault.String, "nextval(")
+		column.IdentityAlways = generation.String == "ALWAYS"
+		column.Generated = generated == "ALWAYS"
+		column.MaxLength = characterMaxLength.Int64
+		if column.Kind() == reader.KindDecimal {
+			column.Precision, column.Scale = numericPrecision.Int64, numericScale.Int64
+		}
+
+		columns = append(columns, &column)
+	}
+
+	return columns, rows.Err()
+}
+
+// GetPrimaryKey returns the primary key of the specified database table
+func (s *storage) GetPrimaryKey(table string) (*reader.Key, error) {
+	keys, err := s.getUniqueIndexes(table, true)
+	if err != nil || len(keys) == 0 {
+		return nil, err
+	}
+
+	return keys[0], nil
+}
+
+// GetUniqueKeys returns the unique keys, other than the primary key, of the specified database table
+func (s *storage) GetUniqueKeys(table string) ([]*reader.Key, error) {
+	return s.getUniqueIndexes(table, false)
+}
+
+// getUniqueIndexes returns either the primary key or the unique indexes of the specified database table.
+// Partial and expression indexes are skipped as they can't be used as a row key.
+func (s *storage) getUniqueIndexes(table string, primary bool) ([]*reader.Key, error) {
+	log.WithField("table", table).Debug("fetching table unique indexes")
+	schema, name := reader.SplitTableName(table)
+	rows, err := s.conn.Query(
+		`SELECT ic.relname, att.attname
+		 FROM pg_catalog.pg_index i
+		 JOIN pg_catalog.pg_class cl ON cl.oid = i.indrelid
+		 JOIN pg_catalog.pg_namespace ns ON ns.oid = cl.relnamespace
+		 JOIN pg_catalog.pg_class ic ON ic.oid = i.indexrelid
+		 CROSS JOIN LATERAL unnest(i.indkey) WITH ORDINALITY AS k(attnum, ord)
+		 JOIN pg_catalog.pg_attribute att ON att.attrelid = i.indrelid AND att.attnum = k.attnum
+		 WHERE i.indisunique
+		 AND i.indisprimary = $3
+		 AND i.indpred IS NULL
+		 AND i.indexprs IS NULL
+		 AND ns.nspname = $1
+		 AND cl.relname = $2
+		 ORDER BY ic.relname, k.ord`,
+		schema,
+		name,
+		primary,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanKeys(rows)
+}
+
+// GetIndexes returns the non unique indexes of the specified database table.
+// Partial and expression indexes are skipped as they can't be defined from their columns.
+func (s *storage) GetIndexes(table string) ([]*reader.Key, error) {
+	log.WithField("table", table).Debug("fetching table indexes")
+	schema, name := reader.SplitTableName(table)
+	rows, err := s.conn.Query(
+		`SELECT ic.relname, att.attname
+		 FROM pg_catalog.pg_index i
+		 JOIN pg_catalog.pg_class cl ON cl.oid = i.indrelid
+		 JOIN pg_catalog.pg_namespace ns ON ns.oid = cl.relnamespace
+		 JOIN pg_catalog.pg_class ic ON ic.oid = i.indexrelid
+		 CROSS JOIN LATERAL unnest(i.indkey) WITH ORDINALITY AS k(attnum, ord)
+		 JOIN pg_catalog.pg_attribute att ON att.attrelid = i.indrelid AND att.attnum = k.attnum
+		 WHERE NOT i.indisunique
+		 AND i.indpred IS NULL
+		 AND i.indexprs IS NULL
+		 AND ns.nspname = $1
+		 AND cl.relname = $2
+		 ORDER BY ic.relname, k.ord`,
+		schema,
+		name,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanKeys(rows)
+}
+
+// scanKeys groups the index name and column rows in keys
+func scanKeys(rows *sql.Rows) ([]*reader.Key, error) {
+	var keys []*reader.Key
+	for rows.Next() {
+		var name, column string
+		if err := rows.Scan(&name, &column); err != nil {
+			return nil, err
+		}
+
+		if len(keys) == 0 || keys[len(keys)-1].Name != name {
+			keys = append(keys, &reader.Key{Name: name})
+		}
+
+		key := keys[len(keys)-1]
+		key.Columns = append(key.Columns, column)
+	}
+
+	return keys, rows.Err()
+}
+
+// EstimateRows returns the number of rows of the specified database table estimated by the last analyze
+func (s *storage) EstimateRows(table string) (int64, error) {
+	schema, name := reader.SplitTableName(table)
+
+	var rows float64
+	err := s.conn.QueryRow(
+		`SELECT cl.reltuples
+		 FROM pg_catalog.pg_class cl
+		 JOIN pg_catalog.pg_namespace ns ON ns.oid = cl.relnamespace
+		 WHERE ns.nspname = $1
+		 AND cl.relname = $2`,
+		schema,
+		name,
+	).Scan(&rows)
+	if err != nil {
+		return 0, err
+	}
+
+	// tables never analyzed are estimated as -1 since PostgreSQL 14
+	if rows < 0 {
+		return 0, nil
+	}
+
+	return int64(rows), nil
+}
+
+// GetForeignKeys returns the foreign keys defined on the specified database table
+func (s *storage) GetForeignKeys(table string) ([]*reader.ForeignKey, error) {
+	log.WithField("table", table).Debug("fetching table foreign keys")
+	schema, name := reader.SplitTableName(table)
 	rows, err := s.conn.Query(
-		"SELECT column_name FROM information_schema.columns WHERE table_catalog=current_database() AND table_name=$1",
-		table,
+		`SELECT con.conname, att.attname, fns.nspname, fcl.relname, fatt.attname
+		 FROM pg_catalog.pg_constraint con
+		 JOIN pg_catalog.pg_class cl ON cl.oid = con.conrelid
+		 JOIN pg_catalog.pg_namespace ns ON ns.oid = cl.relnamespace
+		 JOIN pg_catalog.pg_class fcl ON fcl.oid = con.confrelid
+		 JOIN pg_catalog.pg_namespace fns ON fns.oid = fcl.relnamespace
+		 CROSS JOIN LATERAL unnest(con.conkey, con.confkey) WITH ORDINALITY AS k(attnum, fattnum, ord)
+		 JOIN pg_catalog.pg_attribute att ON att.attrelid = con.conrelid AND att.attnum = k.attnum
+		 JOIN pg_catalog.pg_attribute fatt ON fatt.attrelid = con.confrelid AND fatt.attnum = k.fattnum
+		 WHERE con.contype = 'f'
+		 AND ns.nspname = $1
+		 AND cl.relname = $2
+		 ORDER BY con.conname, k.ord`,
+		schema,
+		name,
 	)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
-	var columns []string
+	var foreignKeys []*reader.ForeignKey
 	for rows.Next() {
-		var column string
-		if err := rows.Scan(&column); err != nil {
+		var constraint, column, referencedSchema, referencedTable, referencedColumn string
+		if err := rows.Scan(&constraint, &column, &referencedSchema, &referencedTable, &referencedColumn); err != nil {
 			return nil, err
 		}
 
-		columns = append(columns, column)
+		if len(foreignKeys) == 0 || foreignKeys[len(foreignKeys)-1].Name != constraint {
+			foreignKeys = append(foreignKeys, &reader.ForeignKey{
+				Name:            constraint,
+				Table:           table,
+				ReferencedTable: reader.TableName(referencedSchema, referencedTable),
+			})
+		}
+
+		fk := foreignKeys[len(foreignKeys)-1]
+		fk.Columns = append(fk.Columns, column)
+		fk.ReferencedColumns = append(fk.ReferencedColumns, referencedColumn)
 	}
 
-	return columns, nil
+	return foreignKeys, rows.Err()
 }
 
 // QuoteIdentifier returns a double-quoted name.
@@ -90,6 +328,89 @@ func (s *storage) QuoteIdentifier(name string) string {
 	return strconv.Quote(name)
 }
 
+// QuoteTable returns a double-quoted table name, the schema of a qualified table name is quoted apart,
+// e.g. `"billing"."invoices"`.
+func (s *storage) QuoteTable(name string) string {
+	schema, table := reader.SplitTableName(name)
+	if schema == reader.DefaultSchema {
+		return pq.QuoteIdentifier(table)
+	}
+
+	return pq.QuoteIdentifier(schema) + "." + pq.QuoteIdentifier(table)
+}
+
+// PlaceholderFormat returns the postgres query placeholder format.
+func (s *storage) PlaceholderFormat() sq.PlaceholderFormat { return sq.Dollar }
+
+// Snapshot exports the snapshot of a repeatable read transaction and imports it in the given number of connections.
+func (s *storage) Snapshot(ctx context.Context, n int) ([]*sql.Conn, error) {
+	exporter, err := s.conn.Conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	// the exporting transaction only needs to be open until the snapshot is imported
+	defer exporter.Close()
+
+	if _, err := exporter.ExecContext(ctx, "BEGIN ISOLATION LEVEL REPEATABLE READ READ ONLY"); err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer exporter.ExecContext(ctx, "ROLLBACK")
+
+	var snapshotID string
+	if err := exporter.QueryRowContext(ctx, "SELECT pg_export_snapshot()").Scan(&snapshotID); err != nil {
+		return nil, fmt.Errorf("failed to export snapshot: %w", err)
+	}
+	log.WithField("snapshot", snapshotID).Debug("exported snapshot")
+
+	conns := make([]*sql.Conn, 0, n)
+	for i := 0; i < n; i++ {
+		conn, err := s.importSnapshot(ctx, snapshotID)
+		if err != nil {
+			for _, c := range conns {
+				c.Close()
+			}
+			return nil, err
+		}
+
+		conns = append(conns, conn)
+	}
+
+	return conns, nil
+}
+
+// importSnapshot opens a connection in a repeatable read transaction seeing the given snapshot.
+func (s *storage) importSnapshot(ctx context.Context, snapshotID string) (*sql.Conn, error) {
+	conn, err := s.conn.Conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := conn.ExecContext(ctx, "BEGIN ISOLATION LEVEL REPEATABLE READ READ ONLY"); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+
+	if _, err := conn.ExecContext(ctx, "SET TRANSACTION SNAPSHOT "+pq.QuoteLiteral(snapshotID)); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to import snapshot: %w", err)
+	}
+
+	return conn, nil
+}
+
+// serverVersion returns the postgres server version number, e.g. 90624 or 120003.
+func (s *storage) serverVersion() (int, error) {
+	s.versionOnce.Do(func() {
+		var version string
+		if s.versionErr = s.conn.QueryRow("SHOW server_version_num").Scan(&version); s.versionErr != nil {
+			return
+		}
+		s.version, s.versionErr = strconv.Atoi(version)
+	})
+
+	return s.version, s.versionErr
+}
+
 // Close closes the postgres connection reader.
 func (s *storage) Close() error {
 	if err := s.conn.Close(); err != nil {
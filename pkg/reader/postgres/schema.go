@@ -0,0 +1,48 @@
+package postgres
+
+import (
+	"strings"
+
+	"github.com/lib/pq"
+)
+
+// schemaFilter selects the schemas read, every user schema is read when both lists are empty.
+type schemaFilter struct {
+	include []string
+	exclude []string
+}
+
+// condition returns the SQL condition selecting the schemas on the given column, the system schemas are never selected.
+func (f schemaFilter) condition(column string) string {
+	cond := column + " NOT IN ('pg_catalog', 'information_schema') AND left(" + column + ", 3) <> 'pg_'"
+	if len(f.include) > 0 {
+		cond += " AND " + column + " IN (" + quoteLiterals(f.include) + ")"
+	}
+	if len(f.exclude) > 0 {
+		cond += " AND " + column + " NOT IN (" + quoteLiterals(f.exclude) + ")"
+	}
+
+	return cond
+}
+
+// pgDumpArgs returns the pg_dump arguments selecting the schemas.
+func (f schemaFilter) pgDumpArgs() []string {
+	var args []string
+	for _, schema := range f.include {
+		args = append(args, "--schema", schema)
+	}
+	for _, schema := range f.exclude {
+		args = append(args, "--exclude-schema", schema)
+	}
+
+	return args
+}
+
+func quoteLiterals(values []string) string {
+	quoted := make([]string, len(values))
+	for i, v := range values {
+		quoted[i] = pq.QuoteLiteral(v)
+	}
+
+	return strings.Join(quoted, ", ")
+}
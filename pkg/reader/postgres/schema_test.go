@@ -0,0 +1,50 @@
+package postgres
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSchemaFilter(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		scenario  string
+		filter    schemaFilter
+		condition string
+		args      []string
+	}{
+		{
+			scenario:  "when every schema is read",
+			condition: `ns.nspname NOT IN ('pg_catalog', 'information_schema') AND left(ns.nspname, 3) <> 'pg_'`,
+		},
+		{
+			scenario: "when the schemas are included and excluded",
+			filter:   schemaFilter{include: []string{"public", "billing"}, exclude: []string{"it's"}},
+			condition: `ns.nspname NOT IN ('pg_catalog', 'information_schema') AND left(ns.nspname, 3) <> 'pg_'` +
+				` AND ns.nspname IN ('public', 'billing') AND ns.nspname NOT IN ('it''s')`,
+			args: []string{"--schema", "public", "--schema", "billing", "--exclude-schema", "it's"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.scenario, func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, test.condition, test.filter.condition("ns.nspname"))
+			assert.Equal(t, test.args, test.filter.pgDumpArgs())
+		})
+	}
+}
+
+func TestQuoteIdentifier(t *testing.T) {
+	t.Parallel()
+
+	s := &storage{}
+	assert.Equal(t, `"users"`, s.QuoteIdentifier("users"))
+	assert.Equal(t, `"first.name"`, s.QuoteIdentifier("first.name"))
+	assert.Equal(t, `"users"`, s.QuoteTable("users"))
+	assert.Equal(t, `"billing"."invoices"`, s.QuoteTable("billing.invoices"))
+	assert.Equal(t, `"audit.log"`, s.QuoteTable(`"public"."audit.log"`))
+}
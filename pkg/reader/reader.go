@@ -1,11 +1,13 @@
 package reader
 
 import (
+	"context"
 	"fmt"
 	"time"
 
 	"github.com/hellofresh/klepto/pkg/config"
 	"github.com/hellofresh/klepto/pkg/database"
+	"github.com/hellofresh/klepto/pkg/progress"
 )
 
 type (
@@ -24,15 +26,33 @@ type (
 		// GetTables returns a list of all databases tables
 		GetTables() ([]string, error)
 		// GetColumns return a list of all columns for a given table
-		GetColumns(string) ([]string, error)
+		GetColumns(string) ([]*Column, error)
+		// GetPrimaryKey returns the primary key of a given table, nil if the table has none
+		GetPrimaryKey(string) (*Key, error)
+		// GetUniqueKeys returns the unique keys, other than the primary key, of a given table
+		GetUniqueKeys(string) ([]*Key, error)
+		// GetForeignKeys returns the foreign keys defined on a given table
+		GetForeignKeys(string) ([]*ForeignKey, error)
 		// FormatColumn returns a escaped table.column string
 		FormatColumn(tableName string, columnName string) string
-		// ReadTable returns a channel with all database rows
-		ReadTable(string, chan<- database.Row, ReadTableOpt) error
+		// ReadTable publishes the table rows to the channel, it stops when the context is done
+		ReadTable(context.Context, string, chan<- database.Row, ReadTableOpt) error
 		// Close closes the reader resources and releases them.
 		Close() error
 	}
 
+	// IndexReader is implemented by readers able to list the indexes which are not keys.
+	IndexReader interface {
+		// GetIndexes returns the non unique indexes on columns of a given table
+		GetIndexes(string) ([]*Key, error)
+	}
+
+	// QueryValidator is implemented by readers able to check the read table query without fetching data.
+	QueryValidator interface {
+		// ValidateQuery executes the query used to read a table without returning any row.
+		ValidateQuery(context.Context, string, ReadTableOpt) error
+	}
+
 	// ReadTableOpt represents the read table options
 	ReadTableOpt struct {
 		// Columns contains the (quoted) column of the table
@@ -45,6 +65,38 @@ type (
 		Limit uint64
 		// Relationships defines an slice of relationship definitions
 		Relationships []*RelationshipOpt
+		// Keys restricts the results to the rows matching one of the given key values
+		Keys *KeysOpt
+	}
+
+	// KeysOpt restricts the rows to the ones which columns match one of the given values
+	KeysOpt struct {
+		// Columns are the (unquoted) key columns.
+		Columns []string
+		// Values are the key values, each entry holds one value per column.
+		Values [][]interface{}
+	}
+
+	// Key represents a primary or unique key, or an index.
+	Key struct {
+		// Name is the constraint or index name.
+		Name string
+		// Columns are the key columns.
+		Columns []string
+	}
+
+	// ForeignKey represents a foreign key constraint.
+	ForeignKey struct {
+		// Name is the constraint name.
+		Name string
+		// Table is the table holding the foreign key.
+		Table string
+		// Columns are the foreign key columns.
+		Columns []string
+		// ReferencedTable is the referenced table name.
+		ReferencedTable string
+		// ReferencedColumns are the referenced columns, in the same order as Columns.
+		ReferencedColumns []string
 	}
 
 	// RelationshipOpt represents the relationships options
@@ -65,12 +117,26 @@ type (
 		DSN string
 		// Timeout is the timeout for read operations.
 		Timeout time.Duration
+		// ChunkSize is the number of rows read by a single query, 0 reads the tables with a single query.
+		ChunkSize uint64
+		// ChunkWorkers is the number of chunks of a table read in parallel.
+		ChunkWorkers int
+		// Snapshot if set to true, all the tables are read from the same consistent snapshot of the database.
+		Snapshot bool
 		// MaxConnLifetime is the maximum amount of time a connection may be reused on the read database.
 		MaxConnLifetime time.Duration
 		// MaxConns is the maximum number of open connections to the read database.
 		MaxConns int
 		// MaxIdleConns is the maximum number of connections in the idle connection pool for the read database.
 		MaxIdleConns int
+		// Progress counts the rows read per table, nil counts nothing.
+		Progress *progress.Tracker
+		// NativeStructure if set to true, the postgres structure is generated from the catalog instead of with pg_dump.
+		NativeStructure bool
+		// Schemas are the postgres schemas read, all the schemas when empty.
+		Schemas []string
+		// ExcludeSchemas are the postgres schemas not read.
+		ExcludeSchemas []string
 	}
 )
 
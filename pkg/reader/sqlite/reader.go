@@ -0,0 +1,347 @@
+package sqlite
+
+import (
+	"database/sql"
+	"fmt"
+	"strconv"
+	"strings"
+
+	sq "github.com/Masterminds/squirrel"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/hellofresh/klepto/pkg/reader"
+	"github.com/hellofresh/klepto/pkg/reader/engine"
+)
+
+// primaryKey is the name of the primary keys, sqlite does not name them.
+const primaryKey = "PRIMARY"
+
+// Hidden column kinds of pragma_table_xinfo.
+const (
+	hiddenVirtualTable     = 1
+	hiddenGeneratedVirtual = 2
+	hiddenGeneratedStored  = 3
+)
+
+type (
+	storage struct {
+		conn *sql.DB
+	}
+)
+
+// NewStorage creates a new sqlite reader.
+func NewStorage(conn *sql.DB, opts engine.Opts) reader.Reader {
+	return engine.New(&storage{
+		conn: conn,
+	}, opts)
+}
+
+// GetTables gets a list of all tables in the database, the sqlite internal tables excluded.
+func (s *storage) GetTables() ([]string, error) {
+	log.Debug("fetching table list")
+
+	rows, err := s.conn.Query(
+		"SELECT name FROM sqlite_master WHERE type = 'table' AND name NOT LIKE 'sqlite\\_%' ESCAPE '\\' ORDER BY name",
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tables := make([]string, 0)
+	for rows.Next() {
+		var tableName string
+		if err := rows.Scan(&tableName); err != nil {
+			return nil, err
+		}
+		tables = append(tables, tableName)
+	}
+
+	log.WithField("tables", tables).Debug("fetched table list")
+
+	return tables, rows.Err()
+}
+
+// GetColumns returns the columns in the specified database table
+func (s *storage) GetColumns(tableName string) ([]*reader.Column, error) {
+	rows, err := s.conn.Query(
+		`SELECT name, type, "notnull", dflt_value, pk, hidden FROM pragma_table_xinfo(?) ORDER BY cid`,
+		tableName,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var (
+		columns []*reader.Column
+		keys    int
+	)
+	for rows.Next() {
+		var (
+			column        reader.Column
+			notNull       bool
+			columnDefault sql.NullString
+			pk, hidden    int
+		)
+		if err := rows.Scan(&column.Name, &column.Type, &notNull, &columnDefault, &pk, &hidden); err != nil {
+			return nil, err
+		}
+		if hidden == hiddenVirtualTable {
+			continue
+		}
+
+		var size []int64
+		column.Type, size = parseType(column.Type)
+		switch {
+		case len(size) == 2:
+			column.Precision, column.Scale = size[0], size[1]
+		case len(size) == 1 && column.Kind() == reader.KindDecimal:
+			column.Precision = size[0]
+		case len(size) == 1:
+			column.MaxLength = size[0]
+		}
+		column.Nullable = !notNull && pk == 0
+		if columnDefault.Valid {
+			column.Default = &columnDefault.String
+		}
+		column.Generated = hidden == hiddenGeneratedVirtual || hidden == hiddenGeneratedStored
+		// a single integer primary key is an alias of the rowid, generated when not set
+		column.Identity = pk > 0 && column.Type == "integer"
+		if pk > 0 {
+			keys++
+		}
+
+		columns = append(columns, &column)
+	}
+
+	if keys > 1 {
+		for _, c := range columns {
+			c.Identity = false
+		}
+	}
+
+	return columns, rows.Err()
+}
+
+// GetPrimaryKey returns the primary key of the specified database table
+func (s *storage) GetPrimaryKey(tableName string) (*reader.Key, error) {
+	rows, err := s.conn.Query(`SELECT name FROM pragma_table_info(?) WHERE pk > 0 ORDER BY pk`, tableName)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var key *reader.Key
+	for rows.Next() {
+		var column string
+		if err := rows.Scan(&column); err != nil {
+			return nil, err
+		}
+
+		if key == nil {
+			key = &reader.Key{Name: primaryKey}
+		}
+		key.Columns = append(key.Columns, column)
+	}
+
+	return key, rows.Err()
+}
+
+// GetUniqueKeys returns the unique keys, other than the primary key, of the specified database table.
+// Partial indexes and indexes on expressions are not keys of the whole table rows, so they are left out.
+func (s *storage) GetUniqueKeys(tableName string) ([]*reader.Key, error) {
+	rows, err := s.conn.Query(
+		`SELECT l.name, i.name FROM pragma_index_list(?) AS l, pragma_index_info(l.name) AS i
+		WHERE l."unique" = 1 AND l.origin != 'pk' AND l.partial = 0
+		ORDER BY l.name, i.seqno`,
+		tableName,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanKeys(rows)
+}
+
+// scanKeys groups the index name and column rows in keys, the indexes on expressions are left out
+func scanKeys(rows *sql.Rows) ([]*reader.Key, error) {
+	var (
+		keys        []*reader.Key
+		expressions = make(map[string]bool)
+	)
+	for rows.Next() {
+		var (
+			name   string
+			column sql.NullString
+		)
+		if err := rows.Scan(&name, &column); err != nil {
+			return nil, err
+		}
+
+		if !column.Valid {
+			expressions[name] = true
+			continue
+		}
+
+		if len(keys) == 0 || keys[len(keys)-1].Name != name {
+			keys = append(keys, &reader.Key{Name: name})
+		}
+
+		key := keys[len(keys)-1]
+		key.Columns = append(key.Columns, column.String)
+	}
+
+	var indexes []*reader.Key
+	for _, key := range keys {
+		if !expressions[key.Name] {
+			indexes = append(indexes, key)
+		}
+	}
+
+	return indexes, rows.Err()
+}
+
+// GetIndexes returns the non unique indexes of the specified database table, partial and expression indexes left out.
+func (s *storage) GetIndexes(tableName string) ([]*reader.Key, error) {
+	rows, err := s.conn.Query(
+		`SELECT l.name, i.name FROM pragma_index_list(?) AS l, pragma_index_info(l.name) AS i
+		WHERE l."unique" = 0 AND l.partial = 0
+		ORDER BY l.name, i.seqno`,
+		tableName,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanKeys(rows)
+}
+
+// GetForeignKeys returns the foreign keys defined on the specified database table. Sqlite does not name
+// the foreign keys, they are named after the table and their position.
+func (s *storage) GetForeignKeys(tableName string) ([]*reader.ForeignKey, error) {
+	rows, err := s.conn.Query(
+		`SELECT id, "table", "from", "to" FROM pragma_foreign_key_list(?) ORDER BY id, seq`,
+		tableName,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var (
+		foreignKeys []*reader.ForeignKey
+		ids         []int
+	)
+	for rows.Next() {
+		var (
+			id                      int
+			referencedTable, column string
+			referencedColumn        sql.NullString
+		)
+		if err := rows.Scan(&id, &referencedTable, &column, &referencedColumn); err != nil {
+			return nil, err
+		}
+
+		if len(ids) == 0 || ids[len(ids)-1] != id {
+			ids = append(ids, id)
+			foreignKeys = append(foreignKeys, &reader.ForeignKey{
+				Name:            fmt.Sprintf("%s_fk_%d", tableName, id),
+				Table:           tableName,
+				ReferencedTable: referencedTable,
+			})
+		}
+
+		fk := foreignKeys[len(foreignKeys)-1]
+		fk.Columns = append(fk.Columns, column)
+		fk.ReferencedColumns = append(fk.ReferencedColumns, referencedColumn.String)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// the foreign keys without referenced columns reference the primary key
+	for _, fk := range foreignKeys {
+		if fk.ReferencedColumns[0] != "" {
+			continue
+		}
+
+		pk, err := s.GetPrimaryKey(fk.ReferencedTable)
+		if err != nil {
+			return nil, err
+		}
+		if pk == nil || len(pk.Columns) != len(fk.Columns) {
+			return nil, fmt.Errorf("foreign key %s references table %s without a matching primary key", fk.Name, fk.ReferencedTable)
+		}
+		fk.ReferencedColumns = pk.Columns
+	}
+
+	return foreignKeys, nil
+}
+
+// GetStructure returns the statements creating the tables, followed by the indexes, views and triggers.
+func (s *storage) GetStructure() (string, error) {
+	rows, err := s.conn.Query(
+		`SELECT sql FROM sqlite_master WHERE sql IS NOT NULL AND name NOT LIKE 'sqlite\_%' ESCAPE '\'
+		ORDER BY CASE type WHEN 'table' THEN 0 WHEN 'index' THEN 1 WHEN 'view' THEN 2 ELSE 3 END, name`,
+	)
+	if err != nil {
+		return "", err
+	}
+	defer rows.Close()
+
+	var buf strings.Builder
+	for rows.Next() {
+		var stmt string
+		if err := rows.Scan(&stmt); err != nil {
+			return "", err
+		}
+
+		buf.WriteString(stmt)
+		buf.WriteString(";\n")
+	}
+
+	return buf.String(), rows.Err()
+}
+
+// QuoteIdentifier returns a double quoted identifier.
+func (s *storage) QuoteIdentifier(name string) string {
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
+
+// PlaceholderFormat returns the sqlite query placeholder format.
+func (s *storage) PlaceholderFormat() sq.PlaceholderFormat { return sq.Question }
+
+// Close closes the sqlite database connection.
+func (s *storage) Close() error {
+	err := s.conn.Close()
+	if err != nil {
+		return fmt.Errorf("failed to close sqlite reader database connection: %w", err)
+	}
+	return nil
+}
+
+// Conn retrieves the storage connection
+func (s *storage) Conn() *sql.DB { return s.conn }
+
+// parseType splits the declared column type in its lower cased name and size, e.g. `VARCHAR(255)` or `DECIMAL(10, 2)`.
+func parseType(declared string) (string, []int64) {
+	name, args, found := strings.Cut(declared, "(")
+	name = strings.ToLower(strings.TrimSpace(name))
+	if !found {
+		return name, nil
+	}
+
+	var size []int64
+	for _, arg := range strings.Split(strings.TrimSuffix(strings.TrimSpace(args), ")"), ",") {
+		n, err := strconv.ParseInt(strings.TrimSpace(arg), 10, 64)
+		if err != nil {
+			return name, nil
+		}
+		size = append(size, n)
+	}
+
+	return name, size
+}
@@ -0,0 +1,144 @@
+package sqlite
+
+import (
+	"database/sql"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/hellofresh/klepto/pkg/reader"
+)
+
+const schema = `
+CREATE TABLE users (
+  id integer PRIMARY KEY,
+  email varchar(255) NOT NULL UNIQUE,
+  name text DEFAULT 'anonymous',
+  lower_email text GENERATED ALWAYS AS (lower(email)) VIRTUAL
+);
+CREATE UNIQUE INDEX users_lower_name ON users (lower(name));
+CREATE UNIQUE INDEX users_active_name ON users (name) WHERE name IS NOT NULL;
+CREATE INDEX users_name_email ON users (name, email);
+CREATE TABLE memberships (
+  user_id integer NOT NULL REFERENCES users,
+  group_name text NOT NULL,
+  PRIMARY KEY (user_id, group_name)
+);
+`
+
+func newTestStorage(t *testing.T) *storage {
+	t.Helper()
+
+	conn, err := sql.Open("sqlite", filepath.Join(t.TempDir(), "test.db"))
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+
+	_, err = conn.Exec(schema)
+	require.NoError(t, err)
+
+	return &storage{conn: conn}
+}
+
+func TestGetColumns(t *testing.T) {
+	t.Parallel()
+
+	s := newTestStorage(t)
+	anonymous := "'anonymous'"
+
+	tests := []struct {
+		scenario string
+		table    string
+		columns  []*reader.Column
+	}{
+		{
+			scenario: "when the table has a rowid primary key and a generated column",
+			table:    "users",
+			columns: []*reader.Column{
+				{Name: "id", Type: "integer", Identity: true},
+				{Name: "email", Type: "varchar", MaxLength: 255},
+				{Name: "name", Type: "text", Nullable: true, Default: &anonymous},
+				{Name: "lower_email", Type: "text", Nullable: true, Generated: true},
+			},
+		},
+		{
+			scenario: "when the table has a composite primary key",
+			table:    "memberships",
+			columns: []*reader.Column{
+				{Name: "user_id", Type: "integer"},
+				{Name: "group_name", Type: "text"},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.scenario, func(t *testing.T) {
+			t.Parallel()
+
+			columns, err := s.GetColumns(test.table)
+			require.NoError(t, err)
+			assert.Equal(t, test.columns, columns)
+		})
+	}
+}
+
+func TestGetKeys(t *testing.T) {
+	t.Parallel()
+
+	s := newTestStorage(t)
+
+	tables, err := s.GetTables()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"memberships", "users"}, tables)
+
+	pk, err := s.GetPrimaryKey("memberships")
+	require.NoError(t, err)
+	assert.Equal(t, &reader.Key{Name: primaryKey, Columns: []string{"user_id", "group_name"}}, pk)
+
+	uniqueKeys, err := s.GetUniqueKeys("users")
+	require.NoError(t, err)
+	require.Len(t, uniqueKeys, 1, "the partial and expression indexes are not keys")
+	assert.Equal(t, []string{"email"}, uniqueKeys[0].Columns)
+
+	indexes, err := s.GetIndexes("users")
+	require.NoError(t, err)
+	assert.Equal(t, []*reader.Key{{Name: "users_name_email", Columns: []string{"name", "email"}}}, indexes)
+
+	foreignKeys, err := s.GetForeignKeys("memberships")
+	require.NoError(t, err)
+	assert.Equal(t, []*reader.ForeignKey{{
+		Name:              "memberships_fk_0",
+		Table:             "memberships",
+		Columns:           []string{"user_id"},
+		ReferencedTable:   "users",
+		ReferencedColumns: []string{"id"},
+	}}, foreignKeys)
+}
+
+func TestParseType(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		scenario string
+		declared string
+		name     string
+		size     []int64
+	}{
+		{scenario: "when the type has a length", declared: "VARCHAR(255)", name: "varchar", size: []int64{255}},
+		{scenario: "when the type has a precision and scale", declared: "DECIMAL(10, 2)", name: "decimal", size: []int64{10, 2}},
+		{scenario: "when the type has no length", declared: "Text", name: "text"},
+		{scenario: "when the type size is not a number", declared: "VARCHAR(max)", name: "varchar"},
+		{scenario: "when the type is not declared", declared: "", name: ""},
+	}
+
+	for _, test := range tests {
+		t.Run(test.scenario, func(t *testing.T) {
+			t.Parallel()
+
+			name, size := parseType(test.declared)
+			assert.Equal(t, test.name, name)
+			assert.Equal(t, test.size, size)
+		})
+	}
+}
@@ -0,0 +1,51 @@
+package sqlite
+
+import (
+	"database/sql"
+	"errors"
+	"strings"
+
+	// registers the pure go sqlite database/sql driver
+	_ "modernc.org/sqlite"
+
+	"github.com/hellofresh/klepto/pkg/reader"
+	"github.com/hellofresh/klepto/pkg/reader/engine"
+)
+
+// scheme is the scheme of the sqlite dsn, e.g. `sqlite:///path/to/file.db`.
+const scheme = "sqlite://"
+
+type driver struct{}
+
+// IsSupported checks if the given dsn connection string is supported.
+func (m *driver) IsSupported(dsn string) bool {
+	return strings.HasPrefix(strings.ToLower(dsn), scheme)
+}
+
+// NewConnection opens the sqlite database file read only and retrieves a new sqlite reader.
+func (m *driver) NewConnection(opts reader.ConnOpts) (reader.Reader, error) {
+	path, params, _ := strings.Cut(opts.DSN[len(scheme):], "?")
+	if path == "" {
+		return nil, errors.New("sqlite database path can not be empty")
+	}
+
+	dsn := "file:" + path + "?mode=ro"
+	if params != "" {
+		dsn += "&" + params
+	}
+
+	conn, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		return nil, err
+	}
+
+	conn.SetMaxOpenConns(opts.MaxConns)
+	conn.SetMaxIdleConns(opts.MaxIdleConns)
+	conn.SetConnMaxLifetime(opts.MaxConnLifetime)
+
+	return NewStorage(conn, engine.NewOpts(opts)), nil
+}
+
+func init() {
+	reader.Register("sqlite", &driver{})
+}
@@ -0,0 +1,73 @@
+package reader
+
+import "strings"
+
+// DefaultSchema is the schema of the tables named without their schema, e.g. `users` is `public.users`.
+const DefaultSchema = "public"
+
+// TableName returns the name of a table, qualified with its schema unless it is in the public schema.
+// The names holding a dot or a double quote are written in the quoted form, e.g. `"public"."audit.log"`,
+// so they are not mistaken for a schema and a table.
+func TableName(schema, name string) string {
+	if strings.ContainsAny(schema, `."`) || strings.ContainsAny(name, `."`) {
+		return quoteName(schema) + "." + quoteName(name)
+	}
+	if schema == DefaultSchema {
+		return name
+	}
+
+	return schema + "." + name
+}
+
+// SplitTableName returns the schema and the name of a table, e.g. `billing` and `invoices` for `billing.invoices`.
+// The quoted parts are unquoted, e.g. `public` and `audit.log` for `"public"."audit.log"`.
+func SplitTableName(table string) (schema, name string) {
+	if first, rest, ok := cutQuotedName(table); ok {
+		if rest == "" {
+			return DefaultSchema, first
+		}
+		if after, found := strings.CutPrefix(rest, "."); found {
+			if second, rest, ok := cutQuotedName(after); ok && rest == "" {
+				return first, second
+			}
+			if !strings.HasPrefix(after, `"`) {
+				return first, after
+			}
+		}
+	}
+
+	if schema, name, ok := strings.Cut(table, "."); ok {
+		return schema, name
+	}
+
+	return DefaultSchema, table
+}
+
+// quoteName quotes a name with double quotes, the double quotes it holds are doubled.
+func quoteName(name string) string {
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
+
+// cutQuotedName returns the unquoted name starting the string and the rest of the string,
+// ok is false when the string does not start with a quoted name.
+func cutQuotedName(s string) (name, rest string, ok bool) {
+	if !strings.HasPrefix(s, `"`) {
+		return "", s, false
+	}
+
+	var b strings.Builder
+	for i := 1; i < len(s); i++ {
+		if s[i] != '"' {
+			b.WriteByte(s[i])
+			continue
+		}
+		if i+1 < len(s) && s[i+1] == '"' {
+			b.WriteByte('"')
+			i++
+			continue
+		}
+		return b.String(), s[i+1:], true
+	}
+
+	return "", s, false
+}
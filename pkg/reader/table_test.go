@@ -0,0 +1,61 @@
+package reader
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTableName(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		scenario string
+		schema   string
+		name     string
+		table    string
+	}{
+		{scenario: "when the table is in the public schema", schema: "public", name: "users", table: "users"},
+		{scenario: "when the table is in another schema", schema: "billing", name: "invoices", table: "billing.invoices"},
+		{scenario: "when the table name holds a dot", schema: "public", name: "audit.log", table: `"public"."audit.log"`},
+		{scenario: "when the schema name holds a dot", schema: "v1.2", name: "users", table: `"v1.2"."users"`},
+		{scenario: "when the table name holds a quote", schema: "billing", name: `a"b`, table: `"billing"."a""b"`},
+	}
+
+	for _, test := range tests {
+		t.Run(test.scenario, func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, test.table, TableName(test.schema, test.name))
+
+			schema, name := SplitTableName(test.table)
+			assert.Equal(t, test.schema, schema)
+			assert.Equal(t, test.name, name)
+		})
+	}
+}
+
+func TestSplitTableName(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		scenario string
+		table    string
+		schema   string
+		name     string
+	}{
+		{scenario: "when the table name is quoted", table: `"users"`, schema: "public", name: "users"},
+		{scenario: "when only the schema is quoted", table: `"billing".invoices`, schema: "billing", name: "invoices"},
+		{scenario: "when the quote is not closed", table: `"billing.invoices`, schema: `"billing`, name: "invoices"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.scenario, func(t *testing.T) {
+			t.Parallel()
+
+			schema, name := SplitTableName(test.table)
+			assert.Equal(t, test.schema, schema)
+			assert.Equal(t, test.name, name)
+		})
+	}
+}
@@ -0,0 +1,330 @@
+package report
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/hellofresh/klepto/pkg/config"
+	"github.com/hellofresh/klepto/pkg/dumper"
+	"github.com/hellofresh/klepto/pkg/progress"
+	"github.com/hellofresh/klepto/pkg/subset"
+)
+
+// Report formats.
+const (
+	FormatJSON = "json"
+	FormatText = "text"
+)
+
+// Statuses of a steal.
+const (
+	StatusSucceeded   = "succeeded"
+	StatusFailed      = "failed"
+	StatusInterrupted = "interrupted"
+)
+
+// Statuses of a table.
+const (
+	// TableStolen is the status of the tables which rows were written to the target.
+	TableStolen = "stolen"
+	// TableFailed is the status of the tables which failed to be read or dumped, their rows were rolled back.
+	TableFailed = "failed"
+	// TableIgnored is the status of the tables which data is ignored by the config.
+	TableIgnored = "ignored"
+	// TableNotStolen is the status of the tables not stolen because the steal stopped, their rows were rolled back.
+	TableNotStolen = "not_stolen"
+)
+
+type (
+	// Report describes what a steal took from the source database and how it was anonymised.
+	Report struct {
+		// StartedAt is the time the steal started.
+		StartedAt time.Time `json:"started_at"`
+		// FinishedAt is the time the steal finished.
+		FinishedAt time.Time `json:"finished_at"`
+		// Duration is the steal duration in seconds.
+		Duration float64 `json:"duration_seconds"`
+		// Status is the steal status.
+		Status string `json:"status"`
+		// Error is the error the steal failed with.
+		Error string `json:"error,omitempty"`
+		// Subset is true when the rows were restricted to a subset of the seed tables.
+		Subset bool `json:"subset"`
+		// SubsetChildren is true when the subset also pulled the rows referencing the seed rows.
+		SubsetChildren bool `json:"subset_children,omitempty"`
+		// Tables are the source database tables.
+		Tables []*Table `json:"tables"`
+	}
+
+	// Table describes what a steal took from a table and how it was anonymised.
+	Table struct {
+		// Name is the table name.
+		Name string `json:"name"`
+		// Status is the table status.
+		Status string `json:"status"`
+		// RowsRead is the number of rows read from the source.
+		RowsRead int64 `json:"rows_read"`
+		// RowsWritten is the number of rows written to the target.
+		RowsWritten int64 `json:"rows_written"`
+		// Duration is the time spent stealing the table in seconds.
+		Duration float64 `json:"duration_seconds"`
+		// Filter is the filter applied to the table rows.
+		Filter *Filter `json:"filter,omitempty"`
+		// Anonymised are the anonymised columns.
+		Anonymised []*Column `json:"anonymised,omitempty"`
+		// Error is the error the table failed with.
+		Error string `json:"error,omitempty"`
+	}
+
+	// Filter is the filter applied to the table rows.
+	Filter struct {
+		// Seed is true when the table rows matching the filter are the starting point of the subset.
+		Seed bool `json:"seed,omitempty"`
+		// Reached is true when the rows are the subset rows reached from the seed rows through the foreign keys and
+		// the relationships, the filter of the table config is not applied.
+		Reached bool `json:"reached,omitempty"`
+		// Match is the condition the rows match.
+		Match string `json:"match,omitempty"`
+		// Limit is the maximum number of rows.
+		Limit uint64 `json:"limit,omitempty"`
+		// Sorts is the rows sort.
+		Sorts map[string]string `json:"sorts,omitempty"`
+		// Relationships are the tables joined to match the rows.
+		Relationships []string `json:"relationships,omitempty"`
+	}
+
+	// Column is an anonymised column.
+	Column struct {
+		// Name is the column name.
+		Name string `json:"name"`
+		// Anonymiser is the anonymiser the column values were replaced with.
+		Anonymiser string `json:"anonymiser"`
+	}
+)
+
+// New builds the report of a steal from the tables progress and the steal error. When the config has seed tables,
+// the filters reported are the ones of the subset.
+func New(startedAt time.Time, tables []string, cfgTables config.Tables, subsetOpts subset.Opts, tracker *progress.Tracker, err error) *Report {
+	finishedAt := time.Now()
+	r := &Report{
+		StartedAt:  startedAt,
+		FinishedAt: finishedAt,
+		Duration:   finishedAt.Sub(startedAt).Seconds(),
+		Status:     StatusSucceeded,
+		Subset:     len(cfgTables.Seeds()) > 0,
+	}
+	r.SubsetChildren = r.Subset && subsetOpts.Children
+
+	switch {
+	case errors.Is(err, context.Canceled):
+		r.Status = StatusInterrupted
+		r.Error = err.Error()
+	case err != nil:
+		r.Status = StatusFailed
+		r.Error = err.Error()
+	}
+
+	stats := make(map[string]progress.Stats)
+	for _, s := range tracker.Stats() {
+		stats[s.Table] = s
+	}
+
+	failures := make(map[string]error)
+	for _, e := range dumper.TableErrors(err) {
+		failures[e.Table] = e.Err
+	}
+
+	for _, name := range tables {
+		table := &Table{Name: name, Status: TableNotStolen}
+		if s, ok := stats[name]; ok {
+			table.RowsRead = s.Read
+			table.RowsWritten = s.Written
+			table.Duration = s.Elapsed.Seconds()
+			if s.Done && !s.Failed {
+				table.Status = TableStolen
+			}
+		}
+
+		if tableErr, ok := failures[name]; ok {
+			table.Status = TableFailed
+			table.Error = tableErr.Error()
+		}
+
+		cfg := cfgTables.FindByName(name)
+		if cfg != nil {
+			if cfg.IgnoreData {
+				table.Status = TableIgnored
+			}
+			table.Filter = newFilter(cfg)
+			table.Anonymised = newColumns(cfg)
+		}
+
+		// the subset only applies the filters of the seed tables, the rows of the other tables are the rows reached
+		if r.Subset && table.Status != TableIgnored && (cfg == nil || !cfg.Seed) {
+			table.Filter = &Filter{Reached: true}
+		}
+
+		r.Tables = append(r.Tables, table)
+	}
+
+	return r
+}
+
+// WriteFile writes the report to the file in the given format.
+func (r *Report) WriteFile(path string, format string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create report: %w", err)
+	}
+
+	if err := r.Write(f, format); err != nil {
+		f.Close()
+		return err
+	}
+
+	return f.Close()
+}
+
+// Write writes the report in the given format.
+func (r *Report) Write(w io.Writer, format string) error {
+	switch format {
+	case FormatJSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(r)
+	case FormatText:
+		return r.writeText(w)
+	default:
+		return fmt.Errorf("unsupported report format %q", format)
+	}
+}
+
+// writeText writes a human readable summary of the report.
+func (r *Report) writeText(w io.Writer) error {
+	fmt.Fprintf(w, "Steal %s in %s (started at %s)\n", r.Status, time.Duration(r.Duration*float64(time.Second)).Round(time.Millisecond), r.StartedAt.Format(time.RFC3339))
+	switch {
+	case r.SubsetChildren:
+		fmt.Fprintln(w, "The rows were restricted to a subset of the seed tables, with the rows they reference and the rows referencing them")
+	case r.Subset:
+		fmt.Fprintln(w, "The rows were restricted to a subset of the seed tables, with the rows they reference")
+	}
+	if r.Error != "" {
+		fmt.Fprintf(w, "Error: %s\n", r.Error)
+	}
+	fmt.Fprintln(w)
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "TABLE\tSTATUS\tREAD\tWRITTEN\tDURATION\tFILTER\tANONYMISED")
+	for _, t := range r.Tables {
+		fmt.Fprintf(
+			tw,
+			"%s\t%s\t%d\t%d\t%s\t%s\t%s\n",
+			t.Name,
+			t.Status,
+			t.RowsRead,
+			t.RowsWritten,
+			time.Duration(t.Duration*float64(time.Second)).Round(time.Millisecond),
+			t.Filter,
+			formatColumns(t.Anonymised),
+		)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	for _, t := range r.Tables {
+		if t.Error != "" {
+			fmt.Fprintf(w, "\n%s: %s", t.Name, t.Error)
+		}
+	}
+	_, err := fmt.Fprintln(w)
+
+	return err
+}
+
+// String returns a short description of the filter.
+func (f *Filter) String() string {
+	if f == nil {
+		return "-"
+	}
+
+	var parts []string
+	if f.Seed {
+		parts = append(parts, "seed")
+	}
+	if f.Reached {
+		parts = append(parts, "reached from the seeds")
+	}
+	if f.Match != "" {
+		parts = append(parts, "match "+f.Match)
+	}
+	if len(f.Relationships) > 0 {
+		parts = append(parts, "join "+strings.Join(f.Relationships, ", "))
+	}
+	if len(f.Sorts) > 0 {
+		sorts := make([]string, 0, len(f.Sorts))
+		for column, order := range f.Sorts {
+			sorts = append(sorts, column+" "+order)
+		}
+		sort.Strings(sorts)
+		parts = append(parts, "sort "+strings.Join(sorts, ", "))
+	}
+	if f.Limit > 0 {
+		parts = append(parts, fmt.Sprintf("limit %d", f.Limit))
+	}
+
+	return strings.Join(parts, "; ")
+}
+
+// newFilter returns the filter of the table config, nil when the rows are not filtered.
+func newFilter(cfg *config.Table) *Filter {
+	f := &Filter{
+		Seed:  cfg.Seed,
+		Match: cfg.Filter.Match,
+		Limit: cfg.Filter.Limit,
+		Sorts: cfg.Filter.Sorts,
+	}
+	for _, r := range cfg.Relationships {
+		f.Relationships = append(f.Relationships, r.ReferencedTable)
+	}
+
+	if !f.Seed && f.Match == "" && f.Limit == 0 && len(f.Sorts) == 0 && len(f.Relationships) == 0 {
+		return nil
+	}
+
+	return f
+}
+
+// newColumns returns the anonymised columns of the table config, sorted by name.
+func newColumns(cfg *config.Table) []*Column {
+	var columns []*Column
+	for name, anonymiser := range cfg.Anonymise {
+		columns = append(columns, &Column{Name: name, Anonymiser: anonymiser})
+	}
+
+	sort.Slice(columns, func(i, j int) bool { return columns[i].Name < columns[j].Name })
+
+	return columns
+}
+
+// formatColumns returns a short description of the anonymised columns.
+func formatColumns(columns []*Column) string {
+	if len(columns) == 0 {
+		return "-"
+	}
+
+	formatted := make([]string, len(columns))
+	for i, c := range columns {
+		formatted[i] = c.Name + "=" + c.Anonymiser
+	}
+
+	return strings.Join(formatted, ", ")
+}
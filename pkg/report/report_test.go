@@ -0,0 +1,135 @@
+package report
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/hellofresh/klepto/pkg/config"
+	"github.com/hellofresh/klepto/pkg/dumper"
+	"github.com/hellofresh/klepto/pkg/progress"
+	"github.com/hellofresh/klepto/pkg/subset"
+)
+
+func TestNew(t *testing.T) {
+	t.Parallel()
+
+	cfgTables := config.Tables{
+		{
+			Name:      "users",
+			Filter:    config.Filter{Match: "active = true", Limit: 10},
+			Anonymise: map[string]string{"name": "FirstName", "email": "EmailAddress"},
+		},
+		{Name: "logs", IgnoreData: true},
+	}
+
+	tracker := progress.NewTracker()
+	users := tracker.Start("users")
+	users.AddRead(10)
+	users.AddWritten(10)
+	users.Done()
+	tracker.Start("orders").Fail()
+
+	tests := []struct {
+		scenario string
+		err      error
+		status   string
+		statuses map[string]string
+	}{
+		{
+			scenario: "when the steal succeeds",
+			status:   StatusSucceeded,
+			statuses: map[string]string{"users": TableStolen, "orders": TableNotStolen, "logs": TableIgnored, "payments": TableNotStolen},
+		},
+		{
+			scenario: "when a table fails",
+			err:      fmt.Errorf("failed to steal: %w", errors.Join(&dumper.TableError{Table: "orders", Err: errors.New("read failed")})),
+			status:   StatusFailed,
+			statuses: map[string]string{"users": TableStolen, "orders": TableFailed, "logs": TableIgnored, "payments": TableNotStolen},
+		},
+		{
+			scenario: "when the steal is interrupted",
+			err:      fmt.Errorf("steal interrupted: %w", context.Canceled),
+			status:   StatusInterrupted,
+			statuses: map[string]string{"users": TableStolen, "orders": TableNotStolen, "logs": TableIgnored, "payments": TableNotStolen},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.scenario, func(t *testing.T) {
+			r := New(time.Now(), []string{"users", "orders", "logs", "payments"}, cfgTables, subset.Opts{}, tracker, test.err)
+
+			assert.Equal(t, test.status, r.Status)
+			require.Len(t, r.Tables, 4)
+			for _, table := range r.Tables {
+				assert.Equal(t, test.statuses[table.Name], table.Status, table.Name)
+			}
+
+			assert.Equal(t, int64(10), r.Tables[0].RowsWritten)
+			assert.Equal(t, &Filter{Match: "active = true", Limit: 10}, r.Tables[0].Filter)
+			assert.Equal(t, []*Column{{Name: "email", Anonymiser: "EmailAddress"}, {Name: "name", Anonymiser: "FirstName"}}, r.Tables[0].Anonymised)
+			assert.Nil(t, r.Tables[2].Filter)
+		})
+	}
+}
+
+func TestNewSubset(t *testing.T) {
+	t.Parallel()
+
+	cfgTables := config.Tables{
+		{Name: "orders", Seed: true, Filter: config.Filter{Match: "id = 1"}},
+		{Name: "users", Filter: config.Filter{Match: "active = true", Limit: 10}},
+		{Name: "logs", IgnoreData: true},
+	}
+
+	tracker := progress.NewTracker()
+	users := tracker.Start("users")
+	users.AddRead(3)
+	users.AddWritten(3)
+	users.Done()
+
+	r := New(time.Now(), []string{"orders", "users", "logs", "countries"}, cfgTables, subset.Opts{Children: true}, tracker, nil)
+
+	assert.True(t, r.Subset)
+	assert.True(t, r.SubsetChildren)
+	require.Len(t, r.Tables, 4)
+	assert.Equal(t, &Filter{Seed: true, Match: "id = 1"}, r.Tables[0].Filter)
+	assert.Equal(t, &Filter{Reached: true}, r.Tables[1].Filter)
+	assert.Equal(t, int64(3), r.Tables[1].RowsRead)
+	assert.Nil(t, r.Tables[2].Filter)
+	assert.Equal(t, &Filter{Reached: true}, r.Tables[3].Filter)
+}
+
+func TestWrite(t *testing.T) {
+	t.Parallel()
+
+	r := &Report{
+		Status: StatusFailed,
+		Tables: []*Table{
+			{Name: "users", Status: TableStolen, RowsRead: 10, RowsWritten: 10, Filter: &Filter{Limit: 10}},
+			{Name: "orders", Status: TableFailed, Error: "read failed"},
+		},
+	}
+
+	var out bytes.Buffer
+	require.NoError(t, r.Write(&out, FormatJSON))
+
+	var decoded Report
+	require.NoError(t, json.Unmarshal(out.Bytes(), &decoded))
+	assert.Equal(t, r.Tables, decoded.Tables)
+
+	out.Reset()
+	require.NoError(t, r.Write(&out, FormatText))
+	assert.Contains(t, out.String(), "Steal failed")
+	assert.Regexp(t, `users\s+stolen\s+10\s+10\s+0s\s+limit 10\s+-`, out.String())
+	assert.Contains(t, out.String(), "orders: read failed")
+
+	assert.Error(t, r.Write(&out, "xml"))
+}
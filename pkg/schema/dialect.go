@@ -0,0 +1,280 @@
+package schema
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	// mysqlMaxPrecision is the maximum number of digits of the mysql decimals.
+	mysqlMaxPrecision = 65
+	// mysqlMaxScale is the maximum number of digits after the decimal point of the mysql decimals.
+	mysqlMaxScale = 30
+	// mysqlUnlimitedScale is the number of digits after the decimal point of the decimals of unlimited precision.
+	mysqlUnlimitedScale = 10
+	// mysqlMaxVarchar is the maximum length of the mysql varchar columns in utf8mb4.
+	mysqlMaxVarchar = 16383
+	// mysqlKeyLength is the length of the unlimited columns used in keys, mysql can only index the columns of a known length.
+	mysqlKeyLength = 255
+)
+
+type (
+	mysqlRenderer    struct{}
+	postgresRenderer struct{}
+	sqliteRenderer   struct{}
+)
+
+var (
+	mysqlEscaper    = strings.NewReplacer(`\`, `\\`, `'`, `''`)
+	standardEscaper = strings.NewReplacer(`'`, `''`)
+)
+
+func (mysqlRenderer) quote(name string) string {
+	return "`" + strings.ReplaceAll(name, "`", "``") + "`"
+}
+
+// columnType returns the mysql column type. Times keep their microseconds and times with time zone are written in UTC.
+func (mysqlRenderer) columnType(t Type, key bool) string {
+	switch t.Name {
+	case TypeSmallInt:
+		return "SMALLINT"
+	case TypeInteger:
+		return "INT"
+	case TypeBigInt:
+		return "BIGINT"
+	case TypeReal:
+		return "FLOAT"
+	case TypeDouble:
+		return "DOUBLE"
+	case TypeDecimal:
+		switch {
+		case t.Precision == 0:
+			return fmt.Sprintf("DECIMAL(%d,%d)", mysqlMaxPrecision, mysqlUnlimitedScale)
+		case t.Precision > mysqlMaxPrecision:
+			return fmt.Sprintf("DECIMAL(%d,%d)", mysqlMaxPrecision, min(t.Scale, mysqlMaxScale))
+		default:
+			return fmt.Sprintf("DECIMAL(%d,%d)", t.Precision, min(t.Scale, mysqlMaxScale))
+		}
+	case TypeBoolean:
+		return "BOOLEAN"
+	case TypeChar:
+		if t.Length <= 255 {
+			return fmt.Sprintf("CHAR(%d)", t.Length)
+		}
+		return fmt.Sprintf("VARCHAR(%d)", t.Length)
+	case TypeVarchar, TypeText:
+		switch {
+		case t.Length > 0 && t.Length <= mysqlMaxVarchar:
+			return fmt.Sprintf("VARCHAR(%d)", t.Length)
+		case key:
+			return fmt.Sprintf("VARCHAR(%d)", mysqlKeyLength)
+		default:
+			return "LONGTEXT"
+		}
+	case TypeBinary:
+		switch {
+		case t.Length > 0 && t.Length <= mysqlMaxVarchar:
+			return fmt.Sprintf("VARBINARY(%d)", t.Length)
+		case key:
+			return fmt.Sprintf("VARBINARY(%d)", mysqlKeyLength)
+		default:
+			return "LONGBLOB"
+		}
+	case TypeDate:
+		return "DATE"
+	case TypeTime:
+		return "TIME(6)"
+	case TypeTimestamp, TypeTimestampTZ:
+		return "DATETIME(6)"
+	case TypeJSON:
+		return "JSON"
+	case TypeUUID:
+		return "CHAR(36)"
+	}
+
+	return "LONGTEXT"
+}
+
+func (mysqlRenderer) identity(t Type) string {
+	if !t.isInteger() {
+		return ""
+	}
+	return "AUTO_INCREMENT"
+}
+
+// literal returns the mysql default, the text, binary and JSON columns can't have literal defaults.
+func (r mysqlRenderer) literal(d *Default, t Type) string {
+	switch {
+	case d == nil:
+		return ""
+	case d.CurrentTimestamp:
+		if t.Name == TypeTimestamp || t.Name == TypeTimestampTZ {
+			return "CURRENT_TIMESTAMP(6)"
+		}
+		return ""
+	case t.Name == TypeBoolean:
+		return booleanLiteral(d.Value, "1", "0")
+	case t.isNumeric():
+		return numberLiteral(d.Value)
+	case t.Name == TypeJSON || t.Name == TypeBinary || r.columnType(t, false) == "LONGTEXT":
+		return ""
+	}
+
+	return "'" + mysqlEscaper.Replace(d.Value) + "'"
+}
+
+func (mysqlRenderer) inlineForeignKeys() bool { return false }
+
+func (postgresRenderer) quote(name string) string {
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
+
+func (postgresRenderer) columnType(t Type, _ bool) string {
+	switch t.Name {
+	case TypeSmallInt:
+		return "smallint"
+	case TypeInteger:
+		return "integer"
+	case TypeBigInt:
+		return "bigint"
+	case TypeReal:
+		return "real"
+	case TypeDouble:
+		return "double precision"
+	case TypeDecimal:
+		if t.Precision == 0 {
+			return "numeric"
+		}
+		return fmt.Sprintf("numeric(%d,%d)", t.Precision, t.Scale)
+	case TypeBoolean:
+		return "boolean"
+	case TypeChar:
+		return fmt.Sprintf("character(%d)", t.Length)
+	case TypeVarchar:
+		if t.Length == 0 {
+			return "character varying"
+		}
+		return fmt.Sprintf("character varying(%d)", t.Length)
+	case TypeBinary:
+		return "bytea"
+	case TypeDate:
+		return "date"
+	case TypeTime:
+		return "time"
+	case TypeTimestamp:
+		return "timestamp"
+	case TypeTimestampTZ:
+		return "timestamp with time zone"
+	case TypeJSON:
+		return "jsonb"
+	case TypeUUID:
+		return "uuid"
+	}
+
+	return "text"
+}
+
+func (postgresRenderer) identity(t Type) string {
+	if !t.isInteger() {
+		return ""
+	}
+	return "GENERATED BY DEFAULT AS IDENTITY"
+}
+
+func (postgresRenderer) literal(d *Default, t Type) string {
+	switch {
+	case d == nil:
+		return ""
+	case d.CurrentTimestamp:
+		switch t.Name {
+		case TypeTimestamp, TypeTimestampTZ:
+			return "CURRENT_TIMESTAMP"
+		case TypeDate:
+			return "CURRENT_DATE"
+		}
+		return ""
+	case t.Name == TypeBoolean:
+		return booleanLiteral(d.Value, "TRUE", "FALSE")
+	case t.isNumeric():
+		return numberLiteral(d.Value)
+	case t.Name == TypeBinary:
+		return ""
+	}
+
+	return "'" + standardEscaper.Replace(d.Value) + "'"
+}
+
+func (postgresRenderer) inlineForeignKeys() bool { return false }
+
+func (sqliteRenderer) quote(name string) string {
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
+
+// columnType returns the sqlite type, chosen for the sqlite affinity of the type.
+func (sqliteRenderer) columnType(t Type, _ bool) string {
+	switch t.Name {
+	case TypeSmallInt, TypeInteger, TypeBigInt:
+		return "INTEGER"
+	case TypeReal, TypeDouble:
+		return "REAL"
+	case TypeDecimal:
+		return "NUMERIC"
+	case TypeBoolean:
+		return "BOOLEAN"
+	case TypeBinary:
+		return "BLOB"
+	case TypeDate:
+		return "DATE"
+	case TypeTime:
+		return "TIME"
+	case TypeTimestamp, TypeTimestampTZ:
+		return "DATETIME"
+	}
+
+	return "TEXT"
+}
+
+// identity returns nothing, an integer primary key is an alias of the rowid which is generated when not set.
+func (sqliteRenderer) identity(Type) string {
+	return ""
+}
+
+func (sqliteRenderer) literal(d *Default, t Type) string {
+	switch {
+	case d == nil:
+		return ""
+	case d.CurrentTimestamp:
+		return "CURRENT_TIMESTAMP"
+	case t.Name == TypeBoolean:
+		return booleanLiteral(d.Value, "1", "0")
+	case t.isNumeric():
+		return numberLiteral(d.Value)
+	case t.Name == TypeBinary:
+		return ""
+	}
+
+	return "'" + standardEscaper.Replace(d.Value) + "'"
+}
+
+// inlineForeignKeys is true, sqlite can't add constraints to existing tables.
+func (sqliteRenderer) inlineForeignKeys() bool { return true }
+
+// booleanLiteral returns the literal of a boolean default, empty when it is not a boolean.
+func booleanLiteral(value string, trueLiteral string, falseLiteral string) string {
+	switch strings.ToLower(value) {
+	case "1", "true", "t", "b'1'":
+		return trueLiteral
+	case "0", "false", "f", "b'0'":
+		return falseLiteral
+	}
+
+	return ""
+}
+
+// numberLiteral returns the literal of a numeric default, empty when it is not a number.
+func numberLiteral(value string) string {
+	if !numberRegexp.MatchString(value) {
+		return ""
+	}
+	return value
+}
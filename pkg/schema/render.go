@@ -0,0 +1,161 @@
+package schema
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/hellofresh/klepto/pkg/reader"
+)
+
+type (
+	// renderer writes the SQL of a dialect.
+	renderer interface {
+		// quote quotes a table, column or constraint name.
+		quote(name string) string
+		// columnType returns the column type, key is true when the column is part of a key, index or foreign key.
+		columnType(t Type, key bool) string
+		// identity returns the clause of an identity column, empty when the column type can't be an identity.
+		identity(t Type) string
+		// literal returns the default literal of the column type, empty when the type can't have the default.
+		literal(d *Default, t Type) string
+		// inlineForeignKeys is true when the foreign keys are created with the tables as they can't be added later.
+		inlineForeignKeys() bool
+	}
+
+	// names makes the constraint and index names unique in the database.
+	names map[string]bool
+)
+
+// Render returns the statements creating the tables of the schema in the dialect, followed by their indexes
+// and foreign keys. The foreign keys are added once all the tables are created, so the tables can be created in any order.
+func Render(s *Schema, dialect string) ([]string, error) {
+	var r renderer
+	switch dialect {
+	case MySQL:
+		r = mysqlRenderer{}
+	case Postgres:
+		r = postgresRenderer{}
+	case SQLite:
+		r = sqliteRenderer{}
+	default:
+		return nil, fmt.Errorf("unsupported dialect %q", dialect)
+	}
+
+	var (
+		tables      []string
+		indexes     []string
+		foreignKeys []string
+		used        = make(names)
+	)
+	// the tables share the namespace of the indexes in postgres and sqlite
+	for _, table := range s.Tables {
+		used[strings.ToLower(table.Name)] = true
+	}
+	for _, table := range s.Tables {
+		tables = append(tables, createTable(r, table, used))
+
+		for _, index := range table.Indexes {
+			indexes = append(indexes, fmt.Sprintf(
+				"CREATE INDEX %s ON %s (%s)",
+				r.quote(used.unique(table.Name, index.Name)), r.quote(table.Name), quoteAll(r, index.Columns),
+			))
+		}
+
+		if r.inlineForeignKeys() {
+			continue
+		}
+		for _, fk := range table.ForeignKeys {
+			foreignKeys = append(foreignKeys, fmt.Sprintf(
+				"ALTER TABLE %s ADD %s", r.quote(table.Name), foreignKey(r, fk, used),
+			))
+		}
+	}
+
+	return append(append(tables, indexes...), foreignKeys...), nil
+}
+
+// createTable returns the statement creating the table, with its primary and unique keys.
+func createTable(r renderer, table *Table, used names) string {
+	keyColumns := table.keyColumns()
+
+	var definitions []string
+	for _, c := range table.Columns {
+		definition := r.quote(c.Name) + " " + r.columnType(c.Type, keyColumns[c.Name])
+		if !c.Nullable {
+			definition += " NOT NULL"
+		}
+		if literal := r.literal(c.Default, c.Type); literal != "" {
+			definition += " DEFAULT " + literal
+		}
+		if c.Identity && canBeIdentity(table, c.Name) {
+			if identity := r.identity(c.Type); identity != "" {
+				definition += " " + identity
+			}
+		}
+
+		definitions = append(definitions, definition)
+	}
+
+	if table.PrimaryKey != nil {
+		definitions = append(definitions, "PRIMARY KEY ("+quoteAll(r, table.PrimaryKey.Columns)+")")
+	}
+	for _, key := range table.UniqueKeys {
+		definitions = append(definitions, fmt.Sprintf(
+			"CONSTRAINT %s UNIQUE (%s)", r.quote(used.unique(table.Name, key.Name)), quoteAll(r, key.Columns),
+		))
+	}
+	if r.inlineForeignKeys() {
+		for _, fk := range table.ForeignKeys {
+			definitions = append(definitions, foreignKey(r, fk, used))
+		}
+	}
+
+	return fmt.Sprintf("CREATE TABLE %s (\n  %s\n)", r.quote(table.Name), strings.Join(definitions, ",\n  "))
+}
+
+// foreignKey returns the foreign key constraint definition.
+func foreignKey(r renderer, fk *reader.ForeignKey, used names) string {
+	return fmt.Sprintf(
+		"CONSTRAINT %s FOREIGN KEY (%s) REFERENCES %s (%s)",
+		r.quote(used.unique(fk.Table, fk.Name)), quoteAll(r, fk.Columns), r.quote(fk.ReferencedTable), quoteAll(r, fk.ReferencedColumns),
+	)
+}
+
+// canBeIdentity returns whether the column is the first column of the primary key or of a unique key,
+// as mysql requires auto increment columns to be indexed.
+func canBeIdentity(table *Table, column string) bool {
+	if table.PrimaryKey != nil && table.PrimaryKey.Columns[0] == column {
+		return true
+	}
+	for _, key := range table.UniqueKeys {
+		if key.Columns[0] == column {
+			return true
+		}
+	}
+
+	return false
+}
+
+// unique returns the name, prefixed with the table name when it is already used by another table,
+// e.g. the mysql `PRIMARY` or index names which are only unique per table.
+func (n names) unique(table string, name string) string {
+	candidate := name
+	if n[strings.ToLower(candidate)] {
+		candidate = table + "_" + name
+	}
+	for i := 2; n[strings.ToLower(candidate)]; i++ {
+		candidate = fmt.Sprintf("%s_%s_%d", table, name, i)
+	}
+
+	n[strings.ToLower(candidate)] = true
+	return candidate
+}
+
+func quoteAll(r renderer, names []string) string {
+	quoted := make([]string, len(names))
+	for i, name := range names {
+		quoted[i] = r.quote(name)
+	}
+
+	return strings.Join(quoted, ", ")
+}
@@ -0,0 +1,216 @@
+// Package schema translates the structure of a database to the SQL dialect of another database engine.
+// The structure is read from the reader metadata into a database independent model, which is rendered
+// as the statements creating the tables in the target dialect.
+package schema
+
+import (
+	"fmt"
+	"net/url"
+	"slices"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/hellofresh/klepto/pkg/reader"
+)
+
+// SQL dialects of the database engines.
+const (
+	MySQL    = "mysql"
+	Postgres = "postgres"
+	SQLite   = "sqlite"
+)
+
+type (
+	// Schema is the database independent structure of a database.
+	Schema struct {
+		// Tables are the database tables, sorted by name.
+		Tables []*Table
+	}
+
+	// Table is the database independent structure of a table.
+	Table struct {
+		// Name is the table name.
+		Name string
+		// Columns are the table columns, the generated columns left out.
+		Columns []*Column
+		// PrimaryKey is the table primary key, nil when the table has none.
+		PrimaryKey *reader.Key
+		// UniqueKeys are the unique keys, other than the primary key.
+		UniqueKeys []*reader.Key
+		// Indexes are the non unique indexes.
+		Indexes []*reader.Key
+		// ForeignKeys are the foreign keys defined on the table.
+		ForeignKeys []*reader.ForeignKey
+	}
+
+	// Column is the database independent structure of a column.
+	Column struct {
+		// Name is the column name.
+		Name string
+		// Type is the column type.
+		Type Type
+		// Nullable is true when the column accepts null values.
+		Nullable bool
+		// Default is the column default, nil when the column has none or it can't be translated.
+		Default *Default
+		// Identity is true when the column value is generated by a sequence or an auto increment.
+		Identity bool
+	}
+
+	// Default is a column default value.
+	Default struct {
+		// Value is the unquoted literal value.
+		Value string
+		// CurrentTimestamp is true when the default is the current time, Value is then empty.
+		CurrentTimestamp bool
+	}
+)
+
+// DialectOf returns the dialect of a database dsn, empty when the dsn is empty.
+// The dump files, e.g. `file:///backups/prod.sql?dialect=postgres`, are in the dialect of their `dialect` param.
+func DialectOf(dsn string) string {
+	lower := strings.ToLower(dsn)
+	switch {
+	case lower == "":
+		return ""
+	case strings.HasPrefix(lower, "postgres://") || strings.HasPrefix(lower, "postgresql://"):
+		return Postgres
+	case strings.HasPrefix(lower, "sqlite://"):
+		return SQLite
+	case strings.HasPrefix(lower, "file://"):
+		_, rawParams, _ := strings.Cut(dsn, "?")
+		params, _ := url.ParseQuery(rawParams)
+		if dialect, err := ParseDialect(params.Get("dialect")); err == nil {
+			return dialect
+		}
+		return MySQL
+	default:
+		return MySQL
+	}
+}
+
+// ParseDialect returns the dialect of the given name, e.g. `postgresql` is the postgres dialect.
+func ParseDialect(name string) (string, error) {
+	switch strings.ToLower(name) {
+	case MySQL:
+		return MySQL, nil
+	case Postgres, "postgresql":
+		return Postgres, nil
+	case SQLite:
+		return SQLite, nil
+	}
+
+	return "", fmt.Errorf("unsupported dialect %q, expected %s, %s or %s", name, MySQL, Postgres, SQLite)
+}
+
+// Read reads the structure of the reader database, the dialect is the one of the reader database.
+// The generated columns are left out, as well as the keys and indexes using them.
+func Read(rdr reader.Reader, dialect string) (*Schema, error) {
+	tables, err := rdr.GetTables()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get tables: %w", err)
+	}
+
+	s := &Schema{}
+	for _, tableName := range slices.Sorted(slices.Values(tables)) {
+		table, err := readTable(rdr, dialect, tableName)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read table %s: %w", tableName, err)
+		}
+
+		s.Tables = append(s.Tables, table)
+	}
+
+	return s, nil
+}
+
+func readTable(rdr reader.Reader, dialect string, tableName string) (*Table, error) {
+	columns, err := rdr.GetColumns(tableName)
+	if err != nil {
+		return nil, err
+	}
+	primaryKey, err := rdr.GetPrimaryKey(tableName)
+	if err != nil {
+		return nil, err
+	}
+	uniqueKeys, err := rdr.GetUniqueKeys(tableName)
+	if err != nil {
+		return nil, err
+	}
+	foreignKeys, err := rdr.GetForeignKeys(tableName)
+	if err != nil {
+		return nil, err
+	}
+
+	var indexes []*reader.Key
+	if indexer, ok := rdr.(reader.IndexReader); ok {
+		if indexes, err = indexer.GetIndexes(tableName); err != nil {
+			return nil, err
+		}
+	}
+
+	table := &Table{Name: tableName}
+	generated := make(map[string]bool)
+	for _, c := range columns {
+		if c.Generated {
+			log.WithFields(log.Fields{"table": tableName, "column": c.Name}).
+				Warn("Generated columns can't be translated, the column is not created")
+			generated[c.Name] = true
+			continue
+		}
+
+		table.Columns = append(table.Columns, &Column{
+			Name:     c.Name,
+			Type:     typeOf(c, dialect),
+			Nullable: c.Nullable,
+			Default:  defaultOf(c, dialect, tableName),
+			Identity: c.Identity,
+		})
+	}
+
+	usesGenerated := func(columns []string) bool {
+		return slices.ContainsFunc(columns, func(name string) bool { return generated[name] })
+	}
+	if primaryKey != nil && !usesGenerated(primaryKey.Columns) {
+		table.PrimaryKey = primaryKey
+	}
+	for _, key := range uniqueKeys {
+		if !usesGenerated(key.Columns) {
+			table.UniqueKeys = append(table.UniqueKeys, key)
+		}
+	}
+	for _, index := range indexes {
+		if !usesGenerated(index.Columns) {
+			table.Indexes = append(table.Indexes, index)
+		}
+	}
+	for _, fk := range foreignKeys {
+		if !usesGenerated(fk.Columns) {
+			table.ForeignKeys = append(table.ForeignKeys, fk)
+		}
+	}
+
+	return table, nil
+}
+
+// keyColumns returns the columns of the table keys, indexes and foreign keys.
+func (t *Table) keyColumns() map[string]bool {
+	columns := make(map[string]bool)
+	keys := slices.Concat(t.UniqueKeys, t.Indexes)
+	if t.PrimaryKey != nil {
+		keys = append(keys, t.PrimaryKey)
+	}
+	for _, key := range keys {
+		for _, c := range key.Columns {
+			columns[c] = true
+		}
+	}
+	for _, fk := range t.ForeignKeys {
+		for _, c := range fk.Columns {
+			columns[c] = true
+		}
+	}
+
+	return columns
+}
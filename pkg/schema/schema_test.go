@@ -0,0 +1,259 @@
+package schema
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/hellofresh/klepto/pkg/reader"
+)
+
+func TestRender(t *testing.T) {
+	t.Parallel()
+
+	rdr := &mockReader{
+		tables: []string{"users", "orders"},
+		columns: map[string][]*reader.Column{
+			"users": {
+				{Name: "id", Type: "int", Identity: true, Unsigned: true},
+				{Name: "email", Type: "varchar", MaxLength: 255},
+				{Name: "active", Type: "boolean", Default: ptr("1")},
+				{Name: "lower_email", Type: "varchar", MaxLength: 255, Generated: true, Nullable: true},
+			},
+			"orders": {
+				{Name: "id", Type: "bigint", Identity: true},
+				{Name: "user_id", Type: "int", Unsigned: true},
+				{Name: "reference", Type: "text", Nullable: true},
+				{Name: "status", Type: "enum", Default: ptr("draft")},
+				{Name: "total", Type: "decimal", Precision: 10, Scale: 2, Default: ptr("0.00")},
+				{Name: "created_at", Type: "datetime", Default: ptr("CURRENT_TIMESTAMP")},
+			},
+		},
+		primaryKeys: map[string]*reader.Key{
+			"users":  {Name: "PRIMARY", Columns: []string{"id"}},
+			"orders": {Name: "PRIMARY", Columns: []string{"id"}},
+		},
+		uniqueKeys: map[string][]*reader.Key{
+			"users":  {{Name: "email", Columns: []string{"email"}}, {Name: "lower_email", Columns: []string{"lower_email"}}},
+			"orders": {{Name: "reference", Columns: []string{"reference"}}},
+		},
+		indexes: map[string][]*reader.Key{
+			"orders": {{Name: "fk_user_id", Columns: []string{"user_id"}}},
+		},
+		foreignKeys: map[string][]*reader.ForeignKey{
+			"orders": {{Name: "fk_user_id", Table: "orders", Columns: []string{"user_id"}, ReferencedTable: "users", ReferencedColumns: []string{"id"}}},
+		},
+	}
+
+	tests := []struct {
+		scenario   string
+		dialect    string
+		statements []string
+	}{
+		{
+			scenario: "when mysql is translated to postgres",
+			dialect:  Postgres,
+			statements: []string{
+				`CREATE TABLE "orders" (
+  "id" bigint NOT NULL GENERATED BY DEFAULT AS IDENTITY,
+  "user_id" bigint NOT NULL,
+  "reference" text,
+  "status" text NOT NULL DEFAULT 'draft',
+  "total" numeric(10,2) NOT NULL DEFAULT 0.00,
+  "created_at" timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY ("id"),
+  CONSTRAINT "reference" UNIQUE ("reference")
+)`,
+				`CREATE TABLE "users" (
+  "id" bigint NOT NULL GENERATED BY DEFAULT AS IDENTITY,
+  "email" character varying(255) NOT NULL,
+  "active" boolean NOT NULL DEFAULT TRUE,
+  PRIMARY KEY ("id"),
+  CONSTRAINT "email" UNIQUE ("email")
+)`,
+				`CREATE INDEX "fk_user_id" ON "orders" ("user_id")`,
+				`ALTER TABLE "orders" ADD CONSTRAINT "orders_fk_user_id" FOREIGN KEY ("user_id") REFERENCES "users" ("id")`,
+			},
+		},
+		{
+			scenario: "when mysql is translated to mysql",
+			dialect:  MySQL,
+			statements: []string{
+				"CREATE TABLE `orders` (\n" +
+					"  `id` BIGINT NOT NULL AUTO_INCREMENT,\n" +
+					"  `user_id` BIGINT NOT NULL,\n" +
+					"  `reference` VARCHAR(255),\n" +
+					"  `status` LONGTEXT NOT NULL,\n" +
+					"  `total` DECIMAL(10,2) NOT NULL DEFAULT 0.00,\n" +
+					"  `created_at` DATETIME(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6),\n" +
+					"  PRIMARY KEY (`id`),\n" +
+					"  CONSTRAINT `reference` UNIQUE (`reference`)\n" +
+					")",
+				"CREATE TABLE `users` (\n" +
+					"  `id` BIGINT NOT NULL AUTO_INCREMENT,\n" +
+					"  `email` VARCHAR(255) NOT NULL,\n" +
+					"  `active` BOOLEAN NOT NULL DEFAULT 1,\n" +
+					"  PRIMARY KEY (`id`),\n" +
+					"  CONSTRAINT `email` UNIQUE (`email`)\n" +
+					")",
+				"CREATE INDEX `fk_user_id` ON `orders` (`user_id`)",
+				"ALTER TABLE `orders` ADD CONSTRAINT `orders_fk_user_id` FOREIGN KEY (`user_id`) REFERENCES `users` (`id`)",
+			},
+		},
+		{
+			scenario: "when mysql is translated to sqlite",
+			dialect:  SQLite,
+			statements: []string{
+				`CREATE TABLE "orders" (
+  "id" INTEGER NOT NULL,
+  "user_id" INTEGER NOT NULL,
+  "reference" TEXT,
+  "status" TEXT NOT NULL DEFAULT 'draft',
+  "total" NUMERIC NOT NULL DEFAULT 0.00,
+  "created_at" DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY ("id"),
+  CONSTRAINT "reference" UNIQUE ("reference"),
+  CONSTRAINT "fk_user_id" FOREIGN KEY ("user_id") REFERENCES "users" ("id")
+)`,
+				`CREATE TABLE "users" (
+  "id" INTEGER NOT NULL,
+  "email" TEXT NOT NULL,
+  "active" BOOLEAN NOT NULL DEFAULT 1,
+  PRIMARY KEY ("id"),
+  CONSTRAINT "email" UNIQUE ("email")
+)`,
+				`CREATE INDEX "orders_fk_user_id" ON "orders" ("user_id")`,
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.scenario, func(t *testing.T) {
+			t.Parallel()
+
+			s, err := Read(rdr, MySQL)
+			require.NoError(t, err)
+
+			statements, err := Render(s, test.dialect)
+			require.NoError(t, err)
+			assert.Equal(t, test.statements, statements)
+		})
+	}
+}
+
+func TestRenderUnsupportedDialect(t *testing.T) {
+	t.Parallel()
+
+	_, err := Render(&Schema{}, "oracle")
+	assert.Error(t, err)
+}
+
+func TestTypeOf(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		scenario string
+		column   *reader.Column
+		dialect  string
+		expected Type
+	}{
+		{scenario: "when the type is a mysql unsigned bigint", column: &reader.Column{Type: "bigint", Unsigned: true}, dialect: MySQL, expected: Type{Name: TypeDecimal, Precision: 20}},
+		{scenario: "when the type is a mysql unsigned tinyint", column: &reader.Column{Type: "tinyint", Unsigned: true}, dialect: MySQL, expected: Type{Name: TypeSmallInt}},
+		{scenario: "when the type is a postgres varchar", column: &reader.Column{Type: "character varying", MaxLength: 20}, dialect: Postgres, expected: Type{Name: TypeVarchar, Length: 20}},
+		{scenario: "when the type is a postgres char without length", column: &reader.Column{Type: "bpchar"}, dialect: Postgres, expected: Type{Name: TypeText}},
+		{scenario: "when the type is a postgres timestamp with time zone", column: &reader.Column{Type: "timestamp with time zone"}, dialect: Postgres, expected: Type{Name: TypeTimestampTZ}},
+		{scenario: "when the type is a postgres numeric", column: &reader.Column{Type: "numeric", Precision: 12, Scale: 4}, dialect: Postgres, expected: Type{Name: TypeDecimal, Precision: 12, Scale: 4}},
+		{scenario: "when the type is a postgres array", column: &reader.Column{Type: "text[]"}, dialect: Postgres, expected: Type{Name: TypeText}},
+		{scenario: "when the type is a sqlite integer", column: &reader.Column{Type: "integer"}, dialect: SQLite, expected: Type{Name: TypeBigInt}},
+		{scenario: "when the type has a sqlite affinity", column: &reader.Column{Type: "unsigned big int"}, dialect: SQLite, expected: Type{Name: TypeBigInt}},
+		{scenario: "when the sqlite column has no type", column: &reader.Column{Type: ""}, dialect: SQLite, expected: Type{Name: TypeBinary}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.scenario, func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, test.expected, typeOf(test.column, test.dialect))
+		})
+	}
+}
+
+func TestDefaultOf(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		scenario string
+		column   *reader.Column
+		dialect  string
+		expected *Default
+	}{
+		{scenario: "when the column has no default", column: &reader.Column{}, dialect: MySQL},
+		{scenario: "when the default is null", column: &reader.Column{Default: ptr("NULL::character varying")}, dialect: Postgres},
+		{scenario: "when the column is an identity", column: &reader.Column{Default: ptr("nextval('users_id_seq'::regclass)"), Identity: true}, dialect: Postgres},
+		{scenario: "when the default is a postgres cast literal", column: &reader.Column{Default: ptr("'it''s'::character varying")}, dialect: Postgres, expected: &Default{Value: "it's"}},
+		{scenario: "when the default is a negative number", column: &reader.Column{Default: ptr("(-1)")}, dialect: Postgres, expected: &Default{Value: "-1"}},
+		{scenario: "when the default is the current time", column: &reader.Column{Default: ptr("now()")}, dialect: Postgres, expected: &Default{CurrentTimestamp: true}},
+		{scenario: "when the default is a mysql string", column: &reader.Column{Default: ptr("draft")}, dialect: MySQL, expected: &Default{Value: "draft"}},
+		{scenario: "when the default is a sqlite quoted string", column: &reader.Column{Default: ptr("'draft'")}, dialect: SQLite, expected: &Default{Value: "draft"}},
+		{scenario: "when the default is an expression", column: &reader.Column{Default: ptr("gen_random_uuid()")}, dialect: Postgres},
+	}
+
+	for _, test := range tests {
+		t.Run(test.scenario, func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, test.expected, defaultOf(test.column, test.dialect, "users"))
+		})
+	}
+}
+
+func TestDialectOf(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, "", DialectOf(""))
+	assert.Equal(t, Postgres, DialectOf("postgres://localhost/klepto"))
+	assert.Equal(t, Postgres, DialectOf("postgresql://localhost/klepto"))
+	assert.Equal(t, SQLite, DialectOf("sqlite:///tmp/klepto.db"))
+	assert.Equal(t, MySQL, DialectOf("root:root@tcp(localhost:3306)/klepto"))
+	assert.Equal(t, Postgres, DialectOf("file:///backups/prod.sql?dialect=postgresql"))
+	assert.Equal(t, MySQL, DialectOf("file:///backups/prod.sql"))
+}
+
+type mockReader struct {
+	reader.Reader
+	tables      []string
+	columns     map[string][]*reader.Column
+	primaryKeys map[string]*reader.Key
+	uniqueKeys  map[string][]*reader.Key
+	indexes     map[string][]*reader.Key
+	foreignKeys map[string][]*reader.ForeignKey
+}
+
+func (m *mockReader) GetTables() ([]string, error) {
+	return m.tables, nil
+}
+
+func (m *mockReader) GetColumns(tableName string) ([]*reader.Column, error) {
+	return m.columns[tableName], nil
+}
+
+func (m *mockReader) GetPrimaryKey(tableName string) (*reader.Key, error) {
+	return m.primaryKeys[tableName], nil
+}
+
+func (m *mockReader) GetUniqueKeys(tableName string) ([]*reader.Key, error) {
+	return m.uniqueKeys[tableName], nil
+}
+
+func (m *mockReader) GetIndexes(tableName string) ([]*reader.Key, error) {
+	return m.indexes[tableName], nil
+}
+
+func (m *mockReader) GetForeignKeys(tableName string) ([]*reader.ForeignKey, error) {
+	return m.foreignKeys[tableName], nil
+}
+
+func ptr(s string) *string {
+	return &s
+}
@@ -0,0 +1,217 @@
+package schema
+
+import (
+	"regexp"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/hellofresh/klepto/pkg/reader"
+)
+
+// Database independent column types.
+const (
+	TypeSmallInt TypeName = iota
+	TypeInteger
+	TypeBigInt
+	TypeReal
+	TypeDouble
+	TypeDecimal
+	TypeBoolean
+	TypeChar
+	TypeVarchar
+	TypeText
+	TypeBinary
+	TypeDate
+	TypeTime
+	TypeTimestamp
+	TypeTimestampTZ
+	TypeJSON
+	TypeUUID
+)
+
+type (
+	// TypeName is a database independent column type.
+	TypeName int
+
+	// Type is a database independent column type and its size.
+	Type struct {
+		// Name is the type.
+		Name TypeName
+		// Length is the maximum length of character and binary types, 0 when unlimited.
+		Length int64
+		// Precision is the number of digits of decimal types, 0 when unlimited.
+		Precision int64
+		// Scale is the number of digits after the decimal point of decimal types.
+		Scale int64
+	}
+)
+
+var (
+	types = map[string]TypeName{
+		"tinyint":                     TypeSmallInt,
+		"smallint":                    TypeSmallInt,
+		"int2":                        TypeSmallInt,
+		"year":                        TypeSmallInt,
+		"mediumint":                   TypeInteger,
+		"int":                         TypeInteger,
+		"integer":                     TypeInteger,
+		"int4":                        TypeInteger,
+		"bigint":                      TypeBigInt,
+		"int8":                        TypeBigInt,
+		"float":                       TypeReal,
+		"real":                        TypeReal,
+		"float4":                      TypeReal,
+		"double":                      TypeDouble,
+		"double precision":            TypeDouble,
+		"float8":                      TypeDouble,
+		"decimal":                     TypeDecimal,
+		"numeric":                     TypeDecimal,
+		"boolean":                     TypeBoolean,
+		"bool":                        TypeBoolean,
+		"char":                        TypeChar,
+		"character":                   TypeChar,
+		"bpchar":                      TypeChar,
+		"varchar":                     TypeVarchar,
+		"character varying":           TypeVarchar,
+		"nvarchar":                    TypeVarchar,
+		"text":                        TypeText,
+		"tinytext":                    TypeText,
+		"mediumtext":                  TypeText,
+		"longtext":                    TypeText,
+		"citext":                      TypeText,
+		"clob":                        TypeText,
+		"enum":                        TypeText,
+		"set":                         TypeText,
+		"binary":                      TypeBinary,
+		"varbinary":                   TypeBinary,
+		"tinyblob":                    TypeBinary,
+		"blob":                        TypeBinary,
+		"mediumblob":                  TypeBinary,
+		"longblob":                    TypeBinary,
+		"bytea":                       TypeBinary,
+		"date":                        TypeDate,
+		"time":                        TypeTime,
+		"time without time zone":      TypeTime,
+		"time with time zone":         TypeTime,
+		"timetz":                      TypeTime,
+		"datetime":                    TypeTimestamp,
+		"timestamp":                   TypeTimestamp,
+		"timestamp without time zone": TypeTimestamp,
+		"timestamp with time zone":    TypeTimestampTZ,
+		"timestamptz":                 TypeTimestampTZ,
+		"json":                        TypeJSON,
+		"jsonb":                       TypeJSON,
+		"uuid":                        TypeUUID,
+	}
+
+	numberRegexp = regexp.MustCompile(`^[+-]?(\d+\.?\d*|\.\d+)([eE][+-]?\d+)?$`)
+	// castRegexp matches the postgres literals cast to their column type, e.g. `'draft'::character varying`.
+	castRegexp = regexp.MustCompile(`^\(?'((?:[^']|'')*)'::[\w\s."\[\]]+\)?$`)
+	// quotedRegexp matches the quoted string literals.
+	quotedRegexp = regexp.MustCompile(`^'((?:[^']|'')*)'$`)
+	// currentTimestampRegexp matches the functions returning the current time.
+	currentTimestampRegexp = regexp.MustCompile(`(?i)^(current_timestamp|now|localtimestamp|transaction_timestamp)(\(\d*\))?$`)
+)
+
+// typeOf returns the database independent type of the column of a database of the dialect.
+func typeOf(c *reader.Column, dialect string) Type {
+	name := strings.ToLower(c.Type)
+	typeName, ok := types[name]
+	if !ok {
+		typeName = TypeText
+		if dialect == SQLite {
+			typeName = affinityOf(name)
+		} else {
+			log.WithField("type", c.Type).Debug("unknown column type, it is translated as text")
+		}
+	}
+
+	switch {
+	case dialect == SQLite && typeName == TypeInteger:
+		// sqlite integers are 64 bits integers
+		typeName = TypeBigInt
+	case dialect == SQLite && typeName == TypeReal:
+		typeName = TypeDouble
+	case c.Unsigned && typeName == TypeSmallInt && name != "tinyint":
+		typeName = TypeInteger
+	case c.Unsigned && typeName == TypeInteger:
+		typeName = TypeBigInt
+	case c.Unsigned && typeName == TypeBigInt:
+		return Type{Name: TypeDecimal, Precision: 20}
+	}
+
+	t := Type{Name: typeName}
+	switch typeName {
+	case TypeChar, TypeVarchar, TypeBinary:
+		t.Length = c.MaxLength
+	case TypeDecimal:
+		t.Precision, t.Scale = c.Precision, c.Scale
+	}
+
+	// characters without length are unlimited
+	if typeName == TypeChar && t.Length == 0 {
+		t.Name = TypeText
+	}
+
+	return t
+}
+
+// affinityOf returns the type of the sqlite type affinity of the column type name.
+func affinityOf(name string) TypeName {
+	switch {
+	case strings.Contains(name, "int"):
+		return TypeBigInt
+	case strings.Contains(name, "char"), strings.Contains(name, "clob"), strings.Contains(name, "text"):
+		return TypeText
+	case name == "", strings.Contains(name, "blob"):
+		return TypeBinary
+	case strings.Contains(name, "real"), strings.Contains(name, "floa"), strings.Contains(name, "doub"):
+		return TypeDouble
+	default:
+		return TypeDecimal
+	}
+}
+
+// defaultOf returns the default of the column, only the literals and the current time can be translated.
+func defaultOf(c *reader.Column, dialect string, tableName string) *Default {
+	if c.Default == nil || c.Identity {
+		return nil
+	}
+
+	value := strings.TrimSpace(*c.Default)
+	switch {
+	case strings.EqualFold(value, "null") || strings.HasPrefix(strings.ToLower(value), "null::"):
+		return nil
+	case currentTimestampRegexp.MatchString(value):
+		return &Default{CurrentTimestamp: true}
+	case numberRegexp.MatchString(strings.Trim(value, "()")):
+		return &Default{Value: strings.Trim(value, "()")}
+	case strings.EqualFold(value, "true") || strings.EqualFold(value, "false"):
+		return &Default{Value: strings.ToLower(value)}
+	case dialect == MySQL:
+		// mysql returns the string literals unquoted
+		return &Default{Value: value}
+	}
+
+	if match := castRegexp.FindStringSubmatch(value); match != nil && dialect == Postgres {
+		return &Default{Value: strings.ReplaceAll(match[1], "''", "'")}
+	}
+	if match := quotedRegexp.FindStringSubmatch(value); match != nil {
+		return &Default{Value: strings.ReplaceAll(match[1], "''", "'")}
+	}
+
+	log.WithFields(log.Fields{"table": tableName, "column": c.Name, "default": value}).
+		Warn("Column default can't be translated, the column is created without default")
+	return nil
+}
+
+// isInteger returns whether the type is an integer type.
+func (t Type) isInteger() bool {
+	return t.Name == TypeSmallInt || t.Name == TypeInteger || t.Name == TypeBigInt
+}
+
+// isNumeric returns whether the type is a number type.
+func (t Type) isNumeric() bool {
+	return t.isInteger() || t.Name == TypeReal || t.Name == TypeDouble || t.Name == TypeDecimal
+}
@@ -0,0 +1,399 @@
+package subset
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/hellofresh/klepto/pkg/config"
+	"github.com/hellofresh/klepto/pkg/database"
+	"github.com/hellofresh/klepto/pkg/reader"
+)
+
+// batchSize is the maximum number of key values fetched by a single query.
+const batchSize = 500
+
+// nullKey is the key used for null values, it can not be produced by a non null value.
+const nullKey = "\x00NULL"
+
+type (
+	// Opts are the subset options.
+	Opts struct {
+		// Children if set to true, the rows referencing the seed rows are also pulled.
+		Children bool
+		// MaxRows is the maximum number of rows of the subset, 0 does not limit it.
+		MaxRows int
+	}
+
+	subset struct {
+		reader.Reader
+		opts Opts
+
+		// parents are the foreign keys by table holding them
+		parents map[string][]*reader.ForeignKey
+		// children are the foreign keys by referenced table
+		children map[string][]*reader.ForeignKey
+
+		// rows are the rows of the subset by table
+		rows map[string][]database.Row
+		// seen are the keys of the rows already in the subset by table, true when their children were followed
+		seen map[string]map[string]bool
+		// requested are the key values already fetched by table and key columns, true when fetched with their children
+		requested map[string]map[string]bool
+		// total is the number of rows of the subset
+		total int
+	}
+
+	// pending are rows added to the subset which relationships were not followed yet.
+	pending struct {
+		table string
+		rows  []database.Row
+		// withChildren is false for rows pulled as parents, following their children
+		// would pull unrelated rows (e.g. all the users of the country of a user).
+		withChildren bool
+	}
+)
+
+// New computes a referentially complete subset of the source database and returns
+// a reader publishing only the subset rows.
+// The subset starts from the seed tables rows matching their filter and transitively pulls
+// the rows they reference through the database foreign keys and the configured relationships.
+// The rows of the subset and the keys of the rows pulled are held in memory until the subset is dumped,
+// the subset fails once it holds more than opts.MaxRows rows.
+func New(ctx context.Context, source reader.Reader, tables config.Tables, opts Opts) (reader.Reader, error) {
+	s := &subset{
+		Reader:    source,
+		opts:      opts,
+		parents:   make(map[string][]*reader.ForeignKey),
+		children:  make(map[string][]*reader.ForeignKey),
+		rows:      make(map[string][]database.Row),
+		seen:      make(map[string]map[string]bool),
+		requested: make(map[string]map[string]bool),
+	}
+
+	if err := s.loadForeignKeys(tables); err != nil {
+		return nil, err
+	}
+
+	var queue []pending
+	for _, table := range tables.Seeds() {
+		logger := log.WithField("table", table.Name)
+		if table.IgnoreData {
+			logger.Warn("seed table data is ignored, skipping it")
+			continue
+		}
+
+		logger.Debug("reading seed rows")
+		rows, err := s.fetch(ctx, table.Name, reader.NewReadTableOpt(table))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read seed table %s: %w", table.Name, err)
+		}
+
+		added, err := s.add(table.Name, rows, true)
+		if err != nil {
+			return nil, err
+		}
+
+		queue = append(queue, pending{table: table.Name, rows: added, withChildren: true})
+	}
+
+	for len(queue) > 0 {
+		p := queue[0]
+		queue = queue[1:]
+
+		for _, fk := range s.parents[p.table] {
+			rows, err := s.fetchByKey(ctx, fk.ReferencedTable, fk.ReferencedColumns, keyValues(p.rows, fk.Columns), false)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read %s rows referenced by %s: %w", fk.ReferencedTable, p.table, err)
+			}
+			if len(rows) > 0 {
+				queue = append(queue, pending{table: fk.ReferencedTable, rows: rows})
+			}
+		}
+
+		if !s.opts.Children || !p.withChildren {
+			continue
+		}
+
+		for _, fk := range s.children[p.table] {
+			rows, err := s.fetchByKey(ctx, fk.Table, fk.Columns, keyValues(p.rows, fk.ReferencedColumns), true)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read %s rows referencing %s: %w", fk.Table, p.table, err)
+			}
+			if len(rows) > 0 {
+				queue = append(queue, pending{table: fk.Table, rows: rows, withChildren: true})
+			}
+		}
+	}
+
+	for table, rows := range s.rows {
+		log.WithFields(log.Fields{"table": table, "rows": len(rows)}).Debug("subset computed")
+	}
+
+	return s, nil
+}
+
+// ReadTable publishes the subset rows of the table.
+func (s *subset) ReadTable(ctx context.Context, tableName string, rowChan chan<- database.Row, opts reader.ReadTableOpt) error {
+	defer close(rowChan)
+
+	for _, row := range s.rows[tableName] {
+		select {
+		case rowChan <- row:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	return nil
+}
+
+// loadForeignKeys loads the foreign keys of all tables and adds the configured relationships.
+func (s *subset) loadForeignKeys(tables config.Tables) error {
+	tableNames, err := s.Reader.GetTables()
+	if err != nil {
+		return fmt.Errorf("failed to get tables: %w", err)
+	}
+
+	for _, tableName := range tableNames {
+		foreignKeys, err := s.Reader.GetForeignKeys(tableName)
+		if err != nil {
+			return fmt.Errorf("failed to get foreign keys of %s: %w", tableName, err)
+		}
+
+		for _, fk := range foreignKeys {
+			s.addForeignKey(fk)
+		}
+	}
+
+	for _, table := range tables {
+		for _, r := range table.Relationships {
+			fkTable := r.Table
+			if fkTable == "" {
+				fkTable = table.Name
+			}
+
+			s.addForeignKey(&reader.ForeignKey{
+				Name:              fmt.Sprintf("%s_%s_relationship", fkTable, r.ForeignKey),
+				Table:             fkTable,
+				Columns:           []string{r.ForeignKey},
+				ReferencedTable:   r.ReferencedTable,
+				ReferencedColumns: []string{r.ReferencedKey},
+			})
+		}
+	}
+
+	return nil
+}
+
+func (s *subset) addForeignKey(fk *reader.ForeignKey) {
+	for _, existing := range s.parents[fk.Table] {
+		if existing.ReferencedTable == fk.ReferencedTable &&
+			strings.Join(existing.Columns, ",") == strings.Join(fk.Columns, ",") &&
+			strings.Join(existing.ReferencedColumns, ",") == strings.Join(fk.ReferencedColumns, ",") {
+			return
+		}
+	}
+
+	s.parents[fk.Table] = append(s.parents[fk.Table], fk)
+	s.children[fk.ReferencedTable] = append(s.children[fk.ReferencedTable], fk)
+}
+
+// fetchByKey fetches the rows of the table matching the key values which were not requested yet with the same
+// or a stronger mode, and returns the ones which were not in the subset or which children were not followed yet.
+func (s *subset) fetchByKey(ctx context.Context, tableName string, columns []string, values [][]interface{}, withChildren bool) ([]database.Row, error) {
+	if err := s.keyArgs(tableName, columns, values); err != nil {
+		return nil, err
+	}
+
+	requestKey := tableName + "\x00" + strings.Join(columns, "\x00")
+	requested, ok := s.requested[requestKey]
+	if !ok {
+		requested = make(map[string]bool)
+		s.requested[requestKey] = requested
+	}
+
+	var missing [][]interface{}
+	for _, v := range values {
+		k := key(v)
+		if children, ok := requested[k]; ok && (children || !withChildren) {
+			continue
+		}
+
+		requested[k] = withChildren
+		missing = append(missing, v)
+	}
+
+	var added []database.Row
+	for start := 0; start < len(missing); start += batchSize {
+		end := start + batchSize
+		if end > len(missing) {
+			end = len(missing)
+		}
+
+		rows, err := s.fetch(ctx, tableName, reader.ReadTableOpt{
+			Keys: &reader.KeysOpt{Columns: columns, Values: missing[start:end]},
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		rows, err = s.add(tableName, rows, withChildren)
+		if err != nil {
+			return nil, err
+		}
+		added = append(added, rows...)
+	}
+
+	return added, nil
+}
+
+// keyArgs converts the key values to the query arguments matching the key columns of the table. The binary values
+// are kept as bytes for the binary columns, and compared as strings for the other columns, some drivers return
+// them for non binary types (e.g. uuid).
+func (s *subset) keyArgs(tableName string, columns []string, values [][]interface{}) error {
+	tableColumns, err := s.Reader.GetColumns(tableName)
+	if err != nil {
+		return fmt.Errorf("failed to get columns of %s: %w", tableName, err)
+	}
+
+	binary := make([]bool, len(columns))
+	for i, name := range columns {
+		for _, c := range tableColumns {
+			if c.Name == name {
+				binary[i] = c.Kind() == reader.KindBinary
+			}
+		}
+	}
+
+	for _, v := range values {
+		for i := range v {
+			if b, ok := v[i].([]byte); ok && !binary[i] {
+				v[i] = string(b)
+			}
+		}
+	}
+
+	return nil
+}
+
+// fetch reads the table rows matching the given options.
+func (s *subset) fetch(ctx context.Context, tableName string, opts reader.ReadTableOpt) ([]database.Row, error) {
+	rowChan := make(chan database.Row)
+	errChan := make(chan error, 1)
+	go func() {
+		errChan <- s.Reader.ReadTable(ctx, tableName, rowChan, opts)
+	}()
+
+	var rows []database.Row
+	for row := range rowChan {
+		rows = append(rows, row)
+	}
+
+	return rows, <-errChan
+}
+
+// add adds the rows to the subset and returns the ones which were not in the subset yet, and the ones
+// which were pulled as parents when they are now pulled with their children, so their children are followed.
+// It fails when the subset holds more than the maximum number of rows.
+func (s *subset) add(tableName string, rows []database.Row, withChildren bool) ([]database.Row, error) {
+	columns, err := s.identity(tableName)
+	if err != nil {
+		log.WithError(err).WithField("table", tableName).Warn("failed to get row identity, rows can not be deduplicated")
+	}
+
+	seen, ok := s.seen[tableName]
+	if !ok {
+		seen = make(map[string]bool)
+		s.seen[tableName] = seen
+	}
+
+	var added, upgraded []database.Row
+	for _, row := range rows {
+		k := key(rowValues(row, columns))
+		children, ok := seen[k]
+		if columns != nil && ok {
+			if !children && withChildren {
+				seen[k] = true
+				upgraded = append(upgraded, row)
+			}
+			continue
+		}
+
+		seen[k] = withChildren
+		added = append(added, row)
+	}
+
+	s.rows[tableName] = append(s.rows[tableName], added...)
+	s.total += len(added)
+	if s.opts.MaxRows > 0 && s.total > s.opts.MaxRows {
+		return nil, fmt.Errorf("subset has more than %d rows, reached while adding %s rows", s.opts.MaxRows, tableName)
+	}
+
+	return append(added, upgraded...), nil
+}
+
+// identity returns the columns identifying a table row, the primary key if any, all the columns otherwise.
+func (s *subset) identity(tableName string) ([]string, error) {
+	primaryKey, err := s.Reader.GetPrimaryKey(tableName)
+	if err != nil {
+		return nil, err
+	}
+	if primaryKey != nil {
+		return primaryKey.Columns, nil
+	}
+
+	columns, err := s.Reader.GetColumns(tableName)
+	if err != nil {
+		return nil, err
+	}
+
+	return reader.ColumnNames(columns), nil
+}
+
+// keyValues returns the distinct non null values of the given columns.
+func keyValues(rows []database.Row, columns []string) [][]interface{} {
+	seen := make(map[string]bool)
+
+	var values [][]interface{}
+	for _, row := range rows {
+		v := rowValues(row, columns)
+		k := key(v)
+		if seen[k] || strings.Contains(k, nullKey) {
+			continue
+		}
+
+		seen[k] = true
+		values = append(values, v)
+	}
+
+	return values
+}
+
+func rowValues(row database.Row, columns []string) []interface{} {
+	values := make([]interface{}, len(columns))
+	for i, c := range columns {
+		values[i] = row[c]
+	}
+
+	return values
+}
+
+// key returns a comparable representation of the values.
+func key(values []interface{}) string {
+	parts := make([]string, len(values))
+	for i, v := range values {
+		switch v := v.(type) {
+		case nil:
+			parts[i] = nullKey
+		case []byte:
+			parts[i] = string(v)
+		default:
+			parts[i] = fmt.Sprint(v)
+		}
+	}
+
+	return strings.Join(parts, "\x00\x00")
+}
@@ -0,0 +1,210 @@
+package subset
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/hellofresh/klepto/pkg/config"
+	"github.com/hellofresh/klepto/pkg/database"
+	"github.com/hellofresh/klepto/pkg/reader"
+)
+
+func TestNew(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		scenario string
+		tables   config.Tables
+		opts     Opts
+		expected map[string][]int64
+	}{
+		{
+			scenario: "when parents are pulled",
+			tables:   config.Tables{{Name: "orders", Seed: true, Filter: config.Filter{Match: "id = 1"}}},
+			expected: map[string][]int64{"orders": {1}, "users": {10}, "countries": {100}},
+		},
+		{
+			scenario: "when children are pulled",
+			tables:   config.Tables{{Name: "orders", Seed: true, Filter: config.Filter{Match: "id = 1"}}},
+			opts:     Opts{Children: true},
+			expected: map[string][]int64{"orders": {1}, "users": {10}, "countries": {100}, "order_items": {1000, 1001}},
+		},
+		{
+			scenario: "when a row pulled as a parent is then pulled with its children",
+			tables: config.Tables{
+				{Name: "order_items", Seed: true, Filter: config.Filter{Match: "id = 1002"}},
+				{Name: "users", Seed: true, Filter: config.Filter{Match: "id = 10"}},
+			},
+			opts:     Opts{Children: true},
+			expected: map[string][]int64{"orders": {1, 2}, "users": {10}, "countries": {100}, "order_items": {1000, 1001, 1002, 1003}},
+		},
+		{
+			scenario: "when relationships are configured",
+			tables: config.Tables{{
+				Name:          "orders",
+				Seed:          true,
+				Filter:        config.Filter{Match: "id = 3"},
+				Relationships: []*config.Relationship{{ForeignKey: "coupon_id", ReferencedTable: "coupons", ReferencedKey: "id"}},
+			}},
+			expected: map[string][]int64{"orders": {3}, "users": {11}, "countries": {100}, "coupons": {7}},
+		},
+		{
+			scenario: "when there is no seed",
+			tables:   config.Tables{{Name: "orders"}},
+			expected: map[string][]int64{},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.scenario, func(t *testing.T) {
+			rdr, err := New(context.Background(), newMockReader(), test.tables, test.opts)
+			require.NoError(t, err)
+
+			actual := make(map[string][]int64)
+			for _, table := range []string{"countries", "users", "orders", "order_items", "coupons"} {
+				rowChan := make(chan database.Row)
+				go func() {
+					require.NoError(t, rdr.ReadTable(context.Background(), table, rowChan, reader.ReadTableOpt{}))
+				}()
+
+				for row := range rowChan {
+					actual[table] = append(actual[table], row["id"].(int64))
+				}
+				sort.Slice(actual[table], func(i, j int) bool { return actual[table][i] < actual[table][j] })
+			}
+
+			assert.Equal(t, test.expected, actual)
+		})
+	}
+}
+
+func TestNewMaxRows(t *testing.T) {
+	t.Parallel()
+
+	tables := config.Tables{{Name: "orders", Seed: true, Filter: config.Filter{Match: "id = 1"}}}
+
+	_, err := New(context.Background(), newMockReader(), tables, Opts{Children: true, MaxRows: 3})
+	assert.EqualError(t, err, "failed to read order_items rows referencing orders: subset has more than 3 rows, reached while adding order_items rows")
+
+	_, err = New(context.Background(), newMockReader(), tables, Opts{Children: true, MaxRows: 5})
+	assert.NoError(t, err)
+}
+
+func TestKeyArgs(t *testing.T) {
+	t.Parallel()
+
+	s := &subset{Reader: &mockReader{columns: map[string][]*reader.Column{
+		"files": {{Name: "hash", Type: "bytea"}, {Name: "uuid", Type: "uuid"}},
+	}}}
+
+	values := [][]interface{}{{[]byte{0xde, 0xad}, []byte("a0eebc99-9c0b-4ef8-bb6d-6bb9bd380a11")}}
+	require.NoError(t, s.keyArgs("files", []string{"hash", "uuid"}, values))
+	assert.Equal(t, [][]interface{}{{[]byte{0xde, 0xad}, "a0eebc99-9c0b-4ef8-bb6d-6bb9bd380a11"}}, values)
+}
+
+type mockReader struct {
+	reader.Reader
+	data        map[string][]database.Row
+	columns     map[string][]*reader.Column
+	foreignKeys map[string][]*reader.ForeignKey
+}
+
+func newMockReader() *mockReader {
+	return &mockReader{
+		data: map[string][]database.Row{
+			"countries": {{"id": int64(100)}, {"id": int64(101)}},
+			"users": {
+				{"id": int64(10), "country_id": int64(100)},
+				{"id": int64(11), "country_id": int64(100)},
+				{"id": int64(12), "country_id": int64(101)},
+			},
+			"orders": {
+				{"id": int64(1), "user_id": int64(10), "coupon_id": nil},
+				{"id": int64(2), "user_id": int64(10), "coupon_id": nil},
+				{"id": int64(3), "user_id": int64(11), "coupon_id": int64(7)},
+			},
+			"order_items": {
+				{"id": int64(1000), "order_id": int64(1)},
+				{"id": int64(1001), "order_id": int64(1)},
+				{"id": int64(1002), "order_id": int64(2)},
+				{"id": int64(1003), "order_id": int64(2)},
+			},
+			"coupons": {{"id": int64(7)}, {"id": int64(8)}},
+		},
+		foreignKeys: map[string][]*reader.ForeignKey{
+			"users":       {{Name: "fk_country", Table: "users", Columns: []string{"country_id"}, ReferencedTable: "countries", ReferencedColumns: []string{"id"}}},
+			"orders":      {{Name: "fk_user", Table: "orders", Columns: []string{"user_id"}, ReferencedTable: "users", ReferencedColumns: []string{"id"}}},
+			"order_items": {{Name: "fk_order", Table: "order_items", Columns: []string{"order_id"}, ReferencedTable: "orders", ReferencedColumns: []string{"id"}}},
+		},
+	}
+}
+
+func (m *mockReader) GetTables() ([]string, error) {
+	return []string{"countries", "users", "orders", "order_items", "coupons"}, nil
+}
+
+func (m *mockReader) GetColumns(table string) ([]*reader.Column, error) {
+	if columns, ok := m.columns[table]; ok {
+		return columns, nil
+	}
+
+	var columns []*reader.Column
+	for column := range m.data[table][0] {
+		columns = append(columns, &reader.Column{Name: column, Type: "bigint"})
+	}
+	sort.Slice(columns, func(i, j int) bool { return columns[i].Name < columns[j].Name })
+	return columns, nil
+}
+
+func (m *mockReader) GetPrimaryKey(table string) (*reader.Key, error) {
+	if table == "coupons" {
+		return nil, nil
+	}
+	return &reader.Key{Name: table + "_pkey", Columns: []string{"id"}}, nil
+}
+
+func (m *mockReader) GetForeignKeys(table string) ([]*reader.ForeignKey, error) {
+	return m.foreignKeys[table], nil
+}
+
+func (m *mockReader) ReadTable(_ context.Context, table string, rowChan chan<- database.Row, opts reader.ReadTableOpt) error {
+	defer close(rowChan)
+
+	for _, row := range m.data[table] {
+		if opts.Match != "" && opts.Match != fmt.Sprintf("id = %d", row["id"]) {
+			continue
+		}
+		if opts.Keys != nil && !matchKeys(row, opts.Keys) {
+			continue
+		}
+
+		copied := make(database.Row, len(row))
+		for k, v := range row {
+			copied[k] = v
+		}
+		rowChan <- copied
+	}
+
+	return nil
+}
+
+func matchKeys(row database.Row, keys *reader.KeysOpt) bool {
+	for _, values := range keys.Values {
+		matched := true
+		for i, column := range keys.Columns {
+			if fmt.Sprint(row[column]) != fmt.Sprint(values[i]) {
+				matched = false
+			}
+		}
+		if matched {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,159 @@
+package validator
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/hellofresh/klepto/pkg/anonymiser"
+	"github.com/hellofresh/klepto/pkg/config"
+	"github.com/hellofresh/klepto/pkg/reader"
+)
+
+type (
+	// Problem describes an issue found in a table configuration.
+	Problem struct {
+		// Table is the configured table name.
+		Table string
+		// Message describes the issue.
+		Message string
+	}
+
+	validator struct {
+		reader  reader.Reader
+		tables  map[string]bool
+		columns map[string]map[string]bool
+	}
+)
+
+// String returns a readable representation of the problem.
+func (p Problem) String() string {
+	return fmt.Sprintf("%s: %s", p.Table, p.Message)
+}
+
+// Validate checks the tables configuration against the source database schema.
+// It returns the problems found, the error is only set when the schema can not be read.
+func Validate(ctx context.Context, rdr reader.Reader, cfgTables config.Tables) ([]Problem, error) {
+	tables, err := rdr.GetTables()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get tables: %w", err)
+	}
+
+	v := &validator{
+		reader:  rdr,
+		tables:  make(map[string]bool, len(tables)),
+		columns: make(map[string]map[string]bool),
+	}
+	for _, tbl := range tables {
+		v.tables[tbl] = true
+	}
+
+	var problems []Problem
+	for _, table := range cfgTables {
+		log.WithField("table", table.Name).Debug("validating table config")
+
+		tableProblems, err := v.validateTable(ctx, table)
+		if err != nil {
+			return nil, err
+		}
+		problems = append(problems, tableProblems...)
+	}
+
+	return problems, nil
+}
+
+func (v *validator) validateTable(ctx context.Context, table *config.Table) ([]Problem, error) {
+	var problems []Problem
+	report := func(format string, args ...interface{}) {
+		problems = append(problems, Problem{Table: table.Name, Message: fmt.Sprintf(format, args...)})
+	}
+
+	// the problems are reported under the table name, the messages do not repeat it
+	anonymised := slices.Sorted(maps.Keys(table.Anonymise))
+	for _, column := range anonymised {
+		if err := anonymiser.ValidateSpec(table.Anonymise[column]); err != nil {
+			report("anonymised column %q: %s", column, err)
+		}
+	}
+
+	if !v.tables[table.Name] {
+		report("table does not exist")
+		return problems, nil
+	}
+
+	for _, column := range anonymised {
+		exists, err := v.hasColumn(table.Name, column)
+		if err != nil {
+			return nil, err
+		}
+		if !exists {
+			report("anonymised column %q does not exist", column)
+		}
+	}
+
+	for _, r := range table.Relationships {
+		relationshipTable := r.Table
+		if relationshipTable == "" {
+			relationshipTable = table.Name
+		}
+
+		for _, key := range []struct{ table, column string }{
+			{relationshipTable, r.ForeignKey},
+			{r.ReferencedTable, r.ReferencedKey},
+		} {
+			if !v.tables[key.table] {
+				report("relationship table %q does not exist", key.table)
+				continue
+			}
+
+			exists, err := v.hasColumn(key.table, key.column)
+			if err != nil {
+				return nil, err
+			}
+			switch {
+			case exists:
+			case key.table == table.Name:
+				report("relationship column %q does not exist", key.column)
+			default:
+				report("relationship column %q does not exist in table %q", key.column, key.table)
+			}
+		}
+	}
+
+	if table.IgnoreData {
+		return problems, nil
+	}
+
+	queryValidator, ok := v.reader.(reader.QueryValidator)
+	if !ok {
+		log.WithField("table", table.Name).Debug("reader is not able to validate queries")
+		return problems, nil
+	}
+
+	if err := queryValidator.ValidateQuery(ctx, table.Name, reader.NewReadTableOpt(table)); err != nil {
+		report("invalid read query: %s", err)
+	}
+
+	return problems, nil
+}
+
+func (v *validator) hasColumn(tableName, columnName string) (bool, error) {
+	columns, ok := v.columns[tableName]
+	if !ok {
+		list, err := v.reader.GetColumns(tableName)
+		if err != nil {
+			return false, fmt.Errorf("failed to get columns of %s: %w", tableName, err)
+		}
+
+		columns = make(map[string]bool, len(list))
+		for _, c := range list {
+			columns[c.Name] = true
+		}
+		v.columns[tableName] = columns
+	}
+
+	return columns[columnName], nil
+}
@@ -0,0 +1,99 @@
+package validator
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/hellofresh/klepto/pkg/config"
+	"github.com/hellofresh/klepto/pkg/database"
+	"github.com/hellofresh/klepto/pkg/reader"
+)
+
+func TestValidate(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		scenario string
+		tables   config.Tables
+		problems []Problem
+	}{
+		{
+			scenario: "when config matches the schema",
+			tables: config.Tables{
+				{Name: "users", Anonymise: map[string]string{"email": "EmailAddress"}},
+				{Name: "orders", Relationships: []*config.Relationship{{ForeignKey: "user_id", ReferencedTable: "users", ReferencedKey: "id"}}},
+			},
+		},
+		{
+			scenario: "when table does not exist",
+			tables:   config.Tables{{Name: "customers"}},
+			problems: []Problem{{Table: "customers", Message: "table does not exist"}},
+		},
+		{
+			scenario: "when anonymised column does not exist",
+			tables:   config.Tables{{Name: "users", Anonymise: map[string]string{"mail": "EmailAddress"}}},
+			problems: []Problem{{Table: "users", Message: `anonymised column "mail" does not exist`}},
+		},
+		{
+			scenario: "when anonymiser is invalid",
+			tables:   config.Tables{{Name: "users", Anonymise: map[string]string{"email": "Email"}}},
+			problems: []Problem{{Table: "users", Message: `anonymised column "email": unknown anonymiser "Email"`}},
+		},
+		{
+			scenario: "when relationship is invalid",
+			tables: config.Tables{
+				{Name: "orders", Relationships: []*config.Relationship{{ForeignKey: "customer_id", ReferencedTable: "customers", ReferencedKey: "id"}}},
+			},
+			problems: []Problem{
+				{Table: "orders", Message: `relationship column "customer_id" does not exist`},
+				{Table: "orders", Message: `relationship table "customers" does not exist`},
+			},
+		},
+		{
+			scenario: "when read query is invalid",
+			tables:   config.Tables{{Name: "users", Filter: config.Filter{Match: "invalid"}}},
+			problems: []Problem{{Table: "users", Message: "invalid read query: syntax error"}},
+		},
+		{
+			scenario: "when data is ignored",
+			tables:   config.Tables{{Name: "users", IgnoreData: true, Filter: config.Filter{Match: "invalid"}}},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.scenario, func(t *testing.T) {
+			problems, err := Validate(context.Background(), &mockReader{}, test.tables)
+			require.NoError(t, err)
+			assert.Equal(t, test.problems, problems)
+		})
+	}
+}
+
+type mockReader struct{}
+
+func (m *mockReader) GetTables() ([]string, error)  { return []string{"users", "orders"}, nil }
+func (m *mockReader) GetStructure() (string, error) { return "", nil }
+func (m *mockReader) GetColumns(table string) ([]*reader.Column, error) {
+	if table == "orders" {
+		return []*reader.Column{{Name: "id", Type: "integer"}, {Name: "user_id", Type: "integer"}}, nil
+	}
+	return []*reader.Column{{Name: "id", Type: "integer"}, {Name: "email", Type: "varchar"}}, nil
+}
+func (m *mockReader) GetPrimaryKey(string) (*reader.Key, error)           { return nil, nil }
+func (m *mockReader) GetUniqueKeys(string) ([]*reader.Key, error)         { return nil, nil }
+func (m *mockReader) GetForeignKeys(string) ([]*reader.ForeignKey, error) { return nil, nil }
+func (m *mockReader) Close() error                                        { return nil }
+func (m *mockReader) FormatColumn(tbl string, col string) string          { return tbl + "." + col }
+func (m *mockReader) ReadTable(context.Context, string, chan<- database.Row, reader.ReadTableOpt) error {
+	return nil
+}
+func (m *mockReader) ValidateQuery(_ context.Context, tableName string, opts reader.ReadTableOpt) error {
+	if opts.Match == "invalid" {
+		return errors.New("syntax error")
+	}
+	return nil
+}